@@ -0,0 +1,99 @@
+package kqlfilter
+
+import "context"
+
+// ParseSession parses filters into ASTs whose nodes are allocated from an arena owned by the session, instead of
+// allocating every node separately, to reduce the pressure on the garbage collector of services parsing many filters.
+// The arena is reused by all calls until Release is called, which makes the nodes of all ASTs returned so far
+// available for reuse:
+//
+//	session := kqlfilter.NewParseSession(kqlfilter.WithMaxComplexity(10))
+//	defer session.Release()
+//	ast, err := session.ParseAST(input)
+//
+// The ASTs must not be used after Release; use Node.Clone to keep a copy of an AST that is allocated normally.
+// Only the nodes are allocated from the arena, not the slices of And and Or nodes. A ParseSession is not safe for
+// concurrent use, but sessions can be reused, e.g. with a sync.Pool.
+type ParseSession struct {
+	options []ParserOption
+	arena   nodeArena
+}
+
+// NewParseSession returns a session parsing filters with the given options.
+func NewParseSession(options ...ParserOption) *ParseSession {
+	return &ParseSession{options: options}
+}
+
+// ParseAST parses the input like ParseAST with the options of the session.
+func (s *ParseSession) ParseAST(input string) (Node, error) {
+	return parseAST(context.Background(), input, s.options, &s.arena)
+}
+
+// ParseASTContext parses the input like ParseASTContext with the options of the session.
+func (s *ParseSession) ParseASTContext(ctx context.Context, input string) (Node, error) {
+	return parseAST(ctx, input, s.options, &s.arena)
+}
+
+// Release releases the nodes of all ASTs parsed by the session for reuse by the next calls. The ASTs must not be
+// used anymore.
+func (s *ParseSession) Release() {
+	s.arena.reset()
+}
+
+// arenaChunkSize is the number of nodes of a type allocated at once by a nodeArena.
+const arenaChunkSize = 32
+
+// nodeArena allocates nodes in chunks per node type.
+type nodeArena struct {
+	ors      slab[OrNode]
+	ands     slab[AndNode]
+	nots     slab[NotNode]
+	is       slab[IsNode]
+	ranges   slab[RangeNode]
+	nesteds  slab[NestedNode]
+	literals slab[LiteralNode]
+	exists   slab[ExistsNode]
+	fuzzies  slab[FuzzyNode]
+}
+
+func (a *nodeArena) reset() {
+	a.ors.reset()
+	a.ands.reset()
+	a.nots.reset()
+	a.is.reset()
+	a.ranges.reset()
+	a.nesteds.reset()
+	a.literals.reset()
+	a.exists.reset()
+	a.fuzzies.reset()
+}
+
+// slab allocates values of type T from chunks of arenaChunkSize values, which are kept when it is reset.
+// Chunks are never grown, so pointers to allocated values stay valid until reset.
+type slab[T any] struct {
+	chunks [][]T
+	chunk  int // index of the chunk to allocate from
+	n      int // number of allocated values in that chunk
+}
+
+// alloc returns a pointer to a zero value.
+func (s *slab[T]) alloc() *T {
+	if s.chunk < len(s.chunks) && s.n == arenaChunkSize {
+		s.chunk++
+		s.n = 0
+	}
+	if s.chunk == len(s.chunks) {
+		s.chunks = append(s.chunks, make([]T, arenaChunkSize))
+	}
+	v := &s.chunks[s.chunk][s.n]
+	s.n++
+	return v
+}
+
+// reset zeroes the allocated values, so that they don't keep the input alive, and makes them available again.
+func (s *slab[T]) reset() {
+	for i := 0; i < len(s.chunks) && i <= s.chunk; i++ {
+		clear(s.chunks[i])
+	}
+	s.chunk, s.n = 0, 0
+}
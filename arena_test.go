@@ -0,0 +1,76 @@
+package kqlfilter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSession(t *testing.T) {
+	session := NewParseSession(WithMaxComplexity(100), WithFuzzyMatching())
+
+	inputs := []string{
+		"a:1 or not b:(x or y)",
+		"c>=2 d:{e:f} g:* h:jon~1",
+		strings.Repeat("i:1 and ", 50) + "j:2",
+	}
+	for _, input := range inputs {
+		expected, err := ParseAST(input, WithMaxComplexity(100), WithFuzzyMatching())
+		require.NoError(t, err)
+		ast, err := session.ParseAST(input)
+		require.NoError(t, err)
+		assert.Equal(t, expected, ast)
+	}
+
+	kept, err := session.ParseAST("k:1")
+	require.NoError(t, err)
+	kept = kept.Clone()
+	session.Release()
+	assert.Equal(t, "k=1", kept.String())
+
+	literal, err := session.ParseAST("l:2")
+	require.NoError(t, err)
+	value := literal.(*IsNode).Value.(*LiteralNode)
+	session.Release()
+	assert.Empty(t, value.Value, "released nodes must not keep the input alive")
+
+	ast, err := session.ParseAST("m:3")
+	require.NoError(t, err)
+	assert.Equal(t, "m=3", ast.String())
+	assert.Same(t, literal, ast, "released nodes are reused")
+
+	_, err = session.ParseAST("n:(")
+	assert.Error(t, err)
+}
+
+func TestParseSessionAllocations(t *testing.T) {
+	session := NewParseSession()
+	allocs := testing.AllocsPerRun(100, func() {
+		_, err := session.ParseAST(concurrencyTestInput)
+		if err != nil {
+			t.Fatal(err)
+		}
+		session.Release()
+	})
+	expected := testing.AllocsPerRun(100, func() {
+		_, err := ParseAST(concurrencyTestInput)
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+	assert.Less(t, allocs, expected)
+}
+
+func BenchmarkParseSession(b *testing.B) {
+	session := NewParseSession()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, err := session.ParseAST(concurrencyTestInput)
+		if err != nil {
+			b.Fatal(err)
+		}
+		session.Release()
+	}
+}
@@ -0,0 +1,48 @@
+package kqlfilter
+
+import (
+	"fmt"
+	"time"
+)
+
+// AsOfField is a reserved pseudo-field to request the state of the data at a point in time, e.g.
+// `_as_of:"2024-06-01T00:00:00Z"` or `_as_of:yesterday`. It does not refer to a column: ExtractAsOf removes it from
+// the filter, and the returned time can be used as the bound of a stale read, such as spanner.ReadTimestamp.
+const AsOfField = "_as_of"
+
+// ExtractAsOf removes the AsOfField clause from the filter and returns the filter without it, together with the
+// requested time. The time is zero if the filter does not contain the pseudo-field.
+//
+// The value must be a timestamp in time.RFC3339Nano format or one of the relative time keywords such as
+// RelativeTimeYesterday, which are resolved using the clock and location set with WithClock and WithDefaultLocation.
+// The pseudo-field can only be used once, with a single value, and cannot be negated or used with range operators.
+func (f Filter) ExtractAsOf(options ...ConverterOption) (Filter, time.Time, error) {
+	o := newConverterOptions(options)
+	var asOf time.Time
+	found := false
+	clauses := make([]Clause, 0, len(f.Clauses))
+	for _, clause := range f.Clauses {
+		if clause.Field != AsOfField {
+			clauses = append(clauses, clause)
+			continue
+		}
+		if found {
+			return Filter{}, time.Time{}, fmt.Errorf("field %s: can only be used once", AsOfField)
+		}
+		if clause.Operator != "=" || len(clause.Values) != 1 {
+			return Filter{}, time.Time{}, fmt.Errorf("field %s: operator %s not supported", AsOfField, clause.Operator)
+		}
+		value := clause.Values[0]
+		t, ok := o.resolveRelativeTime(value)
+		if !ok {
+			var err error
+			t, err = o.parseTime(value)
+			if err != nil {
+				return Filter{}, time.Time{}, fmt.Errorf("field %s: invalid TIMESTAMP value: %w", AsOfField, err)
+			}
+		}
+		asOf = t
+		found = true
+	}
+	return Filter{Clauses: clauses, Groups: f.Groups}, asOf, nil
+}
@@ -0,0 +1,95 @@
+package kqlfilter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractAsOf(t *testing.T) {
+	now := time.Date(2024, 6, 2, 15, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name            string
+		input           string
+		expectedClauses []Clause
+		expectedAsOf    time.Time
+		expectedError   string
+	}{
+		{
+			"absent",
+			"state:active",
+			[]Clause{{Field: "state", Operator: "=", Values: []string{"active"}}},
+			time.Time{},
+			"",
+		},
+		{
+			"timestamp",
+			`state:active _as_of:"2024-06-01T00:00:00Z"`,
+			[]Clause{{Field: "state", Operator: "=", Values: []string{"active"}}},
+			time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+			"",
+		},
+		{
+			"relative time",
+			"_as_of:yesterday",
+			[]Clause{},
+			time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+			"",
+		},
+		{
+			"invalid value",
+			"_as_of:last_week",
+			nil,
+			time.Time{},
+			`field _as_of: invalid TIMESTAMP value: parsing time "last_week" as "2006-01-02T15:04:05.999999999Z07:00": cannot parse "last_week" as "2006"`,
+		},
+		{
+			"negated",
+			"not _as_of:today",
+			nil,
+			time.Time{},
+			"field _as_of: operator != not supported",
+		},
+		{
+			"used twice",
+			"_as_of:today and _as_of:yesterday",
+			nil,
+			time.Time{},
+			"field _as_of: can only be used once",
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			f, err := Parse(test.input)
+			require.NoError(t, err)
+
+			f, asOf, err := f.ExtractAsOf(WithClock(func() time.Time { return now }))
+			if test.expectedError != "" {
+				require.EqualError(t, err, test.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedClauses, f.Clauses)
+			assert.True(t, test.expectedAsOf.Equal(asOf), "expected %s, got %s", test.expectedAsOf, asOf)
+		})
+	}
+}
+
+func TestSpannerBackendAsOf(t *testing.T) {
+	schema := Schema{
+		"state":   {},
+		AsOfField: {Type: FieldTypeTimestamp},
+	}
+	query, err := QueryFromKQL(`state:active _as_of:"2024-06-01T00:00:00Z"`, schema, SpannerBackend())
+	require.NoError(t, err)
+	assert.Equal(t, "state=@KQL0", query.Where())
+	assert.Equal(t, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), query.AsOf)
+
+	query, err = QueryFromKQL("state:active", schema, SpannerBackend())
+	require.NoError(t, err)
+	assert.True(t, query.AsOf.IsZero())
+}
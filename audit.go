@@ -0,0 +1,184 @@
+package kqlfilter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Kinds of findings reported by Audit.
+const (
+	// FindingLeadingWildcard is a value starting with a wildcard (`name:*son`), which can't use an index.
+	FindingLeadingWildcard = "leading wildcard"
+	// FindingRegex is a regular expression value (`name:/jo.*n/`).
+	FindingRegex = "regex"
+	// FindingLargeList is a list of values exceeding AuditPolicy.MaxValues.
+	FindingLargeList = "large list"
+	// FindingOrFanOut is a disjunction of expressions exceeding AuditPolicy.MaxOrBranches.
+	FindingOrFanOut = "or fan-out"
+	// FindingMissingTimeBound is a filter that doesn't bound any of AuditPolicy.TimeFields.
+	FindingMissingTimeBound = "missing time bound"
+)
+
+// AuditPolicy configures the limits checked by Audit. Leading wildcards and regular expressions are always reported.
+type AuditPolicy struct {
+	// Maximum number of values in a list of values (`state:(a OR b)`). Zero means no limit.
+	MaxValues int
+	// Maximum number of expressions joined by OR, counting nested disjunctions (`(a:1 OR b:2) OR c:3`) as one.
+	// Lists of values are limited by MaxValues instead. Zero means no limit.
+	MaxOrBranches int
+	// Fields of which at least one must be bounded, e.g. the timestamp of a partitioned table. A field is bounded by a
+	// lower bound (`>` or `>=`) or an equality that applies to the whole filter, i.e. that is not part of a
+	// disjunction or negation. Fields are named as in the filter, with nested fields named by their path. Defaults to
+	// not requiring any bound.
+	TimeFields []string
+}
+
+// Finding describes an expensive pattern found by Audit.
+type Finding struct {
+	// Kind of the finding, one of the Finding* constants.
+	Kind string
+	// Field of the offending clause, if any. Fields in nested queries are named by their path.
+	Field string
+	// Position of the offending clause, or zero if the finding applies to the whole filter.
+	Pos Pos
+	// Human-readable description of the finding.
+	Message string
+}
+
+func (f Finding) String() string {
+	return f.Message
+}
+
+// Audit returns the expensive patterns in the AST that violate the policy, in the order in which they appear in the
+// filter, e.g. to reject or rate limit expensive filters at a gateway before they reach a backend. Missing time bounds
+// are reported last. It returns nil if nothing was found.
+func Audit(ast Node, policy AuditPolicy) []Finding {
+	if ast == nil {
+		return nil
+	}
+	a := auditor{policy: policy}
+	a.audit(ast, "")
+
+	if len(policy.TimeFields) > 0 && !hasTimeBound(ast, policy.TimeFields) {
+		a.findings = append(a.findings, Finding{
+			Kind:    FindingMissingTimeBound,
+			Message: fmt.Sprintf("filter must bound one of the fields %s", strings.Join(policy.TimeFields, ", ")),
+		})
+	}
+	return a.findings
+}
+
+// auditor collects the findings of Audit.
+type auditor struct {
+	policy   AuditPolicy
+	findings []Finding
+}
+
+func (a *auditor) audit(ast Node, prefix string) {
+	switch n := ast.(type) {
+	case *AndNode:
+		for _, child := range n.Nodes {
+			a.audit(child, prefix)
+		}
+	case *OrNode:
+		a.auditDisjunction(n, prefix)
+	case *NotNode:
+		a.audit(n.Expr, prefix)
+	case *IsNode:
+		field := prefix + n.Identifier
+		switch v := n.Value.(type) {
+		case *NestedNode:
+			a.audit(v.Expr, field+".")
+		case *OrNode:
+			if a.policy.MaxValues > 0 && len(v.Nodes) > a.policy.MaxValues {
+				a.add(FindingLargeList, field, n.Pos, "field %s: list of %d values exceeds maximum of %d", field, len(v.Nodes), a.policy.MaxValues)
+			}
+			for _, child := range v.Nodes {
+				if lit, ok := child.(*LiteralNode); ok {
+					a.auditValue(field, n.Pos, lit.Value)
+				}
+			}
+		case *LiteralNode:
+			a.auditValue(field, n.Pos, v.Value)
+		}
+	case *LiteralNode:
+		if n.Value != "true" && n.Value != "false" {
+			a.auditValue("", n.Pos, n.Value)
+		}
+	}
+}
+
+// auditDisjunction checks the number of expressions of a disjunction, flattening nested disjunctions, and audits them.
+func (a *auditor) auditDisjunction(or *OrNode, prefix string) {
+	var branches []Node
+	var flatten func(nodes []Node)
+	flatten = func(nodes []Node) {
+		for _, child := range nodes {
+			if nested, ok := child.(*OrNode); ok {
+				flatten(nested.Nodes)
+			} else {
+				branches = append(branches, child)
+			}
+		}
+	}
+	flatten(or.Nodes)
+
+	if a.policy.MaxOrBranches > 0 && len(branches) > a.policy.MaxOrBranches {
+		a.add(FindingOrFanOut, "", or.Pos, "%d expressions joined by OR exceed maximum of %d", len(branches), a.policy.MaxOrBranches)
+	}
+	for _, child := range branches {
+		a.audit(child, prefix)
+	}
+}
+
+// auditValue reports leading wildcards and regular expressions. An empty field denotes a value without a field.
+func (a *auditor) auditValue(field string, pos Pos, value string) {
+	subject := "value"
+	if field != "" {
+		subject = "field " + field
+	}
+	if strings.HasPrefix(value, "*") {
+		a.add(FindingLeadingWildcard, field, pos, "%s: leading wildcard in %s", subject, value)
+	}
+	if unescaped := UnescapeValue(value); len(unescaped) >= 2 && strings.HasPrefix(unescaped, "/") && strings.HasSuffix(unescaped, "/") {
+		a.add(FindingRegex, field, pos, "%s: regular expression %s", subject, unescaped)
+	}
+}
+
+func (a *auditor) add(kind, field string, pos Pos, format string, args ...any) {
+	a.findings = append(a.findings, Finding{Kind: kind, Field: field, Pos: pos, Message: fmt.Sprintf(format, args...)})
+}
+
+// hasTimeBound reports whether a conjunct of the AST bounds one of the fields from below or by equality.
+func hasTimeBound(ast Node, fields []string) bool {
+	isTimeField := func(field string) bool {
+		for _, f := range fields {
+			if f == field {
+				return true
+			}
+		}
+		return false
+	}
+	var bounded func(ast Node, prefix string) bool
+	bounded = func(ast Node, prefix string) bool {
+		switch n := ast.(type) {
+		case *AndNode:
+			for _, child := range n.Nodes {
+				if bounded(child, prefix) {
+					return true
+				}
+			}
+		case *IsNode:
+			switch v := n.Value.(type) {
+			case *NestedNode:
+				return bounded(v.Expr, prefix+n.Identifier+".")
+			case *LiteralNode:
+				return isTimeField(prefix+n.Identifier) && !containsWildcard(v.Value)
+			}
+		case *RangeNode:
+			return isTimeField(prefix+n.Identifier) && (n.Operator == RangeOperatorGt || n.Operator == RangeOperatorGte)
+		}
+		return false
+	}
+	return bounded(ast, "")
+}
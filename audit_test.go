@@ -0,0 +1,83 @@
+package kqlfilter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAudit(t *testing.T) {
+	policy := AuditPolicy{MaxValues: 3, MaxOrBranches: 2, TimeFields: []string{"created", "event.time"}}
+
+	testCases := []struct {
+		name     string
+		input    string
+		expected []Finding
+	}{
+		{
+			name:  "cheap filter",
+			input: `created>=2024-01-01 name:jo* state:(a or b or c)`,
+		},
+		{
+			name:  "leading wildcard",
+			input: `created:2024-01-01 name:*son`,
+			expected: []Finding{
+				{Kind: FindingLeadingWildcard, Field: "name", Pos: 19, Message: "field name: leading wildcard in *son"},
+			},
+		},
+		{
+			name:  "free text",
+			input: `created>now-1d and /err.r/`,
+			expected: []Finding{
+				{Kind: FindingRegex, Pos: 19, Message: "value: regular expression /err.r/"},
+			},
+		},
+		{
+			name:  "regex",
+			input: `event:{time>now-1d} name:/^jo.*n$/`,
+			expected: []Finding{
+				{Kind: FindingRegex, Field: "name", Pos: 20, Message: "field name: regular expression /^jo.*n$/"},
+			},
+		},
+		{
+			name:  "large list",
+			input: `created>now-1d not state:(a or b or c or /d/)`,
+			expected: []Finding{
+				{Kind: FindingLargeList, Field: "state", Pos: 19, Message: "field state: list of 4 values exceeds maximum of 3"},
+				{Kind: FindingRegex, Field: "state", Pos: 19, Message: "field state: regular expression /d/"},
+			},
+		},
+		{
+			name:  "or fan-out",
+			input: `created>now-1d (a:1 or (b:2 or c:3))`,
+			expected: []Finding{
+				{Kind: FindingOrFanOut, Pos: 16, Message: "3 expressions joined by OR exceed maximum of 2"},
+			},
+		},
+		{
+			name:  "missing time bound",
+			input: `created<now-1d or created:2024-01-01`,
+			expected: []Finding{
+				{Kind: FindingMissingTimeBound, Message: "filter must bound one of the fields created, event.time"},
+			},
+		},
+		{
+			name:  "negated time bound",
+			input: `not created>now-1d`,
+			expected: []Finding{
+				{Kind: FindingMissingTimeBound, Message: "filter must bound one of the fields created, event.time"},
+			},
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			ast, err := ParseAST(test.input)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, Audit(ast, policy))
+		})
+	}
+
+	assert.Nil(t, Audit(nil, policy))
+}
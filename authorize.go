@@ -0,0 +1,41 @@
+package kqlfilter
+
+import "strings"
+
+// Authorize checks that all fields referenced in the AST are allowed, e.g. for the role of the caller, before the
+// filter is converted, so access control doesn't depend on the config of a backend. Fields are named as in the filter,
+// with fields in nested queries named by their path (`user.email`), so aliases must be allowed as well.
+//
+// It returns a FieldError matching ErrFieldNotAllowed for each forbidden field, in order of first occurrence, joined
+// with errors.Join if there are multiple, or nil if all fields are allowed.
+func Authorize(ast Node, allowed func(field string) bool) error {
+	c := errorCollector{all: true}
+	for _, usage := range ListFields(ast) {
+		if !allowed(usage.Field) {
+			c.add(newFieldError(usage.Field, ErrFieldNotAllowed, "field %s is not allowed", usage.Field))
+		}
+	}
+	return c.err()
+}
+
+// AllowFields returns a function for Authorize that allows the given fields. Allowing a field allows all of its nested
+// fields, e.g. allowing `user` allows `user.name`. Lookups of a role's fields can be written as
+// `AllowFields(fieldsByRole[role]...)`.
+func AllowFields(fields ...string) func(field string) bool {
+	set := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		set[field] = true
+	}
+	return func(field string) bool {
+		for {
+			if set[field] {
+				return true
+			}
+			i := strings.LastIndexByte(field, '.')
+			if i < 0 {
+				return false
+			}
+			field = field[:i]
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package kqlfilter
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthorize(t *testing.T) {
+	allowed := AllowFields("name", "state", "user")
+
+	testCases := []struct {
+		input         string
+		expectedError string
+	}{
+		{input: `name:jo* state:(a or b) user:{email:x and role:admin}`},
+		{input: `user.email:x`},
+		{input: `"free text"`},
+		{input: `salary>1000 name:jo`, expectedError: "field salary is not allowed"},
+		{input: `not salary>1000 or (ssn:* and salary<10)`, expectedError: "field salary is not allowed\nfield ssn is not allowed"},
+		{input: `users:x`, expectedError: "field users is not allowed"},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.input, func(t *testing.T) {
+			ast, err := ParseAST(test.input)
+			require.NoError(t, err)
+			err = Authorize(ast, allowed)
+			if test.expectedError == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, test.expectedError)
+			assert.ErrorIs(t, err, ErrFieldNotAllowed)
+		})
+	}
+
+	ast, err := ParseAST(`name:jo ssn:123`)
+	require.NoError(t, err)
+	var fieldErr *FieldError
+	require.True(t, errors.As(Authorize(ast, allowed), &fieldErr))
+	assert.Equal(t, "ssn", fieldErr.Field)
+	assert.NoError(t, Authorize(nil, allowed))
+}
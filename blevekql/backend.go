@@ -0,0 +1,36 @@
+package blevekql
+
+import (
+	"github.com/MottoStreaming/kqlfilter.go"
+	"github.com/blevesearch/bleve/v2/search/query"
+)
+
+func init() {
+	kqlfilter.RegisterConverter("bleve", func() kqlfilter.Backend[any] {
+		return kqlfilter.AnyBackend(Backend())
+	})
+}
+
+// Backend returns a kqlfilter.Backend producing bleve queries for use with kqlfilter.QueryFromKQL.
+// Field names are resolved against the schema, so aliases are mapped to the canonical field and its column.
+// Values are matched according to the types of the fields in the schema, see WithFieldTypes.
+// Options passed here are applied after the schema based options, so WithFieldMapper overrides the schema.
+func Backend(options ...Option) kqlfilter.Backend[query.Query] {
+	return kqlfilter.BackendFunc[query.Query](func(ast kqlfilter.Node, schema kqlfilter.Schema) (query.Query, error) {
+		if ast == nil {
+			return query.NewMatchAllQuery(), nil
+		}
+		schemaMapper := WithFieldMapper(func(name string) (string, error) {
+			canonical, _, ok := schema.Lookup(name)
+			if !ok {
+				return "", kqlfilter.NewUnknownFieldError(name, schema.FieldNames())
+			}
+			return schema.ColumnName(canonical), nil
+		})
+		fieldTypes := make(map[string]kqlfilter.FieldType, len(schema))
+		for name, fs := range schema {
+			fieldTypes[schema.ColumnName(name)] = fs.Type
+		}
+		return NewQueryGenerator(append([]Option{schemaMapper, WithFieldTypes(fieldTypes)}, options...)...).ConvertAST(ast)
+	})
+}
@@ -0,0 +1,313 @@
+// Package blevekql converts KQL filters to bleve queries.
+//
+// Queries are generated as a bleve query.Query, to be searched with a bleve index:
+//
+//	q, err := blevekql.NewQueryGenerator(blevekql.WithFieldTypes(fieldTypes)).ConvertAST(ast)
+//	result, err := index.Search(bleve.NewSearchRequest(q))
+//
+// Conjunctions and disjunctions are converted to conjunction and disjunction queries, negations to boolean queries
+// with a `must_not` clause, values to term, prefix, wildcard, numeric range, date range and boolean field queries
+// depending on the type of the field, and ranges to numeric, date and term range queries.
+package blevekql
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MottoStreaming/kqlfilter.go"
+	"github.com/blevesearch/bleve/v2/search/query"
+)
+
+type QueryGenerator struct {
+	mapFieldName  func(name string) (string, error)
+	mapFieldValue func(name, value string) (string, error)
+	fieldTypes    map[string]kqlfilter.FieldType
+}
+
+func NewQueryGenerator(options ...Option) *QueryGenerator {
+	g := &QueryGenerator{mapFieldName: defaultFieldNameMapper, mapFieldValue: defaultFieldValueMapper}
+
+	for _, option := range options {
+		option(g)
+	}
+
+	return g
+}
+
+// Option is a function that configures a query generator.
+type Option func(*QueryGenerator)
+
+// WithFieldMapper allows validating incoming field names, and mapping them to internally defined ones.
+// This can be used to prevent users from requiring knowledge about implementation details.
+func WithFieldMapper(fieldMapper func(name string) (string, error)) Option {
+	return func(g *QueryGenerator) {
+		g.mapFieldName = fieldMapper
+	}
+}
+
+// WithFieldValueMapper allows mapping incoming values for a field, or returning an error on invalid values.
+func WithFieldValueMapper(fieldValueMapper func(name, value string) (string, error)) Option {
+	return func(g *QueryGenerator) {
+		g.mapFieldValue = fieldValueMapper
+	}
+}
+
+// WithFieldTypes sets the types of fields, keyed by the field name as returned by the field mapper. Values of numeric
+// fields are matched with numeric range queries, of boolean fields with boolean field queries, and of timestamp fields
+// with date range queries. Values of fields without a type are matched with term queries.
+func WithFieldTypes(fieldTypes map[string]kqlfilter.FieldType) Option {
+	return func(g *QueryGenerator) {
+		g.fieldTypes = fieldTypes
+	}
+}
+
+// ConvertAST converts a KQL AST to a bleve query.
+func (q *QueryGenerator) ConvertAST(root kqlfilter.Node) (query.Query, error) {
+	return q.convertNodeToQuery(root, "")
+}
+
+func (q *QueryGenerator) convertNodeToQuery(node kqlfilter.Node, prefix string) (query.Query, error) {
+	switch n := node.(type) {
+	case *kqlfilter.AndNode:
+		clauses, err := q.convertNodesToQueries(n.Nodes, prefix)
+		if err != nil {
+			return nil, err
+		}
+		return query.NewConjunctionQuery(clauses), nil
+	case *kqlfilter.OrNode:
+		clauses, err := q.convertNodesToQueries(n.Nodes, prefix)
+		if err != nil {
+			return nil, err
+		}
+		return query.NewDisjunctionQuery(clauses), nil
+	case *kqlfilter.NotNode:
+		clause, err := q.convertNodeToQuery(n.Expr, prefix)
+		if err != nil {
+			return nil, err
+		}
+		return query.NewBooleanQuery(nil, nil, []query.Query{clause}), nil
+	case *kqlfilter.IsNode:
+		id, err := q.mapFieldName(prefix + n.Identifier)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", id, err)
+		}
+
+		switch v := n.Value.(type) {
+		case *kqlfilter.NestedNode:
+			// Transform x:{y:z} syntax to x.y:z.
+			return q.convertNodeToQuery(v.Expr, id+".")
+		case *kqlfilter.OrNode:
+			// Transform x:(y or z) syntax to a disjunction of the values.
+			clauses := make([]query.Query, 0, len(v.Nodes))
+			for _, child := range v.Nodes {
+				lit, ok := child.(*kqlfilter.LiteralNode)
+				if !ok {
+					return nil, fmt.Errorf("%s: invalid syntax", id)
+				}
+				clause, err := q.valueQuery(id, lit.Value)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %w", id, err)
+				}
+				clauses = append(clauses, clause)
+			}
+			return query.NewDisjunctionQuery(clauses), nil
+		case *kqlfilter.LiteralNode:
+			vq, err := q.valueQuery(id, v.Value)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", id, err)
+			}
+			return vq, nil
+		default:
+			return nil, fmt.Errorf("%s: expected literal node", id)
+		}
+	case *kqlfilter.ExistsNode:
+		id, err := q.mapFieldName(prefix + n.Identifier)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", id, err)
+		}
+		return nil, fmt.Errorf("%s: existence checks are not supported by bleve", id)
+	case *kqlfilter.FuzzyNode:
+		id, err := q.mapFieldName(prefix + n.Identifier)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", id, err)
+		}
+		value, err := q.mapFieldValue(id, n.Value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", id, err)
+		}
+		fq := query.NewFuzzyQuery(value)
+		fq.SetFuzziness(n.Fuzziness)
+		return withField(fq, id), nil
+	case *kqlfilter.RangeNode:
+		id, err := q.mapFieldName(prefix + n.Identifier)
+		if err != nil {
+			return nil, err
+		}
+
+		lit, ok := n.Value.(*kqlfilter.LiteralNode)
+		if !ok {
+			return nil, fmt.Errorf("%s: expected literal node", id)
+		}
+
+		value, err := q.mapFieldValue(id, kqlfilter.UnescapeValue(lit.Value))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", id, err)
+		}
+
+		rq, err := q.rangeQuery(id, n.Operator, value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", id, err)
+		}
+		return rq, nil
+	case *kqlfilter.LiteralNode:
+		switch n.Value {
+		case "true":
+			return query.NewMatchAllQuery(), nil
+		case "false":
+			return query.NewMatchNoneQuery(), nil
+		default:
+			return nil, fmt.Errorf("only boolean literals are supported; %s", n.Value)
+		}
+	default:
+		return nil, fmt.Errorf("unexpected node type: %T", n)
+	}
+}
+
+func (q *QueryGenerator) convertNodesToQueries(nodes []kqlfilter.Node, prefix string) ([]query.Query, error) {
+	clauses := make([]query.Query, 0, len(nodes))
+	for _, child := range nodes {
+		clause, err := q.convertNodeToQuery(child, prefix)
+		if err != nil {
+			return nil, err
+		}
+		clauses = append(clauses, clause)
+	}
+	return clauses, nil
+}
+
+// valueQuery returns the query matching a value of the field, according to the type of the field.
+func (q *QueryGenerator) valueQuery(id, value string) (query.Query, error) {
+	switch fieldType := q.fieldTypes[id]; fieldType {
+	case kqlfilter.FieldTypeInt64, kqlfilter.FieldTypeFloat64, kqlfilter.FieldTypeBool, kqlfilter.FieldTypeTimestamp:
+		mapped, err := q.mapFieldValue(id, kqlfilter.UnescapeValue(value))
+		if err != nil {
+			return nil, err
+		}
+		typed, err := fieldType.ParseValue(mapped)
+		if err != nil {
+			return nil, err
+		}
+		inclusive := true
+		switch v := typed.(type) {
+		case int64:
+			f := float64(v)
+			return withField(query.NewNumericRangeInclusiveQuery(&f, &f, &inclusive, &inclusive), id), nil
+		case float64:
+			return withField(query.NewNumericRangeInclusiveQuery(&v, &v, &inclusive, &inclusive), id), nil
+		case bool:
+			return withField(query.NewBoolFieldQuery(v), id), nil
+		case time.Time:
+			return withField(query.NewDateRangeInclusiveQuery(v, v, &inclusive, &inclusive), id), nil
+		}
+	}
+
+	text, isPrefix, isSuffix := kqlfilter.SplitWildcards(value, true, true)
+	mapped, err := q.mapFieldValue(id, text)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case isSuffix:
+		// Wildcard queries can't match literal asterisks and question marks.
+		if strings.ContainsAny(mapped, "*?") {
+			return nil, errors.New("wildcard values can't contain literal asterisks or question marks")
+		}
+		pattern := "*" + mapped
+		if isPrefix {
+			pattern += "*"
+		}
+		return withField(query.NewWildcardQuery(pattern), id), nil
+	case isPrefix:
+		return withField(query.NewPrefixQuery(mapped), id), nil
+	default:
+		return withField(query.NewTermQuery(mapped), id), nil
+	}
+}
+
+// rangeQuery returns the range query of a range operator. Numeric and timestamp fields are queried with numeric and
+// date range queries; for other fields, the type of range query is derived from the value: numbers, RFC3339 timestamps
+// and any other value are queried with numeric, date and term range queries respectively.
+func (q *QueryGenerator) rangeQuery(id string, op kqlfilter.RangeOperator, value string) (query.Query, error) {
+	var isMin, inclusive bool
+	switch op {
+	case kqlfilter.RangeOperatorLt:
+	case kqlfilter.RangeOperatorLte:
+		inclusive = true
+	case kqlfilter.RangeOperatorGt:
+		isMin = true
+	case kqlfilter.RangeOperatorGte:
+		isMin, inclusive = true, true
+	default:
+		return nil, fmt.Errorf("unsupported operator %s", op)
+	}
+
+	switch fieldType := q.fieldTypes[id]; fieldType {
+	case kqlfilter.FieldTypeInt64, kqlfilter.FieldTypeFloat64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s value: %w", fieldType, err)
+		}
+		return withField(numericRangeQuery(f, isMin, inclusive), id), nil
+	case kqlfilter.FieldTypeTimestamp:
+		t, err := fieldType.ParseValue(value)
+		if err != nil {
+			return nil, err
+		}
+		return withField(dateRangeQuery(t.(time.Time), isMin, inclusive), id), nil
+	}
+
+	if f, err := strconv.ParseFloat(value, 64); err == nil {
+		return withField(numericRangeQuery(f, isMin, inclusive), id), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return withField(dateRangeQuery(t, isMin, inclusive), id), nil
+	}
+	if isMin {
+		return withField(query.NewTermRangeInclusiveQuery(value, "", &inclusive, nil), id), nil
+	}
+	return withField(query.NewTermRangeInclusiveQuery("", value, nil, &inclusive), id), nil
+}
+
+// numericRangeQuery returns a numeric range query with the value as its minimum or maximum.
+func numericRangeQuery(value float64, isMin, inclusive bool) *query.NumericRangeQuery {
+	if isMin {
+		return query.NewNumericRangeInclusiveQuery(&value, nil, &inclusive, nil)
+	}
+	return query.NewNumericRangeInclusiveQuery(nil, &value, nil, &inclusive)
+}
+
+// dateRangeQuery returns a date range query with the value as its start or end. The other end is the zero time, which
+// bleve treats as unbounded.
+func dateRangeQuery(value time.Time, isMin, inclusive bool) *query.DateRangeQuery {
+	if isMin {
+		return query.NewDateRangeInclusiveQuery(value, time.Time{}, &inclusive, nil)
+	}
+	return query.NewDateRangeInclusiveQuery(time.Time{}, value, nil, &inclusive)
+}
+
+// withField restricts the query to the field and returns it.
+func withField[Q query.FieldableQuery](q Q, field string) Q {
+	q.SetField(field)
+	return q
+}
+
+func defaultFieldNameMapper(name string) (string, error) {
+	return name, nil
+}
+
+func defaultFieldValueMapper(_, value string) (string, error) {
+	return value, nil
+}
@@ -0,0 +1,182 @@
+package blevekql
+
+import (
+	"testing"
+	"time"
+
+	"github.com/MottoStreaming/kqlfilter.go"
+	"github.com/blevesearch/bleve/v2/search/query"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertNodeToQuery(t *testing.T) {
+	fieldTypes := WithFieldTypes(map[string]kqlfilter.FieldType{
+		"age":     kqlfilter.FieldTypeInt64,
+		"score":   kqlfilter.FieldTypeFloat64,
+		"active":  kqlfilter.FieldTypeBool,
+		"created": kqlfilter.FieldTypeTimestamp,
+	})
+	inclusive, exclusive := true, false
+	num := func(f float64) *float64 { return &f }
+	term := func(field, value string) query.Query { return withField(query.NewTermQuery(value), field) }
+
+	testCases := []struct {
+		name          string
+		input         string
+		options       []Option
+		expectedError string
+		expectedQuery query.Query
+	}{
+		{
+			name:          "simple equality",
+			input:         "type_id:team",
+			expectedQuery: term("type_id", "team"),
+		},
+		{
+			name:          "boolean literals",
+			input:         "true or false",
+			expectedQuery: query.NewDisjunctionQuery([]query.Query{query.NewMatchAllQuery(), query.NewMatchNoneQuery()}),
+		},
+		{
+			name:          "multiple values for same field",
+			input:         "type_id:(team OR player)",
+			expectedQuery: query.NewDisjunctionQuery([]query.Query{term("type_id", "team"), term("type_id", "player")}),
+		},
+		{
+			name:  "and, or and not",
+			input: "type_id:team and (name:jon or not name:doe)",
+			expectedQuery: query.NewConjunctionQuery([]query.Query{
+				term("type_id", "team"),
+				query.NewDisjunctionQuery([]query.Query{
+					term("name", "jon"),
+					query.NewBooleanQuery(nil, nil, []query.Query{term("name", "doe")}),
+				}),
+			}),
+		},
+		{
+			name:          "nested query",
+			input:         "fields:{established_year >= 2000}",
+			expectedQuery: withField(query.NewNumericRangeInclusiveQuery(num(2000), nil, &inclusive, nil), "fields.established_year"),
+		},
+		{
+			name:  "date range",
+			input: `time < "2024-01-01T00:00:00Z"`,
+			expectedQuery: withField(query.NewDateRangeInclusiveQuery(
+				time.Time{}, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), nil, &exclusive,
+			), "time"),
+		},
+		{
+			name:          "term range",
+			input:         `id > 01HZX3M5K2`,
+			expectedQuery: withField(query.NewTermRangeInclusiveQuery("01HZX3M5K2", "", &exclusive, nil), "id"),
+		},
+		{
+			name:          "prefix",
+			input:         `name:jo*`,
+			expectedQuery: withField(query.NewPrefixQuery("jo"), "name"),
+		},
+		{
+			name:          "suffix",
+			input:         `email:*@example.com`,
+			expectedQuery: withField(query.NewWildcardQuery("*@example.com"), "email"),
+		},
+		{
+			name:          "escaped asterisk",
+			input:         `name:a\*b`,
+			expectedQuery: term("name", "a*b"),
+		},
+		{
+			name:          "literal asterisk in wildcard query",
+			input:         `name:*a\*b`,
+			expectedError: "name: wildcard values can't contain literal asterisks or question marks",
+		},
+		{
+			name:    "typed values",
+			input:   `age:30 score<=0.5 active:true created:"2024-01-01T00:00:00Z"`,
+			options: []Option{fieldTypes},
+			expectedQuery: query.NewConjunctionQuery([]query.Query{
+				withField(query.NewNumericRangeInclusiveQuery(num(30), num(30), &inclusive, &inclusive), "age"),
+				withField(query.NewNumericRangeInclusiveQuery(nil, num(0.5), nil, &inclusive), "score"),
+				withField(query.NewBoolFieldQuery(true), "active"),
+				withField(query.NewDateRangeInclusiveQuery(
+					time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), &inclusive, &inclusive,
+				), "created"),
+			}),
+		},
+		{
+			name:          "invalid typed value",
+			input:         `age:old`,
+			options:       []Option{fieldTypes},
+			expectedError: `age: invalid int64 value: strconv.ParseInt: parsing "old": invalid syntax`,
+		},
+		{
+			name:          "exists",
+			input:         `name:*`,
+			expectedError: "name: existence checks are not supported by bleve",
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			n, err := kqlfilter.ParseAST(test.input)
+			require.NoError(t, err)
+
+			q, err := NewQueryGenerator(test.options...).ConvertAST(n)
+			if test.expectedError != "" {
+				require.EqualError(t, err, test.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedQuery, q)
+		})
+	}
+}
+
+func TestConvertTypedRange(t *testing.T) {
+	n, err := kqlfilter.ParseAST(`created>="2024-01-01T02:00:00+02:00"`)
+	require.NoError(t, err)
+
+	q, err := NewQueryGenerator(WithFieldTypes(map[string]kqlfilter.FieldType{"created": kqlfilter.FieldTypeTimestamp})).ConvertAST(n)
+	require.NoError(t, err)
+
+	require.IsType(t, &query.DateRangeQuery{}, q)
+	rq := q.(*query.DateRangeQuery)
+	assert.Equal(t, "created", rq.Field())
+	assert.True(t, rq.Start.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)))
+	assert.True(t, rq.End.IsZero())
+	assert.True(t, *rq.InclusiveStart)
+	assert.NoError(t, rq.Validate())
+}
+
+func TestConvertFuzzyNodeToQuery(t *testing.T) {
+	n, err := kqlfilter.ParseAST("name:jon~2", kqlfilter.WithFuzzyMatching())
+	require.NoError(t, err)
+
+	q, err := NewQueryGenerator().ConvertAST(n)
+	require.NoError(t, err)
+
+	require.IsType(t, &query.FuzzyQuery{}, q)
+	fq := q.(*query.FuzzyQuery)
+	assert.Equal(t, "jon", fq.Term)
+	assert.Equal(t, 2, fq.Fuzziness)
+	assert.Equal(t, "name", fq.Field())
+}
+
+func TestBackend(t *testing.T) {
+	schema := kqlfilter.Schema{
+		"active": {Type: kqlfilter.FieldTypeBool, Column: "is_active", Aliases: []string{"enabled"}},
+	}
+	q, err := kqlfilter.QueryFromKQL("enabled:false", schema, Backend())
+	require.NoError(t, err)
+	assert.Equal(t, withField(query.NewBoolFieldQuery(false), "is_active"), q)
+
+	q, err = kqlfilter.QueryFromKQL("", schema, Backend())
+	require.NoError(t, err)
+	assert.Equal(t, query.NewMatchAllQuery(), q)
+
+	_, err = kqlfilter.QueryFromKQL("name:x", schema, Backend())
+	assert.ErrorIs(t, err, kqlfilter.ErrUnknownField)
+
+	assert.Contains(t, kqlfilter.Converters(), "bleve")
+}
@@ -0,0 +1,32 @@
+module github.com/MottoStreaming/kqlfilter.go/blevekql
+
+go 1.21
+
+require (
+	github.com/MottoStreaming/kqlfilter.go v0.0.0-20240423214149-cdc2d3eb4e84
+	github.com/blevesearch/bleve/v2 v2.4.4
+	github.com/stretchr/testify v1.9.0
+)
+
+require (
+	cloud.google.com/go v0.112.0 // indirect
+	github.com/Masterminds/squirrel v1.5.4 // indirect
+	github.com/blevesearch/bleve_index_api v1.1.12 // indirect
+	github.com/blevesearch/geo v0.1.20 // indirect
+	github.com/blevesearch/go-faiss v1.0.24 // indirect
+	github.com/blevesearch/go-porterstemmer v1.0.3 // indirect
+	github.com/blevesearch/segment v0.9.1 // indirect
+	github.com/blevesearch/snowballstem v0.9.0 // indirect
+	github.com/blevesearch/upsidedown_store_api v1.0.2 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/golang/geo v0.0.0-20210211234256-740aa86cb551 // indirect
+	github.com/json-iterator/go v0.0.0-20171115153421-f7279a603ede // indirect
+	github.com/lann/builder v0.0.0-20180802200727-47ae307949d0 // indirect
+	github.com/lann/ps v0.0.0-20150810152359-62de8c46ede0 // indirect
+	github.com/pkg/errors v0.9.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/text v0.14.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/MottoStreaming/kqlfilter.go => ../
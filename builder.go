@@ -0,0 +1,173 @@
+package kqlfilter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FilterBuilder builds a Filter, or the equivalent AST, programmatically instead of by concatenating strings.
+// It is typically used to add server-side constraints to a filter provided by the user:
+//
+//	f, err := kqlfilter.Parse(input)
+//	...
+//	f = kqlfilter.NewFilterFrom(f).Eq("tenant_id", tenantID).Gte("create_time", since).Filter()
+//
+// Values are formatted as strings: time.Time as RFC 3339 with nanoseconds, other values with fmt.Sprint.
+// They are matched literally, so asterisks are escaped rather than treated as wildcards.
+// All clauses are AND'ed.
+type FilterBuilder struct {
+	clauses []Clause
+	groups  [][]Clause
+}
+
+// NewFilter returns an empty FilterBuilder.
+func NewFilter() *FilterBuilder {
+	return &FilterBuilder{}
+}
+
+// NewFilterFrom returns a FilterBuilder that starts with the clauses and groups of f.
+func NewFilterFrom(f Filter) *FilterBuilder {
+	return &FilterBuilder{clauses: append([]Clause(nil), f.Clauses...), groups: f.Groups}
+}
+
+// Eq adds a clause requiring the field to equal the value.
+func (b *FilterBuilder) Eq(field string, value any) *FilterBuilder {
+	return b.add(field, "=", value)
+}
+
+// NotEq adds a clause requiring the field not to equal the value.
+func (b *FilterBuilder) NotEq(field string, value any) *FilterBuilder {
+	return b.add(field, "!=", value)
+}
+
+// In adds a clause requiring the field to equal one of the values.
+func (b *FilterBuilder) In(field string, values ...any) *FilterBuilder {
+	return b.add(field, "IN", values...)
+}
+
+// NotIn adds a clause requiring the field to equal none of the values.
+func (b *FilterBuilder) NotIn(field string, values ...any) *FilterBuilder {
+	return b.add(field, "NOT IN", values...)
+}
+
+// Gt adds a clause requiring the field to be greater than the value.
+func (b *FilterBuilder) Gt(field string, value any) *FilterBuilder {
+	return b.add(field, ">", value)
+}
+
+// Gte adds a clause requiring the field to be greater than or equal to the value.
+func (b *FilterBuilder) Gte(field string, value any) *FilterBuilder {
+	return b.add(field, ">=", value)
+}
+
+// Lt adds a clause requiring the field to be less than the value.
+func (b *FilterBuilder) Lt(field string, value any) *FilterBuilder {
+	return b.add(field, "<", value)
+}
+
+// Lte adds a clause requiring the field to be less than or equal to the value.
+func (b *FilterBuilder) Lte(field string, value any) *FilterBuilder {
+	return b.add(field, "<=", value)
+}
+
+func (b *FilterBuilder) add(field string, operator string, values ...any) *FilterBuilder {
+	clause := Clause{Field: field, Operator: operator, Values: make([]string, len(values))}
+	for i, v := range values {
+		clause.Values[i] = formatBuilderValue(v)
+	}
+	b.clauses = append(b.clauses, clause)
+	return b
+}
+
+// Filter returns the filter with all clauses added so far.
+func (b *FilterBuilder) Filter() Filter {
+	return Filter{Clauses: append([]Clause(nil), b.clauses...), Groups: b.groups}
+}
+
+// AST returns the clauses added so far as an AST, which can be converted by any of the AST based converters.
+// It returns nil if no clauses were added.
+func (b *FilterBuilder) AST() Node {
+	return b.AppendTo(nil)
+}
+
+// AppendTo returns an AST requiring both ast and the clauses added so far, combined with And.
+// The given AST is not modified.
+func (b *FilterBuilder) AppendTo(ast Node) Node {
+	nodes := make([]Node, 0, len(b.clauses)+1)
+	nodes = append(nodes, ast)
+	for _, clause := range b.clauses {
+		nodes = append(nodes, clause.node())
+	}
+	if len(b.groups) > 0 {
+		alternatives := make([]Node, 0, len(b.groups))
+		for _, group := range b.groups {
+			conjunction := make([]Node, 0, len(group))
+			for _, clause := range group {
+				conjunction = append(conjunction, clause.node())
+			}
+			alternatives = append(alternatives, And(conjunction...))
+		}
+		nodes = append(nodes, Or(alternatives...))
+	}
+	return And(nodes...)
+}
+
+// node returns the AST of a clause with one of the operators used by FilterBuilder.
+func (c Clause) node() Node {
+	literal := func(value string) Node {
+		return &LiteralNode{NodeType: NodeLiteral, Value: value}
+	}
+	switch c.Operator {
+	case "=", "!=":
+		var n Node = &IsNode{NodeType: NodeIs, Identifier: c.Field, Value: literal(c.Values[0])}
+		if c.Operator == "!=" {
+			n = &NotNode{NodeType: NodeNot, Expr: n}
+		}
+		return n
+	case "IN", "NOT IN":
+		values := &OrNode{NodeType: NodeOr}
+		for _, v := range c.Values {
+			values.Nodes = append(values.Nodes, literal(v))
+		}
+		var n Node = &IsNode{NodeType: NodeIs, Identifier: c.Field, Value: values}
+		if c.Operator == "NOT IN" {
+			n = &NotNode{NodeType: NodeNot, Expr: n}
+		}
+		return n
+	default:
+		var op RangeOperator
+		switch c.Operator {
+		case ">":
+			op = RangeOperatorGt
+		case ">=":
+			op = RangeOperatorGte
+		case "<":
+			op = RangeOperatorLt
+		default:
+			op = RangeOperatorLte
+		}
+		return &RangeNode{NodeType: NodeRange, Identifier: c.Field, Operator: op, Value: literal(c.Values[0])}
+	}
+}
+
+// formatBuilderValue formats a value for a clause, escaping asterisks so they are matched literally.
+func formatBuilderValue(v any) string {
+	var s string
+	switch v := v.(type) {
+	case string:
+		s = v
+	case time.Time:
+		s = v.Format(time.RFC3339Nano)
+	case bool:
+		s = strconv.FormatBool(v)
+	default:
+		s = fmt.Sprint(v)
+	}
+	if strings.Contains(s, "*") {
+		s = strings.ReplaceAll(s, `\`, `\\`)
+		s = strings.ReplaceAll(s, "*", `\*`)
+	}
+	return s
+}
@@ -0,0 +1,54 @@
+package kqlfilter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilterBuilder(t *testing.T) {
+	since := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	b := NewFilter().
+		Eq("user_id", 1).
+		Gte("created_at", since).
+		In("state", "active", "paused").
+		NotEq("name", "a*")
+
+	assert.Equal(t, Filter{Clauses: []Clause{
+		{Field: "user_id", Operator: "=", Values: []string{"1"}},
+		{Field: "created_at", Operator: ">=", Values: []string{"2024-05-01T12:00:00Z"}},
+		{Field: "state", Operator: "IN", Values: []string{"active", "paused"}},
+		{Field: "name", Operator: "!=", Values: []string{`a\*`}},
+	}}, b.Filter())
+	assert.Equal(t, `(user_id=1 AND created_at>=2024-05-01T12:00:00Z AND state=(active OR paused) AND NOT name=a\*)`, b.AST().String())
+
+	conditions, params, err := b.Filter().ToSpannerSQL(map[string]FilterToSpannerFieldConfig{
+		"user_id":    {ColumnType: FilterToSpannerFieldColumnTypeInt64},
+		"created_at": {ColumnType: FilterToSpannerFieldColumnTypeTimestamp, AllowRanges: true},
+		"state":      {ColumnType: FilterToSpannerFieldColumnTypeString, AllowMultipleValues: true},
+		"name":       {AllowPrefixMatch: true},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"user_id=@KQL0", "created_at>=@KQL1", "state IN UNNEST(@KQL2)", "name!=@KQL3"}, conditions)
+	assert.Equal(t, "a*", params["KQL3"])
+
+	assert.Nil(t, NewFilter().AST())
+}
+
+func TestFilterBuilderAppend(t *testing.T) {
+	f, err := Parse("state:active")
+	require.NoError(t, err)
+	f = NewFilterFrom(f).Eq("tenant_id", "t1").Filter()
+	assert.Equal(t, []Clause{
+		{Field: "state", Operator: "=", Values: []string{"active"}},
+		{Field: "tenant_id", Operator: "=", Values: []string{"t1"}},
+	}, f.Clauses)
+
+	ast, err := ParseAST("state:active or state:paused")
+	require.NoError(t, err)
+	combined := NewFilter().Eq("tenant_id", "t1").NotIn("type", "a", "b").AppendTo(ast)
+	assert.Equal(t, "((state=active OR state=paused) AND tenant_id=t1 AND NOT type=(a OR b))", combined.String())
+	assert.Equal(t, "(state=active OR state=paused)", ast.String())
+}
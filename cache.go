@@ -0,0 +1,76 @@
+package kqlfilter
+
+import (
+	"container/list"
+	"sync"
+)
+
+// ParserCache caches the results of ParseAST for the most recently used inputs, for clients that send the same
+// filters over and over again, e.g. on every poll. It is safe for concurrent use.
+//
+// The parser options are given when creating the cache and apply to all inputs, so inputs are the only cache key.
+// Every call returns a deep copy of the cached AST, which the caller is free to modify.
+// Parse errors are cached as well.
+type ParserCache struct {
+	size    int
+	options []ParserOption
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List // of *parserCacheEntry, most recently used first
+}
+
+type parserCacheEntry struct {
+	input string
+	ast   Node
+	err   error
+}
+
+// NewParserCache returns a cache holding the parse results of at most size inputs, parsed with the given options.
+// It panics if size is not positive.
+func NewParserCache(size int, options ...ParserOption) *ParserCache {
+	if size <= 0 {
+		panic("kqlfilter: NewParserCache size must be positive")
+	}
+	return &ParserCache{
+		size:    size,
+		options: options,
+		entries: make(map[string]*list.Element, size),
+		lru:     list.New(),
+	}
+}
+
+// ParseAST parses the input like ParseAST with the options of the cache, returning the cached result if the input
+// was parsed before.
+func (c *ParserCache) ParseAST(input string) (Node, error) {
+	c.mu.Lock()
+	if e, ok := c.entries[input]; ok {
+		c.lru.MoveToFront(e)
+		entry := e.Value.(*parserCacheEntry)
+		c.mu.Unlock()
+		return cloneNode(entry.ast), entry.err
+	}
+	c.mu.Unlock()
+
+	// Parse without holding the lock; concurrent misses for the same input parse it more than once.
+	ast, err := ParseAST(input, c.options...)
+
+	c.mu.Lock()
+	if _, ok := c.entries[input]; !ok {
+		c.entries[input] = c.lru.PushFront(&parserCacheEntry{input: input, ast: ast, err: err})
+		if c.lru.Len() > c.size {
+			oldest := c.lru.Back()
+			c.lru.Remove(oldest)
+			delete(c.entries, oldest.Value.(*parserCacheEntry).input)
+		}
+	}
+	c.mu.Unlock()
+	return cloneNode(ast), err
+}
+
+// Len returns the number of cached inputs.
+func (c *ParserCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
@@ -0,0 +1,69 @@
+package kqlfilter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParserCache(t *testing.T) {
+	cache := NewParserCache(2, DisableComplexExpressions())
+
+	ast, err := cache.ParseAST("a:1 b:2")
+	require.NoError(t, err)
+	assert.Equal(t, "(a=1 AND b=2)", ast.String())
+
+	// Modifying the result must not modify the cached AST.
+	ast.(*AndNode).Nodes[0].(*IsNode).Identifier = "c"
+	ast, err = cache.ParseAST("a:1 b:2")
+	require.NoError(t, err)
+	assert.Equal(t, "(a=1 AND b=2)", ast.String())
+	assert.Equal(t, 1, cache.Len())
+
+	// The options of the cache apply, and errors are cached.
+	_, err = cache.ParseAST("a:1 or b:2")
+	assert.EqualError(t, err, "parser error: complex expressions are not allowed at pos 4")
+	_, err = cache.ParseAST("a:1 or b:2")
+	assert.EqualError(t, err, "parser error: complex expressions are not allowed at pos 4")
+	assert.Equal(t, 2, cache.Len())
+
+	// The least recently used input is evicted.
+	_, err = cache.ParseAST("a:1 b:2")
+	require.NoError(t, err)
+	_, err = cache.ParseAST("c:3")
+	require.NoError(t, err)
+	assert.Equal(t, 2, cache.Len())
+	cache.mu.Lock()
+	_, cachedAB := cache.entries["a:1 b:2"]
+	_, cachedOr := cache.entries["a:1 or b:2"]
+	cache.mu.Unlock()
+	assert.True(t, cachedAB)
+	assert.False(t, cachedOr)
+}
+
+func TestConcurrentParserCache(t *testing.T) {
+	cache := NewParserCache(1)
+	runConcurrently(32, func(i int) {
+		input := concurrencyTestInput
+		if i%2 == 0 {
+			input = "a:1"
+		}
+		expected, err := ParseAST(input)
+		assert.NoError(t, err)
+		ast, err := cache.ParseAST(input)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, ast)
+	})
+}
+
+func BenchmarkParserCache(b *testing.B) {
+	cache := NewParserCache(16)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, err := cache.ParseAST(concurrencyTestInput)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"sort"
+	"text/template"
+
+	"github.com/MottoStreaming/kqlfilter.go"
+)
+
+// config holds the settings of a generator run.
+type config struct {
+	// Name of the schema definition file, mentioned in the generated header.
+	source string
+	// Package of the generated file.
+	pkg string
+	// Prefix of the generated identifiers.
+	name    string
+	spanner bool
+	elastic bool
+}
+
+// schemaDefinition is the JSON representation of a kqlfilter.Schema.
+type schemaDefinition struct {
+	Fields map[string]fieldDefinition `json:"fields"`
+}
+
+// fieldDefinition is the JSON representation of a kqlfilter.FieldSchema. MapValue cannot be expressed in JSON.
+type fieldDefinition struct {
+	Column              string              `json:"column"`
+	Type                kqlfilter.FieldType `json:"type"`
+	Aliases             []string            `json:"aliases"`
+	Required            bool                `json:"required"`
+	AllowPrefixMatch    bool                `json:"allow_prefix_match"`
+	AllowSuffixMatch    bool                `json:"allow_suffix_match"`
+	AllowMultipleValues bool                `json:"allow_multiple_values"`
+	AllowNegation       bool                `json:"allow_negation"`
+	AllowRanges         bool                `json:"allow_ranges"`
+	AllowedValues       []string            `json:"allowed_values"`
+}
+
+// goFieldTypes maps field types to the name of the constant in the kqlfilter package.
+var goFieldTypes = map[kqlfilter.FieldType]string{
+	kqlfilter.FieldTypeString:    "FieldTypeString",
+	kqlfilter.FieldTypeInt64:     "FieldTypeInt64",
+	kqlfilter.FieldTypeFloat64:   "FieldTypeFloat64",
+	kqlfilter.FieldTypeBool:      "FieldTypeBool",
+	kqlfilter.FieldTypeTimestamp: "FieldTypeTimestamp",
+}
+
+type templateField struct {
+	Name string
+	fieldDefinition
+	GoType string
+}
+
+var fileTemplate = template.Must(template.New("file").Parse(`// Code generated by kqlfilter-gen from {{.Source}}. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"github.com/MottoStreaming/kqlfilter.go"
+{{- if .Elastic}}
+	"github.com/MottoStreaming/kqlfilter.go/elastic"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
+{{- end}}
+)
+
+// {{.Name}}FilterSchema describes the fields that can be used in {{.Name}} filters.
+var {{.Name}}FilterSchema = kqlfilter.Schema{
+{{- range .Fields}}
+	{{printf "%q" .Name}}: {
+		{{- if .Column}}
+		Column: {{printf "%q" .Column}},
+		{{- end}}
+		{{- if ne .GoType "FieldTypeString"}}
+		Type: kqlfilter.{{.GoType}},
+		{{- end}}
+		{{- if .Aliases}}
+		Aliases: []string{ {{- range $i, $a := .Aliases}}{{if $i}}, {{end}}{{printf "%q" $a}}{{end -}} },
+		{{- end}}
+		{{- if .Required}}
+		Required: true,
+		{{- end}}
+		{{- if .AllowPrefixMatch}}
+		AllowPrefixMatch: true,
+		{{- end}}
+		{{- if .AllowSuffixMatch}}
+		AllowSuffixMatch: true,
+		{{- end}}
+		{{- if .AllowMultipleValues}}
+		AllowMultipleValues: true,
+		{{- end}}
+		{{- if .AllowNegation}}
+		AllowNegation: true,
+		{{- end}}
+		{{- if .AllowRanges}}
+		AllowRanges: true,
+		{{- end}}
+		{{- if .AllowedValues}}
+		AllowedValues: []string{ {{- range $i, $v := .AllowedValues}}{{if $i}}, {{end}}{{printf "%q" $v}}{{end -}} },
+		{{- end}}
+	},
+{{- end}}
+}
+
+// Validate{{.Name}}Filter checks that the filter can be parsed and only uses fields of {{.Name}}FilterSchema as allowed.
+func Validate{{.Name}}Filter(filter string, options ...kqlfilter.QueryOption) error {
+	_, err := kqlfilter.QueryFromKQL(filter, {{.Name}}FilterSchema, kqlfilter.BackendFunc[struct{}](func(kqlfilter.Node, kqlfilter.Schema) (struct{}, error) {
+		return struct{}{}, nil
+	}), options...)
+	return err
+}
+{{- if .Spanner}}
+
+// {{.Name}}FilterSpannerFieldConfigs are the field configs of {{.Name}}FilterSchema to use with Filter.ToSpannerSQL.
+var {{.Name}}FilterSpannerFieldConfigs = {{.Name}}FilterSchema.SpannerFieldConfigs()
+
+// {{.Name}}FilterToSpanner validates the filter and converts it to Spanner SQL conditions.
+func {{.Name}}FilterToSpanner(filter string, options ...kqlfilter.QueryOption) (kqlfilter.SpannerQuery, error) {
+	return kqlfilter.QueryFromKQL(filter, {{.Name}}FilterSchema, kqlfilter.SpannerBackend(), options...)
+}
+{{- end}}
+{{- if .Elastic}}
+
+// {{.Name}}FilterToElastic validates the filter and converts it to an Elasticsearch query.
+func {{.Name}}FilterToElastic(filter string, options ...kqlfilter.QueryOption) (types.Query, error) {
+	return kqlfilter.QueryFromKQL(filter, {{.Name}}FilterSchema, elastic.Backend(), options...)
+}
+{{- end}}
+`))
+
+// generate returns the formatted source of the generated file.
+func generate(cfg config, def schemaDefinition) ([]byte, error) {
+	if len(def.Fields) == 0 {
+		return nil, fmt.Errorf("schema definition has no fields")
+	}
+	names := make([]string, 0, len(def.Fields))
+	for name := range def.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fields := make([]templateField, 0, len(names))
+	for _, name := range names {
+		fd := def.Fields[name]
+		goType, ok := goFieldTypes[fd.Type]
+		if !ok {
+			return nil, fmt.Errorf("field %s: unknown field type %d", name, fd.Type)
+		}
+		fields = append(fields, templateField{Name: name, fieldDefinition: fd, GoType: goType})
+	}
+
+	var buf bytes.Buffer
+	err := fileTemplate.Execute(&buf, map[string]any{
+		"Source":  cfg.source,
+		"Package": cfg.pkg,
+		"Name":    cfg.name,
+		"Spanner": cfg.spanner,
+		"Elastic": cfg.elastic,
+		"Fields":  fields,
+	})
+	if err != nil {
+		return nil, err
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated code: %w\n%s", err, buf.Bytes())
+	}
+	return src, nil
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func TestGenerate(t *testing.T) {
+	data, err := os.ReadFile("testdata/users_filter.json")
+	require.NoError(t, err)
+	var def schemaDefinition
+	require.NoError(t, json.Unmarshal(data, &def))
+
+	src, err := generate(config{source: "users_filter.json", pkg: "users", name: "User", spanner: true, elastic: true}, def)
+	require.NoError(t, err)
+
+	const golden = "testdata/users_filter_gen.go.golden"
+	if *update {
+		require.NoError(t, os.WriteFile(golden, src, 0o644))
+	}
+	expected, err := os.ReadFile(golden)
+	require.NoError(t, err)
+	assert.Equal(t, string(expected), string(src))
+}
+
+func TestGenerateErrors(t *testing.T) {
+	_, err := generate(config{pkg: "users", name: "User"}, schemaDefinition{})
+	assert.EqualError(t, err, "schema definition has no fields")
+
+	var def schemaDefinition
+	err = json.Unmarshal([]byte(`{"fields": {"user_id": {"type": "int32"}}}`), &def)
+	assert.EqualError(t, err, `unknown field type "int32"`)
+}
@@ -0,0 +1,68 @@
+// Command kqlfilter-gen generates the filter plumbing of a list endpoint from a schema definition: the Schema and
+// field config maps, request validation, and conversion to Spanner and Elasticsearch queries.
+//
+// It is meant to be used with go:generate:
+//
+//	//go:generate go run github.com/MottoStreaming/kqlfilter.go/cmd/kqlfilter-gen -schema users_filter.json -name User
+//
+// The schema definition is a JSON document describing the fields, e.g.
+//
+//	{
+//		"fields": {
+//			"user_id": {"type": "int64", "aliases": ["userId"]},
+//			"state": {"allow_multiple_values": true, "allowed_values": ["active", "frozen"]},
+//			"created_at": {"column": "create_time", "type": "timestamp", "allow_ranges": true}
+//		}
+//	}
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("kqlfilter-gen: ")
+
+	var cfg config
+	schemaPath := flag.String("schema", "", "path of the JSON schema definition (required)")
+	flag.StringVar(&cfg.name, "name", "", "prefix of the generated identifiers, e.g. User (required)")
+	flag.StringVar(&cfg.pkg, "package", os.Getenv("GOPACKAGE"), "package of the generated file; defaults to $GOPACKAGE")
+	out := flag.String("out", "", "output file; defaults to <schema>_gen.go")
+	flag.BoolVar(&cfg.spanner, "spanner", true, "generate Spanner conversion")
+	flag.BoolVar(&cfg.elastic, "elastic", false, "generate Elasticsearch conversion")
+	flag.Parse()
+
+	if *schemaPath == "" || cfg.name == "" || cfg.pkg == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *out == "" {
+		*out = strings.TrimSuffix(*schemaPath, filepath.Ext(*schemaPath)) + "_gen.go"
+	}
+
+	data, err := os.ReadFile(*schemaPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var def schemaDefinition
+	if err := json.Unmarshal(data, &def); err != nil {
+		log.Fatalf("%s: %v", *schemaPath, err)
+	}
+	cfg.source = filepath.Base(*schemaPath)
+
+	src, err := generate(cfg, def)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*out, src, 0o644); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("wrote", *out)
+}
@@ -0,0 +1,232 @@
+package kqlfilter
+
+import (
+	"sort"
+	"strings"
+)
+
+// CompletionKind is the kind of token expected at the cursor.
+type CompletionKind int
+
+const (
+	// CompletionField is a field name, e.g. at the start of the input or after whitespace following a clause.
+	CompletionField CompletionKind = iota
+	// CompletionOperator is an operator, after a field name followed by whitespace.
+	CompletionOperator
+	// CompletionValue is a value, after an operator or in a list of values.
+	CompletionValue
+	// CompletionKeyword is a boolean operator, after a value in a list of values.
+	CompletionKeyword
+)
+
+func (k CompletionKind) String() string {
+	switch k {
+	case CompletionField:
+		return "field"
+	case CompletionOperator:
+		return "operator"
+	case CompletionValue:
+		return "value"
+	case CompletionKeyword:
+		return "keyword"
+	default:
+		return "???"
+	}
+}
+
+// Completion holds the suggestions for the token at the cursor of a partial filter.
+type Completion struct {
+	// Kind of the token at the cursor.
+	Kind CompletionKind
+	// Field whose operator or value is completed, as written in the filter, including the names of the enclosing
+	// nested fields, e.g. `user.name`. Empty for other kinds.
+	Field string
+	// Partial token before the cursor that the suggestions complete, e.g. `pla` in `type:pla`. May be empty.
+	Token string
+	// Start and end of the partial token in the input. A suggestion replaces the input between these positions.
+	Start, End Pos
+	// Suggestions for the token, sorted. Values that must be quoted are quoted.
+	Suggestions []string
+}
+
+// Complete returns suggestions to complete the partial input at the cursor, which is a byte offset in the input, using
+// the lexer of the parser to determine what is expected at the cursor:
+//
+//   - field names at the start of the input, after whitespace following a clause, and after a boolean operator or
+//     an opening parenthesis, e.g. `type:team cr`,
+//   - operators after a field name followed by whitespace, e.g. `created `: `:` and range operators if the field
+//     allows ranges,
+//   - values after an operator and in a list of values, e.g. `type:(team or pl`: the allowed values of the field,
+//     `true` and `false` for booleans, and the relative time keywords for timestamps,
+//   - boolean operators after a value in a list of values.
+//
+// Suggestions start with the partial token, ignoring case. The input after the cursor is ignored.
+func Complete(input string, cursor int, schema Schema) Completion {
+	cursor = min(max(cursor, 0), len(input))
+	text := input[:cursor]
+
+	// Lex the input up to the cursor. A lexer error, e.g. an unterminated quoted string, makes the rest of the input
+	// the partial token.
+	var items []item
+	start := Pos(len(text))
+	l := lex(text)
+	for {
+		it := l.nextItem()
+		if it.typ == itemEOF {
+			break
+		}
+		if it.typ == itemError {
+			start = it.pos
+			break
+		}
+		items = append(items, it)
+	}
+	if start == Pos(len(text)) && len(items) > 0 {
+		switch last := items[len(items)-1]; last.typ {
+		case itemString, itemBool, itemAnd, itemOr, itemNot:
+			start = last.pos
+			items = items[:len(items)-1]
+		}
+	}
+	c := Completion{Token: text[start:], Start: start, End: Pos(len(text))}
+
+	// Track the lists of values and nested fields enclosing the cursor.
+	type frame struct {
+		list   bool   // whether the frame is a list of values of field
+		field  string // field of the list, including prefix
+		prefix string // prefix of the fields in the frame
+	}
+	stack := []frame{{}}
+	var prev, field item // last non-space item, and field of the last operator
+	spaced := false      // whether whitespace follows prev
+	for i, it := range items {
+		top := stack[len(stack)-1]
+		switch it.typ {
+		case itemSpace:
+			spaced = true
+			continue
+		case itemColon, itemRangeOperator:
+			field = prev
+		case itemLeftParen:
+			if prev.typ == itemColon {
+				stack = append(stack, frame{list: true, field: top.prefix + field.val, prefix: top.prefix})
+			} else {
+				stack = append(stack, frame{prefix: top.prefix})
+			}
+		case itemLeftBrace:
+			stack = append(stack, frame{prefix: top.prefix + field.val + "."})
+		case itemRightParen, itemRightBrace:
+			if len(stack) > 1 {
+				stack = stack[:len(stack)-1]
+			}
+		}
+		prev, spaced = items[i], false
+	}
+	top := stack[len(stack)-1]
+
+	switch {
+	case prev.typ == itemColon || prev.typ == itemRangeOperator:
+		c.Kind, c.Field = CompletionValue, top.prefix+field.val
+	case top.list && (prev.typ == itemLeftParen || prev.typ == itemComma || prev.typ == itemOr || prev.typ == itemAnd || prev.typ == itemNot):
+		c.Kind, c.Field = CompletionValue, top.field
+	case top.list:
+		c.Kind = CompletionKeyword
+	case prev.typ == itemString && spaced && c.Token == "" && !isValue(items, prev):
+		if _, _, ok := schema.Lookup(top.prefix + prev.val); ok {
+			c.Kind, c.Field = CompletionOperator, top.prefix+prev.val
+		} else {
+			c.Kind = CompletionField
+		}
+	default:
+		c.Kind = CompletionField
+	}
+
+	switch c.Kind {
+	case CompletionField:
+		c.Suggestions = completeFields(schema, top.prefix, c.Token)
+	case CompletionOperator:
+		c.Suggestions = []string{":"}
+		if _, fs, _ := schema.Lookup(c.Field); fs.AllowRanges {
+			c.Suggestions = append(c.Suggestions, "<", "<=", ">", ">=")
+		}
+	case CompletionValue:
+		c.Suggestions = completeValues(schema, c.Field, c.Token)
+	case CompletionKeyword:
+		c.Suggestions = matchPrefix([]string{"and", "or"}, c.Token)
+	}
+	return c
+}
+
+// isValue reports whether the string item is the value of a clause, i.e. it follows an operator.
+func isValue(items []item, it item) bool {
+	for i := len(items) - 1; i >= 0; i-- {
+		if items[i].pos >= it.pos || items[i].typ == itemSpace {
+			continue
+		}
+		return items[i].typ == itemColon || items[i].typ == itemRangeOperator
+	}
+	return false
+}
+
+// completeFields returns the field names and aliases with the prefix of the enclosing nested fields, without that
+// prefix, that start with the token.
+func completeFields(schema Schema, prefix, token string) []string {
+	var names []string
+	for _, name := range schema.FieldNames() {
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, strings.TrimPrefix(name, prefix))
+		}
+	}
+	return matchPrefix(names, token)
+}
+
+// completeValues returns the values of the field that start with the token, which may be a partial quoted string.
+func completeValues(schema Schema, field, token string) []string {
+	_, fs, ok := schema.Lookup(field)
+	if !ok {
+		return nil
+	}
+	var values []string
+	switch {
+	case len(fs.AllowedValues) > 0:
+		values = fs.AllowedValues
+	case fs.Type == FieldTypeBool:
+		values = []string{"false", "true"}
+	case fs.Type == FieldTypeTimestamp:
+		values = []string{RelativeTimeNow, RelativeTimeThisWeek, RelativeTimeToday, RelativeTimeYesterday}
+	}
+	suggestions := matchPrefix(values, strings.TrimPrefix(token, `"`))
+	for i, s := range suggestions {
+		suggestions[i] = quoteIfNeeded(s)
+	}
+	return suggestions
+}
+
+// matchPrefix returns the sorted candidates that start with the token, ignoring case.
+func matchPrefix(candidates []string, token string) []string {
+	token = strings.ToLower(token)
+	var matches []string
+	for _, c := range candidates {
+		if strings.HasPrefix(strings.ToLower(c), token) {
+			matches = append(matches, c)
+		}
+	}
+	sort.Strings(matches)
+	return matches
+}
+
+// quoteIfNeeded quotes a value that would otherwise not be lexed as a single string, e.g. because it contains
+// whitespace, a special symbol or is a keyword.
+func quoteIfNeeded(value string) string {
+	needsQuotes := value == "" || (keyword(value) != 0 && keyword(value) != itemBool)
+	for _, r := range value {
+		if isSpace(r) || isSpecialSymbol(r) {
+			needsQuotes = true
+			break
+		}
+	}
+	if !needsQuotes {
+		return value
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
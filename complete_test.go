@@ -0,0 +1,165 @@
+package kqlfilter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComplete(t *testing.T) {
+	schema := Schema{
+		"type":        {AllowedValues: []string{"team", "player", "play off"}, AllowMultipleValues: true},
+		"type_id":     {Type: FieldTypeInt64},
+		"created":     {Type: FieldTypeTimestamp, AllowRanges: true},
+		"active":      {Type: FieldTypeBool, Aliases: []string{"enabled"}},
+		"user.name":   {},
+		"user.status": {AllowedValues: []string{"new", "banned"}},
+	}
+
+	testCases := []struct {
+		name     string
+		input    string
+		cursor   int
+		expected Completion
+	}{
+		{
+			name:  "empty input",
+			input: "",
+			expected: Completion{
+				Kind:        CompletionField,
+				Suggestions: []string{"active", "created", "enabled", "type", "type_id", "user.name", "user.status"},
+			},
+		},
+		{
+			name:  "partial field",
+			input: "typ",
+			expected: Completion{
+				Kind: CompletionField, Token: "typ", Start: 0, End: 3,
+				Suggestions: []string{"type", "type_id"},
+			},
+		},
+		{
+			name:  "field after clause",
+			input: "type:team AND cr",
+			expected: Completion{
+				Kind: CompletionField, Token: "cr", Start: 14, End: 16,
+				Suggestions: []string{"created"},
+			},
+		},
+		{
+			name:  "field after whitespace",
+			input: "type:team ",
+			expected: Completion{
+				Kind: CompletionField, Start: 10, End: 10,
+				Suggestions: []string{"active", "created", "enabled", "type", "type_id", "user.name", "user.status"},
+			},
+		},
+		{
+			name:  "operator",
+			input: "created ",
+			expected: Completion{
+				Kind: CompletionOperator, Field: "created", Start: 8, End: 8,
+				Suggestions: []string{":", "<", "<=", ">", ">="},
+			},
+		},
+		{
+			name:  "operator without ranges",
+			input: "not enabled ",
+			expected: Completion{
+				Kind: CompletionOperator, Field: "enabled", Start: 12, End: 12,
+				Suggestions: []string{":"},
+			},
+		},
+		{
+			name:  "value",
+			input: "type:pl",
+			expected: Completion{
+				Kind: CompletionValue, Field: "type", Token: "pl", Start: 5, End: 7,
+				Suggestions: []string{`"play off"`, "player"},
+			},
+		},
+		{
+			name:  "quoted value",
+			input: `type: "play o`,
+			expected: Completion{
+				Kind: CompletionValue, Field: "type", Token: `"play o`, Start: 6, End: 13,
+				Suggestions: []string{`"play off"`},
+			},
+		},
+		{
+			name:  "value in list",
+			input: "type:(team or p",
+			expected: Completion{
+				Kind: CompletionValue, Field: "type", Token: "p", Start: 14, End: 15,
+				Suggestions: []string{`"play off"`, "player"},
+			},
+		},
+		{
+			name:  "value in comma-separated list",
+			input: "type:(team, p",
+			expected: Completion{
+				Kind: CompletionValue, Field: "type", Token: "p", Start: 12, End: 13,
+				Suggestions: []string{`"play off"`, "player"},
+			},
+		},
+		{
+			name:  "keyword in list",
+			input: "type:(team o",
+			expected: Completion{
+				Kind: CompletionKeyword, Token: "o", Start: 11, End: 12,
+				Suggestions: []string{"or"},
+			},
+		},
+		{
+			name:  "boolean values",
+			input: "active:",
+			expected: Completion{
+				Kind: CompletionValue, Field: "active", Start: 7, End: 7,
+				Suggestions: []string{"false", "true"},
+			},
+		},
+		{
+			name:  "relative time",
+			input: "created>=t",
+			expected: Completion{
+				Kind: CompletionValue, Field: "created", Token: "t", Start: 9, End: 10,
+				Suggestions: []string{"this_week", "today"},
+			},
+		},
+		{
+			name:  "nested field",
+			input: "user:{st",
+			expected: Completion{
+				Kind: CompletionField, Token: "st", Start: 6, End: 8,
+				Suggestions: []string{"status"},
+			},
+		},
+		{
+			name:  "nested value",
+			input: "user:{name:jon and status:",
+			expected: Completion{
+				Kind: CompletionValue, Field: "user.status", Start: 26, End: 26,
+				Suggestions: []string{"banned", "new"},
+			},
+		},
+		{
+			name:   "cursor in the middle",
+			input:  "type:team created>today",
+			cursor: 7,
+			expected: Completion{
+				Kind: CompletionValue, Field: "type", Token: "te", Start: 5, End: 7,
+				Suggestions: []string{"team"},
+			},
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			cursor := test.cursor
+			if cursor == 0 {
+				cursor = len(test.input)
+			}
+			assert.Equal(t, test.expected, Complete(test.input, cursor, schema))
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package kqlfilter
+
+// And returns an AST requiring all of the nodes, e.g. to combine a user-provided filter with server-enforced
+// conditions such as tenant scoping before conversion. Nil nodes are skipped and conjunctions are flattened, so the
+// result can still be converted to a Filter if the nodes can. It returns nil if there are no nodes, and the node
+// itself if there is only one. The given nodes are not modified.
+func And(nodes ...Node) Node {
+	and := &AndNode{NodeType: NodeAnd}
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case nil:
+		case *AndNode:
+			and.Nodes = append(and.Nodes, n.Nodes...)
+		default:
+			and.Nodes = append(and.Nodes, n)
+		}
+	}
+	switch len(and.Nodes) {
+	case 0:
+		return nil
+	case 1:
+		return and.Nodes[0]
+	default:
+		and.Pos = and.Nodes[0].Position()
+		return and
+	}
+}
+
+// Or returns an AST requiring any of the nodes. Like And, nil nodes are skipped and disjunctions are flattened.
+// It returns nil if there are no nodes, and the node itself if there is only one. The given nodes are not modified.
+func Or(nodes ...Node) Node {
+	or := &OrNode{NodeType: NodeOr}
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case nil:
+		case *OrNode:
+			or.Nodes = append(or.Nodes, n.Nodes...)
+		default:
+			or.Nodes = append(or.Nodes, n)
+		}
+	}
+	switch len(or.Nodes) {
+	case 0:
+		return nil
+	case 1:
+		return or.Nodes[0]
+	default:
+		or.Pos = or.Nodes[0].Position()
+		return or
+	}
+}
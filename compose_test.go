@@ -0,0 +1,33 @@
+package kqlfilter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAndOr(t *testing.T) {
+	user, err := ParseAST("state:active and (type:a or type:b)")
+	require.NoError(t, err)
+	tenant, err := ParseAST("tenant_id:t1")
+	require.NoError(t, err)
+
+	combined := And(user, nil, tenant)
+	assert.Equal(t, "(state=active AND (type=a OR type=b) AND tenant_id=t1)", combined.String())
+	assert.Equal(t, "(state=active AND (type=a OR type=b))", user.String())
+
+	simple, err := ParseAST("state:active and type:a")
+	require.NoError(t, err)
+	f, err := convertToFilter(And(simple, tenant))
+	require.NoError(t, err)
+	assert.Len(t, f.Clauses, 3)
+
+	either, err := ParseAST("owner:me or shared:true")
+	require.NoError(t, err)
+	assert.Equal(t, "(owner=me OR shared=true OR public=true)", Or(either, NewFilter().Eq("public", true).AST()).String())
+
+	assert.Equal(t, tenant, And(nil, tenant))
+	assert.Nil(t, And())
+	assert.Nil(t, Or(nil, nil))
+}
@@ -0,0 +1,178 @@
+package kqlfilter
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const concurrencyTestInput = `userId:12345 state:(active OR paused) email:john* created_at >= "2024-01-01T00:00:00Z"`
+
+var concurrencyTestSpannerConfigs = map[string]FilterToSpannerFieldConfig{
+	"userId": {
+		ColumnName: "user_id",
+		ColumnType: FilterToSpannerFieldColumnTypeInt64,
+	},
+	"state": {
+		ColumnType:          FilterToSpannerFieldColumnTypeString,
+		AllowMultipleValues: true,
+	},
+	"email": {
+		ColumnType:       FilterToSpannerFieldColumnTypeString,
+		AllowPrefixMatch: true,
+	},
+	"created_at": {
+		ColumnType:  FilterToSpannerFieldColumnTypeTimestamp,
+		AllowRanges: true,
+	},
+}
+
+var concurrencyTestSquirrelConfigs = map[string]FilterToSquirrelSqlFieldConfig{
+	"userId": {
+		ColumnName: "user_id",
+		ColumnType: FilterToSquirrelSqlFieldColumnTypeInt64,
+	},
+	"state": {
+		ColumnType:          FilterToSquirrelSqlFieldColumnTypeString,
+		AllowMultipleValues: true,
+	},
+	"email": {
+		ColumnType:       FilterToSquirrelSqlFieldColumnTypeString,
+		AllowPrefixMatch: true,
+	},
+	"created_at": {
+		ColumnType:  FilterToSquirrelSqlFieldColumnTypeTimestamp,
+		AllowRanges: true,
+	},
+}
+
+// runConcurrently calls f from n goroutines at once and waits for all of them to finish.
+func runConcurrently(n int, f func(i int)) {
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			f(i)
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+}
+
+// These tests are most useful when run with the race detector (go test -race).
+
+func TestConcurrentParse(t *testing.T) {
+	expected, err := ParseAST(concurrencyTestInput)
+	require.NoError(t, err)
+
+	runConcurrently(32, func(i int) {
+		ast, err := ParseAST(concurrencyTestInput)
+		assert.NoError(t, err)
+		assert.Equal(t, expected.String(), ast.String())
+	})
+}
+
+func TestConcurrentParseOptions(t *testing.T) {
+	// Parsers are reused between calls; the options of one call must not leak into another.
+	runConcurrently(32, func(i int) {
+		_, err := ParseAST("a:1 OR b:2", DisableComplexExpressions())
+		assert.Error(t, err)
+		ast, err := ParseAST("a:1 OR b:2")
+		assert.NoError(t, err)
+		assert.Equal(t, "(a=1 OR b=2)", ast.String())
+	})
+}
+
+func TestConcurrentSharedFilterConversion(t *testing.T) {
+	f, err := Parse(concurrencyTestInput)
+	require.NoError(t, err)
+	original := fmt.Sprint(f)
+
+	expectedSQL, expectedParams, err := f.ToSpannerSQL(concurrencyTestSpannerConfigs)
+	require.NoError(t, err)
+	expectedStmt, err := f.ToSquirrelSql(sq.Select("*").From("t"), concurrencyTestSquirrelConfigs)
+	require.NoError(t, err)
+	expectedSquirrelSQL, expectedArgs, err := expectedStmt.ToSql()
+	require.NoError(t, err)
+
+	runConcurrently(32, func(i int) {
+		if i%2 == 0 {
+			sql, params, err := f.ToSpannerSQL(concurrencyTestSpannerConfigs)
+			assert.NoError(t, err)
+			assert.Equal(t, expectedSQL, sql)
+			assert.Equal(t, expectedParams, params)
+			return
+		}
+		stmt, err := f.ToSquirrelSql(sq.Select("*").From("t"), concurrencyTestSquirrelConfigs)
+		assert.NoError(t, err)
+		sql, args, err := stmt.ToSql()
+		assert.NoError(t, err)
+		assert.Equal(t, expectedSquirrelSQL, sql)
+		assert.Equal(t, expectedArgs, args)
+	})
+
+	assert.Equal(t, original, fmt.Sprint(f), "conversion must not modify the filter")
+}
+
+func TestConcurrentSharedASTConversion(t *testing.T) {
+	ast, err := ParseAST(concurrencyTestInput)
+	require.NoError(t, err)
+	original := ast.String()
+
+	runConcurrently(32, func(i int) {
+		f, err := convertToFilter(ast)
+		assert.NoError(t, err)
+		_, _, err = f.ToSpannerSQL(concurrencyTestSpannerConfigs)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, HasMustEqual(ast, "state"))
+	})
+
+	assert.Equal(t, original, ast.String(), "conversion must not modify the AST")
+}
+
+func BenchmarkParseAST(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, err := ParseAST(concurrencyTestInput)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkToSpannerSQLParallel(b *testing.B) {
+	f, err := Parse(concurrencyTestInput)
+	require.NoError(b, err)
+
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_, _, err := f.ToSpannerSQL(concurrencyTestSpannerConfigs)
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkToSquirrelSqlParallel(b *testing.B) {
+	f, err := Parse(concurrencyTestInput)
+	require.NoError(b, err)
+
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_, err := f.ToSquirrelSql(sq.Select("*").From("t"), concurrencyTestSquirrelConfigs)
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
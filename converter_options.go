@@ -0,0 +1,265 @@
+package kqlfilter
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/civil"
+)
+
+// ConverterOption is a function that configures the conversion of a Filter into a backend query.
+// Converter options are accepted by Filter.ToSpannerSQL and Filter.ToSquirrelSql.
+type ConverterOption func(*converterOptions)
+
+type converterOptions struct {
+	now                 func() time.Time
+	location            *time.Location
+	softDeleteColumn    string
+	allowIncludeDeleted bool
+	collapseRanges      bool
+	dedupParams         bool
+	startsWith          bool
+	ilike               bool
+	conditions          []implicitCondition
+	timeLayouts         []string
+	epochUnit           EpochUnit
+	dateRanges          bool
+	allErrors           bool
+	maxValues           int
+}
+
+// implicitCondition is a condition added to every converted filter with WithCondition.
+type implicitCondition struct {
+	sql    string
+	params map[string]any
+}
+
+func newConverterOptions(options []ConverterOption) converterOptions {
+	o := converterOptions{
+		now:      time.Now,
+		location: time.UTC,
+	}
+	for _, option := range options {
+		option(&o)
+	}
+	return o
+}
+
+// WithClock sets the function used to determine the current time when resolving relative time keywords such as
+// `today`. Defaults to time.Now.
+func WithClock(now func() time.Time) ConverterOption {
+	return func(o *converterOptions) {
+		o.now = now
+	}
+}
+
+// WithSoftDelete excludes soft-deleted rows by adding a `column = false` condition to every converted filter.
+// If allowIncludeDeleted is true, the IncludeDeletedField pseudo-field can be used to omit that condition, e.g. for
+// admin tooling; otherwise using the pseudo-field results in an error.
+func WithSoftDelete(column string, allowIncludeDeleted bool) ConverterOption {
+	return func(o *converterOptions) {
+		o.softDeleteColumn = column
+		o.allowIncludeDeleted = allowIncludeDeleted
+	}
+}
+
+// WithCondition adds a condition to every filter converted by Filter.ToSpannerSQL and Filter.ToPostgresSQL, e.g.
+// `deleted_at IS NULL` or `tenant_id=@tenant`, together with the params it references. This enforces conditions such
+// as tenant scoping regardless of the user-provided filter. Param names must not start with `KQL`, which is used for
+// the params of the filter.
+func WithCondition(condition string, params map[string]any) ConverterOption {
+	return func(o *converterOptions) {
+		o.conditions = append(o.conditions, implicitCondition{sql: condition, params: params})
+	}
+}
+
+// appendConditions appends the conditions added with WithCondition and their params.
+func (o converterOptions) appendConditions(conditions []string, params map[string]any) ([]string, error) {
+	for _, c := range o.conditions {
+		for name, value := range c.params {
+			if _, ok := params[name]; ok || strings.HasPrefix(name, "KQL") {
+				return nil, fmt.Errorf("param %s of condition %q conflicts with the params of the filter", name, c.sql)
+			}
+			params[name] = value
+		}
+		conditions = append(conditions, c.sql)
+	}
+	return conditions, nil
+}
+
+// WithMaxValues sets the maximum number of values of a clause, e.g. `state:(a OR b OR c)`, in Filter.ToSpannerSQL for
+// fields without a MaxValues of their own, to prevent the query plan problems of very long IN lists. Clauses with more
+// values are rejected with an error matching ErrMultipleValuesNotAllowed. Defaults to no limit.
+func WithMaxValues(n int) ConverterOption {
+	return func(o *converterOptions) {
+		o.maxValues = n
+	}
+}
+
+// WithCollapseRanges combines an inclusive lower and upper bound on the same column into a single BETWEEN condition
+// in Filter.ToSpannerSQL, which can yield better query plans. Defaults to keeping separate conditions.
+func WithCollapseRanges() ConverterOption {
+	return func(o *converterOptions) {
+		o.collapseRanges = true
+	}
+}
+
+// WithParamDeduplication makes Filter.ToSpannerSQL reuse a single param for identical values instead of binding each
+// value separately, e.g. `a:x or b:x` results in `a=@KQL0` and `b=@KQL0`. This reduces the number of params of
+// complex filters and makes equivalent statements more likely to share a cached query plan. Defaults to one param
+// per value.
+func WithParamDeduplication() ConverterOption {
+	return func(o *converterOptions) {
+		o.dedupParams = true
+	}
+}
+
+// WithStartsWith makes Filter.ToSpannerSQL emit prefix matches as `STARTS_WITH(column, @param)` and suffix matches
+// as `ENDS_WITH(column, @param)` instead of LIKE, so values are bound as-is without escaping `%` and `_`, and prefix
+// matches can use an index on the column. Values with a wildcard on both sides still use LIKE. Defaults to LIKE.
+func WithStartsWith() ConverterOption {
+	return func(o *converterOptions) {
+		o.startsWith = true
+	}
+}
+
+// WithILike uses ILIKE for fields with AllowCaseInsensitiveMatch in Filter.ToSquirrelSql, as supported by PostgreSQL.
+// Defaults to comparing the lowercased column and value, which works with any database.
+func WithILike() ConverterOption {
+	return func(o *converterOptions) {
+		o.ilike = true
+	}
+}
+
+// WithDefaultLocation sets the timezone in which relative time keywords are resolved, e.g. when the day starts for
+// `today`, and in which timestamps without a timezone are interpreted, see WithTimeLayouts. Defaults to UTC.
+func WithDefaultLocation(loc *time.Location) ConverterOption {
+	return func(o *converterOptions) {
+		o.location = loc
+	}
+}
+
+// WithTimeLayouts sets additional layouts, as used by time.Parse, that timestamp values may be written in, e.g.
+// "2006-01-02 15:04" or "2006-01-02" for filters entered by humans. The layouts are tried in order after RFC 3339.
+// Values without a timezone are interpreted in the location set with WithDefaultLocation.
+// Defaults to accepting RFC 3339 only.
+func WithTimeLayouts(layouts ...string) ConverterOption {
+	return func(o *converterOptions) {
+		o.timeLayouts = append(o.timeLayouts, layouts...)
+	}
+}
+
+// EpochUnit is the unit of numeric timestamp values, see WithEpochTimestamps.
+type EpochUnit int
+
+const (
+	// EpochSeconds interprets numeric timestamp values as seconds since the Unix epoch.
+	EpochSeconds EpochUnit = iota + 1
+	// EpochMilliseconds interprets numeric timestamp values as milliseconds since the Unix epoch.
+	EpochMilliseconds
+)
+
+// WithEpochTimestamps accepts integer values for timestamp fields, e.g. `created>1714521600`, interpreted as Unix
+// seconds or milliseconds depending on unit, as preferred by machine clients. They are converted to time.Time params
+// like RFC 3339 values. Defaults to accepting formatted timestamps only.
+func WithEpochTimestamps(unit EpochUnit) ConverterOption {
+	return func(o *converterOptions) {
+		o.epochUnit = unit
+	}
+}
+
+// WithAllErrors makes Filter.ToSpannerSQL and Filter.ToSquirrelSql report the errors of all clauses instead of stopping
+// at the first one, joined with errors.Join, so users can fix all of them at once. The individual errors can be
+// inspected with errors.As, or by unwrapping the returned error. Defaults to returning the first error.
+func WithAllErrors() ConverterOption {
+	return func(o *converterOptions) {
+		o.allErrors = true
+	}
+}
+
+// errorCollector collects the errors of a conversion, all of them with WithAllErrors or only the first one otherwise.
+type errorCollector struct {
+	all  bool
+	errs []error
+}
+
+// add records the error and reports whether the conversion must stop.
+func (c *errorCollector) add(err error) bool {
+	c.errs = append(c.errs, err)
+	return !c.all
+}
+
+// err returns the recorded errors joined with errors.Join, or nil if there are none.
+func (c *errorCollector) err() error {
+	if len(c.errs) == 1 {
+		return c.errs[0]
+	}
+	return errors.Join(c.errs...)
+}
+
+// WithDateRanges expands equality on a timestamp field with a date only (`created_at:2024-05-01`) to the range of that
+// day, `created_at >= 2024-05-01T00:00:00 AND created_at < 2024-05-02T00:00:00`, like Kibana does. Days start in the
+// location set with WithDefaultLocation. Applies to Filter.ToSpannerSQL and Filter.ToSquirrelSql. Dates are checked
+// against AllowedValues first, and are not expanded for fields with a MapValue function, which converts them itself.
+// Defaults to requiring a complete timestamp.
+func WithDateRanges() ConverterOption {
+	return func(o *converterOptions) {
+		o.dateRanges = true
+	}
+}
+
+// dateRange returns the start of the day of a date-only value and the start of the next day, if enabled with
+// WithDateRanges.
+func (o converterOptions) dateRange(value string) (time.Time, time.Time, bool) {
+	if !o.dateRanges {
+		return time.Time{}, time.Time{}, false
+	}
+	d, err := civil.ParseDate(value)
+	if err != nil {
+		return time.Time{}, time.Time{}, false
+	}
+	return d.In(o.location), d.AddDays(1).In(o.location), true
+}
+
+// parseTime parses a timestamp value as RFC 3339, with one of the layouts set with WithTimeLayouts, or as an epoch
+// timestamp if enabled with WithEpochTimestamps.
+func (o converterOptions) parseTime(value string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err == nil {
+		return t, nil
+	}
+	if t, ok := o.parseEpoch(value); ok {
+		return t, nil
+	}
+	if len(o.timeLayouts) == 0 {
+		return t, err
+	}
+	for _, layout := range o.timeLayouts {
+		if t, err := time.ParseInLocation(layout, value, o.location); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("parsing time %q: not RFC 3339 or one of the layouts %q", value, o.timeLayouts)
+}
+
+// parseEpoch parses an integer value as an epoch timestamp in the unit set with WithEpochTimestamps.
+func (o converterOptions) parseEpoch(value string) (time.Time, bool) {
+	if o.epochUnit == 0 {
+		return time.Time{}, false
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	switch o.epochUnit {
+	case EpochSeconds:
+		return time.Unix(n, 0).UTC(), true
+	case EpochMilliseconds:
+		return time.UnixMilli(n).UTC(), true
+	default:
+		return time.Time{}, false
+	}
+}
@@ -0,0 +1,163 @@
+package kqlfilter
+
+import (
+	"testing"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTime(t *testing.T) {
+	amsterdam, err := time.LoadLocation("Europe/Amsterdam")
+	require.NoError(t, err)
+
+	layouts := WithTimeLayouts("2006-01-02 15:04", "2006-01-02")
+
+	testCases := []struct {
+		name          string
+		value         string
+		options       []ConverterOption
+		expected      time.Time
+		expectedError bool
+	}{
+		{"RFC 3339", "2024-05-01T10:00:00+02:00", nil, time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC), false},
+		{"layouts are not accepted by default", "2024-05-01", nil, time.Time{}, true},
+		{"RFC 3339 with layouts", "2024-05-01T10:00:00Z", []ConverterOption{layouts}, time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC), false},
+		{"date and time", "2024-05-01 10:00", []ConverterOption{layouts}, time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC), false},
+		{"date", "2024-05-01", []ConverterOption{layouts}, time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), false},
+		{"default location", "2024-05-01 10:00", []ConverterOption{layouts, WithDefaultLocation(amsterdam)}, time.Date(2024, 5, 1, 10, 0, 0, 0, amsterdam), false},
+		{"no matching layout", "01/05/2024", []ConverterOption{layouts}, time.Time{}, true},
+		{"epoch is not accepted by default", "1714557600", nil, time.Time{}, true},
+		{"epoch seconds", "1714557600", []ConverterOption{WithEpochTimestamps(EpochSeconds)}, time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC), false},
+		{"epoch milliseconds", "1714557600123", []ConverterOption{WithEpochTimestamps(EpochMilliseconds)}, time.Date(2024, 5, 1, 10, 0, 0, 123000000, time.UTC), false},
+		{"negative epoch", "-86400", []ConverterOption{WithEpochTimestamps(EpochSeconds)}, time.Date(1969, 12, 31, 0, 0, 0, 0, time.UTC), false},
+		{"fractional epoch", "1714557600.5", []ConverterOption{WithEpochTimestamps(EpochSeconds)}, time.Time{}, true},
+		{"epoch with layouts", "1714557600", []ConverterOption{layouts, WithEpochTimestamps(EpochSeconds)}, time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC), false},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			parsed, err := newConverterOptions(test.options).parseTime(test.value)
+			if test.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, test.expected.Equal(parsed), "expected %s, got %s", test.expected, parsed)
+		})
+	}
+}
+
+func TestTimeLayoutsConversion(t *testing.T) {
+	amsterdam, err := time.LoadLocation("Europe/Amsterdam")
+	require.NoError(t, err)
+	options := []ConverterOption{WithTimeLayouts("2006-01-02 15:04", "2006-01-02"), WithDefaultLocation(amsterdam)}
+
+	f, err := Parse(`created_at >= 2024-05-01 created_at < "2024-05-02 12:30"`)
+	require.NoError(t, err)
+
+	_, params, err := f.ToSpannerSQL(map[string]FilterToSpannerFieldConfig{
+		"created_at": {ColumnType: FilterToSpannerFieldColumnTypeTimestamp, AllowRanges: true},
+	}, options...)
+	require.NoError(t, err)
+	assert.True(t, time.Date(2024, 5, 1, 0, 0, 0, 0, amsterdam).Equal(params["KQL0"].(time.Time)))
+	assert.True(t, time.Date(2024, 5, 2, 12, 30, 0, 0, amsterdam).Equal(params["KQL1"].(time.Time)))
+
+	_, params, err = f.ToPostgresSQL(map[string]FilterToPostgresFieldConfig{
+		"created_at": {ColumnType: FilterToPostgresFieldColumnTypeTimestampTZ, FieldOptions: FieldOptions{AllowRanges: true}},
+	}, options...)
+	require.NoError(t, err)
+	assert.True(t, time.Date(2024, 5, 1, 0, 0, 0, 0, amsterdam).Equal(params["KQL0"].(time.Time)))
+
+	v, err := FieldTypeTimestamp.ParseValue("2024-05-01", options...)
+	require.NoError(t, err)
+	assert.True(t, time.Date(2024, 5, 1, 0, 0, 0, 0, amsterdam).Equal(v.(time.Time)))
+}
+
+func TestEpochTimestampsConversion(t *testing.T) {
+	f, err := Parse(`created_at >= 1714557600000 created_at < "2024-05-02T00:00:00Z"`)
+	require.NoError(t, err)
+
+	conditions, params, err := f.ToSpannerSQL(map[string]FilterToSpannerFieldConfig{
+		"created_at": {ColumnType: FilterToSpannerFieldColumnTypeTimestamp, AllowRanges: true},
+	}, WithEpochTimestamps(EpochMilliseconds))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"created_at>=@KQL0", "created_at<@KQL1"}, conditions)
+	assert.Equal(t, time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC), params["KQL0"])
+	assert.Equal(t, time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC), params["KQL1"])
+
+	_, _, err = f.ToSpannerSQL(map[string]FilterToSpannerFieldConfig{
+		"created_at": {ColumnType: FilterToSpannerFieldColumnTypeTimestamp, AllowRanges: true},
+	})
+	assert.Error(t, err)
+}
+
+func TestDateRangesConversion(t *testing.T) {
+	amsterdam, err := time.LoadLocation("Europe/Amsterdam")
+	require.NoError(t, err)
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, amsterdam)
+	end := time.Date(2024, 5, 2, 0, 0, 0, 0, amsterdam)
+	options := []ConverterOption{WithDateRanges(), WithDefaultLocation(amsterdam)}
+
+	f, err := Parse(`created_at:2024-05-01 name:2024-05-01`)
+	require.NoError(t, err)
+
+	conditions, params, err := f.ToSpannerSQL(map[string]FilterToSpannerFieldConfig{
+		"created_at": {ColumnType: FilterToSpannerFieldColumnTypeTimestamp},
+		"name":       {},
+	}, options...)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"created_at>=@KQL0", "created_at<@KQL1", "name=@KQL2"}, conditions)
+	assert.Equal(t, map[string]any{"KQL0": start, "KQL1": end, "KQL2": "2024-05-01"}, params)
+
+	stmt, err := f.ToSquirrelSql(sq.Select("*").From("t"), map[string]FilterToSquirrelSqlFieldConfig{
+		"created_at": {ColumnType: FilterToSquirrelSqlFieldColumnTypeTimestamp},
+		"name":       {},
+	}, options...)
+	require.NoError(t, err)
+	sql, args, err := stmt.ToSql()
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT * FROM t WHERE (created_at >= ? AND created_at < ?) AND name = ?", sql)
+	assert.Equal(t, []any{start, end, "2024-05-01"}, args)
+
+	// Without the option, a date is not a valid timestamp.
+	_, _, err = f.ToSpannerSQL(map[string]FilterToSpannerFieldConfig{
+		"created_at": {ColumnType: FilterToSpannerFieldColumnTypeTimestamp},
+		"name":       {},
+	})
+	assert.Error(t, err)
+
+	// Dates are validated before they are expanded.
+	_, _, err = f.ToSpannerSQL(map[string]FilterToSpannerFieldConfig{
+		"created_at": {ColumnType: FilterToSpannerFieldColumnTypeTimestamp, AllowedValues: []string{"2024-06-01"}},
+		"name":       {},
+	}, options...)
+	assert.ErrorIs(t, err, ErrValueInvalid)
+	_, err = f.ToSquirrelSql(sq.Select("*").From("t"), map[string]FilterToSquirrelSqlFieldConfig{
+		"created_at": {ColumnType: FilterToSquirrelSqlFieldColumnTypeTimestamp, AllowedValues: []string{"2024-06-01"}},
+		"name":       {},
+	}, options...)
+	assert.Error(t, err)
+
+	// A MapValue function converts dates itself.
+	noon := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	mapValue := func(string) (any, error) { return noon, nil }
+	conditions, params, err = f.ToSpannerSQL(map[string]FilterToSpannerFieldConfig{
+		"created_at": {ColumnType: FilterToSpannerFieldColumnTypeTimestamp, MapValue: mapValue},
+		"name":       {},
+	}, options...)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"created_at=@KQL0", "name=@KQL1"}, conditions)
+	assert.Equal(t, map[string]any{"KQL0": noon, "KQL1": "2024-05-01"}, params)
+	stmt, err = f.ToSquirrelSql(sq.Select("*").From("t"), map[string]FilterToSquirrelSqlFieldConfig{
+		"created_at": {ColumnType: FilterToSquirrelSqlFieldColumnTypeTimestamp, MapValue: mapValue},
+		"name":       {},
+	}, options...)
+	require.NoError(t, err)
+	sql, args, err = stmt.ToSql()
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT * FROM t WHERE created_at = ? AND name = ?", sql)
+	assert.Equal(t, []any{noon, "2024-05-01"}, args)
+}
@@ -0,0 +1,276 @@
+package kqlfilter
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Phrases are the phrases used by Describe, which can be replaced to localize descriptions. Clause phrases are format
+// strings taking the field label as first and the value as second argument, e.g. `%[1]s is %[2]s`.
+type Phrases struct {
+	Is         string // `type:team`
+	IsNot      string // `not type:team`
+	IsAnyOf    string // `type:(team or player)`, with the values joined by Or
+	IsNoneOf   string // `not type:(team or player)`, with the values joined by Nor
+	Exists     string // `type:*`, without value
+	NotExists  string // `not type:*`, without value
+	StartsWith string // `name:jo*`
+	EndsWith   string // `name:*son`
+	Contains   string // `name:*jo*`
+	Matches    string // values with other wildcards, e.g. `name:j*n`
+	Similar    string // `name:jon~1`
+	Gt         string // `age>18`
+	Gte        string // `age>=18`
+	Lt         string // `age<18`
+	Lte        string // `age<=18`
+	After      string // `created>2024-01-01`, for dates, timestamps and relative time keywords
+	OnOrAfter  string // `created>=2024-01-01`
+	Before     string // `created<2024-01-01`
+	OnOrBefore string // `created<=2024-01-01`
+	Text       string // a value without field, e.g. `foo`, with the value as only argument
+	True       string // the boolean literal `true`
+	False      string // the boolean literal `false`
+	Not        string // negation of any other expression, with the expression as only argument
+	And        string // separator of the clauses of an AND
+	Or         string // separator of the clauses of an OR, and of the values of IsAnyOf
+	Nor        string // separator of the values of IsNoneOf
+}
+
+// EnglishPhrases are the default phrases of Describe.
+var EnglishPhrases = Phrases{
+	Is:         "%[1]s is %[2]s",
+	IsNot:      "%[1]s is not %[2]s",
+	IsAnyOf:    "%[1]s is %[2]s",
+	IsNoneOf:   "%[1]s is neither %[2]s",
+	Exists:     "%[1]s is set",
+	NotExists:  "%[1]s is not set",
+	StartsWith: "%[1]s starts with %[2]s",
+	EndsWith:   "%[1]s ends with %[2]s",
+	Contains:   "%[1]s contains %[2]s",
+	Matches:    "%[1]s matches %[2]s",
+	Similar:    "%[1]s is similar to %[2]s",
+	Gt:         "%[1]s is greater than %[2]s",
+	Gte:        "%[1]s is at least %[2]s",
+	Lt:         "%[1]s is less than %[2]s",
+	Lte:        "%[1]s is at most %[2]s",
+	After:      "%[1]s after %[2]s",
+	OnOrAfter:  "%[1]s on or after %[2]s",
+	Before:     "%[1]s before %[2]s",
+	OnOrBefore: "%[1]s on or before %[2]s",
+	Text:       "matches %q",
+	True:       "everything",
+	False:      "nothing",
+	Not:        "not %s",
+	And:        ", and ",
+	Or:         " or ",
+	Nor:        " nor ",
+}
+
+// DescribeOption is a function that configures Describe.
+type DescribeOption func(*describer)
+
+// WithFieldLabels sets the labels of fields, e.g. `{"create_time": "created"}`. Fields without a label are described
+// by their name. Nested fields are labeled by their full name, e.g. `user.name`.
+func WithFieldLabels(labels map[string]string) DescribeOption {
+	return func(d *describer) {
+		d.labels = labels
+	}
+}
+
+// WithValueFormatter sets a function formatting the values of a field, e.g. to show the label of an enum value or to
+// format a timestamp in the locale of the user. It receives the value without escapes or wildcards.
+// Defaults to showing the value as is.
+func WithValueFormatter(format func(field, value string) string) DescribeOption {
+	return func(d *describer) {
+		d.formatValue = format
+	}
+}
+
+// WithPhrases sets the phrases used in descriptions, e.g. to localize them. Defaults to EnglishPhrases.
+func WithPhrases(phrases Phrases) DescribeOption {
+	return func(d *describer) {
+		d.phrases = phrases
+	}
+}
+
+// Describe returns a human-readable description of the AST, e.g. `type is team or player, and created after
+// 2024-01-01` for `type:(team or player) and created>2024-01-01`, to show the active filter to users. Compound
+// expressions within other compound expressions are put between parentheses. An empty AST returns an empty string.
+func Describe(ast Node, options ...DescribeOption) string {
+	if ast == nil {
+		return ""
+	}
+	d := describer{phrases: EnglishPhrases}
+	for _, option := range options {
+		option(&d)
+	}
+	return d.describe(ast, "")
+}
+
+type describer struct {
+	labels      map[string]string
+	formatValue func(field, value string) string
+	phrases     Phrases
+}
+
+func (d *describer) describe(ast Node, prefix string) string {
+	switch n := ast.(type) {
+	case *AndNode:
+		return d.join(n.Nodes, prefix, d.phrases.And)
+	case *OrNode:
+		return d.join(n.Nodes, prefix, d.phrases.Or)
+	case *NotNode:
+		return d.describeNot(n.Expr, prefix)
+	case *IsNode:
+		field := prefix + n.Identifier
+		switch v := n.Value.(type) {
+		case *NestedNode:
+			return d.describe(v.Expr, field+".")
+		case *LiteralNode:
+			return d.describeLiteral(field, v.Value)
+		case *OrNode:
+			return fmt.Sprintf(d.phrases.IsAnyOf, d.label(field), d.values(field, v, d.phrases.Or))
+		}
+	case *RangeNode:
+		if v, ok := n.Value.(*LiteralNode); ok {
+			return fmt.Sprintf(d.rangePhrase(n.Operator, v.Value), d.label(prefix+n.Identifier), d.value(prefix+n.Identifier, v.Value))
+		}
+	case *ExistsNode:
+		return fmt.Sprintf(d.phrases.Exists, d.label(prefix+n.Identifier))
+	case *FuzzyNode:
+		return fmt.Sprintf(d.phrases.Similar, d.label(prefix+n.Identifier), d.value(prefix+n.Identifier, n.Value))
+	case *LiteralNode:
+		switch n.Value {
+		case "true":
+			return d.phrases.True
+		case "false":
+			return d.phrases.False
+		default:
+			return fmt.Sprintf(d.phrases.Text, UnescapeValue(n.Value))
+		}
+	}
+	return ast.String()
+}
+
+// describeNot describes the negation of an expression, using the negated phrase of simple clauses.
+func (d *describer) describeNot(ast Node, prefix string) string {
+	switch n := ast.(type) {
+	case *IsNode:
+		field := prefix + n.Identifier
+		switch v := n.Value.(type) {
+		case *LiteralNode:
+			if !containsWildcard(v.Value) {
+				return fmt.Sprintf(d.phrases.IsNot, d.label(field), d.value(field, v.Value))
+			}
+		case *OrNode:
+			return fmt.Sprintf(d.phrases.IsNoneOf, d.label(field), d.values(field, v, d.phrases.Nor))
+		}
+	case *ExistsNode:
+		return fmt.Sprintf(d.phrases.NotExists, d.label(prefix+n.Identifier))
+	}
+	return fmt.Sprintf(d.phrases.Not, d.group(ast, prefix))
+}
+
+// describeLiteral describes equality with a value, which can contain wildcards.
+func (d *describer) describeLiteral(field, value string) string {
+	if !containsWildcard(value) {
+		return fmt.Sprintf(d.phrases.Is, d.label(field), d.value(field, value))
+	}
+	text, prefix, suffix := SplitWildcards(value, true, true)
+	switch {
+	case containsWildcard(text):
+		return fmt.Sprintf(d.phrases.Matches, d.label(field), value)
+	case prefix && suffix:
+		return fmt.Sprintf(d.phrases.Contains, d.label(field), d.formattedValue(field, text))
+	case prefix:
+		return fmt.Sprintf(d.phrases.StartsWith, d.label(field), d.formattedValue(field, text))
+	default:
+		return fmt.Sprintf(d.phrases.EndsWith, d.label(field), d.formattedValue(field, text))
+	}
+}
+
+// rangePhrase returns the phrase of a range operator, using the time phrases for dates, timestamps and relative time
+// keywords.
+func (d *describer) rangePhrase(op RangeOperator, value string) string {
+	isTime := false
+	if _, ok := newConverterOptions(nil).resolveRelativeTime(value); ok {
+		isTime = true
+	} else if _, err := time.Parse(time.DateOnly, value); err == nil {
+		isTime = true
+	} else if _, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		isTime = true
+	}
+	switch op {
+	case RangeOperatorGt:
+		if isTime {
+			return d.phrases.After
+		}
+		return d.phrases.Gt
+	case RangeOperatorGte:
+		if isTime {
+			return d.phrases.OnOrAfter
+		}
+		return d.phrases.Gte
+	case RangeOperatorLt:
+		if isTime {
+			return d.phrases.Before
+		}
+		return d.phrases.Lt
+	default:
+		if isTime {
+			return d.phrases.OnOrBefore
+		}
+		return d.phrases.Lte
+	}
+}
+
+// join describes the nodes joined by the separator.
+func (d *describer) join(nodes []Node, prefix, separator string) string {
+	parts := make([]string, 0, len(nodes))
+	for _, n := range nodes {
+		parts = append(parts, d.group(n, prefix))
+	}
+	return strings.Join(parts, separator)
+}
+
+// group describes a node, between parentheses if it is compound.
+func (d *describer) group(ast Node, prefix string) string {
+	switch ast.(type) {
+	case *AndNode, *OrNode:
+		return "(" + d.describe(ast, prefix) + ")"
+	default:
+		return d.describe(ast, prefix)
+	}
+}
+
+// values describes the values of a list joined by the separator.
+func (d *describer) values(field string, list *OrNode, separator string) string {
+	values := make([]string, 0, len(list.Nodes))
+	for _, n := range list.Nodes {
+		if literal, ok := n.(*LiteralNode); ok {
+			values = append(values, d.value(field, literal.Value))
+		}
+	}
+	return strings.Join(values, separator)
+}
+
+func (d *describer) label(field string) string {
+	if label, ok := d.labels[field]; ok {
+		return label
+	}
+	return field
+}
+
+// value formats a value of the AST, which can contain escapes.
+func (d *describer) value(field, value string) string {
+	return d.formattedValue(field, UnescapeValue(value))
+}
+
+// formattedValue formats a value without escapes.
+func (d *describer) formattedValue(field, value string) string {
+	if d.formatValue != nil {
+		return d.formatValue(field, value)
+	}
+	return value
+}
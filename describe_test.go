@@ -0,0 +1,65 @@
+package kqlfilter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDescribe(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"type:(team or player) and created>2024-01-01", "type is team or player, and created after 2024-01-01"},
+		{"not type:(team or player)", "type is neither team nor player"},
+		{"not state:active", "state is not active"},
+		{"age>=18 age<65", "age is at least 18, and age is less than 65"},
+		{`created<="2024-01-01T00:00:00Z" updated>today`, "created on or before 2024-01-01T00:00:00Z, and updated after today"},
+		{"deleted:* and not archived:*", "deleted is set, and archived is not set"},
+		{`name:jo* email:*@example.com title:*go* path:a*b`, "name starts with jo, and email ends with @example.com, and title contains go, and path matches a*b"},
+		{`title:5\*`, "title is 5*"},
+		{"a:1 or (b:2 and c:3)", "a is 1 or (b is 2, and c is 3)"},
+		{"not (a:1 or b:2)", "not (a is 1 or b is 2)"},
+		{"not name:jo*", "not name starts with jo"},
+		{"user:{name:jon and age>30}", "user.name is jon, and user.age is greater than 30"},
+		{"foo", `matches "foo"`},
+		{"true", "everything"},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.input, func(t *testing.T) {
+			ast, err := ParseAST(test.input)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, Describe(ast))
+		})
+	}
+
+	assert.Empty(t, Describe(nil))
+}
+
+func TestDescribeOptions(t *testing.T) {
+	ast, err := ParseAST("type:(team or player) create_time>2024-01-01 not name:*")
+	require.NoError(t, err)
+
+	phrases := EnglishPhrases
+	phrases.IsAnyOf = "%[1]s ist %[2]s"
+	phrases.After = "%[1]s nach %[2]s"
+	phrases.NotExists = "%[1]s ist leer"
+	phrases.Or = " oder "
+	phrases.And = " und "
+
+	description := Describe(ast,
+		WithFieldLabels(map[string]string{"type": "Typ", "create_time": "erstellt"}),
+		WithValueFormatter(func(field, value string) string {
+			if field == "type" {
+				return strings.ToUpper(value[:1]) + value[1:]
+			}
+			return value
+		}),
+		WithPhrases(phrases),
+	)
+	assert.Equal(t, "Typ ist Team oder Player und erstellt nach 2024-01-01 und name ist leer", description)
+}
@@ -0,0 +1,12 @@
+// Package kqlfilter parses Kibana Query Language (KQL) filters and converts them to queries for various backends.
+//
+// # Concurrency
+//
+// Parsing does not share any state between calls, so ParseAST and Parse can be called concurrently.
+// A parsed Node or Filter can be converted concurrently by any number of goroutines, to the same or to different
+// backends: converters only read the tree and keep all per-conversion state, such as parameter counters, local to the
+// call. The field config maps passed to converters are only read as well and can be shared.
+//
+// The exception is NodeMapper.Map, which rewrites the tree in place and must not run concurrently with any other use
+// of the same tree.
+package kqlfilter
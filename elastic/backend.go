@@ -0,0 +1,42 @@
+package elastic
+
+import (
+	"github.com/MottoStreaming/kqlfilter.go"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
+)
+
+func init() {
+	kqlfilter.RegisterConverter("elastic", func() kqlfilter.Backend[any] {
+		return kqlfilter.AnyBackend(Backend())
+	})
+}
+
+// Backend returns a kqlfilter.Backend producing Elasticsearch queries for use with kqlfilter.QueryFromKQL.
+// Field names are resolved against the schema, so aliases are mapped to the canonical field and its column.
+// Term values of numeric and boolean fields are typed according to the schema, see WithFieldTypes, and text fields
+// are matched with match queries, see WithTextFields.
+// Options passed here are applied after the schema based options, so WithFieldMapper overrides the schema.
+func Backend(options ...Option) kqlfilter.Backend[types.Query] {
+	return kqlfilter.BackendFunc[types.Query](func(ast kqlfilter.Node, schema kqlfilter.Schema) (types.Query, error) {
+		if ast == nil {
+			return types.Query{MatchAll: &types.MatchAllQuery{}}, nil
+		}
+		schemaMapper := WithFieldMapper(func(name string) (string, error) {
+			canonical, _, ok := schema.Lookup(name)
+			if !ok {
+				return "", kqlfilter.NewUnknownFieldError(name, schema.FieldNames())
+			}
+			return schema.ColumnName(canonical), nil
+		})
+		fieldTypes := make(map[string]kqlfilter.FieldType, len(schema))
+		var textFields []string
+		for name, fs := range schema {
+			fieldTypes[schema.ColumnName(name)] = fs.Type
+			if fs.Text {
+				textFields = append(textFields, schema.ColumnName(name))
+			}
+		}
+		schemaOptions := []Option{schemaMapper, WithFieldTypes(fieldTypes), WithTextFields(textFields...)}
+		return NewQueryGenerator(append(schemaOptions, options...)...).ConvertAST(ast)
+	})
+}
@@ -0,0 +1,61 @@
+package elastic
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+
+	"github.com/MottoStreaming/kqlfilter.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestConcurrentConvertAST is most useful when run with the race detector (go test -race).
+func TestConcurrentConvertAST(t *testing.T) {
+	ast, err := kqlfilter.ParseAST(`type_id:(team OR player) fields.rank >= 3 not fields.name:x`)
+	require.NoError(t, err)
+	original := ast.String()
+
+	g := NewQueryGenerator(WithFieldValueMapper(func(name, value string) (string, error) {
+		if name == "type_id" {
+			return "mapped_" + value, nil
+		}
+		return value, nil
+	}))
+
+	expected, err := g.ConvertAST(ast)
+	require.NoError(t, err)
+	expectedJSON, err := json.Marshal(expected)
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q, err := g.ConvertAST(ast)
+			assert.NoError(t, err)
+			data, err := json.Marshal(q)
+			assert.NoError(t, err)
+			assert.JSONEq(t, string(expectedJSON), string(data))
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, original, ast.String(), "conversion must not modify the AST")
+}
+
+func BenchmarkConvertASTParallel(b *testing.B) {
+	ast, err := kqlfilter.ParseAST(`type_id:(team OR player) fields.rank >= 3 not fields.name:x`)
+	require.NoError(b, err)
+	g := NewQueryGenerator()
+
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := g.ConvertAST(ast); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
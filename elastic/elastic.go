@@ -1,10 +1,13 @@
 package elastic
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/MottoStreaming/kqlfilter.go"
@@ -14,10 +17,29 @@ import (
 type QueryGenerator struct {
 	mapFieldName  func(name string) (string, error)
 	mapFieldValue func(name, value string) (string, error)
+	filterContext bool
+	fieldTypes    map[string]kqlfilter.FieldType
+	dateRanges    *time.Location
+	// Format and time zone of date range queries, for all fields and by field.
+	dateRangeOptions      *DateRangeOptions
+	fieldDateRangeOptions map[string]DateRangeOptions
+	boosts                map[string]float32
+	textFields            map[string]bool
+	// minimumShouldMatch of `should` clauses, omitted if zero.
+	minimumShouldMatch int
+	// Limits of the generated query, unlimited if zero.
+	maxDepth   int
+	maxClauses int
 }
 
+// Errors returned by ConvertAST when the generated query exceeds a limit set with WithMaxDepth or WithMaxClauses.
+var (
+	ErrMaxDepthExceeded   = errors.New("maximum nesting depth exceeded")
+	ErrMaxClausesExceeded = errors.New("maximum number of clauses exceeded")
+)
+
 func NewQueryGenerator(options ...Option) *QueryGenerator {
-	g := &QueryGenerator{mapFieldName: defaultFieldNameMapper, mapFieldValue: defaultFieldValueMapper}
+	g := &QueryGenerator{mapFieldName: defaultFieldNameMapper, mapFieldValue: defaultFieldValueMapper, minimumShouldMatch: 1}
 
 	for _, option := range options {
 		option(g)
@@ -48,6 +70,115 @@ func WithFieldMapper(fieldMapper func(name string) (string, error)) Option {
 	}
 }
 
+// WithFilterContext generates queries that run in filter context: conjunctions are put under `filter` instead of
+// `must`, and any other query is wrapped in a `bool` query with a `filter` clause. Filter context skips scoring and
+// allows Elasticsearch to cache the clauses, which is preferable when results are not sorted by relevance.
+func WithFilterContext() Option {
+	return func(g *QueryGenerator) {
+		g.filterContext = true
+	}
+}
+
+// WithFieldTypes sets the types of fields, keyed by the field name as returned by the field mapper. Values of numeric
+// and boolean fields are emitted as numbers and booleans in term and terms queries instead of strings, which are
+// interpreted inconsistently across Elasticsearch versions, e.g. for boolean mappings. Values are converted after
+// applying the field value mapper, and an error is returned if they cannot be converted. Fields without a type are
+// emitted as strings.
+func WithFieldTypes(fieldTypes map[string]kqlfilter.FieldType) Option {
+	return func(g *QueryGenerator) {
+		g.fieldTypes = fieldTypes
+	}
+}
+
+// WithDateRanges expands equality on a timestamp field with a date only (`created:2024-05-01`) to a range query for that
+// day, with days starting in the given location, like Kibana does. Timestamp fields are set with WithFieldTypes.
+func WithDateRanges(loc *time.Location) Option {
+	return func(g *QueryGenerator) {
+		g.dateRanges = loc
+	}
+}
+
+// DateRangeOptions are the parameters of the date range queries of a field, which determine how Elasticsearch
+// interprets the dates.
+type DateRangeOptions struct {
+	// Format of the dates, e.g. `yyyy-MM-dd||strict_date_optional_time`. Defaults to the format of the field mapping.
+	Format string
+	// Time zone in which dates without a time zone are interpreted, e.g. `Europe/Amsterdam` or `+02:00`. Defaults to
+	// UTC.
+	TimeZone string
+}
+
+// WithDateRangeOptions sets the format and time zone of the date range queries of all timestamp fields (see
+// WithFieldTypes), e.g. so that `created>=2024-05-01` is interpreted in the time zone of the caller. Values of range
+// queries on these fields are passed to Elasticsearch as-is, and any date it accepts can be used. Combined with
+// WithDateRanges, a date-only value (`created:2024-05-01`) is expanded to its day in the time zone, using date math.
+// Options set with WithFieldDateRangeOptions take precedence.
+func WithDateRangeOptions(options DateRangeOptions) Option {
+	return func(g *QueryGenerator) {
+		g.dateRangeOptions = &options
+	}
+}
+
+// WithFieldDateRangeOptions sets the format and time zone of the date range queries of fields, keyed by the field
+// name as returned by the field mapper, like WithDateRangeOptions. The fields are treated as timestamp fields.
+func WithFieldDateRangeOptions(options map[string]DateRangeOptions) Option {
+	return func(g *QueryGenerator) {
+		g.fieldDateRangeOptions = options
+	}
+}
+
+// WithFieldBoosts sets the boosts of fields, keyed by the field name as returned by the field mapper, which are attached
+// to the term, terms, match and fuzzy queries generated for these fields to tune their relevance. Boosts have no effect in
+// filter context.
+func WithFieldBoosts(boosts map[string]float32) Option {
+	return func(g *QueryGenerator) {
+		g.boosts = boosts
+	}
+}
+
+// WithTextFields marks fields as analyzed text fields, keyed by the field name as returned by the field mapper. Term
+// queries only match the exact tokens in the index, so values of text fields are matched with a `match` query instead,
+// and quoted values of multiple words (`title:"quick fox"`) with a `match_phrase` query. Multiple values are matched
+// with a `bool` query with a `should` clause per value.
+func WithTextFields(fields ...string) Option {
+	return func(g *QueryGenerator) {
+		if g.textFields == nil {
+			g.textFields = make(map[string]bool, len(fields))
+		}
+		for _, field := range fields {
+			g.textFields[field] = true
+		}
+	}
+}
+
+// WithMinimumShouldMatch sets `minimum_should_match` on the `bool` queries with `should` clauses generated for OR
+// expressions, which defaults to 1. Without it Elasticsearch applies a default that depends on the surrounding query,
+// e.g. none of the `should` clauses has to match once `must` or `filter` clauses are added to the query. Zero omits the
+// parameter.
+func WithMinimumShouldMatch(n int) Option {
+	return func(g *QueryGenerator) {
+		g.minimumShouldMatch = n
+	}
+}
+
+// WithMaxDepth sets the maximum nesting depth of the `bool` queries of the generated query, e.g. 1 for `a:1 and b:2`
+// and 2 for `a:1 and (b:2 or c:3)`. Deeper queries are rejected with an error matching ErrMaxDepthExceeded. Defaults to
+// no limit.
+func WithMaxDepth(depth int) Option {
+	return func(g *QueryGenerator) {
+		g.maxDepth = depth
+	}
+}
+
+// WithMaxClauses sets the maximum total number of clauses of the `bool` queries of the generated query, to reject
+// queries that would fail with a `too_many_clauses` error in Elasticsearch with an error matching
+// ErrMaxClausesExceeded instead. Defaults to no limit.
+func WithMaxClauses(clauses int) Option {
+	return func(g *QueryGenerator) {
+		g.maxClauses = clauses
+	}
+}
+
 // WithFieldValueMapper allows mapping incoming values for a field, or returning an error on invalid values.
 // Example usage:
 //
@@ -65,7 +196,79 @@ func WithFieldValueMapper(fieldValueMapper func(name, value string) (string, err
 
 // ConvertAST converts a KQL AST to an Elasticsearch query.
 func (q *QueryGenerator) ConvertAST(root kqlfilter.Node) (types.Query, error) {
-	return q.convertNodeToQuery(root, "")
+	query, err := q.convertNodeToQuery(root, "")
+	if err != nil {
+		return query, err
+	}
+	if q.filterContext && (query.Bool == nil || len(query.Bool.Must) > 0 || len(query.Bool.Should) > 0) {
+		// Queries with only filter and must_not clauses already run in filter context.
+		query = types.Query{
+			Bool: &types.BoolQuery{
+				Filter: []types.Query{query},
+			},
+		}
+	}
+	if err := q.checkLimits(query); err != nil {
+		return types.Query{}, err
+	}
+	return query, nil
+}
+
+// checkLimits returns an error if the query exceeds the limits set with WithMaxDepth and WithMaxClauses.
+func (q *QueryGenerator) checkLimits(query types.Query) error {
+	if q.maxDepth <= 0 && q.maxClauses <= 0 {
+		return nil
+	}
+	depth, clauses := boolQueryStats(query)
+	if q.maxDepth > 0 && depth > q.maxDepth {
+		return fmt.Errorf("%w: query depth %d exceeds maximum of %d", ErrMaxDepthExceeded, depth, q.maxDepth)
+	}
+	if q.maxClauses > 0 && clauses > q.maxClauses {
+		return fmt.Errorf("%w: %d clauses exceed maximum of %d", ErrMaxClausesExceeded, clauses, q.maxClauses)
+	}
+	return nil
+}
+
+// boolQueryStats returns the nesting depth of the bool queries of the query and their total number of clauses.
+func boolQueryStats(query types.Query) (depth int, clauses int) {
+	if query.Bool == nil {
+		return 0, 0
+	}
+	maxChildDepth := 0
+	for _, children := range [][]types.Query{query.Bool.Must, query.Bool.Filter, query.Bool.Should, query.Bool.MustNot} {
+		for _, child := range children {
+			childDepth, childClauses := boolQueryStats(child)
+			maxChildDepth = max(maxChildDepth, childDepth)
+			clauses += 1 + childClauses
+		}
+	}
+	return maxChildDepth + 1, clauses
+}
+
+// ConvertASTToMap converts a KQL AST to an Elasticsearch query in its JSON representation, for callers that don't
+// use the typed client, e.g. with OpenSearch or a plain HTTP client. Numbers are returned as json.Number.
+func (q *QueryGenerator) ConvertASTToMap(root kqlfilter.Node) (map[string]any, error) {
+	query, err := q.ConvertAST(root)
+	if err != nil {
+		return nil, err
+	}
+	return QueryToMap(query)
+}
+
+// QueryToMap returns the JSON representation of a query, as used by ConvertASTToMap. Numbers are returned as
+// json.Number.
+func QueryToMap(query types.Query) (map[string]any, error) {
+	data, err := json.Marshal(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode query: %w", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var m map[string]any
+	if err := decoder.Decode(&m); err != nil {
+		return nil, fmt.Errorf("failed to decode query: %w", err)
+	}
+	return m, nil
 }
 
 func (q *QueryGenerator) convertNodeToQuery(node kqlfilter.Node, prefix string) (types.Query, error) {
@@ -79,6 +282,13 @@ func (q *QueryGenerator) convertNodeToQuery(node kqlfilter.Node, prefix string)
 			}
 			clauses = append(clauses, q)
 		}
+		if q.filterContext {
+			return types.Query{
+				Bool: &types.BoolQuery{
+					Filter: clauses,
+				},
+			}, nil
+		}
 		return types.Query{
 			Bool: &types.BoolQuery{
 				Must: clauses,
@@ -93,11 +303,15 @@ func (q *QueryGenerator) convertNodeToQuery(node kqlfilter.Node, prefix string)
 			}
 			clauses = append(clauses, q)
 		}
-		return types.Query{
+		query := types.Query{
 			Bool: &types.BoolQuery{
 				Should: clauses,
 			},
-		}, nil
+		}
+		if q.minimumShouldMatch > 0 {
+			query.Bool.MinimumShouldMatch = q.minimumShouldMatch
+		}
+		return query, nil
 	case *kqlfilter.NotNode:
 		q, err := q.convertNodeToQuery(n.Expr, prefix)
 		if err != nil {
@@ -123,6 +337,30 @@ func (q *QueryGenerator) convertNodeToQuery(node kqlfilter.Node, prefix string)
 		}
 
 		or, ok := n.Value.(*kqlfilter.OrNode)
+		if ok && q.textFields[id] {
+			// Match each value of a text field separately.
+			var clauses []types.Query
+			for _, child := range or.Nodes {
+				lit, ok := child.(*kqlfilter.LiteralNode)
+				if !ok {
+					return types.Query{}, fmt.Errorf("%s: invalid syntax", id)
+				}
+				clause, err := q.matchQuery(id, lit.Value)
+				if err != nil {
+					return types.Query{}, fmt.Errorf("%s: %w", id, err)
+				}
+				clauses = append(clauses, clause)
+			}
+			query := types.Query{
+				Bool: &types.BoolQuery{
+					Should: clauses,
+				},
+			}
+			if q.minimumShouldMatch > 0 {
+				query.Bool.MinimumShouldMatch = q.minimumShouldMatch
+			}
+			return query, nil
+		}
 		if ok {
 			// Transform x:(y or z) syntax.
 			var vals []types.FieldValue
@@ -132,15 +370,40 @@ func (q *QueryGenerator) convertNodeToQuery(node kqlfilter.Node, prefix string)
 					return types.Query{}, fmt.Errorf("%s: invalid syntax", id)
 				}
 				lit := child.(*kqlfilter.LiteralNode)
-				lit.Value, err = q.mapFieldValue(id, lit.Value)
+				value, err := q.termValue(id, lit.Value)
 				if err != nil {
 					return types.Query{}, fmt.Errorf("%s: %w", id, err)
 				}
-				vals = append(vals, lit.Value)
+				vals = append(vals, value)
+			}
+
+			if q.fieldTypes[id] == kqlfilter.FieldTypeBool {
+				// Booleans are matched with a term query per value, like the equalities of the other converters.
+				var clauses []types.Query
+				for i, value := range vals {
+					if slices.Contains(vals[:i], value) {
+						continue
+					}
+					clauses = append(clauses, types.Query{
+						Term: map[string]types.TermQuery{
+							id: {Value: value, Boost: q.boost(id)},
+						},
+					})
+				}
+				query := types.Query{
+					Bool: &types.BoolQuery{
+						Should: clauses,
+					},
+				}
+				if q.minimumShouldMatch > 0 {
+					query.Bool.MinimumShouldMatch = q.minimumShouldMatch
+				}
+				return query, nil
 			}
 
 			return types.Query{
 				Terms: &types.TermsQuery{
+					Boost: q.boost(id),
 					TermsQuery: map[string]types.TermsQueryField{
 						id: vals,
 					},
@@ -154,7 +417,23 @@ func (q *QueryGenerator) convertNodeToQuery(node kqlfilter.Node, prefix string)
 			return types.Query{}, fmt.Errorf("%s: expected literal node", id)
 		}
 
-		lit.Value, err = q.mapFieldValue(id, lit.Value)
+		if rq, ok := q.dateRangeQuery(id, lit.Value); ok {
+			return types.Query{
+				Range: map[string]types.RangeQuery{
+					id: rq,
+				},
+			}, nil
+		}
+
+		if q.textFields[id] {
+			query, err := q.matchQuery(id, lit.Value)
+			if err != nil {
+				return types.Query{}, fmt.Errorf("%s: %w", id, err)
+			}
+			return query, nil
+		}
+
+		value, err := q.termValue(id, lit.Value)
 		if err != nil {
 			return types.Query{}, fmt.Errorf("%s: %w", id, err)
 		}
@@ -162,7 +441,36 @@ func (q *QueryGenerator) convertNodeToQuery(node kqlfilter.Node, prefix string)
 		return types.Query{
 			Term: map[string]types.TermQuery{
 				id: {
-					Value: lit.Value,
+					Value: value,
+					Boost: q.boost(id),
+				},
+			},
+		}, nil
+	case *kqlfilter.ExistsNode:
+		id, err := q.mapFieldName(prefix + n.Identifier)
+		if err != nil {
+			return types.Query{}, fmt.Errorf("%s: %w", id, err)
+		}
+		return types.Query{
+			Exists: &types.ExistsQuery{
+				Field: id,
+			},
+		}, nil
+	case *kqlfilter.FuzzyNode:
+		id, err := q.mapFieldName(prefix + n.Identifier)
+		if err != nil {
+			return types.Query{}, fmt.Errorf("%s: %w", id, err)
+		}
+		value, err := q.mapFieldValue(id, n.Value)
+		if err != nil {
+			return types.Query{}, fmt.Errorf("%s: %w", id, err)
+		}
+		return types.Query{
+			Fuzzy: map[string]types.FuzzyQuery{
+				id: {
+					Value:     value,
+					Fuzziness: strconv.Itoa(n.Fuzziness),
+					Boost:     q.boost(id),
 				},
 			},
 		}, nil
@@ -177,14 +485,19 @@ func (q *QueryGenerator) convertNodeToQuery(node kqlfilter.Node, prefix string)
 			return types.Query{}, fmt.Errorf("%s: expected literal node", id)
 		}
 
-		lit.Value, err = q.mapFieldValue(id, lit.Value)
+		value, err := q.mapFieldValue(id, lit.Value)
 		if err != nil {
 			return types.Query{}, fmt.Errorf("%s: %w", id, err)
 		}
 
-		rq, err := convertRangeNode(n.Operator, lit)
-		if err != nil {
-			return types.Query{}, fmt.Errorf("%s: %w", id, err)
+		var rq types.RangeQuery
+		if options, ok := q.dateRangeOptionsOf(id); ok {
+			rq = dateRangeQueryWithOptions(n.Operator, value, options)
+		} else {
+			rq, err = convertRangeNode(n.Operator, value)
+			if err != nil {
+				return types.Query{}, fmt.Errorf("%s: %w", id, err)
+			}
 		}
 		return types.Query{
 			Range: map[string]types.RangeQuery{
@@ -209,9 +522,123 @@ func (q *QueryGenerator) convertNodeToQuery(node kqlfilter.Node, prefix string)
 	}
 }
 
-func convertRangeNode(op kqlfilter.RangeOperator, lit *kqlfilter.LiteralNode) (types.RangeQuery, error) {
-	// Here we check the type of the literal node, and then we can create the correct range query.
-	fVal, err := strconv.ParseFloat(lit.Value, 64)
+// termValue maps the value of a term query and converts it to the type of the field, if known.
+// Term queries match the value literally, so escaped asterisks are unescaped.
+func (q *QueryGenerator) termValue(id, value string) (types.FieldValue, error) {
+	value, err := q.mapFieldValue(id, kqlfilter.UnescapeValue(value))
+	if err != nil {
+		return nil, err
+	}
+	switch q.fieldTypes[id] {
+	case kqlfilter.FieldTypeInt64, kqlfilter.FieldTypeFloat64, kqlfilter.FieldTypeBool:
+		return q.fieldTypes[id].ParseValue(value)
+	default:
+		return value, nil
+	}
+}
+
+// matchQuery returns the query matching a value of a text field: a `match_phrase` query if the value consists of
+// multiple words, which is only possible for quoted values, and a `match` query otherwise.
+func (q *QueryGenerator) matchQuery(id, value string) (types.Query, error) {
+	value, err := q.mapFieldValue(id, kqlfilter.UnescapeValue(value))
+	if err != nil {
+		return types.Query{}, err
+	}
+	if len(strings.Fields(value)) > 1 {
+		return types.Query{
+			MatchPhrase: map[string]types.MatchPhraseQuery{
+				id: {
+					Query: value,
+					Boost: q.boost(id),
+				},
+			},
+		}, nil
+	}
+	return types.Query{
+		Match: map[string]types.MatchQuery{
+			id: {
+				Query: value,
+				Boost: q.boost(id),
+			},
+		},
+	}, nil
+}
+
+// boost returns the boost of the field, or nil if it has none.
+func (q *QueryGenerator) boost(id string) *float32 {
+	boost, ok := q.boosts[id]
+	if !ok {
+		return nil
+	}
+	return &boost
+}
+
+// dateRangeQuery returns a range query matching the whole day of a date-only value of a timestamp field, if enabled with
+// WithDateRanges.
+func (q *QueryGenerator) dateRangeQuery(id, value string) (types.RangeQuery, bool) {
+	if q.dateRanges == nil {
+		return nil, false
+	}
+	if options, ok := q.dateRangeOptionsOf(id); ok {
+		if _, err := time.Parse(time.DateOnly, value); err != nil {
+			return nil, false
+		}
+		// The day is resolved by Elasticsearch, in the time zone of the options.
+		rq := dateRangeQueryWithOptions(kqlfilter.RangeOperatorGte, value, options)
+		lt := value + "||+1d"
+		rq.Lt = &lt
+		return rq, true
+	}
+	if q.fieldTypes[id] != kqlfilter.FieldTypeTimestamp {
+		return nil, false
+	}
+	start, err := time.ParseInLocation(time.DateOnly, value, q.dateRanges)
+	if err != nil {
+		return nil, false
+	}
+	gte := start.Format(time.RFC3339)
+	lt := start.AddDate(0, 0, 1).Format(time.RFC3339)
+	return &types.DateRangeQuery{Gte: &gte, Lt: &lt}, true
+}
+
+// dateRangeOptionsOf returns the date range options of a field, if set with WithFieldDateRangeOptions, or with
+// WithDateRangeOptions for a timestamp field.
+func (q *QueryGenerator) dateRangeOptionsOf(id string) (DateRangeOptions, bool) {
+	if options, ok := q.fieldDateRangeOptions[id]; ok {
+		return options, true
+	}
+	if q.dateRangeOptions != nil && q.fieldTypes[id] == kqlfilter.FieldTypeTimestamp {
+		return *q.dateRangeOptions, true
+	}
+	return DateRangeOptions{}, false
+}
+
+// dateRangeQueryWithOptions returns a date range query with the value as-is, to be parsed by Elasticsearch according
+// to the options.
+func dateRangeQueryWithOptions(op kqlfilter.RangeOperator, value string, options DateRangeOptions) *types.DateRangeQuery {
+	rq := &types.DateRangeQuery{}
+	switch op {
+	case kqlfilter.RangeOperatorLt:
+		rq.Lt = &value
+	case kqlfilter.RangeOperatorLte:
+		rq.Lte = &value
+	case kqlfilter.RangeOperatorGt:
+		rq.Gt = &value
+	case kqlfilter.RangeOperatorGte:
+		rq.Gte = &value
+	}
+	if options.Format != "" {
+		rq.Format = &options.Format
+	}
+	if options.TimeZone != "" {
+		rq.TimeZone = &options.TimeZone
+	}
+	return rq
+}
+
+func convertRangeNode(op kqlfilter.RangeOperator, value string) (types.RangeQuery, error) {
+	// Here we check the type of the literal value, and then we can create the correct range query.
+	fVal, err := strconv.ParseFloat(value, 64)
 	if err == nil {
 		// it is an int
 		esFVal := types.Float64(fVal)
@@ -230,7 +657,7 @@ func convertRangeNode(op kqlfilter.RangeOperator, lit *kqlfilter.LiteralNode) (t
 	}
 
 	// It is not a number, so we check if it is a date.
-	_, err = time.Parse(time.RFC3339, lit.Value)
+	_, err = time.Parse(time.RFC3339, value)
 	if err != nil {
 		return nil, errors.New("expected number or date literal")
 	}
@@ -238,13 +665,13 @@ func convertRangeNode(op kqlfilter.RangeOperator, lit *kqlfilter.LiteralNode) (t
 	rq := &types.DateRangeQuery{}
 	switch op {
 	case kqlfilter.RangeOperatorLt:
-		rq.Lt = &lit.Value
+		rq.Lt = &value
 	case kqlfilter.RangeOperatorLte:
-		rq.Lte = &lit.Value
+		rq.Lte = &value
 	case kqlfilter.RangeOperatorGt:
-		rq.Gt = &lit.Value
+		rq.Gt = &value
 	case kqlfilter.RangeOperatorGte:
-		rq.Gte = &lit.Value
+		rq.Gte = &value
 	}
 
 	return rq, nil
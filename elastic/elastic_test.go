@@ -123,7 +123,8 @@ func TestConvertNodeToQuery(t *testing.T) {
           }
         }
       }
-    ]
+    ],
+    "minimum_should_match": 1
   }
 }`,
 		},
@@ -158,7 +159,8 @@ func TestConvertNodeToQuery(t *testing.T) {
                 }
               }
             }
-          ]
+          ],
+          "minimum_should_match": 1
         }
       }
     ]
@@ -234,6 +236,34 @@ func TestConvertNodeToQuery(t *testing.T) {
 	  }
 }`,
 		},
+		{
+			name:              "exists",
+			input:             "fields.home_team:*",
+			expectedError:     nil,
+			expectedQueryJSON: `{"exists":{"field":"fields.home_team"}}`,
+		},
+		{
+			name:          "negated exists",
+			input:         "not type_id:*",
+			expectedError: nil,
+			expectedQueryJSON: `{
+  "bool": {
+    "must_not": [
+      {
+        "exists": {
+          "field": "type_id"
+        }
+      }
+    ]
+  }
+}`,
+		},
+		{
+			name:              "escaped asterisk",
+			input:             `type_id:team\*`,
+			expectedError:     nil,
+			expectedQueryJSON: `{"term":{"type_id":{"value":"team*"}}}`,
+		},
 		{
 			name:          "field validator works",
 			input:         `time:"2000-01-01T00:00:04.123Z"`,
@@ -308,3 +338,314 @@ func TestConvertNodeToQuery(t *testing.T) {
 		})
 	}
 }
+
+func TestConvertNodeToQueryFilterContext(t *testing.T) {
+	testCases := []struct {
+		name              string
+		input             string
+		expectedQueryJSON string
+	}{
+		{
+			name:              "single term",
+			input:             "type_id:team",
+			expectedQueryJSON: `{"bool":{"filter":[{"term":{"type_id":{"value":"team"}}}]}}`,
+		},
+		{
+			name:  "conjunction",
+			input: "type_id:team and not fields.active:false",
+			expectedQueryJSON: `{"bool":{"filter":[
+				{"term":{"type_id":{"value":"team"}}},
+				{"bool":{"must_not":[{"term":{"fields.active":{"value":"false"}}}]}}
+			]}}`,
+		},
+		{
+			name:  "disjunction",
+			input: "type_id:team or type_id:player",
+			expectedQueryJSON: `{"bool":{"filter":[{"bool":{"should":[
+				{"term":{"type_id":{"value":"team"}}},
+				{"term":{"type_id":{"value":"player"}}}
+			],"minimum_should_match":1}}]}}`,
+		},
+		{
+			name:              "negation",
+			input:             "not type_id:team",
+			expectedQueryJSON: `{"bool":{"must_not":[{"term":{"type_id":{"value":"team"}}}]}}`,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			n, err := kqlfilter.ParseAST(test.input)
+			require.NoError(t, err)
+
+			q, err := NewQueryGenerator(WithFilterContext()).ConvertAST(n)
+			require.NoError(t, err)
+
+			data, err := json.Marshal(q)
+			require.NoError(t, err)
+			assert.JSONEq(t, test.expectedQueryJSON, string(data))
+		})
+	}
+}
+
+func TestConvertNodeToQueryMinimumShouldMatch(t *testing.T) {
+	n, err := kqlfilter.ParseAST("type_id:team or type_id:player")
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name              string
+		options           []Option
+		expectedQueryJSON string
+	}{
+		{
+			name:              "default",
+			expectedQueryJSON: `{"bool":{"should":[{"term":{"type_id":{"value":"team"}}},{"term":{"type_id":{"value":"player"}}}],"minimum_should_match":1}}`,
+		},
+		{
+			name:              "custom",
+			options:           []Option{WithMinimumShouldMatch(2)},
+			expectedQueryJSON: `{"bool":{"should":[{"term":{"type_id":{"value":"team"}}},{"term":{"type_id":{"value":"player"}}}],"minimum_should_match":2}}`,
+		},
+		{
+			name:              "omitted",
+			options:           []Option{WithMinimumShouldMatch(0)},
+			expectedQueryJSON: `{"bool":{"should":[{"term":{"type_id":{"value":"team"}}},{"term":{"type_id":{"value":"player"}}}]}}`,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			q, err := NewQueryGenerator(test.options...).ConvertAST(n)
+			require.NoError(t, err)
+
+			data, err := json.Marshal(q)
+			require.NoError(t, err)
+			assert.JSONEq(t, test.expectedQueryJSON, string(data))
+		})
+	}
+}
+
+func TestConvertNodeToQueryLimits(t *testing.T) {
+	// Depth 2 with 5 clauses: the conjunction with 2 clauses, and the disjunction with 3 clauses.
+	n, err := kqlfilter.ParseAST("a:1 and (b:2 or c:3 or d:4)")
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name          string
+		options       []Option
+		expectedError error
+	}{
+		{name: "unlimited"},
+		{name: "within limits", options: []Option{WithMaxDepth(2), WithMaxClauses(5)}},
+		{name: "too deep", options: []Option{WithMaxDepth(1)}, expectedError: ErrMaxDepthExceeded},
+		{name: "too many clauses", options: []Option{WithMaxClauses(4)}, expectedError: ErrMaxClausesExceeded},
+	}
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewQueryGenerator(test.options...).ConvertAST(n)
+			if test.expectedError != nil {
+				require.ErrorIs(t, err, test.expectedError)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+
+	_, err = NewQueryGenerator(WithMaxDepth(1)).ConvertAST(&kqlfilter.IsNode{Identifier: "a", Value: &kqlfilter.LiteralNode{Value: "1"}})
+	require.NoError(t, err)
+}
+
+func TestConvertFuzzyNodeToQuery(t *testing.T) {
+	n, err := kqlfilter.ParseAST("name:jon~1 and not city:amsterdm~", kqlfilter.WithFuzzyMatching())
+	require.NoError(t, err)
+
+	q, err := NewQueryGenerator().ConvertAST(n)
+	require.NoError(t, err)
+
+	data, err := json.Marshal(q)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"bool":{"must":[
+		{"fuzzy":{"name":{"value":"jon","fuzziness":"1"}}},
+		{"bool":{"must_not":[{"fuzzy":{"city":{"value":"amsterdm","fuzziness":"2"}}}]}}
+	]}}`, string(data))
+}
+
+func TestConvertNodeToQueryFieldTypes(t *testing.T) {
+	g := NewQueryGenerator(WithFieldTypes(map[string]kqlfilter.FieldType{
+		"active":  kqlfilter.FieldTypeBool,
+		"age":     kqlfilter.FieldTypeInt64,
+		"score":   kqlfilter.FieldTypeFloat64,
+		"created": kqlfilter.FieldTypeTimestamp,
+	}))
+
+	n, err := kqlfilter.ParseAST(`active:true age:(18 or 21) score:1.5 name:true created:"2024-01-01T00:00:00Z"`)
+	require.NoError(t, err)
+	q, err := g.ConvertAST(n)
+	require.NoError(t, err)
+
+	data, err := json.Marshal(q)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"bool":{"must":[
+		{"term":{"active":{"value":true}}},
+		{"terms":{"age":[18,21]}},
+		{"term":{"score":{"value":1.5}}},
+		{"term":{"name":{"value":"true"}}},
+		{"term":{"created":{"value":"2024-01-01T00:00:00Z"}}}
+	]}}`, string(data))
+
+	n, err = kqlfilter.ParseAST("age:abc")
+	require.NoError(t, err)
+	_, err = g.ConvertAST(n)
+	assert.EqualError(t, err, `age: invalid int64 value: strconv.ParseInt: parsing "abc": invalid syntax`)
+}
+
+func TestConvertNodeToQueryBoolValues(t *testing.T) {
+	g := NewQueryGenerator(WithFieldTypes(map[string]kqlfilter.FieldType{"active": kqlfilter.FieldTypeBool}))
+
+	n, err := kqlfilter.ParseAST(`active:(true or false or true)`)
+	require.NoError(t, err)
+	q, err := g.ConvertAST(n)
+	require.NoError(t, err)
+
+	data, err := json.Marshal(q)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"bool":{"should":[
+		{"term":{"active":{"value":true}}},
+		{"term":{"active":{"value":false}}}
+	],"minimum_should_match":1}}`, string(data))
+
+	n, err = kqlfilter.ParseAST("active:(true or yes)")
+	require.NoError(t, err)
+	_, err = g.ConvertAST(n)
+	assert.Error(t, err)
+}
+
+func TestBackendFieldTypes(t *testing.T) {
+	schema := kqlfilter.Schema{
+		"active": {Type: kqlfilter.FieldTypeBool, Column: "is_active"},
+	}
+	q, err := kqlfilter.QueryFromKQL("active:false", schema, Backend())
+	require.NoError(t, err)
+
+	data, err := json.Marshal(q)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"term":{"is_active":{"value":false}}}`, string(data))
+}
+
+func TestConvertASTToMap(t *testing.T) {
+	n, err := kqlfilter.ParseAST("type_id:team fields.established_year < 2000")
+	require.NoError(t, err)
+
+	m, err := NewQueryGenerator().ConvertASTToMap(n)
+	require.NoError(t, err)
+
+	must := m["bool"].(map[string]any)["must"].([]any)
+	require.Len(t, must, 2)
+	assert.Equal(t, map[string]any{"term": map[string]any{"type_id": map[string]any{"value": "team"}}}, must[0])
+	assert.Equal(t, json.Number("2000"), must[1].(map[string]any)["range"].(map[string]any)["fields.established_year"].(map[string]any)["lt"])
+
+	data, err := json.Marshal(m)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"bool":{"must":[
+		{"term":{"type_id":{"value":"team"}}},
+		{"range":{"fields.established_year":{"lt":2000}}}
+	]}}`, string(data))
+}
+
+func TestConvertNodeToQueryDateRanges(t *testing.T) {
+	amsterdam, err := time.LoadLocation("Europe/Amsterdam")
+	require.NoError(t, err)
+	g := NewQueryGenerator(
+		WithFieldTypes(map[string]kqlfilter.FieldType{"created": kqlfilter.FieldTypeTimestamp}),
+		WithDateRanges(amsterdam),
+	)
+
+	n, err := kqlfilter.ParseAST(`created:2024-05-01 name:2024-05-01 created:"2024-05-01T10:00:00Z"`)
+	require.NoError(t, err)
+	q, err := g.ConvertAST(n)
+	require.NoError(t, err)
+
+	data, err := json.Marshal(q)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"bool":{"must":[
+		{"range":{"created":{"gte":"2024-05-01T00:00:00+02:00","lt":"2024-05-02T00:00:00+02:00"}}},
+		{"term":{"name":{"value":"2024-05-01"}}},
+		{"term":{"created":{"value":"2024-05-01T10:00:00Z"}}}
+	]}}`, string(data))
+}
+
+func TestConvertNodeToQueryDateRangeOptions(t *testing.T) {
+	g := NewQueryGenerator(
+		WithFieldTypes(map[string]kqlfilter.FieldType{"created": kqlfilter.FieldTypeTimestamp, "updated": kqlfilter.FieldTypeTimestamp}),
+		WithDateRangeOptions(DateRangeOptions{TimeZone: "Europe/Amsterdam"}),
+		WithFieldDateRangeOptions(map[string]DateRangeOptions{
+			"updated":  {Format: "yyyy-MM-dd", TimeZone: "+02:00"},
+			"birthday": {Format: "dd/MM/yyyy"},
+		}),
+		WithDateRanges(time.UTC),
+	)
+
+	n, err := kqlfilter.ParseAST(`created>=2024-05-01 updated<2024-06-01 birthday>"01/02/2000" created:2024-05-01 age>=18`)
+	require.NoError(t, err)
+	q, err := g.ConvertAST(n)
+	require.NoError(t, err)
+
+	data, err := json.Marshal(q)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"bool":{"must":[
+		{"range":{"created":{"gte":"2024-05-01","time_zone":"Europe/Amsterdam"}}},
+		{"range":{"updated":{"lt":"2024-06-01","format":"yyyy-MM-dd","time_zone":"+02:00"}}},
+		{"range":{"birthday":{"gt":"01/02/2000","format":"dd/MM/yyyy"}}},
+		{"range":{"created":{"gte":"2024-05-01","lt":"2024-05-01||+1d","time_zone":"Europe/Amsterdam"}}},
+		{"range":{"age":{"gte":18}}}
+	]}}`, string(data))
+}
+
+func TestConvertNodeToQueryFieldBoosts(t *testing.T) {
+	g := NewQueryGenerator(WithFieldBoosts(map[string]float32{"title": 2, "tags": 1.5, "name": 0.5}))
+
+	n, err := kqlfilter.ParseAST(`title:go tags:(a or b) name:jon~1 body:x`, kqlfilter.WithFuzzyMatching())
+	require.NoError(t, err)
+	q, err := g.ConvertAST(n)
+	require.NoError(t, err)
+
+	data, err := json.Marshal(q)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"bool":{"must":[
+		{"term":{"title":{"value":"go","boost":2}}},
+		{"terms":{"tags":["a","b"],"boost":1.5}},
+		{"fuzzy":{"name":{"value":"jon","fuzziness":"1","boost":0.5}}},
+		{"term":{"body":{"value":"x"}}}
+	]}}`, string(data))
+}
+
+func TestConvertNodeToQueryTextFields(t *testing.T) {
+	g := NewQueryGenerator(WithTextFields("title", "body"), WithFieldBoosts(map[string]float32{"title": 2}))
+
+	n, err := kqlfilter.ParseAST(`title:go body:"quick brown fox" body:(fox or "lazy dog") type:post`)
+	require.NoError(t, err)
+	q, err := g.ConvertAST(n)
+	require.NoError(t, err)
+
+	data, err := json.Marshal(q)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"bool":{"must":[
+		{"match":{"title":{"query":"go","boost":2}}},
+		{"match_phrase":{"body":{"query":"quick brown fox"}}},
+		{"bool":{"should":[
+			{"match":{"body":{"query":"fox"}}},
+			{"match_phrase":{"body":{"query":"lazy dog"}}}
+		],"minimum_should_match":1}},
+		{"term":{"type":{"value":"post"}}}
+	]}}`, string(data))
+
+	schema := kqlfilter.Schema{
+		"title": {Text: true, Column: "post_title"},
+	}
+	q, err = kqlfilter.QueryFromKQL(`title:"hello world"`, schema, Backend())
+	require.NoError(t, err)
+
+	data, err = json.Marshal(q)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"match_phrase":{"post_title":{"query":"hello world"}}}`, string(data))
+}
@@ -0,0 +1,260 @@
+// Package entkql converts kqlfilter filters to ent predicates.
+package entkql
+
+import (
+	"errors"
+	"fmt"
+
+	"entgo.io/ent/dialect/sql"
+	"github.com/MottoStreaming/kqlfilter.go"
+)
+
+// FieldConfig describes a field that is allowed to be queried via Predicate.
+type FieldConfig struct {
+	// SQL table column name. Can be omitted if the column name is equal to the key in the configs map.
+	ColumnName string
+	// Type of the column. Defaults to kqlfilter.FieldTypeString.
+	ColumnType kqlfilter.FieldType
+	// Allow prefix matching when a wildcard (`*`) is present at the end of a string. Defaults to false.
+	AllowPrefixMatch bool
+	// Allow suffix matching when a wildcard (`*`) is present at the beginning of a string. Defaults to false.
+	AllowSuffixMatch bool
+	// Match string values case-insensitively. Only applies to equality, not to prefix or suffix matching.
+	// Defaults to false.
+	AllowCaseInsensitiveMatch bool
+	// Allow multiple values for this field. Defaults to false.
+	AllowMultipleValues bool
+	// Allow negated matching of multiple values (e.g. `not state:(a OR b)`). Defaults to false.
+	AllowNegation bool
+	// Allow this field to be queried with one or more range operators. Defaults to false.
+	AllowRanges bool
+	// A function that takes a string value as provided by the user and converts it to the value stored in the column.
+	// It should return an error when the user provides an illegal value. Defaults to converting the value according to
+	// the column type.
+	MapValue func(string) (any, error)
+	// The values that are allowed for this field, e.g. the values of an enum. Defaults to allowing any value.
+	AllowedValues []string
+}
+
+// FieldConfigs returns the field configs to use with Predicate, derived from the schema.
+// Aliases are added as separate entries.
+func FieldConfigs(schema kqlfilter.Schema) map[string]FieldConfig {
+	configs := make(map[string]FieldConfig, len(schema))
+	for name, fs := range schema {
+		config := FieldConfig{
+			ColumnName:                schema.ColumnName(name),
+			ColumnType:                fs.Type,
+			AllowPrefixMatch:          fs.AllowPrefixMatch,
+			AllowSuffixMatch:          fs.AllowSuffixMatch,
+			AllowCaseInsensitiveMatch: fs.AllowCaseInsensitiveMatch,
+			AllowMultipleValues:       fs.AllowMultipleValues,
+			AllowNegation:             fs.AllowNegation,
+			AllowRanges:               fs.AllowRanges,
+			MapValue:                  fs.MapValue,
+			AllowedValues:             fs.AllowedValues,
+		}
+		configs[name] = config
+		for _, alias := range fs.Aliases {
+			configs[alias] = config
+		}
+	}
+	return configs
+}
+
+// Predicate converts the filter to a selector predicate, which can be converted to any predicate type generated by
+// ent, so that user filters can be applied without handwritten switch statements:
+//
+//	p, err := entkql.Predicate(filter, entkql.FieldConfigs(schema))
+//	if err != nil {
+//		return err
+//	}
+//	users, err := client.User.Query().Where(predicate.User(p)).All(ctx)
+//
+// It takes a map of fields that are allowed to be queried via this filter, keyed by the field name used in the filter.
+// Columns are qualified with the table of the selector the predicate is applied to. The groups of the filter are
+// combined into a single predicate, the disjunction of their conjunctions. The filter is validated and its values are
+// converted when Predicate is called, so the returned predicate cannot fail.
+func Predicate(f kqlfilter.Filter, configs map[string]FieldConfig, options ...kqlfilter.ConverterOption) (func(*sql.Selector), error) {
+	preds, err := clausePredicates(f.Clauses, configs, options)
+	if err != nil {
+		return nil, err
+	}
+	groups := make([][]func(*sql.Selector) *sql.Predicate, 0, len(f.Groups))
+	for _, group := range f.Groups {
+		if len(group) == 0 {
+			return nil, errors.New("empty clause group")
+		}
+		groupPreds, err := clausePredicates(group, configs, options)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, groupPreds)
+	}
+	return func(s *sql.Selector) {
+		if len(preds) == 0 && len(groups) == 0 {
+			return
+		}
+		ps := make([]*sql.Predicate, 0, len(preds)+1)
+		for _, pred := range preds {
+			ps = append(ps, pred(s))
+		}
+		if len(groups) > 0 {
+			ors := make([]*sql.Predicate, 0, len(groups))
+			for _, groupPreds := range groups {
+				ands := make([]*sql.Predicate, 0, len(groupPreds))
+				for _, pred := range groupPreds {
+					ands = append(ands, pred(s))
+				}
+				ors = append(ors, sql.And(ands...))
+			}
+			ps = append(ps, sql.Or(ors...))
+		}
+		s.Where(sql.And(ps...))
+	}, nil
+}
+
+// clausePredicates converts clauses to predicates, in order.
+func clausePredicates(clauses []kqlfilter.Clause, configs map[string]FieldConfig, options []kqlfilter.ConverterOption) ([]func(*sql.Selector) *sql.Predicate, error) {
+	preds := make([]func(*sql.Selector) *sql.Predicate, 0, len(clauses))
+	for _, clause := range clauses {
+		pred, err := clausePredicate(clause, configs, options)
+		if err != nil {
+			return nil, err
+		}
+		preds = append(preds, pred)
+	}
+	return preds, nil
+}
+
+func clausePredicate(clause kqlfilter.Clause, configs map[string]FieldConfig, options []kqlfilter.ConverterOption) (func(*sql.Selector) *sql.Predicate, error) {
+	config, ok := configs[clause.Field]
+	if !ok {
+		if clause.Field == "1" && clause.Operator == "=" && len(clause.Values) == 1 && (clause.Values[0] == "1" || clause.Values[0] == "0") {
+			// Special case for boolean literals
+			expr := "1 = " + clause.Values[0]
+			return func(*sql.Selector) *sql.Predicate { return sql.ExprP(expr) }, nil
+		}
+		names := make([]string, 0, len(configs))
+		for name := range configs {
+			names = append(names, name)
+		}
+		return nil, kqlfilter.NewUnknownFieldError(clause.Field, names)
+	}
+
+	column := config.ColumnName
+	if column == "" {
+		column = clause.Field
+	}
+
+	if clause.Operator == "~" {
+		return nil, fmt.Errorf("field %s: fuzzy matching is not supported by ent", clause.Field)
+	}
+	if len(clause.Values) > 1 && !config.AllowMultipleValues {
+		return nil, fmt.Errorf("field %s: multiple values are not allowed", clause.Field)
+	}
+
+	if clause.Operator == "=" && config.ColumnType == kqlfilter.FieldTypeString && config.MapValue == nil {
+		if pred, ok := config.matchPredicate(column, clause.Values[0]); ok {
+			return pred, nil
+		}
+	}
+
+	values, err := config.mapValues(clause.Values, options)
+	if err != nil {
+		return nil, fmt.Errorf("field %s: %w", clause.Field, err)
+	}
+
+	switch clause.Operator {
+	case "=":
+		if s, ok := values[0].(string); ok && config.AllowCaseInsensitiveMatch {
+			return func(sel *sql.Selector) *sql.Predicate { return sql.EqualFold(sel.C(column), s) }, nil
+		}
+		return func(sel *sql.Selector) *sql.Predicate { return sql.EQ(sel.C(column), values[0]) }, nil
+	case "!=":
+		return func(sel *sql.Selector) *sql.Predicate { return sql.NEQ(sel.C(column), values[0]) }, nil
+	case "IN":
+		return func(sel *sql.Selector) *sql.Predicate { return sql.In(sel.C(column), values...) }, nil
+	case "NOT IN":
+		if !config.AllowNegation {
+			return nil, fmt.Errorf("operator %s not supported for field: %s", clause.Operator, clause.Field)
+		}
+		return func(sel *sql.Selector) *sql.Predicate { return sql.NotIn(sel.C(column), values...) }, nil
+	case ">", ">=", "<", "<=":
+		if !config.AllowRanges {
+			return nil, fmt.Errorf("operator %s not supported for field: %s", clause.Operator, clause.Field)
+		}
+		if config.ColumnType == kqlfilter.FieldTypeString || config.ColumnType == kqlfilter.FieldTypeBool {
+			return nil, fmt.Errorf("operator %s not supported for field type %s", clause.Operator, config.ColumnType)
+		}
+		var op func(string, any) *sql.Predicate
+		switch clause.Operator {
+		case ">":
+			op = sql.GT
+		case ">=":
+			op = sql.GTE
+		case "<":
+			op = sql.LT
+		default:
+			op = sql.LTE
+		}
+		return func(sel *sql.Selector) *sql.Predicate { return op(sel.C(column), values[0]) }, nil
+	default:
+		return nil, fmt.Errorf("operator %s not supported for field: %s", clause.Operator, clause.Field)
+	}
+}
+
+// matchPredicate returns a LIKE predicate if the value has a leading or trailing wildcard that is allowed by the config.
+// The special characters of LIKE patterns in the value are escaped by ent.
+func (c FieldConfig) matchPredicate(column string, value string) (func(*sql.Selector) *sql.Predicate, bool) {
+	if err := checkAllowedValue(value, c.AllowedValues); err != nil {
+		return nil, false
+	}
+	s, needsPrefixMatch, needsSuffixMatch := kqlfilter.SplitWildcards(value, c.AllowPrefixMatch, c.AllowSuffixMatch)
+	switch {
+	case needsPrefixMatch && needsSuffixMatch:
+		return func(sel *sql.Selector) *sql.Predicate { return sql.Contains(sel.C(column), s) }, true
+	case needsPrefixMatch:
+		return func(sel *sql.Selector) *sql.Predicate { return sql.HasPrefix(sel.C(column), s) }, true
+	case needsSuffixMatch:
+		return func(sel *sql.Selector) *sql.Predicate { return sql.HasSuffix(sel.C(column), s) }, true
+	default:
+		return nil, false
+	}
+}
+
+func (c FieldConfig) mapValues(values []string, options []kqlfilter.ConverterOption) ([]any, error) {
+	mapped := make([]any, 0, len(values))
+	for _, value := range values {
+		if err := checkAllowedValue(value, c.AllowedValues); err != nil {
+			return nil, err
+		}
+		var v any
+		var err error
+		if c.MapValue != nil {
+			v, err = c.MapValue(value)
+			if err != nil && len(c.AllowedValues) > 0 {
+				return nil, kqlfilter.NewInvalidValueError(value, err, c.AllowedValues)
+			}
+		} else {
+			v, err = c.ColumnType.ParseValue(kqlfilter.UnescapeValue(value), options...)
+		}
+		if err != nil {
+			return nil, err
+		}
+		mapped = append(mapped, v)
+	}
+	return mapped, nil
+}
+
+// checkAllowedValue returns an InvalidValueError if allowed is not empty and does not contain value.
+func checkAllowedValue(value string, allowed []string) error {
+	if len(allowed) == 0 {
+		return nil
+	}
+	for _, a := range allowed {
+		if a == value {
+			return nil
+		}
+	}
+	return kqlfilter.NewInvalidValueError(value, nil, allowed)
+}
@@ -0,0 +1,149 @@
+package entkql
+
+import (
+	"testing"
+
+	"entgo.io/ent/dialect"
+	"entgo.io/ent/dialect/sql"
+	"github.com/MottoStreaming/kqlfilter.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPredicate(t *testing.T) {
+	configs := map[string]FieldConfig{
+		"userId": {
+			ColumnName: "user_id",
+			ColumnType: kqlfilter.FieldTypeInt64,
+		},
+		"state": {
+			AllowMultipleValues: true,
+			AllowNegation:       true,
+			AllowedValues:       []string{"active", "frozen", "deleted"},
+		},
+		"email": {
+			AllowPrefixMatch: true,
+		},
+		"age": {
+			ColumnType:  kqlfilter.FieldTypeInt64,
+			AllowRanges: true,
+		},
+	}
+
+	testCases := []struct {
+		name          string
+		input         string
+		expectedSQL   string
+		expectedArgs  []any
+		expectedError string
+	}{
+		{
+			name:         "empty filter",
+			input:        "",
+			expectedSQL:  `SELECT * FROM "users"`,
+			expectedArgs: nil,
+		},
+		{
+			name:         "equality and multiple values",
+			input:        "userId:12345 state:(active OR frozen)",
+			expectedSQL:  `SELECT * FROM "users" WHERE "users"."user_id" = $1 AND "users"."state" IN ($2, $3)`,
+			expectedArgs: []any{int64(12345), "active", "frozen"},
+		},
+		{
+			name:         "negated multiple values",
+			input:        "not state:(active OR frozen)",
+			expectedSQL:  `SELECT * FROM "users" WHERE "users"."state" NOT IN ($1, $2)`,
+			expectedArgs: []any{"active", "frozen"},
+		},
+		{
+			name:         "prefix match",
+			input:        "email:john*",
+			expectedSQL:  `SELECT * FROM "users" WHERE "users"."email" LIKE $1`,
+			expectedArgs: []any{"john%"},
+		},
+		{
+			name:         "range",
+			input:        "age>=18",
+			expectedSQL:  `SELECT * FROM "users" WHERE "users"."age" >= $1`,
+			expectedArgs: []any{int64(18)},
+		},
+		{
+			name:          "unknown field",
+			input:         "title:john",
+			expectedError: "unknown field: title",
+		},
+		{
+			name:          "invalid value",
+			input:         "userId:abc",
+			expectedError: `field userId: invalid int64 value: strconv.ParseInt: parsing "abc": invalid syntax`,
+		},
+		{
+			name:          "value not allowed",
+			input:         "state:actve",
+			expectedError: `field state: invalid value "actve"; did you mean active? (allowed values: active, frozen, deleted)`,
+		},
+		{
+			name:          "range not allowed",
+			input:         "userId>1",
+			expectedError: "operator > not supported for field: userId",
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			f, err := kqlfilter.Parse(test.input)
+			require.NoError(t, err)
+
+			p, err := Predicate(f, configs)
+			if test.expectedError != "" {
+				require.EqualError(t, err, test.expectedError)
+				return
+			}
+			require.NoError(t, err)
+
+			s := sql.Dialect(dialect.Postgres).Select("*").From(sql.Table("users"))
+			p(s)
+			query, args := s.Query()
+			assert.Equal(t, test.expectedSQL, query)
+			assert.Equal(t, test.expectedArgs, args)
+		})
+	}
+}
+
+func TestPredicateGroups(t *testing.T) {
+	configs := map[string]FieldConfig{
+		"userId": {ColumnName: "user_id", ColumnType: kqlfilter.FieldTypeInt64},
+		"state":  {},
+	}
+	f := kqlfilter.Filter{
+		Clauses: []kqlfilter.Clause{{Field: "userId", Operator: "=", Values: []string{"1"}}},
+		Groups: [][]kqlfilter.Clause{
+			{{Field: "state", Operator: "=", Values: []string{"active"}}, {Field: "userId", Operator: "=", Values: []string{"2"}}},
+			{{Field: "state", Operator: "=", Values: []string{"frozen"}}},
+		},
+	}
+
+	p, err := Predicate(f, configs)
+	require.NoError(t, err)
+	s := sql.Dialect(dialect.Postgres).Select("*").From(sql.Table("users"))
+	p(s)
+	query, args := s.Query()
+	assert.Equal(t, `SELECT * FROM "users" WHERE "users"."user_id" = $1 AND (("users"."state" = $2 AND "users"."user_id" = $3) OR "users"."state" = $4)`, query)
+	assert.Equal(t, []any{int64(1), "active", int64(2), "frozen"}, args)
+
+	ast, err := kqlfilter.ParseAST("(userId:1 and state:active) or state:frozen")
+	require.NoError(t, err)
+	f, err = kqlfilter.ConvertToFilter(ast)
+	require.NoError(t, err)
+	p, err = Predicate(f, configs)
+	require.NoError(t, err)
+	s = sql.Dialect(dialect.Postgres).Select("*").From(sql.Table("users"))
+	p(s)
+	query, args = s.Query()
+	assert.Equal(t, `SELECT * FROM "users" WHERE ("users"."user_id" = $1 AND "users"."state" = $2) OR "users"."state" = $3`, query)
+	assert.Equal(t, []any{int64(1), "active", "frozen"}, args)
+
+	f.Groups[1][0].Field = "title"
+	_, err = Predicate(f, configs)
+	assert.EqualError(t, err, "unknown field: title")
+}
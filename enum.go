@@ -0,0 +1,30 @@
+package kqlfilter
+
+import (
+	"sort"
+)
+
+// EnumMap returns a MapValue function that maps the keys of values to their typed values, e.g. the names of a
+// protobuf enum to its numbers. Any other value is rejected with an InvalidValueError that lists the keys of values
+// as the allowed values and suggests the closest ones.
+func EnumMap[T any](values map[string]T) func(string) (any, error) {
+	allowedValues := EnumValues(values)
+	return func(value string) (any, error) {
+		v, ok := values[value]
+		if !ok {
+			return nil, NewInvalidValueError(value, nil, allowedValues)
+		}
+		return v, nil
+	}
+}
+
+// EnumValues returns the sorted keys of values, e.g. to set AllowedValues of a field that uses EnumMap, so that the
+// allowed values are also advertised in JSON schemas.
+func EnumValues[T any](values map[string]T) []string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
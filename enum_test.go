@@ -0,0 +1,64 @@
+package kqlfilter
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testState int64
+
+const (
+	testStateActive testState = iota + 1
+	testStateArchived
+)
+
+func TestEnumMap(t *testing.T) {
+	mapValue := EnumMap(map[string]testState{
+		"ACTIVE":   testStateActive,
+		"ARCHIVED": testStateArchived,
+	})
+
+	v, err := mapValue("ARCHIVED")
+	require.NoError(t, err)
+	assert.Equal(t, testStateArchived, v)
+
+	_, err = mapValue("ACTIV")
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrValueInvalid))
+	var valueErr *InvalidValueError
+	require.True(t, errors.As(err, &valueErr))
+	assert.Equal(t, []string{"ACTIVE", "ARCHIVED"}, valueErr.AllowedValues)
+	assert.Equal(t, []string{"ACTIVE"}, valueErr.Suggestions)
+	assert.Equal(t, `invalid value "ACTIV"; did you mean ACTIVE? (allowed values: ACTIVE, ARCHIVED)`, err.Error())
+}
+
+func TestEnumMapSpanner(t *testing.T) {
+	states := map[string]int64{"ACTIVE": 1, "ARCHIVED": 2}
+	fieldConfigs := map[string]FilterToSpannerFieldConfig{
+		"state": {
+			ColumnType:          FilterToSpannerFieldColumnTypeInt64,
+			AllowMultipleValues: true,
+			MapValue:            EnumMap(states),
+		},
+	}
+
+	f, err := Parse("state:(ACTIVE OR ARCHIVED)")
+	require.NoError(t, err)
+	conditions, params, err := f.ToSpannerSQL(fieldConfigs)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"state IN UNNEST(@KQL0)"}, conditions)
+	assert.Equal(t, map[string]any{"KQL0": []int64{1, 2}}, params)
+
+	f, err = Parse("state:DELETED")
+	require.NoError(t, err)
+	_, _, err = f.ToSpannerSQL(fieldConfigs)
+	assert.True(t, errors.Is(err, ErrValueInvalid))
+}
+
+func TestEnumValues(t *testing.T) {
+	assert.Equal(t, []string{"a", "b", "c"}, EnumValues(map[string]int{"c": 3, "a": 1, "b": 2}))
+	assert.Empty(t, EnumValues(map[string]int{}))
+}
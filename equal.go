@@ -0,0 +1,238 @@
+package kqlfilter
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Normalize returns a copy of the AST in a canonical form, so that equivalent filters that are written differently
+// result in the same AST:
+//
+//   - nested queries are expanded to fields named by their path, e.g. `user:{name:jon}` to `user.name:jon`,
+//   - nested conjunctions and disjunctions are flattened, and conjunctions and disjunctions of a single node are
+//     replaced by that node,
+//   - the nodes of conjunctions and disjunctions, including lists of values, are deduplicated and put in a canonical
+//     order,
+//   - double negations are removed,
+//   - positions are cleared.
+//
+// It returns nil for a nil AST. The input AST is not modified.
+func Normalize(ast Node) Node {
+	if ast == nil {
+		return nil
+	}
+	return normalize(ast, "")
+}
+
+// Equal reports whether the ASTs are structurally equal after normalization, e.g. to deduplicate saved searches.
+// `a:1 and b:2` equals `b:2 and a:1`, but `a>=1` does not equal `not a<1`.
+func Equal(a, b Node) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return canonical(Normalize(a)) == canonical(Normalize(b))
+}
+
+// NodeDiff holds the differences between two filters, as the clauses of their normalized conjunctions.
+type NodeDiff struct {
+	// Clauses of the second filter that are not in the first.
+	Added []Node
+	// Clauses of the first filter that are not in the second.
+	Removed []Node
+}
+
+// Empty reports whether the filters are equal.
+func (d NodeDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0
+}
+
+// String returns the removed and added clauses, one per line, prefixed with `-` and `+`, e.g. for audit logs.
+func (d NodeDiff) String() string {
+	var sb strings.Builder
+	for _, n := range d.Removed {
+		sb.WriteString("- ")
+		sb.WriteString(n.String())
+		sb.WriteString("\n")
+	}
+	for _, n := range d.Added {
+		sb.WriteString("+ ")
+		sb.WriteString(n.String())
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// Diff returns the clauses that were added and removed when changing the filter a into b, e.g. to audit changes to
+// alert rules. Both filters are normalized, and a clause is a node of the top-level conjunction, or the filter itself
+// if it is not a conjunction. Changing a value of a clause removes the clause and adds the changed one.
+func Diff(a, b Node) NodeDiff {
+	var before, after []Node
+	if a != nil {
+		before = conjuncts(Normalize(a))
+	}
+	if b != nil {
+		after = conjuncts(Normalize(b))
+	}
+	inBefore := make(map[string]bool, len(before))
+	for _, n := range before {
+		inBefore[canonical(n)] = true
+	}
+	inAfter := make(map[string]bool, len(after))
+	for _, n := range after {
+		inAfter[canonical(n)] = true
+	}
+	var diff NodeDiff
+	for _, n := range before {
+		if !inAfter[canonical(n)] {
+			diff.Removed = append(diff.Removed, n)
+		}
+	}
+	for _, n := range after {
+		if !inBefore[canonical(n)] {
+			diff.Added = append(diff.Added, n)
+		}
+	}
+	return diff
+}
+
+// normalize returns the normalized copy of a node, prefixing fields with the path of the enclosing nested queries.
+func normalize(ast Node, prefix string) Node {
+	switch n := ast.(type) {
+	case *AndNode:
+		return normalizeJunction(NodeAnd, n.Nodes, func(child Node) Node { return normalize(child, prefix) })
+	case *OrNode:
+		return normalizeJunction(NodeOr, n.Nodes, func(child Node) Node { return normalize(child, prefix) })
+	case *NotNode:
+		expr := normalize(n.Expr, prefix)
+		if not, ok := expr.(*NotNode); ok {
+			return not.Expr
+		}
+		return &NotNode{NodeType: NodeNot, Expr: expr}
+	case *IsNode:
+		if nested, ok := n.Value.(*NestedNode); ok {
+			return normalize(nested.Expr, prefix+n.Identifier+".")
+		}
+		return &IsNode{NodeType: NodeIs, Identifier: prefix + n.Identifier, Value: normalizeValue(n.Value)}
+	case *RangeNode:
+		return &RangeNode{NodeType: NodeRange, Identifier: prefix + n.Identifier, Operator: n.Operator, Value: normalizeValue(n.Value)}
+	case *ExistsNode:
+		return &ExistsNode{NodeType: NodeExists, Identifier: prefix + n.Identifier}
+	case *FuzzyNode:
+		return &FuzzyNode{NodeType: NodeFuzzy, Identifier: prefix + n.Identifier, Value: n.Value, Fuzziness: n.Fuzziness}
+	case *LiteralNode:
+		return &LiteralNode{NodeType: NodeLiteral, Value: n.Value}
+	default:
+		return ast
+	}
+}
+
+// normalizeValue returns the normalized copy of the value of a clause.
+func normalizeValue(ast Node) Node {
+	switch n := ast.(type) {
+	case *AndNode:
+		return normalizeJunction(NodeAnd, n.Nodes, normalizeValue)
+	case *OrNode:
+		return normalizeJunction(NodeOr, n.Nodes, normalizeValue)
+	case *NotNode:
+		expr := normalizeValue(n.Expr)
+		if not, ok := expr.(*NotNode); ok {
+			return not.Expr
+		}
+		return &NotNode{NodeType: NodeNot, Expr: expr}
+	default:
+		return normalize(ast, "")
+	}
+}
+
+// normalizeJunction returns the normalized conjunction or disjunction of the nodes, which are normalized by
+// normalizeChild.
+func normalizeJunction(typ NodeType, nodes []Node, normalizeChild func(Node) Node) Node {
+	var children []Node
+	seen := make(map[string]bool, len(nodes))
+	add := func(child Node) {
+		key := canonical(child)
+		if !seen[key] {
+			seen[key] = true
+			children = append(children, child)
+		}
+	}
+	for _, child := range nodes {
+		child = normalizeChild(child)
+		switch c := child.(type) {
+		case *AndNode:
+			if typ == NodeAnd {
+				for _, grandchild := range c.Nodes {
+					add(grandchild)
+				}
+				continue
+			}
+		case *OrNode:
+			if typ == NodeOr {
+				for _, grandchild := range c.Nodes {
+					add(grandchild)
+				}
+				continue
+			}
+		}
+		add(child)
+	}
+	sort.Slice(children, func(i, j int) bool {
+		return canonical(children[i]) < canonical(children[j])
+	})
+	switch {
+	case len(children) == 1:
+		return children[0]
+	case typ == NodeAnd:
+		return &AndNode{NodeType: NodeAnd, Nodes: children}
+	default:
+		return &OrNode{NodeType: NodeOr, Nodes: children}
+	}
+}
+
+// canonical returns an unambiguous string representation of a node, ignoring positions.
+func canonical(ast Node) string {
+	var sb strings.Builder
+	writeCanonical(&sb, ast)
+	return sb.String()
+}
+
+func writeCanonical(sb *strings.Builder, ast Node) {
+	writeNodes := func(name string, nodes []Node) {
+		sb.WriteString(name)
+		sb.WriteString("(")
+		for i, n := range nodes {
+			if i > 0 {
+				sb.WriteString(",")
+			}
+			writeCanonical(sb, n)
+		}
+		sb.WriteString(")")
+	}
+	switch n := ast.(type) {
+	case *AndNode:
+		writeNodes("and", n.Nodes)
+	case *OrNode:
+		writeNodes("or", n.Nodes)
+	case *NotNode:
+		writeNodes("not", []Node{n.Expr})
+	case *IsNode:
+		sb.WriteString("is(" + strconv.Quote(n.Identifier) + ",")
+		writeCanonical(sb, n.Value)
+		sb.WriteString(")")
+	case *RangeNode:
+		sb.WriteString("range(" + strconv.Quote(n.Identifier) + "," + n.Operator.String() + ",")
+		writeCanonical(sb, n.Value)
+		sb.WriteString(")")
+	case *NestedNode:
+		writeNodes("nested", []Node{n.Expr})
+	case *ExistsNode:
+		sb.WriteString("exists(" + strconv.Quote(n.Identifier) + ")")
+	case *FuzzyNode:
+		sb.WriteString("fuzzy(" + strconv.Quote(n.Identifier) + "," + strconv.Quote(n.Value) + "," + strconv.Itoa(n.Fuzziness) + ")")
+	case *LiteralNode:
+		sb.WriteString(strconv.Quote(n.Value))
+	case nil:
+		sb.WriteString("nil")
+	}
+}
@@ -0,0 +1,81 @@
+package kqlfilter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalize(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"b:2 and a:1", "(a=1 AND b=2)"},
+		{"a:1 and (b:2 and a:1)", "(a=1 AND b=2)"},
+		{"state:(paused or active or paused)", "state=(active OR paused)"},
+		{"not (not a:1)", "a=1"},
+		{"user:{name:jon and age>30}", "(user.name=jon AND user.age>30)"},
+		{"(a:1 or b:2) or c:3", "(a=1 OR b=2 OR c=3)"},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.input, func(t *testing.T) {
+			ast, err := ParseAST(test.input)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, Normalize(ast).String())
+		})
+	}
+
+	assert.Nil(t, Normalize(nil))
+}
+
+func TestEqual(t *testing.T) {
+	testCases := []struct {
+		a, b     string
+		expected bool
+	}{
+		{"a:1 and b:2", "b:2 a:1", true},
+		{"state:(active or paused)", "state:(paused or active)", true},
+		{"user:{name:jon}", "user.name:jon", true},
+		{"not (not a:1)", "a:1", true},
+		{"a:1 or (b:2 or c:3)", "c:3 or b:2 or a:1", true},
+		{"a:1", "a:2", false},
+		{"a:1 and b:2", "a:1 or b:2", false},
+		{"a>=1", "not a<1", false},
+		{`a:"x y"`, "a:x", false},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.a+" vs "+test.b, func(t *testing.T) {
+			a, err := ParseAST(test.a)
+			require.NoError(t, err)
+			b, err := ParseAST(test.b)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, Equal(a, b))
+		})
+	}
+
+	a, err := ParseAST("a:1")
+	require.NoError(t, err)
+	assert.True(t, Equal(nil, nil))
+	assert.False(t, Equal(a, nil))
+}
+
+func TestDiff(t *testing.T) {
+	a, err := ParseAST("severity:(high or critical) service:api env:prod")
+	require.NoError(t, err)
+	b, err := ParseAST("env:prod and service:web and severity:(critical or high) and region:eu")
+	require.NoError(t, err)
+
+	diff := Diff(a, b)
+	assert.False(t, diff.Empty())
+	assert.Equal(t, "- service=api\n+ region=eu\n+ service=web\n", diff.String())
+
+	assert.True(t, Diff(a, a.Clone()).Empty())
+
+	diff = Diff(nil, a)
+	assert.Len(t, diff.Added, 3)
+	assert.Empty(t, diff.Removed)
+}
@@ -0,0 +1,69 @@
+package kqlfilter
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// Errors returned by the Spanner converter. Unknown fields are reported as an UnknownFieldError, all other errors
+// are wrapped in a FieldError naming the offending field.
+// Use errors.Is to check for them and errors.As to retrieve the field, e.g. to map them to API errors.
+var (
+	// ErrUnknownField is returned when a filter references a field that is not configured.
+	ErrUnknownField = errors.New("unknown field")
+	// ErrOperatorNotAllowed is returned when an operator is not supported by the field or its type.
+	ErrOperatorNotAllowed = errors.New("operator not allowed")
+	// ErrValueInvalid is returned when a value cannot be converted to the field's type or is not allowed.
+	ErrValueInvalid = errors.New("invalid value")
+	// ErrMultipleValuesNotAllowed is returned when multiple values are provided for a field or operator that
+	// only supports a single value.
+	ErrMultipleValuesNotAllowed = errors.New("multiple values are not allowed")
+	// ErrRequiredFieldMissing is returned when a required field is missing, either because it is required in every
+	// filter or because it is required by another field in the filter.
+	ErrRequiredFieldMissing = errors.New("required field missing")
+	// ErrFieldNotAllowed is returned by Authorize when a filter references a field the caller may not filter on.
+	ErrFieldNotAllowed = errors.New("field not allowed")
+)
+
+// FieldError is returned when a clause of a filter cannot be converted.
+// It matches its Kind with errors.Is, as well as any error wrapped by Err.
+type FieldError struct {
+	// Field as provided in the filter.
+	Field string
+	// One of the Err* errors of this package.
+	Kind error
+	// Detailed error.
+	Err error
+}
+
+// newFieldError returns a FieldError of the given kind, formatting the detailed error like fmt.Errorf.
+func newFieldError(field string, kind error, format string, args ...any) *FieldError {
+	return &FieldError{Field: field, Kind: kind, Err: fmt.Errorf(format, args...)}
+}
+
+func (e *FieldError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *FieldError) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
+
+// ValueConstraintError is returned when a value violates the MaxLength or Pattern of a field config. It is wrapped in
+// a FieldError of kind ErrValueInvalid.
+type ValueConstraintError struct {
+	// Value without escapes and wildcards.
+	Value string
+	// Maximum length in characters, if the value is too long.
+	MaxLength int
+	// Pattern that the value doesn't match, if it is not too long.
+	Pattern *regexp.Regexp
+}
+
+func (e *ValueConstraintError) Error() string {
+	if e.Pattern != nil {
+		return fmt.Sprintf("value %q does not match pattern %s", e.Value, e.Pattern)
+	}
+	return fmt.Sprintf("value %q exceeds maximum length of %d", e.Value, e.MaxLength)
+}
@@ -0,0 +1,73 @@
+package kqlfilter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Feature is a part of the grammar that can be disabled with WithFeatures. Features can be combined with `|`.
+type Feature uint
+
+const (
+	// FeatureRanges are range operators, e.g. `price>=10`, and ranges in brackets, e.g. `price:[10 TO 20]`.
+	FeatureRanges Feature = 1 << iota
+	// FeatureNesting are nested queries, e.g. `user:{name:jon and age>18}`.
+	FeatureNesting
+	// FeatureWildcards are wildcards in values, e.g. `name:jo*`, including existence checks, e.g. `name:*`.
+	FeatureWildcards
+	// FeatureNegation is the `not` operator, e.g. `not state:deleted`, including `IS NULL` of WithExtendedSyntax.
+	FeatureNegation
+
+	// FeatureAll are all features, which are enabled by default.
+	FeatureAll = FeatureRanges | FeatureNesting | FeatureWildcards | FeatureNegation
+)
+
+func (f Feature) String() string {
+	var names []string
+	for _, feature := range []struct {
+		feature Feature
+		name    string
+	}{
+		{FeatureRanges, "ranges"},
+		{FeatureNesting, "nested queries"},
+		{FeatureWildcards, "wildcards"},
+		{FeatureNegation, "negation"},
+	} {
+		if f&feature.feature != 0 {
+			names = append(names, feature.name)
+		}
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, ", ")
+}
+
+// FeatureError is returned by the parser when the input uses a feature that is not enabled with WithFeatures.
+type FeatureError struct {
+	// The disabled feature.
+	Feature Feature
+	// Position of the offending part of the input.
+	Pos Pos
+}
+
+func (e *FeatureError) Error() string {
+	return fmt.Sprintf("parser error: %s not allowed at pos %d", e.Feature, e.Pos)
+}
+
+// WithFeatures sets the grammar features that may be used in the input, e.g. `FeatureRanges|FeatureWildcards`, to
+// limit the feature surface of an endpoint. Using any other feature is rejected with a FeatureError.
+// Defaults to FeatureAll.
+func WithFeatures(features Feature) ParserOption {
+	return func(p *parser) {
+		p.disabledFeatures = FeatureAll &^ features
+	}
+}
+
+// requireFeature terminates processing if the feature is disabled.
+func (p *parser) requireFeature(pos Pos, feature Feature) {
+	if p.disabledFeatures&feature != 0 {
+		p.Root = nil
+		panic(&FeatureError{Feature: feature, Pos: pos})
+	}
+}
@@ -0,0 +1,98 @@
+package kqlfilter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFeatures(t *testing.T) {
+	testCases := []struct {
+		name          string
+		input         string
+		options       []ParserOption
+		expectedError *FeatureError
+	}{
+		{
+			"all features by default",
+			"not a:1 b>2 c:jo* d:{e:1} f:[1 TO 2]",
+			nil,
+			nil,
+		},
+		{
+			"no features",
+			"a:1 b:(2 or 3)",
+			[]ParserOption{WithFeatures(0)},
+			nil,
+		},
+		{
+			"range operator",
+			"a:1 b>=2",
+			[]ParserOption{WithFeatures(FeatureAll &^ FeatureRanges)},
+			&FeatureError{Feature: FeatureRanges, Pos: 5},
+		},
+		{
+			"range brackets",
+			"b:[1 TO 2]",
+			[]ParserOption{WithFeatures(FeatureWildcards)},
+			&FeatureError{Feature: FeatureRanges, Pos: 2},
+		},
+		{
+			"nested query",
+			"user:{name:jon}",
+			[]ParserOption{WithFeatures(FeatureRanges | FeatureWildcards)},
+			&FeatureError{Feature: FeatureNesting, Pos: 5},
+		},
+		{
+			"wildcard",
+			"name:jo*",
+			[]ParserOption{WithFeatures(FeatureRanges)},
+			&FeatureError{Feature: FeatureWildcards, Pos: 7},
+		},
+		{
+			"exists",
+			"name:*",
+			[]ParserOption{WithFeatures(FeatureRanges)},
+			&FeatureError{Feature: FeatureWildcards, Pos: 5},
+		},
+		{
+			"negation",
+			"a:1 not b:2",
+			[]ParserOption{WithFeatures(FeatureRanges)},
+			&FeatureError{Feature: FeatureNegation, Pos: 4},
+		},
+		{
+			"null check",
+			"a IS NULL",
+			[]ParserOption{WithExtendedSyntax(), WithFeatures(FeatureRanges)},
+			&FeatureError{Feature: FeatureNegation, Pos: 0},
+		},
+		{
+			"not null check",
+			"a IS NOT NULL",
+			[]ParserOption{WithExtendedSyntax(), WithFeatures(FeatureRanges)},
+			nil,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ParseAST(test.input, test.options...)
+			if test.expectedError == nil {
+				require.NoError(t, err)
+				return
+			}
+			var featureErr *FeatureError
+			require.ErrorAs(t, err, &featureErr)
+			assert.Equal(t, test.expectedError, featureErr)
+		})
+	}
+}
+
+func TestFeatureString(t *testing.T) {
+	assert.Equal(t, "ranges", FeatureRanges.String())
+	assert.Equal(t, "nested queries, negation", (FeatureNesting | FeatureNegation).String())
+	assert.Equal(t, "none", Feature(0).String())
+	assert.Equal(t, "parser error: wildcards not allowed at pos 3", (&FeatureError{Feature: FeatureWildcards, Pos: 3}).Error())
+}
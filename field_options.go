@@ -0,0 +1,115 @@
+package kqlfilter
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+)
+
+// FieldOptions holds the settings shared by the field configs of Filter.ToPostgresSQL, Filter.ToSQLiteSQL,
+// Filter.ToDynamoDBExpression, Filter.ToClickHouseSQL, Filter.ToTrinoSQL, Filter.ToMySQLSQL and
+// Filter.ToFirestoreFilters, which embed it.
+type FieldOptions struct {
+	// If true, the filter must at least contain this field. Will not apply to empty filters. Defaults to false.
+	Required bool
+	// Allow multiple values for this field. Defaults to false.
+	AllowMultipleValues bool
+	// Allow negated matching of multiple values (e.g. `not state:(active OR canceled)`). Only applicable in combination
+	// with AllowMultipleValues, except for Firestore, which requires it for any negated clause. Defaults to false.
+	AllowNegation bool
+	// Allow this field to be queried with one or more range operators. Defaults to false.
+	AllowRanges bool
+	// A list of aliases for this field, e.g. to accept both `type_id` and `typeId`.
+	Aliases []string
+	// A function that takes a string value as provided by the user and converts it to the value stored in the backend.
+	// It should return an error when the user provides an illegal value. Mapped values are passed as-is.
+	// Defaults to converting the value according to the column type.
+	MapValue func(string) (any, error)
+	// The values that are allowed for this field, e.g. the values of an enum. When set, any other value is rejected
+	// with an InvalidValueError that lists the allowed values and suggests the closest ones. Defaults to allowing any
+	// value.
+	AllowedValues []string
+}
+
+func (f FieldOptions) fieldOptions() FieldOptions {
+	return f
+}
+
+// fieldConfig is a field config that embeds FieldOptions.
+type fieldConfig interface {
+	fieldOptions() FieldOptions
+}
+
+// mapValues converts the values of a clause with MapValue, or with convert if it is not set, after checking them
+// against AllowedValues.
+func (f FieldOptions) mapValues(values []string, o converterOptions, convert func(string, converterOptions) (any, error)) ([]any, error) {
+	mapped := make([]any, 0, len(values))
+	for _, value := range values {
+		if err := checkAllowedValue(value, f.AllowedValues); err != nil {
+			return nil, err
+		}
+		if f.MapValue != nil {
+			v, err := f.MapValue(value)
+			if err != nil {
+				if len(f.AllowedValues) > 0 {
+					return nil, NewInvalidValueError(value, err, f.AllowedValues)
+				}
+				return nil, err
+			}
+			mapped = append(mapped, v)
+			continue
+		}
+		v, err := convert(value, o)
+		if err != nil {
+			return nil, err
+		}
+		mapped = append(mapped, v)
+	}
+	return mapped, nil
+}
+
+// lookupField returns the name and config of the given field name or alias.
+func lookupField[C fieldConfig](fieldConfigs map[string]C, field string) (string, C, bool) {
+	if fc, ok := fieldConfigs[field]; ok {
+		return field, fc, true
+	}
+	for name, fc := range fieldConfigs {
+		if slices.Contains(fc.fieldOptions().Aliases, field) {
+			return name, fc, true
+		}
+	}
+	var zero C
+	return "", zero, false
+}
+
+// newUnknownFieldError returns the UnknownFieldError of a field, suggesting the closest field names and aliases.
+func newUnknownFieldError[C fieldConfig](fieldConfigs map[string]C, field string) error {
+	names := make([]string, 0, len(fieldConfigs))
+	for name, fc := range fieldConfigs {
+		names = append(names, name)
+		names = append(names, fc.fieldOptions().Aliases...)
+	}
+	return NewUnknownFieldError(field, names)
+}
+
+// checkRequiredFields returns an error if a Required field is missing from the filter, i.e. from its clauses and from
+// a group. Fields are checked in alphabetical order, so that the error is the same on every call.
+func checkRequiredFields[C fieldConfig](fieldConfigs map[string]C, f Filter) error {
+	var required []string
+	for field, fc := range fieldConfigs {
+		if fc.fieldOptions().Required {
+			required = append(required, field)
+		}
+	}
+	sort.Strings(required)
+	for _, field := range required {
+		aliases := fieldConfigs[field].fieldOptions().Aliases
+		found := f.hasClause(func(clause Clause) bool {
+			return clause.Field == field || slices.Contains(aliases, clause.Field)
+		})
+		if !found {
+			return fmt.Errorf("required field %s missing", field)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package kqlfilter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckRequiredFields(t *testing.T) {
+	fieldConfigs := map[string]FilterToSQLiteFieldConfig{
+		"c": {FieldOptions: FieldOptions{Required: true}},
+		"a": {FieldOptions: FieldOptions{Required: true, Aliases: []string{"alias"}}},
+		"b": {FieldOptions: FieldOptions{Required: true}},
+		"d": {},
+	}
+
+	// The first missing field in alphabetical order is reported, whatever the map iteration order is.
+	for i := 0; i < 20; i++ {
+		assert.EqualError(t, checkRequiredFields(fieldConfigs, Filter{}), "required field a missing")
+	}
+	assert.EqualError(t, checkRequiredFields(fieldConfigs, Filter{Clauses: []Clause{{Field: "alias"}}}), "required field b missing")
+	assert.NoError(t, checkRequiredFields(fieldConfigs, Filter{Clauses: []Clause{{Field: "a"}, {Field: "b"}, {Field: "c"}}}))
+}
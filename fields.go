@@ -0,0 +1,87 @@
+package kqlfilter
+
+// FieldUsage describes how a field is referenced in a filter.
+type FieldUsage struct {
+	// Field identifier. Fields in nested queries (`parent:{child:value}`) are joined with a dot (`parent.child`).
+	Field string
+	// Number of clauses referencing the field.
+	Count int
+	// Distinct operators used with the field, in order of first use. These are the operators of the Filter
+	// representation: `=`, `IN`, `<`, `<=`, `>` and `>=`. Negated clauses are reported as `!=`, `NOT IN`, or the range
+	// operator prefixed with `NOT `. Existence checks (`field:*`) are reported as `EXISTS` or `NOT EXISTS`, and fuzzy matches
+	// (`field:value~1`) as `FUZZY` or `NOT FUZZY`.
+	Operators []string
+}
+
+// ListFields returns all fields referenced in the AST, in order of first occurrence.
+// It is useful for authorization checks ("is the caller allowed to filter on this field?") and query analytics.
+func ListFields(ast Node) []FieldUsage {
+	var usages []FieldUsage
+	index := make(map[string]int)
+	listFields(ast, "", false, func(field, operator string) {
+		i, ok := index[field]
+		if !ok {
+			i = len(usages)
+			index[field] = i
+			usages = append(usages, FieldUsage{Field: field})
+		}
+		usages[i].Count++
+		for _, op := range usages[i].Operators {
+			if op == operator {
+				return
+			}
+		}
+		usages[i].Operators = append(usages[i].Operators, operator)
+	})
+	return usages
+}
+
+func listFields(ast Node, prefix string, negated bool, add func(field, operator string)) {
+	switch n := ast.(type) {
+	case *AndNode:
+		for _, child := range n.Nodes {
+			listFields(child, prefix, negated, add)
+		}
+	case *OrNode:
+		for _, child := range n.Nodes {
+			listFields(child, prefix, negated, add)
+		}
+	case *NotNode:
+		listFields(n.Expr, prefix, !negated, add)
+	case *IsNode:
+		switch v := n.Value.(type) {
+		case *NestedNode:
+			listFields(v.Expr, prefix+n.Identifier+".", negated, add)
+		case *OrNode, *AndNode:
+			if negated {
+				add(prefix+n.Identifier, "NOT IN")
+			} else {
+				add(prefix+n.Identifier, "IN")
+			}
+		default:
+			if negated {
+				add(prefix+n.Identifier, "!=")
+			} else {
+				add(prefix+n.Identifier, "=")
+			}
+		}
+	case *ExistsNode:
+		if negated {
+			add(prefix+n.Identifier, "NOT EXISTS")
+		} else {
+			add(prefix+n.Identifier, "EXISTS")
+		}
+	case *FuzzyNode:
+		if negated {
+			add(prefix+n.Identifier, "NOT FUZZY")
+		} else {
+			add(prefix+n.Identifier, "FUZZY")
+		}
+	case *RangeNode:
+		if negated {
+			add(prefix+n.Identifier, "NOT "+n.Operator.String())
+		} else {
+			add(prefix+n.Identifier, n.Operator.String())
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package kqlfilter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListFields(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected []FieldUsage
+	}{
+		{
+			"single field",
+			"owner_id:123",
+			[]FieldUsage{
+				{Field: "owner_id", Count: 1, Operators: []string{"="}},
+			},
+		},
+		{
+			"boolean literal only",
+			"true",
+			nil,
+		},
+		{
+			"repeated field with ranges",
+			"created_at >= 1 and created_at < 5 and state:(a OR b)",
+			[]FieldUsage{
+				{Field: "created_at", Count: 2, Operators: []string{">=", "<"}},
+				{Field: "state", Count: 1, Operators: []string{"IN"}},
+			},
+		},
+		{
+			"exists",
+			"deleted_at:* and not owner:{email:*}",
+			[]FieldUsage{
+				{Field: "deleted_at", Count: 1, Operators: []string{"EXISTS"}},
+				{Field: "owner.email", Count: 1, Operators: []string{"NOT EXISTS"}},
+			},
+		},
+		{
+			"negations",
+			"not a:1 and not (b:(x OR y) or c > 2) and a:2",
+			[]FieldUsage{
+				{Field: "a", Count: 2, Operators: []string{"!=", "="}},
+				{Field: "b", Count: 1, Operators: []string{"NOT IN"}},
+				{Field: "c", Count: 1, Operators: []string{"NOT >"}},
+			},
+		},
+		{
+			"double negation",
+			"not (not a:1)",
+			[]FieldUsage{
+				{Field: "a", Count: 1, Operators: []string{"="}},
+			},
+		},
+		{
+			"nested fields",
+			"user:{id:1 or profile:{name:x}} and user.id:2",
+			[]FieldUsage{
+				{Field: "user.id", Count: 2, Operators: []string{"="}},
+				{Field: "user.profile.name", Count: 1, Operators: []string{"="}},
+			},
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			ast, err := ParseAST(test.input)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, ListFields(ast))
+		})
+	}
+
+	assert.Nil(t, ListFields(nil))
+}
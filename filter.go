@@ -1,22 +1,86 @@
 package kqlfilter
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 type Filter struct {
+	// Clauses that must all hold.
 	Clauses []Clause
+	// Alternative conjunctions of clauses, of which at least one must hold in addition to Clauses, e.g.
+	// `(a:1 and b:2) or c:3` is represented by the groups [a=1, b=2] and [c=3]. Empty if the filter has no disjunction.
+	// Only ConvertToFilter produces groups. The SQL converters render them as a single condition,
+	// `((a AND b) OR (c))`, and converters that cannot express disjunctions reject filters with groups with
+	// ErrGroupsNotSupported.
+	Groups [][]Clause
+}
+
+// ErrGroupsNotSupported is returned by converters that cannot express disjunctions when a Filter has Groups.
+var ErrGroupsNotSupported = errors.New("clause groups are not supported")
+
+// hasClause reports whether one of the clauses, or a clause of every group, matches.
+func (f Filter) hasClause(matches func(Clause) bool) bool {
+	if slices.ContainsFunc(f.Clauses, matches) {
+		return true
+	}
+	if len(f.Groups) == 0 {
+		return false
+	}
+	for _, group := range f.Groups {
+		if !slices.ContainsFunc(group, matches) {
+			return false
+		}
+	}
+	return true
+}
+
+// convertGroups converts the groups of a filter to a single condition, the disjunction of their conjunctions, with
+// convertClause returning the condition of a clause.
+func convertGroups(groups [][]Clause, convertClause func(Clause) (string, error)) (string, error) {
+	alternatives := make([][]string, 0, len(groups))
+	for _, group := range groups {
+		conditions := make([]string, 0, len(group))
+		for _, clause := range group {
+			condition, err := convertClause(clause)
+			if err != nil {
+				return "", err
+			}
+			conditions = append(conditions, condition)
+		}
+		alternatives = append(alternatives, conditions)
+	}
+	return disjunction(alternatives)
+}
+
+// disjunction returns the disjunction of conjunctions of conditions, e.g. `((a AND b) OR (c))`.
+func disjunction(alternatives [][]string) (string, error) {
+	ors := make([]string, 0, len(alternatives))
+	for _, conditions := range alternatives {
+		if len(conditions) == 0 {
+			return "", errors.New("empty clause group")
+		}
+		ors = append(ors, "("+strings.Join(conditions, " AND ")+")")
+	}
+	return "(" + strings.Join(ors, " OR ") + ")", nil
 }
 
 type Clause struct {
 	Field string
-	// One of the following: `=`, `<`, `<=`, `>`, `>=`, `IN`
+	// One of the following: `=`, `!=`, `<`, `<=`, `>`, `>=`, `IN`, `NOT IN`, `~`
 	Operator string
 	// List of values for the clause.
-	// For `IN` operator, this is a list of values to match against.
+	// For `IN` and `NOT IN` operators, this is a list of values to match against.
+	// For the `~` (fuzzy match) operator, this is the value followed by the maximum edit distance.
 	// For other operators, this is a list of one string.
+	// Values containing an asterisk are wildcard patterns, in which `\*` is a literal asterisk and `\\` a literal
+	// backslash; any other `*` is a wildcard. Use UnescapeValue or SplitWildcards to get their literal text.
 	Values []string
 }
 
@@ -38,19 +102,71 @@ func Parse(input string) (Filter, error) {
 // ParseAST parses a filter string into an AST.
 // The filter string must be a valid Kibana query language filter string.
 func ParseAST(input string, options ...ParserOption) (n Node, err error) {
-	p := &parser{
+	return ParseASTContext(context.Background(), input, options...)
+}
+
+// ParseASTContext parses a filter string into an AST like ParseAST, but stops parsing when the context is done, e.g.
+// when the request providing the filter is cancelled. The returned error then wraps the error of the context, so it
+// can be matched with errors.Is against context.Canceled or context.DeadlineExceeded.
+// See WithTimeBudget to limit the parsing time without a context.
+func ParseASTContext(ctx context.Context, input string, options ...ParserOption) (n Node, err error) {
+	return parseAST(ctx, input, options, nil)
+}
+
+// parseAST parses the input with a pooled parser, allocating the nodes from the arena if it is not nil.
+func parseAST(ctx context.Context, input string, options []ParserOption, arena *nodeArena) (Node, error) {
+	p := parserPool.Get().(*parser)
+	defer func() {
+		// Don't keep the input and the tree alive while the parser is pooled.
+		*p = parser{}
+		parserPool.Put(p)
+	}()
+	p.configure(options)
+	p.ctx = ctx
+	p.arena = arena
+	return p.run(input)
+}
+
+// parserPool holds parsers for reuse by ParseAST, so that parsing doesn't allocate the parser and lexer state.
+var parserPool = sync.Pool{
+	New: func() any {
+		return &parser{}
+	},
+}
+
+// newParser creates a parser configured with the given options.
+func newParser(options []ParserOption) *parser {
+	p := &parser{}
+	p.configure(options)
+	return p
+}
+
+// configure resets the parser to the default settings and applies the options.
+func (p *parser) configure(options []ParserOption) {
+	*p = parser{
 		maxDepth:      20,
 		maxComplexity: 20,
 	}
 	for _, option := range options {
 		option(p)
 	}
+}
+
+// run parses the input and returns the root of the tree.
+func (p *parser) run(input string) (n Node, err error) {
 	p.text = input
+	if p.maxInputLength > 0 && len(input) > p.maxInputLength {
+		return nil, &LimitError{Limit: LimitInputLength, Max: p.maxInputLength, Actual: len(input)}
+	}
 
+	if p.timeBudget > 0 {
+		p.deadline = time.Now().Add(p.timeBudget)
+	}
 	defer p.recover(&err)
-	p.lex = lex(input)
+	p.lex.reset(input)
+	p.lex.keywordCase = p.keywordCase
+	p.lex.placeholders = p.placeholders
 	p.parse()
-	p.lex = nil // release lexer for garbage collection
 
 	return p.Root, err
 }
@@ -72,6 +188,58 @@ func WithMaxDepth(depth int) ParserOption {
 	}
 }
 
+// WithMaxInputLength sets the maximum length of the input in bytes. Longer inputs are rejected with a LimitError
+// before parsing. Defaults to no limit.
+func WithMaxInputLength(length int) ParserOption {
+	return func(p *parser) {
+		p.maxInputLength = length
+	}
+}
+
+// WithTimeBudget sets the maximum time spent parsing an input. When exceeded, parsing stops with an error that wraps
+// context.DeadlineExceeded. The time is checked periodically while parsing, so the budget can be exceeded slightly.
+// Defaults to no limit.
+func WithTimeBudget(budget time.Duration) ParserOption {
+	return func(p *parser) {
+		p.timeBudget = budget
+	}
+}
+
+// WithMaxValueLength sets the maximum length of a single value in bytes, after removing quotes and escapes.
+// Longer values are rejected with a LimitError. Defaults to no limit.
+func WithMaxValueLength(length int) ParserOption {
+	return func(p *parser) {
+		p.maxValueLength = length
+	}
+}
+
+// WithFuzzyMatching enables the Lucene-style fuzziness suffix, e.g. `name:jon~1`, which is parsed into a FuzzyNode.
+// The suffix is only recognized on a single unquoted value; quote the value to match a literal `~`.
+// By default, the suffix is part of the value.
+func WithFuzzyMatching() ParserOption {
+	return func(p *parser) {
+		p.fuzzyMatching = true
+	}
+}
+
+// WithExtendedSyntax enables SQL-style null checks and lists: `field IS NOT NULL` is parsed like `field:*` into an
+// ExistsNode, `field IS NULL` into its negation, and `field IN (a, b)` like `field:(a, b)`. The keywords are
+// case-insensitive. By default, `is`, `null` and `in` are values.
+func WithExtendedSyntax() ParserOption {
+	return func(p *parser) {
+		p.extendedSyntax = true
+	}
+}
+
+// WithKeywordCase sets which spellings of `and`, `or` and `not` are operators; other spellings are parsed as strings.
+// Defaults to KeywordCaseInsensitive. Regardless of this option, a field or value spelled like an operator can be
+// quoted (`"or":1`) or escaped (`\or:1`).
+func WithKeywordCase(keywordCase KeywordCase) ParserOption {
+	return func(p *parser) {
+		p.keywordCase = keywordCase
+	}
+}
+
 // WithMaxComplexity sets limit to maximum number of individual clauses separated by boolean operators.
 func WithMaxComplexity(complexity int) ParserOption {
 	return func(p *parser) {
@@ -79,6 +247,25 @@ func WithMaxComplexity(complexity int) ParserOption {
 	}
 }
 
+// ConvertToFilter converts an AST to a Filter. Unlike Parse, it accepts a disjunction at the top level, e.g. from
+// `(a:1 and b:2) or c:3`, which results in a filter with one group per alternative. Each alternative must be
+// convertible on its own, so disjunctions cannot be nested in conjunctions or in other disjunctions.
+func ConvertToFilter(ast Node) (Filter, error) {
+	n, ok := ast.(*OrNode)
+	if !ok {
+		return convertToFilter(ast)
+	}
+	var filter Filter
+	for _, node := range n.Nodes {
+		f, err := convertToFilter(node)
+		if err != nil {
+			return Filter{}, err
+		}
+		filter.Groups = append(filter.Groups, f.Clauses)
+	}
+	return filter, nil
+}
+
 func convertToFilter(ast Node) (Filter, error) {
 	if ast == nil {
 		return Filter{}, nil
@@ -88,6 +275,10 @@ func convertToFilter(ast Node) (Filter, error) {
 		return convertAndNode(n)
 	case *IsNode:
 		return convertIsNode(n)
+	case *ExistsNode:
+		return convertExistsNode(n)
+	case *FuzzyNode:
+		return convertFuzzyNode(n)
 	case *RangeNode:
 		return convertRangeNode(n)
 	case *NotNode:
@@ -134,8 +325,15 @@ func convertAndNode(ast *AndNode) (Filter, error) {
 		var f Filter
 		var err error
 		switch n := node.(type) {
+		case *AndNode:
+			// The range checks of a range in brackets, e.g. `price:[10 TO 20]`.
+			f, err = convertAndNode(n)
 		case *IsNode:
 			f, err = convertIsNode(n)
+		case *ExistsNode:
+			f, err = convertExistsNode(n)
+		case *FuzzyNode:
+			f, err = convertFuzzyNode(n)
 		case *NotNode:
 			f, err = convertNotNode(n)
 		case *RangeNode:
@@ -184,12 +382,45 @@ func convertIsNode(ast *IsNode) (Filter, error) {
 	}, nil
 }
 
+// convertExistsNode converts `field:*` to an equality clause with a single wildcard value,
+// which is how it was represented before the parser produced ExistsNode.
+func convertExistsNode(ast *ExistsNode) (Filter, error) {
+	return Filter{
+		Clauses: []Clause{
+			{
+				Field:    ast.Identifier,
+				Operator: "=",
+				Values:   []string{"*"},
+			},
+		},
+	}, nil
+}
+
+// convertFuzzyNode converts `field:value~fuzziness` to a clause with the `~` operator.
+func convertFuzzyNode(ast *FuzzyNode) (Filter, error) {
+	return Filter{
+		Clauses: []Clause{
+			{
+				Field:    ast.Identifier,
+				Operator: "~",
+				Values:   []string{ast.Value, strconv.Itoa(ast.Fuzziness)},
+			},
+		},
+	}, nil
+}
+
 func convertNotNode(ast *NotNode) (Filter, error) {
 	var err error
 	var filter Filter
 	switch n := ast.Expr.(type) {
 	case *IsNode:
 		filter, err = convertIsNode(n)
+	case *ExistsNode:
+		filter, err = convertExistsNode(n)
+	case *FuzzyNode:
+		filter, err = convertFuzzyNode(n)
+	case *RangeNode:
+		filter, err = convertRangeNode(n)
 	default:
 		return Filter{}, fmt.Errorf("unsupported node type %T", ast.Expr)
 	}
@@ -199,9 +430,21 @@ func convertNotNode(ast *NotNode) (Filter, error) {
 	}
 
 	for i := range filter.Clauses {
-		if filter.Clauses[i].Operator == "=" {
+		switch filter.Clauses[i].Operator {
+		case "=":
 			filter.Clauses[i].Operator = "!="
-		} else {
+		case "IN":
+			filter.Clauses[i].Operator = "NOT IN"
+		// A negated range is the complementary range, e.g. `not price >= 100` is `price < 100`.
+		case ">=":
+			filter.Clauses[i].Operator = "<"
+		case ">":
+			filter.Clauses[i].Operator = "<="
+		case "<=":
+			filter.Clauses[i].Operator = ">"
+		case "<":
+			filter.Clauses[i].Operator = ">="
+		default:
 			return Filter{}, fmt.Errorf("cannot support negation on operator %s", filter.Clauses[i].Operator)
 		}
 	}
@@ -0,0 +1,351 @@
+package kqlfilter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type FilterToClickHouseFieldColumnType int
+
+const (
+	FilterToClickHouseFieldColumnTypeUnspecified FilterToClickHouseFieldColumnType = iota
+	FilterToClickHouseFieldColumnTypeString
+	FilterToClickHouseFieldColumnTypeInt64
+	FilterToClickHouseFieldColumnTypeFloat64
+	FilterToClickHouseFieldColumnTypeBool
+	// A DateTime64 column, compared with `toDateTime64(value, precision, 'UTC')`; see FilterToClickHouseFieldConfig.Precision.
+	FilterToClickHouseFieldColumnTypeDateTime64
+	// A Date column, compared with `toDate(value)`.
+	FilterToClickHouseFieldColumnTypeDate
+)
+
+func (c FilterToClickHouseFieldColumnType) String() string {
+	switch c {
+	case FilterToClickHouseFieldColumnTypeUnspecified, FilterToClickHouseFieldColumnTypeString:
+		return "String"
+	case FilterToClickHouseFieldColumnTypeInt64:
+		return "Int64"
+	case FilterToClickHouseFieldColumnTypeFloat64:
+		return "Float64"
+	case FilterToClickHouseFieldColumnTypeBool:
+		return "Bool"
+	case FilterToClickHouseFieldColumnTypeDateTime64:
+		return "DateTime64"
+	case FilterToClickHouseFieldColumnTypeDate:
+		return "Date"
+	default:
+		return "???"
+	}
+}
+
+type FilterToClickHouseFieldConfig struct {
+	// SQL table column name. Can be omitted if the column name is equal to the key in the fieldConfigs map.
+	ColumnName string
+	// SQL column type, or the element type of an Array column. Defaults to FilterToClickHouseFieldColumnTypeString.
+	ColumnType FilterToClickHouseFieldColumnType
+	// The column is an Array of ColumnType. A value matches if the array contains it, with `has(column, value)`, and
+	// multiple values match if the array contains any of them, with `hasAny(column, [values])`. Wildcards and ranges
+	// do not apply. Defaults to false.
+	Array bool
+	// The precision (number of digits of fractional seconds) of a FilterToClickHouseFieldColumnTypeDateTime64 column.
+	// Defaults to 3 (milliseconds).
+	Precision int
+	// Settings shared with the field configs of the other converters, e.g. AllowMultipleValues and MapValue.
+	FieldOptions
+	// Allow prefix matching when a wildcard (`*`) is present at the end of a string.
+	// Only applicable for FilterToClickHouseFieldColumnTypeString. Defaults to false.
+	AllowPrefixMatch bool
+	// Allow suffix matching when a wildcard (`*`) is present at the beginning of a string.
+	// Only applicable for FilterToClickHouseFieldColumnTypeString. Defaults to false.
+	AllowSuffixMatch bool
+}
+
+// ToClickHouseSQL turns a Filter into conditions for a ClickHouse WHERE clause, using positional `?` placeholders as
+// supported by clickhouse-go. It takes a map of fields that are allowed to be queried via this filter, and returns the
+// conditions, which must be joined by AND, along with the arguments in the order of their placeholders:
+//
+//	conditions, args, err := filter.ToClickHouseSQL(fieldConfigs)
+//	rows, err := conn.Query(ctx, "SELECT * FROM events WHERE "+strings.Join(conditions, " AND "), args...)
+//
+// Given the filter `userId:12345 email:john* tags:(a OR b) time>="2024-05-01T00:00:00Z"` and matching field configs,
+// with `tags` an Array column, the conditions are
+//
+//	["user_id = ?", "email LIKE ?", "hasAny(tags, [?, ?])", "time >= toDateTime64(?, 3, 'UTC')"]
+//
+// with arguments
+//
+//	[int64(12345), "john%", "a", "b", "2024-05-01 00:00:00.000"]
+//
+// Timestamps are passed as strings in UTC and converted with toDateTime64 and toDate, so they do not depend on the
+// time zone of the server or the session. DateTime64 and Date fields accept RFC3339 values, values in the layouts set
+// with WithTimeLayouts, as well as relative time keywords such as `today` (see RelativeTimeToday), which are resolved
+// using the clock and location set with WithClock and WithDefaultLocation.
+//
+// Conditions added with WithCondition are appended as-is; they must not have params, as the placeholders are
+// positional. Use ToClickHouseSQLNamed for conditions with params.
+func (f Filter) ToClickHouseSQL(fieldConfigs map[string]FilterToClickHouseFieldConfig, options ...ConverterOption) ([]string, []any, error) {
+	c := &clickHouseConversion{}
+	conditions, err := c.convert(f, fieldConfigs, newConverterOptions(options))
+	if err != nil {
+		return nil, nil, err
+	}
+	return conditions, c.args, nil
+}
+
+// ToClickHouseSQLNamed is like ToClickHouseSQL, but uses named `@KQL0` placeholders, which are bound with
+// `clickhouse.Named`. Params of conditions added with WithCondition are added to the returned params.
+func (f Filter) ToClickHouseSQLNamed(fieldConfigs map[string]FilterToClickHouseFieldConfig, options ...ConverterOption) ([]string, map[string]any, error) {
+	c := &clickHouseConversion{params: make(map[string]any)}
+	conditions, err := c.convert(f, fieldConfigs, newConverterOptions(options))
+	if err != nil {
+		return nil, nil, err
+	}
+	return conditions, c.params, nil
+}
+
+// clickHouseConversion holds the arguments of a filter while its clauses are converted: positional if params is nil,
+// and named otherwise.
+type clickHouseConversion struct {
+	args   []any
+	params map[string]any
+}
+
+// bind adds the value as an argument and returns its placeholder.
+func (c *clickHouseConversion) bind(value any) string {
+	if c.params == nil {
+		c.args = append(c.args, value)
+		return "?"
+	}
+	name := fmt.Sprintf("%s%d", "KQL", len(c.params))
+	c.params[name] = value
+	return "@" + name
+}
+
+// bindAll adds the values as arguments and returns their placeholders, separated by commas.
+func (c *clickHouseConversion) bindAll(values []any) string {
+	placeholders := make([]string, len(values))
+	for i, v := range values {
+		placeholders[i] = c.bind(v)
+	}
+	return strings.Join(placeholders, ", ")
+}
+
+func (c *clickHouseConversion) convert(f Filter, fieldConfigs map[string]FilterToClickHouseFieldConfig, o converterOptions) ([]string, error) {
+	var conditions []string
+
+	f, includeDeleted, err := o.extractIncludeDeleted(f)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, clause := range f.Clauses {
+		condition, err := c.convertClause(clause, fieldConfigs, o)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, condition)
+	}
+	if len(f.Groups) > 0 {
+		condition, err := convertGroups(f.Groups, func(clause Clause) (string, error) {
+			return c.convertClause(clause, fieldConfigs, o)
+		})
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, condition)
+	}
+
+	if err := checkRequiredFields(fieldConfigs, f); err != nil {
+		return nil, err
+	}
+
+	if o.softDeleteColumn != "" && !includeDeleted {
+		conditions = append(conditions, o.softDeleteColumn+" = "+c.bind(false))
+	}
+
+	if c.params == nil {
+		for _, condition := range o.conditions {
+			if len(condition.params) > 0 {
+				return nil, fmt.Errorf("condition %q: params are not supported with positional placeholders", condition.sql)
+			}
+			conditions = append(conditions, condition.sql)
+		}
+		return conditions, nil
+	}
+	return o.appendConditions(conditions, c.params)
+}
+
+// convertClause returns the condition of a clause.
+func (c *clickHouseConversion) convertClause(clause Clause, fieldConfigs map[string]FilterToClickHouseFieldConfig, o converterOptions) (string, error) {
+	name, fieldConfig, ok := lookupField(fieldConfigs, clause.Field)
+	if !ok {
+		if clause.Field == "1" && clause.Operator == "=" && len(clause.Values) == 1 && (clause.Values[0] == "1" || clause.Values[0] == "0") {
+			// Special case for boolean literals
+			value, _ := strconv.ParseInt(clause.Values[0], 10, 64)
+			return "1 = " + c.bind(value), nil
+		}
+		return "", newUnknownFieldError(fieldConfigs, clause.Field)
+	}
+
+	if clause.Operator == "~" {
+		return "", fmt.Errorf("field %s: fuzzy matching is not supported by ClickHouse", clause.Field)
+	}
+
+	columnName := fieldConfig.ColumnName
+	if columnName == "" {
+		columnName = name
+	}
+
+	if len(clause.Values) > 1 && !fieldConfig.AllowMultipleValues {
+		return "", fmt.Errorf("field %s: multiple values are not allowed", clause.Field)
+	}
+	values, err := fieldConfig.mapValues(clause.Values, o, fieldConfig.convertValue)
+	if err != nil {
+		return "", fmt.Errorf("field %s: %w", clause.Field, err)
+	}
+
+	if fieldConfig.Array {
+		return c.convertArrayClause(clause, columnName, fieldConfig, values)
+	}
+
+	var condition string
+	switch clause.Operator {
+	case "IN", "NOT IN":
+		if clause.Operator == "NOT IN" && !(fieldConfig.AllowNegation && fieldConfig.AllowMultipleValues) {
+			return "", fmt.Errorf("operator %s not supported for field: %s", clause.Operator, clause.Field)
+		}
+		placeholders := make([]string, len(values))
+		for i, v := range values {
+			if s, ok := v.(string); ok {
+				v = UnescapeValue(s)
+			}
+			placeholders[i] = fieldConfig.convertPlaceholder(c.bind(v))
+		}
+		condition = fmt.Sprintf("%s %s (%s)", columnName, clause.Operator, strings.Join(placeholders, ", "))
+	case "=", "!=":
+		value := values[0]
+		if s, ok := value.(string); ok {
+			text, needsPrefixMatch, needsSuffixMatch := SplitWildcards(s, fieldConfig.AllowPrefixMatch && clause.Operator == "=", fieldConfig.AllowSuffixMatch && clause.Operator == "=")
+			if needsPrefixMatch || needsSuffixMatch {
+				pattern := escapePrefixSuffixSpecialChars(text)
+				if needsPrefixMatch {
+					pattern += "%"
+				}
+				if needsSuffixMatch {
+					pattern = "%" + pattern
+				}
+				return columnName + " LIKE " + c.bind(pattern), nil
+			}
+			value = text
+		}
+		operator := "="
+		if clause.Operator == "!=" {
+			operator = "!="
+		}
+		condition = fmt.Sprintf("%s %s %s", columnName, operator, fieldConfig.convertPlaceholder(c.bind(value)))
+	case ">=", "<=", ">", "<":
+		if !fieldConfig.AllowRanges {
+			return "", fmt.Errorf("operator %s not supported for field: %s", clause.Operator, clause.Field)
+		}
+		switch fieldConfig.ColumnType {
+		case FilterToClickHouseFieldColumnTypeInt64, FilterToClickHouseFieldColumnTypeFloat64, FilterToClickHouseFieldColumnTypeDateTime64, FilterToClickHouseFieldColumnTypeDate:
+			condition = fmt.Sprintf("%s %s %s", columnName, clause.Operator, fieldConfig.convertPlaceholder(c.bind(values[0])))
+		default:
+			return "", fmt.Errorf("operator %s not supported for field type %s", clause.Operator, fieldConfig.ColumnType)
+		}
+	default:
+		return "", fmt.Errorf("operator %s not supported for field: %s", clause.Operator, clause.Field)
+	}
+	return condition, nil
+}
+
+// convertArrayClause returns the condition of a clause on an Array column.
+func (c *clickHouseConversion) convertArrayClause(clause Clause, columnName string, fieldConfig FilterToClickHouseFieldConfig, values []any) (string, error) {
+	placeholders := make([]string, len(values))
+	for i, v := range values {
+		if s, ok := v.(string); ok {
+			v = UnescapeValue(s)
+		}
+		placeholders[i] = fieldConfig.convertPlaceholder(c.bind(v))
+	}
+	switch clause.Operator {
+	case "=":
+		return fmt.Sprintf("has(%s, %s)", columnName, placeholders[0]), nil
+	case "!=":
+		return fmt.Sprintf("NOT has(%s, %s)", columnName, placeholders[0]), nil
+	case "IN":
+		return fmt.Sprintf("hasAny(%s, [%s])", columnName, strings.Join(placeholders, ", ")), nil
+	case "NOT IN":
+		if !(fieldConfig.AllowNegation && fieldConfig.AllowMultipleValues) {
+			return "", fmt.Errorf("operator %s not supported for field: %s", clause.Operator, clause.Field)
+		}
+		return fmt.Sprintf("NOT hasAny(%s, [%s])", columnName, strings.Join(placeholders, ", ")), nil
+	default:
+		return "", fmt.Errorf("operator %s not supported for field: %s", clause.Operator, clause.Field)
+	}
+}
+
+// convertPlaceholder wraps the placeholder of a value in the function converting it to the column type, if needed.
+func (f FilterToClickHouseFieldConfig) convertPlaceholder(placeholder string) string {
+	if f.MapValue != nil {
+		return placeholder
+	}
+	switch f.ColumnType {
+	case FilterToClickHouseFieldColumnTypeDateTime64:
+		return fmt.Sprintf("toDateTime64(%s, %d, 'UTC')", placeholder, f.precision())
+	case FilterToClickHouseFieldColumnTypeDate:
+		return fmt.Sprintf("toDate(%s)", placeholder)
+	default:
+		return placeholder
+	}
+}
+
+func (f FilterToClickHouseFieldConfig) precision() int {
+	if f.Precision == 0 {
+		return 3
+	}
+	return f.Precision
+}
+
+func (f FilterToClickHouseFieldConfig) convertValue(value string, o converterOptions) (any, error) {
+	switch f.ColumnType {
+	case FilterToClickHouseFieldColumnTypeInt64:
+		intVal, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Int64 value: %w", err)
+		}
+		return intVal, nil
+	case FilterToClickHouseFieldColumnTypeFloat64:
+		floatVal, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Float64 value: %w", err)
+		}
+		return floatVal, nil
+	case FilterToClickHouseFieldColumnTypeBool:
+		boolVal, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Bool value: %w", err)
+		}
+		return boolVal, nil
+	case FilterToClickHouseFieldColumnTypeDateTime64, FilterToClickHouseFieldColumnTypeDate:
+		t, ok := o.resolveRelativeTime(value)
+		if !ok {
+			var err error
+			t, err = o.parseTime(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s value: %w", f.ColumnType, err)
+			}
+		}
+		if f.ColumnType == FilterToClickHouseFieldColumnTypeDate {
+			return t.In(o.location).Format("2006-01-02"), nil
+		}
+		layout := "2006-01-02 15:04:05"
+		if precision := f.precision(); precision > 0 {
+			layout += "." + strings.Repeat("0", precision)
+		}
+		return t.UTC().Format(layout), nil
+	default:
+		return value, nil
+	}
+}
@@ -0,0 +1,192 @@
+package kqlfilter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilterToClickHouseSQL(t *testing.T) {
+	fieldConfigs := map[string]FilterToClickHouseFieldConfig{
+		"userId": {
+			ColumnName: "user_id",
+			ColumnType: FilterToClickHouseFieldColumnTypeInt64,
+		},
+		"email": {
+			AllowPrefixMatch: true,
+			AllowSuffixMatch: true,
+		},
+		"state": {
+			FieldOptions: FieldOptions{AllowMultipleValues: true, AllowNegation: true},
+		},
+		"tags": {
+			Array:        true,
+			FieldOptions: FieldOptions{AllowMultipleValues: true, AllowNegation: true},
+		},
+		"time": {
+			ColumnType:   FilterToClickHouseFieldColumnTypeDateTime64,
+			FieldOptions: FieldOptions{AllowRanges: true},
+		},
+		"precise": {
+			ColumnType:   FilterToClickHouseFieldColumnTypeDateTime64,
+			Precision:    6,
+			FieldOptions: FieldOptions{AllowRanges: true},
+		},
+		"day": {
+			ColumnType:   FilterToClickHouseFieldColumnTypeDate,
+			FieldOptions: FieldOptions{AllowRanges: true},
+		},
+		"score": {
+			ColumnType:   FilterToClickHouseFieldColumnTypeFloat64,
+			FieldOptions: FieldOptions{AllowRanges: true},
+		},
+		"active": {
+			ColumnType: FilterToClickHouseFieldColumnTypeBool,
+		},
+		"name": {
+			FieldOptions: FieldOptions{AllowRanges: true},
+		},
+	}
+
+	testCases := []struct {
+		name               string
+		input              string
+		expectedConditions []string
+		expectedArgs       []any
+		expectedError      string
+	}{
+		{
+			name:               "example",
+			input:              `userId:12345 email:john* tags:(a OR b) time>="2024-05-01T00:00:00Z"`,
+			expectedConditions: []string{"user_id = ?", "email LIKE ?", "hasAny(tags, [?, ?])", "time >= toDateTime64(?, 3, 'UTC')"},
+			expectedArgs:       []any{int64(12345), "john%", "a", "b", "2024-05-01 00:00:00.000"},
+		},
+		{
+			name:               "suffix match with special characters",
+			input:              `email:*_1%`,
+			expectedConditions: []string{"email LIKE ?"},
+			expectedArgs:       []any{`%\_1\%`},
+		},
+		{
+			name:               "negated values",
+			input:              `not state:(active or paused) not userId:1`,
+			expectedConditions: []string{"state NOT IN (?, ?)", "user_id != ?"},
+			expectedArgs:       []any{"active", "paused", int64(1)},
+		},
+		{
+			name:               "array contains",
+			input:              `tags:a not tags:(c or d)`,
+			expectedConditions: []string{"has(tags, ?)", "NOT hasAny(tags, [?, ?])"},
+			expectedArgs:       []any{"a", "c", "d"},
+		},
+		{
+			name:               "array does not contain",
+			input:              `not tags:b`,
+			expectedConditions: []string{"NOT has(tags, ?)"},
+			expectedArgs:       []any{"b"},
+		},
+		{
+			name:               "timestamps",
+			input:              `precise<"2024-05-01T12:00:00.123456+02:00" day>="2024-05-01T23:00:00Z"`,
+			expectedConditions: []string{"precise < toDateTime64(?, 6, 'UTC')", "day >= toDate(?)"},
+			expectedArgs:       []any{"2024-05-01 10:00:00.123456", "2024-05-01"},
+		},
+		{
+			name:               "numbers and booleans",
+			input:              `score>0.5 active:true`,
+			expectedConditions: []string{"score > ?", "active = ?"},
+			expectedArgs:       []any{0.5, true},
+		},
+		{
+			name:               "boolean literal",
+			input:              `1:0`,
+			expectedConditions: []string{"1 = ?"},
+			expectedArgs:       []any{int64(0)},
+		},
+		{
+			name:          "range on string column",
+			input:         `name>a`,
+			expectedError: "operator > not supported for field type String",
+		},
+		{
+			name:          "range on array column",
+			input:         `tags>a`,
+			expectedError: "operator > not supported for field: tags",
+		},
+		{
+			name:          "unknown field",
+			input:         `foo:bar`,
+			expectedError: "unknown field: foo",
+		},
+		{
+			name:          "invalid timestamp",
+			input:         `time>yesterdayish`,
+			expectedError: `field time: invalid DateTime64 value: parsing time "yesterdayish" as "2006-01-02T15:04:05.999999999Z07:00": cannot parse "yesterdayish" as "2006"`,
+		},
+		{
+			name:          "multiple values not allowed",
+			input:         `email:(a or b)`,
+			expectedError: "field email: multiple values are not allowed",
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			f, err := Parse(test.input)
+			require.NoError(t, err)
+			conditions, args, err := f.ToClickHouseSQL(fieldConfigs)
+			if test.expectedError != "" {
+				assert.EqualError(t, err, test.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedConditions, conditions)
+			assert.Equal(t, test.expectedArgs, args)
+		})
+	}
+}
+
+func TestFilterToClickHouseSQLNamed(t *testing.T) {
+	fieldConfigs := map[string]FilterToClickHouseFieldConfig{
+		"state": {
+			FieldOptions: FieldOptions{AllowMultipleValues: true},
+		},
+		"tags": {
+			Array: true,
+		},
+	}
+
+	f, err := Parse("state:(active or paused) tags:a")
+	require.NoError(t, err)
+	conditions, params, err := f.ToClickHouseSQLNamed(fieldConfigs, WithSoftDelete("deleted", false), WithCondition("owner = @owner", map[string]any{"owner": "jon"}))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"state IN (@KQL0, @KQL1)", "has(tags, @KQL2)", "deleted = @KQL3", "owner = @owner"}, conditions)
+	assert.Equal(t, map[string]any{"KQL0": "active", "KQL1": "paused", "KQL2": "a", "KQL3": false, "owner": "jon"}, params)
+
+	_, _, err = f.ToClickHouseSQL(fieldConfigs, WithCondition("owner = @owner", map[string]any{"owner": "jon"}))
+	assert.EqualError(t, err, `condition "owner = @owner": params are not supported with positional placeholders`)
+}
+
+func TestFilterToClickHouseSQLGroups(t *testing.T) {
+	f := Filter{
+		Groups: [][]Clause{
+			{{Field: "state", Operator: "=", Values: []string{"active"}}, {Field: "tags", Operator: "IN", Values: []string{"a", "b"}}},
+			{{Field: "state", Operator: "=", Values: []string{"frozen"}}},
+		},
+	}
+	fieldConfigs := map[string]FilterToClickHouseFieldConfig{
+		"state": {},
+		"tags":  {Array: true, FieldOptions: FieldOptions{AllowMultipleValues: true}},
+	}
+
+	conditions, args, err := f.ToClickHouseSQL(fieldConfigs)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"((state = ? AND hasAny(tags, [?, ?])) OR (state = ?))"}, conditions)
+	assert.Equal(t, []any{"active", "a", "b", "frozen"}, args)
+
+	conditions, params, err := f.ToClickHouseSQLNamed(fieldConfigs)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"((state = @KQL0 AND hasAny(tags, [@KQL1, @KQL2])) OR (state = @KQL3))"}, conditions)
+	assert.Equal(t, map[string]any{"KQL0": "active", "KQL1": "a", "KQL2": "b", "KQL3": "frozen"}, params)
+}
@@ -0,0 +1,409 @@
+package kqlfilter
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type FilterToDynamoDBAttributeType int
+
+const (
+	FilterToDynamoDBAttributeTypeUnspecified FilterToDynamoDBAttributeType = iota
+	FilterToDynamoDBAttributeTypeString
+	FilterToDynamoDBAttributeTypeNumber
+	FilterToDynamoDBAttributeTypeBoolean
+	// A timestamp stored as a string in UTC, formatted with time.RFC3339, which sorts chronologically.
+	FilterToDynamoDBAttributeTypeTimestamp
+	// A timestamp stored as a number of seconds since the Unix epoch, e.g. the TTL attribute of a table.
+	FilterToDynamoDBAttributeTypeUnixTime
+)
+
+func (t FilterToDynamoDBAttributeType) String() string {
+	switch t {
+	case FilterToDynamoDBAttributeTypeString:
+		return "S"
+	case FilterToDynamoDBAttributeTypeNumber:
+		return "N"
+	case FilterToDynamoDBAttributeTypeBoolean:
+		return "BOOL"
+	case FilterToDynamoDBAttributeTypeTimestamp:
+		return "TIMESTAMP"
+	case FilterToDynamoDBAttributeTypeUnixTime:
+		return "UNIXTIME"
+	default:
+		return "???"
+	}
+}
+
+// FilterToDynamoDBKeyType is the role of an attribute in the primary key of the table or index that is queried.
+type FilterToDynamoDBKeyType int
+
+const (
+	// An attribute that is not part of the key, which can only be used in the filter expression.
+	FilterToDynamoDBKeyTypeNone FilterToDynamoDBKeyType = iota
+	// The partition (hash) key.
+	FilterToDynamoDBKeyTypePartition
+	// The sort (range) key.
+	FilterToDynamoDBKeyTypeSort
+)
+
+type FilterToDynamoDBFieldConfig struct {
+	// Attribute name. Can be omitted if the attribute name is equal to the key in the fieldConfigs map.
+	AttributeName string
+	// Attribute type. Defaults to FilterToDynamoDBAttributeTypeString.
+	AttributeType FilterToDynamoDBAttributeType
+	// Role of the attribute in the key of the table or index. Defaults to FilterToDynamoDBKeyTypeNone.
+	Key FilterToDynamoDBKeyType
+	// Settings shared with the field configs of the other converters, e.g. AllowMultipleValues and MapValue.
+	FieldOptions
+	// Allow prefix matching when a wildcard (`*`) is present at the end of a string, which is emitted as begins_with.
+	// Only applicable for FilterToDynamoDBAttributeTypeString. Defaults to false.
+	AllowPrefixMatch bool
+}
+
+// DynamoDBExpression holds the expressions of a DynamoDB Query or Scan request. The attribute names and values are
+// referenced by placeholders in the expressions, so attribute names may be reserved words. Values are Go values, to
+// be converted with `attributevalue.MarshalMap` of the AWS SDK.
+type DynamoDBExpression struct {
+	// Key condition expression of a Query request, or empty if the filter does not select a partition, in which case
+	// the table must be scanned.
+	KeyConditionExpression string
+	// Filter expression of a Query or Scan request, or empty if there are no other conditions.
+	FilterExpression string
+	// Attribute names by their placeholder, e.g. `#KQL0`.
+	ExpressionAttributeNames map[string]string
+	// Attribute values by their placeholder, e.g. `:KQL0`.
+	ExpressionAttributeValues map[string]any
+}
+
+// ToDynamoDBExpression turns a Filter into the expressions of a DynamoDB Query or Scan request. It takes a map of
+// fields that are allowed to be queried via this filter, in which the key attributes of the table or index are marked
+// with Key:
+//
+//	expr, err := filter.ToDynamoDBExpression(fieldConfigs)
+//	values, err := attributevalue.MarshalMap(expr.ExpressionAttributeValues)
+//
+// Given the filter `tenant:abc created>="2024-01-01T00:00:00Z" state:(active OR frozen)`, with `tenant` as the
+// partition key and `created` as the sort key, the expressions are
+//
+//	KeyConditionExpression: "#KQL0 = :KQL0 AND #KQL2 >= :KQL3"
+//	FilterExpression:       "#KQL1 IN (:KQL1, :KQL2)"
+//
+// Placeholders are numbered in the order they are used, where conditions on the sort key are converted last.
+//
+// The filter selects a partition if it has a single `=` clause on the partition key. The clauses on the key attributes
+// are then put in the key condition expression, which allows a single condition on the sort key: `=`, a range
+// operator, a prefix match, or an inclusive lower and upper bound, which are combined into BETWEEN. Other clauses on
+// the sort key return an error. If the filter does not select a partition, all clauses are put in the filter
+// expression of a Scan request.
+//
+// Filter.Groups are put in the filter expression as a single condition, `((a AND b) OR (c))`. As the filter expression
+// of a Query request can't reference key attributes, groups with clauses on them return ErrGroupsNotSupported if the
+// filter selects a partition.
+//
+// TIMESTAMP and UNIXTIME fields accept RFC3339 values, values in the layouts set with WithTimeLayouts, as well as
+// relative time keywords such as `today` (see RelativeTimeToday), which are resolved using the clock and location set
+// with WithClock and WithDefaultLocation.
+//
+// Conditions added with WithCondition are appended to the filter expression as-is, and their params are added to the
+// attribute values with a `:` prefix, e.g. `@owner` becomes `:owner`.
+func (f Filter) ToDynamoDBExpression(fieldConfigs map[string]FilterToDynamoDBFieldConfig, options ...ConverterOption) (DynamoDBExpression, error) {
+	o := newConverterOptions(options)
+	d := &dynamoDBConversion{
+		names:  make(map[string]string),
+		values: make(map[string]any),
+	}
+
+	f, includeDeleted, err := o.extractIncludeDeleted(f)
+	if err != nil {
+		return DynamoDBExpression{}, err
+	}
+
+	// The filter selects a partition if it has a single equality clause on the partition key.
+	partitionClauses, selectsPartition := 0, false
+	for _, clause := range f.Clauses {
+		if _, fieldConfig, ok := lookupField(fieldConfigs, clause.Field); ok && fieldConfig.Key == FilterToDynamoDBKeyTypePartition {
+			partitionClauses++
+			selectsPartition = clause.Operator == "=" && len(clause.Values) == 1 && !hasTrailingWildcard(clause.Values[0])
+		}
+	}
+	selectsPartition = selectsPartition && partitionClauses == 1
+
+	var keyConditions, filterConditions []string
+	var sortKeyClauses []Clause
+	for _, clause := range f.Clauses {
+		name, fieldConfig, ok := lookupField(fieldConfigs, clause.Field)
+		if !ok {
+			if clause.Field == "1" && clause.Operator == "=" && len(clause.Values) == 1 && (clause.Values[0] == "1" || clause.Values[0] == "0") {
+				// Special case for boolean literals. DynamoDB expressions have no constants, and true is a no-op.
+				if clause.Values[0] == "0" {
+					return DynamoDBExpression{}, errors.New("boolean literal false is not supported by DynamoDB")
+				}
+				continue
+			}
+			return DynamoDBExpression{}, newUnknownFieldError(fieldConfigs, clause.Field)
+		}
+
+		if clause.Operator == "~" {
+			return DynamoDBExpression{}, fmt.Errorf("field %s: fuzzy matching is not supported by DynamoDB", clause.Field)
+		}
+
+		if len(clause.Values) > 1 && !fieldConfig.AllowMultipleValues {
+			return DynamoDBExpression{}, fmt.Errorf("field %s: multiple values are not allowed", clause.Field)
+		}
+
+		if selectsPartition && fieldConfig.Key == FilterToDynamoDBKeyTypeSort {
+			sortKeyClauses = append(sortKeyClauses, clause)
+			continue
+		}
+
+		condition, err := d.convertClause(clause, name, fieldConfig, o)
+		if err != nil {
+			return DynamoDBExpression{}, err
+		}
+		if selectsPartition && fieldConfig.Key == FilterToDynamoDBKeyTypePartition {
+			keyConditions = append(keyConditions, condition)
+		} else {
+			filterConditions = append(filterConditions, condition)
+		}
+	}
+
+	if len(sortKeyClauses) > 0 {
+		condition, err := d.convertSortKeyClauses(sortKeyClauses, fieldConfigs, o)
+		if err != nil {
+			return DynamoDBExpression{}, err
+		}
+		keyConditions = append(keyConditions, condition)
+	}
+
+	if len(f.Groups) > 0 {
+		condition, err := convertGroups(f.Groups, func(clause Clause) (string, error) {
+			return d.convertGroupClause(clause, fieldConfigs, selectsPartition, o)
+		})
+		if err != nil {
+			return DynamoDBExpression{}, err
+		}
+		filterConditions = append(filterConditions, condition)
+	}
+
+	if err := checkRequiredFields(fieldConfigs, f); err != nil {
+		return DynamoDBExpression{}, err
+	}
+
+	if o.softDeleteColumn != "" && !includeDeleted {
+		filterConditions = append(filterConditions, fmt.Sprintf("%s = %s", d.name(o.softDeleteColumn), d.value(false)))
+	}
+
+	for _, c := range o.conditions {
+		filterConditions = append(filterConditions, c.sql)
+		for name, value := range c.params {
+			d.values[":"+name] = value
+		}
+	}
+
+	expr := DynamoDBExpression{
+		KeyConditionExpression: strings.Join(keyConditions, " AND "),
+		FilterExpression:       strings.Join(filterConditions, " AND "),
+	}
+	if len(d.names) > 0 {
+		expr.ExpressionAttributeNames = d.names
+	}
+	if len(d.values) > 0 {
+		expr.ExpressionAttributeValues = d.values
+	}
+	return expr, nil
+}
+
+// dynamoDBConversion holds the attribute names and values of a filter while its clauses are converted.
+type dynamoDBConversion struct {
+	names      map[string]string
+	nameIndex  map[string]string
+	values     map[string]any
+	valueIndex int
+}
+
+// name returns the placeholder of an attribute name, reusing the placeholder of an attribute that was used before.
+func (d *dynamoDBConversion) name(attribute string) string {
+	if d.nameIndex == nil {
+		d.nameIndex = make(map[string]string)
+	}
+	if placeholder, ok := d.nameIndex[attribute]; ok {
+		return placeholder
+	}
+	placeholder := fmt.Sprintf("#%s%d", "KQL", len(d.nameIndex))
+	d.nameIndex[attribute] = placeholder
+	d.names[placeholder] = attribute
+	return placeholder
+}
+
+// value returns the placeholder of a new attribute value.
+func (d *dynamoDBConversion) value(v any) string {
+	placeholder := fmt.Sprintf(":%s%d", "KQL", d.valueIndex)
+	d.valueIndex++
+	d.values[placeholder] = v
+	return placeholder
+}
+
+// convertClause returns the condition of a clause, as used in a filter expression.
+func (d *dynamoDBConversion) convertClause(clause Clause, name string, fieldConfig FilterToDynamoDBFieldConfig, o converterOptions) (string, error) {
+	attributeName := fieldConfig.AttributeName
+	if attributeName == "" {
+		attributeName = name
+	}
+
+	values, err := fieldConfig.mapValues(clause.Values, o, fieldConfig.convertValue)
+	if err != nil {
+		return "", fmt.Errorf("field %s: %w", clause.Field, err)
+	}
+
+	switch clause.Operator {
+	case "IN", "NOT IN":
+		if clause.Operator == "NOT IN" && !(fieldConfig.AllowNegation && fieldConfig.AllowMultipleValues) {
+			return "", fmt.Errorf("operator %s not supported for field: %s", clause.Operator, clause.Field)
+		}
+		placeholders := make([]string, len(values))
+		for i, v := range values {
+			if s, ok := v.(string); ok {
+				v = UnescapeValue(s)
+			}
+			placeholders[i] = d.value(v)
+		}
+		condition := fmt.Sprintf("%s IN (%s)", d.name(attributeName), strings.Join(placeholders, ", "))
+		if clause.Operator == "NOT IN" {
+			condition = "NOT (" + condition + ")"
+		}
+		return condition, nil
+	case "=", "!=":
+		value := values[0]
+		if s, ok := value.(string); ok {
+			text, needsPrefixMatch, _ := SplitWildcards(s, fieldConfig.AllowPrefixMatch && clause.Operator == "=", false)
+			if needsPrefixMatch {
+				return fmt.Sprintf("begins_with(%s, %s)", d.name(attributeName), d.value(text)), nil
+			}
+			value = text
+		}
+		if clause.Operator == "=" {
+			return fmt.Sprintf("%s = %s", d.name(attributeName), d.value(value)), nil
+		}
+		return fmt.Sprintf("%s <> %s", d.name(attributeName), d.value(value)), nil
+	case ">=", "<=", ">", "<":
+		if !fieldConfig.AllowRanges {
+			return "", fmt.Errorf("operator %s not supported for field: %s", clause.Operator, clause.Field)
+		}
+		if fieldConfig.AttributeType == FilterToDynamoDBAttributeTypeBoolean {
+			return "", fmt.Errorf("operator %s not supported for field type %s", clause.Operator, fieldConfig.AttributeType)
+		}
+		value := values[0]
+		if s, ok := value.(string); ok {
+			value = UnescapeValue(s)
+		}
+		return fmt.Sprintf("%s %s %s", d.name(attributeName), clause.Operator, d.value(value)), nil
+	default:
+		return "", fmt.Errorf("operator %s not supported for field: %s", clause.Operator, clause.Field)
+	}
+}
+
+// convertGroupClause returns the condition of a clause of a group, as used in a filter expression. The filter
+// expression of a Query request can't reference the key attributes, so clauses on them are only supported if the
+// filter does not select a partition.
+func (d *dynamoDBConversion) convertGroupClause(clause Clause, fieldConfigs map[string]FilterToDynamoDBFieldConfig, selectsPartition bool, o converterOptions) (string, error) {
+	name, fieldConfig, ok := lookupField(fieldConfigs, clause.Field)
+	if !ok {
+		if clause.Field == "1" && clause.Operator == "=" && len(clause.Values) == 1 && (clause.Values[0] == "1" || clause.Values[0] == "0") {
+			// DynamoDB expressions have no constants.
+			return "", errors.New("boolean literals in clause groups are not supported by DynamoDB")
+		}
+		return "", newUnknownFieldError(fieldConfigs, clause.Field)
+	}
+
+	if selectsPartition && fieldConfig.Key != FilterToDynamoDBKeyTypeNone {
+		return "", fmt.Errorf("key attribute %s in a clause group of a Query: %w", clause.Field, ErrGroupsNotSupported)
+	}
+
+	if clause.Operator == "~" {
+		return "", fmt.Errorf("field %s: fuzzy matching is not supported by DynamoDB", clause.Field)
+	}
+
+	if len(clause.Values) > 1 && !fieldConfig.AllowMultipleValues {
+		return "", fmt.Errorf("field %s: multiple values are not allowed", clause.Field)
+	}
+
+	return d.convertClause(clause, name, fieldConfig, o)
+}
+
+// convertSortKeyClauses returns the key condition of the clauses on the sort key, which must be a single condition or
+// an inclusive lower and upper bound.
+func (d *dynamoDBConversion) convertSortKeyClauses(clauses []Clause, fieldConfigs map[string]FilterToDynamoDBFieldConfig, o converterOptions) (string, error) {
+	for _, clause := range clauses {
+		if clause.Operator == "!=" || clause.Operator == "IN" || clause.Operator == "NOT IN" {
+			return "", fmt.Errorf("operator %s not supported for sort key: %s", clause.Operator, clause.Field)
+		}
+	}
+	name, fieldConfig, _ := lookupField(fieldConfigs, clauses[0].Field)
+	if len(clauses) == 1 {
+		return d.convertClause(clauses[0], name, fieldConfig, o)
+	}
+
+	lower, upper := clauses[0], clauses[1]
+	if lower.Operator == "<=" {
+		lower, upper = upper, lower
+	}
+	if len(clauses) > 2 || lower.Operator != ">=" || upper.Operator != "<=" {
+		return "", fmt.Errorf("sort key %s: only a single condition or an inclusive lower and upper bound are supported", clauses[0].Field)
+	}
+	if !fieldConfig.AllowRanges {
+		return "", fmt.Errorf("operator %s not supported for field: %s", lower.Operator, lower.Field)
+	}
+	bounds, err := fieldConfig.mapValues([]string{lower.Values[0], upper.Values[0]}, o, fieldConfig.convertValue)
+	if err != nil {
+		return "", fmt.Errorf("field %s: %w", lower.Field, err)
+	}
+	for i, v := range bounds {
+		if s, ok := v.(string); ok {
+			bounds[i] = UnescapeValue(s)
+		}
+	}
+	attributeName := fieldConfig.AttributeName
+	if attributeName == "" {
+		attributeName = name
+	}
+	return fmt.Sprintf("%s BETWEEN %s AND %s", d.name(attributeName), d.value(bounds[0]), d.value(bounds[1])), nil
+}
+
+func (f FilterToDynamoDBFieldConfig) convertValue(value string, o converterOptions) (any, error) {
+	switch f.AttributeType {
+	case FilterToDynamoDBAttributeTypeNumber:
+		if intVal, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return intVal, nil
+		}
+		floatVal, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number value: %w", err)
+		}
+		return floatVal, nil
+	case FilterToDynamoDBAttributeTypeBoolean:
+		boolVal, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid boolean value: %w", err)
+		}
+		return boolVal, nil
+	case FilterToDynamoDBAttributeTypeTimestamp, FilterToDynamoDBAttributeTypeUnixTime:
+		t, ok := o.resolveRelativeTime(value)
+		if !ok {
+			var err error
+			t, err = o.parseTime(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid timestamp value: %w", err)
+			}
+		}
+		if f.AttributeType == FilterToDynamoDBAttributeTypeUnixTime {
+			return t.Unix(), nil
+		}
+		return t.UTC().Format(time.RFC3339), nil
+	default:
+		return value, nil
+	}
+}
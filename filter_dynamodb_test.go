@@ -0,0 +1,224 @@
+package kqlfilter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilterToDynamoDBExpression(t *testing.T) {
+	fieldConfigs := map[string]FilterToDynamoDBFieldConfig{
+		"tenant": {
+			Key: FilterToDynamoDBKeyTypePartition,
+		},
+		"created": {
+			AttributeType: FilterToDynamoDBAttributeTypeTimestamp,
+			Key:           FilterToDynamoDBKeyTypeSort,
+			FieldOptions:  FieldOptions{AllowRanges: true},
+		},
+		"sk": {
+			AttributeName:    "SK",
+			Key:              FilterToDynamoDBKeyTypeSort,
+			AllowPrefixMatch: true,
+			FieldOptions:     FieldOptions{AllowRanges: true},
+		},
+		"state": {
+			FieldOptions: FieldOptions{AllowMultipleValues: true, AllowNegation: true},
+		},
+		"name": {
+			AttributeName:    "Name",
+			AllowPrefixMatch: true,
+		},
+		"age": {
+			AttributeType: FilterToDynamoDBAttributeTypeNumber,
+			FieldOptions:  FieldOptions{AllowRanges: true, Aliases: []string{"years"}},
+		},
+		"active": {
+			AttributeType: FilterToDynamoDBAttributeTypeBoolean,
+		},
+		"expires": {
+			AttributeType: FilterToDynamoDBAttributeTypeUnixTime,
+			FieldOptions:  FieldOptions{AllowRanges: true},
+		},
+	}
+
+	testCases := []struct {
+		name          string
+		input         string
+		expected      DynamoDBExpression
+		expectedError string
+	}{
+		{
+			name:  "query with sort key range",
+			input: `tenant:abc created>="2024-01-01T00:00:00Z" state:(active OR frozen)`,
+			expected: DynamoDBExpression{
+				KeyConditionExpression:   "#KQL0 = :KQL0 AND #KQL2 >= :KQL3",
+				FilterExpression:         "#KQL1 IN (:KQL1, :KQL2)",
+				ExpressionAttributeNames: map[string]string{"#KQL0": "tenant", "#KQL1": "state", "#KQL2": "created"},
+				ExpressionAttributeValues: map[string]any{
+					":KQL0": "abc",
+					":KQL1": "active",
+					":KQL2": "frozen",
+					":KQL3": "2024-01-01T00:00:00Z",
+				},
+			},
+		},
+		{
+			name:  "query with sort key bounds",
+			input: `tenant:abc created<="2024-02-01T00:00:00Z" created>="2024-01-01T00:00:00Z"`,
+			expected: DynamoDBExpression{
+				KeyConditionExpression:   "#KQL0 = :KQL0 AND #KQL1 BETWEEN :KQL1 AND :KQL2",
+				ExpressionAttributeNames: map[string]string{"#KQL0": "tenant", "#KQL1": "created"},
+				ExpressionAttributeValues: map[string]any{
+					":KQL0": "abc",
+					":KQL1": "2024-01-01T00:00:00Z",
+					":KQL2": "2024-02-01T00:00:00Z",
+				},
+			},
+		},
+		{
+			name:  "query with sort key prefix",
+			input: `tenant:abc sk:ORDER#*`,
+			expected: DynamoDBExpression{
+				KeyConditionExpression:    "#KQL0 = :KQL0 AND begins_with(#KQL1, :KQL1)",
+				ExpressionAttributeNames:  map[string]string{"#KQL0": "tenant", "#KQL1": "SK"},
+				ExpressionAttributeValues: map[string]any{":KQL0": "abc", ":KQL1": "ORDER#"},
+			},
+		},
+		{
+			name:  "scan",
+			input: `created>="2024-01-01T00:00:00Z" name:jo* years>=18 active:true not state:(deleted or banned)`,
+			expected: DynamoDBExpression{
+				FilterExpression:         "#KQL0 >= :KQL0 AND begins_with(#KQL1, :KQL1) AND #KQL2 >= :KQL2 AND #KQL3 = :KQL3 AND NOT (#KQL4 IN (:KQL4, :KQL5))",
+				ExpressionAttributeNames: map[string]string{"#KQL0": "created", "#KQL1": "Name", "#KQL2": "age", "#KQL3": "active", "#KQL4": "state"},
+				ExpressionAttributeValues: map[string]any{
+					":KQL0": "2024-01-01T00:00:00Z",
+					":KQL1": "jo",
+					":KQL2": int64(18),
+					":KQL3": true,
+					":KQL4": "deleted",
+					":KQL5": "banned",
+				},
+			},
+		},
+		{
+			name:  "unix time",
+			input: `expires<"2024-01-01T00:00:00Z"`,
+			expected: DynamoDBExpression{
+				FilterExpression:          "#KQL0 < :KQL0",
+				ExpressionAttributeNames:  map[string]string{"#KQL0": "expires"},
+				ExpressionAttributeValues: map[string]any{":KQL0": time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).Unix()},
+			},
+		},
+		{
+			name:  "repeated attribute",
+			input: `age>=18 age<65`,
+			expected: DynamoDBExpression{
+				FilterExpression:          "#KQL0 >= :KQL0 AND #KQL0 < :KQL1",
+				ExpressionAttributeNames:  map[string]string{"#KQL0": "age"},
+				ExpressionAttributeValues: map[string]any{":KQL0": int64(18), ":KQL1": int64(65)},
+			},
+		},
+		{
+			name:          "sort key with two exclusive bounds",
+			input:         `tenant:abc created>"2024-01-01T00:00:00Z" created<"2024-02-01T00:00:00Z"`,
+			expectedError: "sort key created: only a single condition or an inclusive lower and upper bound are supported",
+		},
+		{
+			name:          "negated sort key",
+			input:         `tenant:abc not sk:ORDER#1`,
+			expectedError: "operator != not supported for sort key: sk",
+		},
+		{
+			name:          "unknown field",
+			input:         `foo:bar`,
+			expectedError: "unknown field: foo",
+		},
+		{
+			name:          "multiple values not allowed",
+			input:         `name:(a or b)`,
+			expectedError: "field name: multiple values are not allowed",
+		},
+		{
+			name:          "invalid number",
+			input:         `age:abc`,
+			expectedError: `field age: invalid number value: strconv.ParseFloat: parsing "abc": invalid syntax`,
+		},
+		{
+			name:          "range not allowed",
+			input:         `name>a`,
+			expectedError: "operator > not supported for field: name",
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			f, err := Parse(test.input)
+			require.NoError(t, err)
+			expr, err := f.ToDynamoDBExpression(fieldConfigs)
+			if test.expectedError != "" {
+				assert.EqualError(t, err, test.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, expr)
+		})
+	}
+}
+
+func TestFilterToDynamoDBExpressionOptions(t *testing.T) {
+	fieldConfigs := map[string]FilterToDynamoDBFieldConfig{
+		"state": {},
+	}
+
+	f, err := Parse("state:active")
+	require.NoError(t, err)
+	expr, err := f.ToDynamoDBExpression(fieldConfigs, WithSoftDelete("deleted", false), WithCondition("owner = :owner", map[string]any{"owner": "jon"}))
+	require.NoError(t, err)
+	assert.Equal(t, DynamoDBExpression{
+		FilterExpression:          "#KQL0 = :KQL0 AND #KQL1 = :KQL1 AND owner = :owner",
+		ExpressionAttributeNames:  map[string]string{"#KQL0": "state", "#KQL1": "deleted"},
+		ExpressionAttributeValues: map[string]any{":KQL0": "active", ":KQL1": false, ":owner": "jon"},
+	}, expr)
+}
+
+func TestFilterToDynamoDBExpressionGroups(t *testing.T) {
+	fieldConfigs := map[string]FilterToDynamoDBFieldConfig{
+		"tenant": {Key: FilterToDynamoDBKeyTypePartition},
+		"sk":     {Key: FilterToDynamoDBKeyTypeSort},
+		"state":  {},
+		"age":    {AttributeType: FilterToDynamoDBAttributeTypeNumber, FieldOptions: FieldOptions{AllowRanges: true}},
+	}
+	f := Filter{
+		Clauses: []Clause{{Field: "tenant", Operator: "=", Values: []string{"abc"}}},
+		Groups: [][]Clause{
+			{{Field: "state", Operator: "=", Values: []string{"active"}}, {Field: "age", Operator: ">=", Values: []string{"18"}}},
+			{{Field: "state", Operator: "=", Values: []string{"frozen"}}},
+		},
+	}
+
+	expr, err := f.ToDynamoDBExpression(fieldConfigs)
+	require.NoError(t, err)
+	assert.Equal(t, DynamoDBExpression{
+		KeyConditionExpression:    "#KQL0 = :KQL0",
+		FilterExpression:          "((#KQL1 = :KQL1 AND #KQL2 >= :KQL2) OR (#KQL1 = :KQL3))",
+		ExpressionAttributeNames:  map[string]string{"#KQL0": "tenant", "#KQL1": "state", "#KQL2": "age"},
+		ExpressionAttributeValues: map[string]any{":KQL0": "abc", ":KQL1": "active", ":KQL2": int64(18), ":KQL3": "frozen"},
+	}, expr)
+
+	// The filter expression of a Query can't reference key attributes, unlike that of a Scan.
+	f.Groups[1][0] = Clause{Field: "sk", Operator: "=", Values: []string{"x"}}
+	_, err = f.ToDynamoDBExpression(fieldConfigs)
+	assert.ErrorIs(t, err, ErrGroupsNotSupported)
+
+	f.Clauses = nil
+	expr, err = f.ToDynamoDBExpression(fieldConfigs)
+	require.NoError(t, err)
+	assert.Empty(t, expr.KeyConditionExpression)
+	assert.Equal(t, "((#KQL0 = :KQL0 AND #KQL1 >= :KQL1) OR (#KQL2 = :KQL2))", expr.FilterExpression)
+
+	_, err = Filter{Groups: [][]Clause{{{Field: "1", Operator: "=", Values: []string{"1"}}}}}.ToDynamoDBExpression(fieldConfigs)
+	assert.EqualError(t, err, "boolean literals in clause groups are not supported by DynamoDB")
+}
@@ -0,0 +1,297 @@
+package kqlfilter
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+type FilterToFirestoreFieldType int
+
+const (
+	FilterToFirestoreFieldTypeUnspecified FilterToFirestoreFieldType = iota
+	FilterToFirestoreFieldTypeString
+	FilterToFirestoreFieldTypeInt64
+	FilterToFirestoreFieldTypeFloat64
+	FilterToFirestoreFieldTypeBool
+	FilterToFirestoreFieldTypeTimestamp
+)
+
+func (t FilterToFirestoreFieldType) String() string {
+	switch t {
+	case FilterToFirestoreFieldTypeString:
+		return "string"
+	case FilterToFirestoreFieldTypeInt64:
+		return "integer"
+	case FilterToFirestoreFieldTypeFloat64:
+		return "double"
+	case FilterToFirestoreFieldTypeBool:
+		return "boolean"
+	case FilterToFirestoreFieldTypeTimestamp:
+		return "timestamp"
+	default:
+		return "???"
+	}
+}
+
+// Limits of the values of a single Firestore filter.
+const (
+	firestoreMaxInValues    = 30
+	firestoreMaxNotInValues = 10
+)
+
+// ErrNotSupportedByFirestore is returned by Filter.ToFirestoreFilters when a filter uses a construct that can't be
+// expressed in a Firestore query, e.g. a disjunction across fields or a second negated clause.
+var ErrNotSupportedByFirestore = errors.New("not supported by Firestore")
+
+type FilterToFirestoreFieldConfig struct {
+	// Field path in the document, e.g. `address.city`. Can be omitted if the path is equal to the key in the
+	// fieldConfigs map.
+	Path string
+	// Field type. Defaults to FilterToFirestoreFieldTypeString.
+	FieldType FilterToFirestoreFieldType
+	// Settings shared with the field configs of the other converters, e.g. AllowMultipleValues and MapValue.
+	FieldOptions
+	// Allow prefix matching when a wildcard (`*`) is present at the end of a string, which is emitted as a range from
+	// the prefix up to the prefix followed by U+F8FF. Only applicable for FilterToFirestoreFieldTypeString.
+	// Defaults to false.
+	AllowPrefixMatch bool
+	// The field is an array, which is matched if it contains the value, emitted as `array-contains`, or any of the
+	// values, emitted as `array-contains-any`. Firestore allows a single such filter per query. Defaults to false.
+	ArrayContains bool
+}
+
+// FirestorePropertyFilter is a filter on a single field of a Firestore query. It has the same fields as
+// `firestore.PropertyFilter` of the Firestore client, so it can be converted to one, or applied with `Query.Where`.
+type FirestorePropertyFilter struct {
+	// Field path, e.g. `address.city`.
+	Path string
+	// One of `==`, `!=`, `<`, `<=`, `>`, `>=`, `in`, `not-in`, `array-contains` and `array-contains-any`.
+	Operator string
+	// Value of the filter, a slice for `in`, `not-in` and `array-contains-any`.
+	Value any
+}
+
+// ToFirestoreFilters turns a Filter into the filters of a Firestore query, which are all AND'ed. It takes a map of
+// fields that are allowed to be queried via this filter:
+//
+//	filters, err := filter.ToFirestoreFilters(fieldConfigs)
+//	for _, pf := range filters {
+//		q = q.Where(pf.Path, pf.Operator, pf.Value)
+//	}
+//
+// Given the filter `state:(active OR frozen) name:jo* age>=18`, the filters are
+//
+//	[{state in [active frozen]} {name >= jo} {name < jo\uf8ff} {age >= 18}]
+//
+// Firestore queries can't express every filter. Filters that can't be converted return an error matching
+// ErrNotSupportedByFirestore, naming the construct: disjunctions across fields (see ConvertToFilter), fuzzy matches,
+// more than one negated clause, negation combined with `in` or `array-contains-any`, more than one array filter, and
+// lists of values longer than Firestore allows.
+//
+// Timestamp fields accept RFC3339 values, values in the layouts set with WithTimeLayouts, as well as relative time
+// keywords such as `today` (see RelativeTimeToday). Conditions added with WithCondition are SQL, and are not
+// supported.
+func (f Filter) ToFirestoreFilters(fieldConfigs map[string]FilterToFirestoreFieldConfig, options ...ConverterOption) ([]FirestorePropertyFilter, error) {
+	o := newConverterOptions(options)
+
+	if len(f.Groups) > 0 {
+		return nil, fmt.Errorf("disjunctions across fields: %w", ErrNotSupportedByFirestore)
+	}
+	if len(o.conditions) > 0 {
+		return nil, fmt.Errorf("conditions added with WithCondition: %w", ErrNotSupportedByFirestore)
+	}
+	f, includeDeleted, err := o.extractIncludeDeleted(f)
+	if err != nil {
+		return nil, err
+	}
+
+	var filters []FirestorePropertyFilter
+	for _, clause := range f.Clauses {
+		name, fieldConfig, ok := lookupField(fieldConfigs, clause.Field)
+		if !ok {
+			if clause.Field == "1" && clause.Operator == "=" && len(clause.Values) == 1 && (clause.Values[0] == "1" || clause.Values[0] == "0") {
+				// Special case for boolean literals. Firestore queries have no constants, and true is a no-op.
+				if clause.Values[0] == "0" {
+					return nil, fmt.Errorf("boolean literal false: %w", ErrNotSupportedByFirestore)
+				}
+				continue
+			}
+			return nil, newUnknownFieldError(fieldConfigs, clause.Field)
+		}
+
+		if clause.Operator == "~" {
+			return nil, fmt.Errorf("field %s: fuzzy matching: %w", clause.Field, ErrNotSupportedByFirestore)
+		}
+
+		if len(clause.Values) > 1 && !fieldConfig.AllowMultipleValues {
+			return nil, fmt.Errorf("field %s: multiple values are not allowed", clause.Field)
+		}
+
+		clauseFilters, err := convertFirestoreClause(clause, name, fieldConfig, o)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, clauseFilters...)
+	}
+
+	if err := checkRequiredFields(fieldConfigs, f); err != nil {
+		return nil, err
+	}
+
+	if o.softDeleteColumn != "" && !includeDeleted {
+		filters = append(filters, FirestorePropertyFilter{Path: o.softDeleteColumn, Operator: "==", Value: false})
+	}
+
+	if err := checkFirestoreFilters(filters); err != nil {
+		return nil, err
+	}
+	return filters, nil
+}
+
+// convertFirestoreClause returns the filters of a clause, which are two for a prefix match.
+func convertFirestoreClause(clause Clause, name string, fieldConfig FilterToFirestoreFieldConfig, o converterOptions) ([]FirestorePropertyFilter, error) {
+	path := fieldConfig.Path
+	if path == "" {
+		path = name
+	}
+
+	values, err := fieldConfig.mapValues(clause.Values, o, fieldConfig.convertValue)
+	if err != nil {
+		return nil, fmt.Errorf("field %s: %w", clause.Field, err)
+	}
+
+	switch clause.Operator {
+	case "IN", "NOT IN":
+		operator := "in"
+		if fieldConfig.ArrayContains {
+			operator = "array-contains-any"
+		}
+		if clause.Operator == "NOT IN" {
+			if !(fieldConfig.AllowNegation && fieldConfig.AllowMultipleValues) {
+				return nil, fmt.Errorf("operator %s not supported for field: %s", clause.Operator, clause.Field)
+			}
+			if fieldConfig.ArrayContains {
+				return nil, fmt.Errorf("field %s: negated array match: %w", clause.Field, ErrNotSupportedByFirestore)
+			}
+			operator = "not-in"
+		}
+		for i, v := range values {
+			if s, ok := v.(string); ok {
+				values[i] = UnescapeValue(s)
+			}
+		}
+		return []FirestorePropertyFilter{{Path: path, Operator: operator, Value: values}}, nil
+	case "=", "!=":
+		if clause.Operator == "!=" && !fieldConfig.AllowNegation {
+			return nil, fmt.Errorf("operator %s not supported for field: %s", clause.Operator, clause.Field)
+		}
+		value := values[0]
+		if s, ok := value.(string); ok {
+			text, needsPrefixMatch, _ := SplitWildcards(s, fieldConfig.AllowPrefixMatch, false)
+			if needsPrefixMatch {
+				if clause.Operator == "!=" || fieldConfig.ArrayContains {
+					return nil, fmt.Errorf("field %s: prefix match in a negated or array filter: %w", clause.Field, ErrNotSupportedByFirestore)
+				}
+				return []FirestorePropertyFilter{
+					{Path: path, Operator: ">=", Value: text},
+					{Path: path, Operator: "<", Value: text + "\uf8ff"},
+				}, nil
+			}
+			value = text
+		}
+		operator := "=="
+		if clause.Operator == "!=" {
+			if fieldConfig.ArrayContains {
+				return nil, fmt.Errorf("field %s: negated array match: %w", clause.Field, ErrNotSupportedByFirestore)
+			}
+			operator = "!="
+		} else if fieldConfig.ArrayContains {
+			operator = "array-contains"
+		}
+		return []FirestorePropertyFilter{{Path: path, Operator: operator, Value: value}}, nil
+	case ">=", "<=", ">", "<":
+		if !fieldConfig.AllowRanges {
+			return nil, fmt.Errorf("operator %s not supported for field: %s", clause.Operator, clause.Field)
+		}
+		if fieldConfig.FieldType == FilterToFirestoreFieldTypeBool || fieldConfig.ArrayContains {
+			return nil, fmt.Errorf("operator %s not supported for field: %s", clause.Operator, clause.Field)
+		}
+		value := values[0]
+		if s, ok := value.(string); ok {
+			value = UnescapeValue(s)
+		}
+		return []FirestorePropertyFilter{{Path: path, Operator: clause.Operator, Value: value}}, nil
+	default:
+		return nil, fmt.Errorf("operator %s not supported for field: %s", clause.Operator, clause.Field)
+	}
+}
+
+// checkFirestoreFilters returns an error if the filters can't be combined in a single Firestore query.
+func checkFirestoreFilters(filters []FirestorePropertyFilter) error {
+	var negated, arrays, in int
+	for _, pf := range filters {
+		switch pf.Operator {
+		case "!=", "not-in":
+			negated++
+		case "array-contains", "array-contains-any":
+			arrays++
+		}
+		switch pf.Operator {
+		case "in", "array-contains-any":
+			in++
+			if n := len(pf.Value.([]any)); n > firestoreMaxInValues {
+				return fmt.Errorf("field %s: %d values, more than %d: %w", pf.Path, n, firestoreMaxInValues, ErrNotSupportedByFirestore)
+			}
+		case "not-in":
+			if n := len(pf.Value.([]any)); n > firestoreMaxNotInValues {
+				return fmt.Errorf("field %s: %d negated values, more than %d: %w", pf.Path, n, firestoreMaxNotInValues, ErrNotSupportedByFirestore)
+			}
+		}
+	}
+	if negated > 1 {
+		return fmt.Errorf("more than one negated clause: %w", ErrNotSupportedByFirestore)
+	}
+	if arrays > 1 {
+		return fmt.Errorf("more than one array match: %w", ErrNotSupportedByFirestore)
+	}
+	if negated > 0 && in > 0 {
+		return fmt.Errorf("negation combined with multiple values of another field: %w", ErrNotSupportedByFirestore)
+	}
+	return nil
+}
+
+func (f FilterToFirestoreFieldConfig) convertValue(value string, o converterOptions) (any, error) {
+	switch f.FieldType {
+	case FilterToFirestoreFieldTypeInt64:
+		intVal, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid integer value: %w", err)
+		}
+		return intVal, nil
+	case FilterToFirestoreFieldTypeFloat64:
+		floatVal, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid double value: %w", err)
+		}
+		return floatVal, nil
+	case FilterToFirestoreFieldTypeBool:
+		boolVal, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid boolean value: %w", err)
+		}
+		return boolVal, nil
+	case FilterToFirestoreFieldTypeTimestamp:
+		t, ok := o.resolveRelativeTime(value)
+		if !ok {
+			var err error
+			t, err = o.parseTime(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid timestamp value: %w", err)
+			}
+		}
+		return t, nil
+	default:
+		return value, nil
+	}
+}
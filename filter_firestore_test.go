@@ -0,0 +1,165 @@
+package kqlfilter
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilterToFirestoreFilters(t *testing.T) {
+	fieldConfigs := map[string]FilterToFirestoreFieldConfig{
+		"state": {
+			FieldOptions: FieldOptions{AllowMultipleValues: true, AllowNegation: true},
+		},
+		"name": {
+			AllowPrefixMatch: true,
+		},
+		"city": {
+			Path: "address.city",
+		},
+		"age": {
+			FieldType:    FilterToFirestoreFieldTypeInt64,
+			FieldOptions: FieldOptions{AllowRanges: true, Aliases: []string{"years"}},
+		},
+		"active": {
+			FieldType: FilterToFirestoreFieldTypeBool,
+		},
+		"created": {
+			FieldType:    FilterToFirestoreFieldTypeTimestamp,
+			FieldOptions: FieldOptions{AllowRanges: true},
+		},
+		"tags": {
+			ArrayContains: true,
+			FieldOptions:  FieldOptions{AllowMultipleValues: true},
+		},
+		"owner": {
+			FieldOptions: FieldOptions{AllowNegation: true},
+		},
+	}
+
+	testCases := []struct {
+		name          string
+		input         string
+		expected      []FirestorePropertyFilter
+		expectedError string
+	}{
+		{
+			name:  "equality and multiple values",
+			input: `state:(active OR frozen) city:Amsterdam active:true`,
+			expected: []FirestorePropertyFilter{
+				{Path: "state", Operator: "in", Value: []any{"active", "frozen"}},
+				{Path: "address.city", Operator: "==", Value: "Amsterdam"},
+				{Path: "active", Operator: "==", Value: true},
+			},
+		},
+		{
+			name:  "prefix match and ranges",
+			input: `name:jo* years>=18 created<"2024-01-01T00:00:00Z"`,
+			expected: []FirestorePropertyFilter{
+				{Path: "name", Operator: ">=", Value: "jo"},
+				{Path: "name", Operator: "<", Value: "jo\uf8ff"},
+				{Path: "age", Operator: ">=", Value: int64(18)},
+				{Path: "created", Operator: "<", Value: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+			},
+		},
+		{
+			name:  "negation",
+			input: `not state:(deleted OR banned) name:jon`,
+			expected: []FirestorePropertyFilter{
+				{Path: "state", Operator: "not-in", Value: []any{"deleted", "banned"}},
+				{Path: "name", Operator: "==", Value: "jon"},
+			},
+		},
+		{
+			name:  "array",
+			input: `tags:(red OR blue)`,
+			expected: []FirestorePropertyFilter{
+				{Path: "tags", Operator: "array-contains-any", Value: []any{"red", "blue"}},
+			},
+		},
+		{
+			name:          "two negations",
+			input:         `not state:deleted not owner:jon`,
+			expectedError: "more than one negated clause: not supported by Firestore",
+		},
+		{
+			name:          "negation and multiple values",
+			input:         `not owner:jon tags:(red OR blue)`,
+			expectedError: "negation combined with multiple values of another field: not supported by Firestore",
+		},
+		{
+			name:          "too many negated values",
+			input:         `not state:(a OR b OR c OR d OR e OR f OR g OR h OR i OR j OR k)`,
+			expectedError: "field state: 11 negated values, more than 10: not supported by Firestore",
+		},
+		{
+			name:          "negation not allowed",
+			input:         `not name:jon`,
+			expectedError: "operator != not supported for field: name",
+		},
+		{
+			name:          "unknown field",
+			input:         `foo:bar`,
+			expectedError: "unknown field: foo",
+		},
+		{
+			name:          "invalid integer",
+			input:         `age:abc`,
+			expectedError: `field age: invalid integer value: strconv.ParseInt: parsing "abc": invalid syntax`,
+		},
+		{
+			name:          "range not allowed",
+			input:         `name>a`,
+			expectedError: "operator > not supported for field: name",
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			f, err := Parse(test.input)
+			require.NoError(t, err)
+			filters, err := f.ToFirestoreFilters(fieldConfigs)
+			if test.expectedError != "" {
+				assert.EqualError(t, err, test.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, filters)
+		})
+	}
+}
+
+func TestFilterToFirestoreFiltersUnsupported(t *testing.T) {
+	fieldConfigs := map[string]FilterToFirestoreFieldConfig{
+		"state": {FieldOptions: FieldOptions{AllowMultipleValues: true}},
+		"owner": {},
+	}
+
+	ast, err := ParseAST("state:active or owner:jon")
+	require.NoError(t, err)
+	f, err := ConvertToFilter(ast)
+	require.NoError(t, err)
+	_, err = f.ToFirestoreFilters(fieldConfigs)
+	assert.True(t, errors.Is(err, ErrNotSupportedByFirestore))
+	assert.EqualError(t, err, "disjunctions across fields: not supported by Firestore")
+
+	f = Filter{Clauses: []Clause{{Field: "state", Operator: "IN", Values: strings.Split(strings.Repeat("a,", 30)+"a", ",")}}}
+	_, err = f.ToFirestoreFilters(fieldConfigs)
+	assert.True(t, errors.Is(err, ErrNotSupportedByFirestore))
+
+	f, err = Parse("owner:jon")
+	require.NoError(t, err)
+	_, err = f.ToFirestoreFilters(fieldConfigs, WithCondition("tenant = @tenant", map[string]any{"tenant": "a"}))
+	assert.True(t, errors.Is(err, ErrNotSupportedByFirestore))
+
+	filters, err := f.ToFirestoreFilters(fieldConfigs, WithSoftDelete("deleted", false))
+	require.NoError(t, err)
+	assert.Equal(t, []FirestorePropertyFilter{
+		{Path: "owner", Operator: "==", Value: "jon"},
+		{Path: "deleted", Operator: "==", Value: false},
+	}, filters)
+}
@@ -0,0 +1,274 @@
+package kqlfilter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type FilterToMySQLFieldColumnType int
+
+const (
+	FilterToMySQLFieldColumnTypeUnspecified FilterToMySQLFieldColumnType = iota
+	FilterToMySQLFieldColumnTypeVarchar
+	FilterToMySQLFieldColumnTypeBigint
+	FilterToMySQLFieldColumnTypeDouble
+	// A BOOLEAN, i.e. TINYINT(1), column.
+	FilterToMySQLFieldColumnTypeBoolean
+	// A DATETIME column storing timestamps in UTC. Values are passed in the `2006-01-02 15:04:05.999999` format.
+	FilterToMySQLFieldColumnTypeDatetime
+	// A DATE column. Values are passed in the `2006-01-02` format, as the date in the location set with
+	// WithDefaultLocation.
+	FilterToMySQLFieldColumnTypeDate
+)
+
+func (c FilterToMySQLFieldColumnType) String() string {
+	switch c {
+	case FilterToMySQLFieldColumnTypeUnspecified, FilterToMySQLFieldColumnTypeVarchar:
+		return "VARCHAR"
+	case FilterToMySQLFieldColumnTypeBigint:
+		return "BIGINT"
+	case FilterToMySQLFieldColumnTypeDouble:
+		return "DOUBLE"
+	case FilterToMySQLFieldColumnTypeBoolean:
+		return "BOOLEAN"
+	case FilterToMySQLFieldColumnTypeDatetime:
+		return "DATETIME"
+	case FilterToMySQLFieldColumnTypeDate:
+		return "DATE"
+	default:
+		return "???"
+	}
+}
+
+// mysqlDatetimeLayout is the layout of FilterToMySQLFieldColumnTypeDatetime values.
+const mysqlDatetimeLayout = "2006-01-02 15:04:05.999999"
+
+type FilterToMySQLFieldConfig struct {
+	// SQL column name, optionally qualified with the table name, e.g. `u.user_id`. It is quoted with backticks, so
+	// reserved words such as `key` or `order` can be used. Can be omitted if the column name is equal to the key in the
+	// fieldConfigs map.
+	ColumnName string
+	// SQL column type. Defaults to FilterToMySQLFieldColumnTypeVarchar.
+	ColumnType FilterToMySQLFieldColumnType
+	// Settings shared with the field configs of the other converters, e.g. AllowMultipleValues and MapValue.
+	FieldOptions
+	// Allow prefix matching when a wildcard (`*`) is present at the end of a string.
+	// Only applicable for FilterToMySQLFieldColumnTypeVarchar. Defaults to false.
+	AllowPrefixMatch bool
+	// Allow suffix matching when a wildcard (`*`) is present at the beginning of a string.
+	// Only applicable for FilterToMySQLFieldColumnTypeVarchar. Defaults to false.
+	AllowSuffixMatch bool
+	// Compare string values case-sensitively, with `= BINARY ?` and `LIKE BINARY ?`. The default collations of MySQL
+	// are case-insensitive, so by default `name:jon` also matches `Jon`. Defaults to false.
+	CaseSensitive bool
+}
+
+// ToMySQLSQL turns a Filter into conditions for a MySQL WHERE clause, using `?` placeholders as supported by
+// go-sql-driver/mysql. It takes a map of fields that are allowed to be queried via this filter, and returns the
+// conditions, which must be joined by AND, along with the arguments in the order of their placeholders:
+//
+//	conditions, args, err := filter.ToMySQLSQL(fieldConfigs)
+//	rows, err := db.QueryContext(ctx, "SELECT * FROM users WHERE "+strings.Join(conditions, " AND "), args...)
+//
+// Given the filter `userId:12345 email:john* state:(active OR frozen)` and matching field configs, the conditions are
+//
+//	["`user_id` = ?", "`email` LIKE ?", "`state` IN (?, ?)"]
+//
+// with arguments
+//
+//	[int64(12345), "john%", "active", "frozen"]
+//
+// Column names are quoted with backticks. LIKE patterns rely on the backslash being the default escape character of
+// MySQL, as an `ESCAPE '\'` clause is an unterminated string unless the NO_BACKSLASH_ESCAPES SQL mode is enabled.
+// String comparisons follow the collation of the column, unless the field is CaseSensitive.
+//
+// DATETIME and DATE fields accept RFC3339 values, values in the layouts set with WithTimeLayouts, as well as relative
+// time keywords such as `today` (see RelativeTimeToday), which are resolved using the clock and location set with
+// WithClock and WithDefaultLocation.
+//
+// Conditions added with WithCondition are appended as-is; they must not have params, as the placeholders are
+// positional.
+func (f Filter) ToMySQLSQL(fieldConfigs map[string]FilterToMySQLFieldConfig, options ...ConverterOption) ([]string, []any, error) {
+	o := newConverterOptions(options)
+	var conditions []string
+	var args []any
+	bind := func(v any) string {
+		args = append(args, v)
+		return "?"
+	}
+
+	f, includeDeleted, err := o.extractIncludeDeleted(f)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, clause := range f.Clauses {
+		condition, err := convertMySQLClause(clause, fieldConfigs, o, bind)
+		if err != nil {
+			return nil, nil, err
+		}
+		conditions = append(conditions, condition)
+	}
+	if len(f.Groups) > 0 {
+		condition, err := convertGroups(f.Groups, func(clause Clause) (string, error) {
+			return convertMySQLClause(clause, fieldConfigs, o, bind)
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		conditions = append(conditions, condition)
+	}
+
+	if err := checkRequiredFields(fieldConfigs, f); err != nil {
+		return nil, nil, err
+	}
+
+	if o.softDeleteColumn != "" && !includeDeleted {
+		conditions = append(conditions, quoteMySQLIdentifier(o.softDeleteColumn)+" = "+bind(false))
+	}
+
+	for _, condition := range o.conditions {
+		if len(condition.params) > 0 {
+			return nil, nil, fmt.Errorf("condition %q: params are not supported with positional placeholders", condition.sql)
+		}
+		conditions = append(conditions, condition.sql)
+	}
+	return conditions, args, nil
+}
+
+// convertMySQLClause returns the condition of a clause, binding its values with bind.
+func convertMySQLClause(clause Clause, fieldConfigs map[string]FilterToMySQLFieldConfig, o converterOptions, bind func(any) string) (string, error) {
+	name, fieldConfig, ok := lookupField(fieldConfigs, clause.Field)
+	if !ok {
+		if clause.Field == "1" && clause.Operator == "=" && len(clause.Values) == 1 && (clause.Values[0] == "1" || clause.Values[0] == "0") {
+			// Special case for boolean literals
+			value, _ := strconv.ParseInt(clause.Values[0], 10, 64)
+			return "1 = " + bind(value), nil
+		}
+		return "", newUnknownFieldError(fieldConfigs, clause.Field)
+	}
+
+	if clause.Operator == "~" {
+		return "", fmt.Errorf("field %s: fuzzy matching is not supported by MySQL", clause.Field)
+	}
+
+	columnName := fieldConfig.ColumnName
+	if columnName == "" {
+		columnName = name
+	}
+	columnName = quoteMySQLIdentifier(columnName)
+
+	if len(clause.Values) > 1 && !fieldConfig.AllowMultipleValues {
+		return "", fmt.Errorf("field %s: multiple values are not allowed", clause.Field)
+	}
+	values, err := fieldConfig.mapValues(clause.Values, o, fieldConfig.convertValue)
+	if err != nil {
+		return "", fmt.Errorf("field %s: %w", clause.Field, err)
+	}
+
+	var condition string
+	switch clause.Operator {
+	case "IN", "NOT IN":
+		if clause.Operator == "NOT IN" && !(fieldConfig.AllowNegation && fieldConfig.AllowMultipleValues) {
+			return "", fmt.Errorf("operator %s not supported for field: %s", clause.Operator, clause.Field)
+		}
+		placeholders := make([]string, len(values))
+		for i, v := range values {
+			placeholders[i] = fieldConfig.binary(v) + bind(unescapeStringValue(v))
+		}
+		condition = fmt.Sprintf("%s %s (%s)", columnName, clause.Operator, strings.Join(placeholders, ", "))
+	case "=", "!=":
+		value := values[0]
+		if s, ok := value.(string); ok {
+			text, needsPrefixMatch, needsSuffixMatch := SplitWildcards(s, fieldConfig.AllowPrefixMatch && clause.Operator == "=", fieldConfig.AllowSuffixMatch && clause.Operator == "=")
+			if needsPrefixMatch || needsSuffixMatch {
+				pattern := escapePrefixSuffixSpecialChars(text)
+				if needsPrefixMatch {
+					pattern += "%"
+				}
+				if needsSuffixMatch {
+					pattern = "%" + pattern
+				}
+				return columnName + " LIKE " + fieldConfig.binary(pattern) + bind(pattern), nil
+			}
+			value = text
+		}
+		operator := "="
+		if clause.Operator == "!=" {
+			operator = "<>"
+		}
+		condition = fmt.Sprintf("%s %s %s%s", columnName, operator, fieldConfig.binary(value), bind(value))
+	case ">=", "<=", ">", "<":
+		if !fieldConfig.AllowRanges {
+			return "", fmt.Errorf("operator %s not supported for field: %s", clause.Operator, clause.Field)
+		}
+		switch fieldConfig.ColumnType {
+		case FilterToMySQLFieldColumnTypeBigint, FilterToMySQLFieldColumnTypeDouble, FilterToMySQLFieldColumnTypeDatetime, FilterToMySQLFieldColumnTypeDate:
+			condition = fmt.Sprintf("%s %s %s", columnName, clause.Operator, bind(values[0]))
+		default:
+			return "", fmt.Errorf("operator %s not supported for field type %s", clause.Operator, fieldConfig.ColumnType)
+		}
+	default:
+		return "", fmt.Errorf("operator %s not supported for field: %s", clause.Operator, clause.Field)
+	}
+	return condition, nil
+}
+
+// quoteMySQLIdentifier quotes each part of a possibly qualified identifier with backticks, e.g. u.key as `u`.`key`.
+// Backticks within the identifier are doubled.
+func quoteMySQLIdentifier(identifier string) string {
+	parts := strings.Split(identifier, ".")
+	for i, part := range parts {
+		parts[i] = "`" + strings.ReplaceAll(part, "`", "``") + "`"
+	}
+	return strings.Join(parts, ".")
+}
+
+// binary returns the BINARY operator to compare the value case-sensitively, if the field is CaseSensitive and the value
+// is a string, and an empty string otherwise.
+func (f FilterToMySQLFieldConfig) binary(value any) string {
+	if _, ok := value.(string); ok && f.CaseSensitive {
+		return "BINARY "
+	}
+	return ""
+}
+
+func (f FilterToMySQLFieldConfig) convertValue(value string, o converterOptions) (any, error) {
+	switch f.ColumnType {
+	case FilterToMySQLFieldColumnTypeBigint:
+		intVal, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid BIGINT value: %w", err)
+		}
+		return intVal, nil
+	case FilterToMySQLFieldColumnTypeDouble:
+		floatVal, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid DOUBLE value: %w", err)
+		}
+		return floatVal, nil
+	case FilterToMySQLFieldColumnTypeBoolean:
+		boolVal, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid BOOLEAN value: %w", err)
+		}
+		return boolVal, nil
+	case FilterToMySQLFieldColumnTypeDatetime, FilterToMySQLFieldColumnTypeDate:
+		t, ok := o.resolveRelativeTime(value)
+		if !ok {
+			var err error
+			t, err = o.parseTime(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s value: %w", f.ColumnType, err)
+			}
+		}
+		if f.ColumnType == FilterToMySQLFieldColumnTypeDate {
+			return t.In(o.location).Format(time.DateOnly), nil
+		}
+		return t.UTC().Format(mysqlDatetimeLayout), nil
+	default:
+		return value, nil
+	}
+}
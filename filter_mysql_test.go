@@ -0,0 +1,184 @@
+package kqlfilter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilterToMySQLSQL(t *testing.T) {
+	fieldConfigs := map[string]FilterToMySQLFieldConfig{
+		"userId": {
+			ColumnName: "u.user_id",
+			ColumnType: FilterToMySQLFieldColumnTypeBigint,
+		},
+		"email": {
+			AllowPrefixMatch: true,
+			AllowSuffixMatch: true,
+		},
+		"code": {
+			AllowPrefixMatch: true,
+			CaseSensitive:    true,
+			FieldOptions:     FieldOptions{AllowMultipleValues: true},
+		},
+		"state": {
+			FieldOptions: FieldOptions{AllowMultipleValues: true, AllowNegation: true},
+		},
+		"key": {},
+		"time": {
+			ColumnType:   FilterToMySQLFieldColumnTypeDatetime,
+			FieldOptions: FieldOptions{AllowRanges: true},
+		},
+		"day": {
+			ColumnType:   FilterToMySQLFieldColumnTypeDate,
+			FieldOptions: FieldOptions{AllowRanges: true},
+		},
+		"score": {
+			ColumnType:   FilterToMySQLFieldColumnTypeDouble,
+			FieldOptions: FieldOptions{AllowRanges: true},
+		},
+		"active": {
+			ColumnType: FilterToMySQLFieldColumnTypeBoolean,
+		},
+		"name": {
+			FieldOptions: FieldOptions{AllowRanges: true},
+		},
+	}
+
+	testCases := []struct {
+		name               string
+		input              string
+		expectedConditions []string
+		expectedArgs       []any
+		expectedError      string
+	}{
+		{
+			name:               "example",
+			input:              `userId:12345 email:john* state:(active OR frozen)`,
+			expectedConditions: []string{"`u`.`user_id` = ?", "`email` LIKE ?", "`state` IN (?, ?)"},
+			expectedArgs:       []any{int64(12345), "john%", "active", "frozen"},
+		},
+		{
+			name:               "reserved word",
+			input:              `key:a`,
+			expectedConditions: []string{"`key` = ?"},
+			expectedArgs:       []any{"a"},
+		},
+		{
+			name:               "suffix match with special characters",
+			input:              `email:*_1%`,
+			expectedConditions: []string{"`email` LIKE ?"},
+			expectedArgs:       []any{`%\_1\%`},
+		},
+		{
+			name:               "case sensitive",
+			input:              `code:AB* not code:Z`,
+			expectedConditions: []string{"`code` LIKE BINARY ?", "`code` <> BINARY ?"},
+			expectedArgs:       []any{"AB%", "Z"},
+		},
+		{
+			name:               "case sensitive values",
+			input:              `code:(X or Y)`,
+			expectedConditions: []string{"`code` IN (BINARY ?, BINARY ?)"},
+			expectedArgs:       []any{"X", "Y"},
+		},
+		{
+			name:               "negated values",
+			input:              `not state:(active or paused) not userId:1`,
+			expectedConditions: []string{"`state` NOT IN (?, ?)", "`u`.`user_id` <> ?"},
+			expectedArgs:       []any{"active", "paused", int64(1)},
+		},
+		{
+			name:               "timestamps",
+			input:              `time<"2024-05-01T12:00:00.123456+02:00" day>="2024-05-01T23:00:00Z"`,
+			expectedConditions: []string{"`time` < ?", "`day` >= ?"},
+			expectedArgs:       []any{"2024-05-01 10:00:00.123456", "2024-05-01"},
+		},
+		{
+			name:               "relative time",
+			input:              `time>=today`,
+			expectedConditions: []string{"`time` >= ?"},
+			expectedArgs:       []any{"2024-05-01 00:00:00"},
+		},
+		{
+			name:               "numbers and booleans",
+			input:              `score>0.5 active:true`,
+			expectedConditions: []string{"`score` > ?", "`active` = ?"},
+			expectedArgs:       []any{0.5, true},
+		},
+		{
+			name:               "boolean literal",
+			input:              `1:0`,
+			expectedConditions: []string{"1 = ?"},
+			expectedArgs:       []any{int64(0)},
+		},
+		{
+			name:          "range on string column",
+			input:         `name>a`,
+			expectedError: "operator > not supported for field type VARCHAR",
+		},
+		{
+			name:          "unknown field",
+			input:         `foo:bar`,
+			expectedError: "unknown field: foo",
+		},
+		{
+			name:          "invalid number",
+			input:         `userId:abc`,
+			expectedError: `field userId: invalid BIGINT value: strconv.ParseInt: parsing "abc": invalid syntax`,
+		},
+		{
+			name:          "multiple values not allowed",
+			input:         `email:(a or b)`,
+			expectedError: "field email: multiple values are not allowed",
+		},
+	}
+
+	now := time.Date(2024, 5, 1, 15, 0, 0, 0, time.UTC)
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			f, err := Parse(test.input)
+			require.NoError(t, err)
+			conditions, args, err := f.ToMySQLSQL(fieldConfigs, WithClock(func() time.Time { return now }))
+			if test.expectedError != "" {
+				assert.EqualError(t, err, test.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedConditions, conditions)
+			assert.Equal(t, test.expectedArgs, args)
+		})
+	}
+}
+
+func TestFilterToMySQLSQLOptions(t *testing.T) {
+	f, err := Parse("state:active")
+	require.NoError(t, err)
+	fieldConfigs := map[string]FilterToMySQLFieldConfig{"state": {}}
+
+	conditions, args, err := f.ToMySQLSQL(fieldConfigs, WithSoftDelete("deleted", false), WithCondition("tenant_id = 7", nil))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"`state` = ?", "`deleted` = ?", "tenant_id = 7"}, conditions)
+	assert.Equal(t, []any{"active", false}, args)
+
+	_, _, err = f.ToMySQLSQL(fieldConfigs, WithCondition("tenant_id = @tenant", map[string]any{"tenant": 7}))
+	assert.EqualError(t, err, `condition "tenant_id = @tenant": params are not supported with positional placeholders`)
+}
+
+func TestFilterToMySQLSQLGroups(t *testing.T) {
+	f := Filter{
+		Clauses: []Clause{{Field: "state", Operator: "=", Values: []string{"active"}}},
+		Groups: [][]Clause{
+			{{Field: "name", Operator: "=", Values: []string{"jo*"}}, {Field: "state", Operator: "!=", Values: []string{"frozen"}}},
+			{{Field: "name", Operator: "=", Values: []string{"ann"}}},
+		},
+	}
+	fieldConfigs := map[string]FilterToMySQLFieldConfig{"state": {}, "name": {AllowPrefixMatch: true}}
+
+	conditions, args, err := f.ToMySQLSQL(fieldConfigs, WithSoftDelete("deleted", false))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"`state` = ?", "((`name` LIKE ? AND `state` <> ?) OR (`name` = ?))", "`deleted` = ?"}, conditions)
+	assert.Equal(t, []any{"active", "jo%", "frozen", "ann", false}, args)
+}
@@ -0,0 +1,300 @@
+package kqlfilter
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+type FilterToPostgresFieldColumnType int
+
+const (
+	FilterToPostgresFieldColumnTypeUnspecified FilterToPostgresFieldColumnType = iota
+	FilterToPostgresFieldColumnTypeText
+	FilterToPostgresFieldColumnTypeBigInt
+	FilterToPostgresFieldColumnTypeDouble
+	FilterToPostgresFieldColumnTypeBoolean
+	FilterToPostgresFieldColumnTypeTimestampTZ
+	FilterToPostgresFieldColumnTypeTstzRange
+)
+
+func (c FilterToPostgresFieldColumnType) String() string {
+	switch c {
+	case FilterToPostgresFieldColumnTypeText:
+		return "text"
+	case FilterToPostgresFieldColumnTypeBigInt:
+		return "bigint"
+	case FilterToPostgresFieldColumnTypeDouble:
+		return "double precision"
+	case FilterToPostgresFieldColumnTypeBoolean:
+		return "boolean"
+	case FilterToPostgresFieldColumnTypeTimestampTZ:
+		return "timestamptz"
+	case FilterToPostgresFieldColumnTypeTstzRange:
+		return "tstzrange"
+	default:
+		return "???"
+	}
+}
+
+type FilterToPostgresFieldConfig struct {
+	// SQL table column name. Can be omitted if the column name is equal to the key in the fieldConfigs map.
+	ColumnName string
+	// SQL column type. Defaults to FilterToPostgresFieldColumnTypeText.
+	ColumnType FilterToPostgresFieldColumnType
+	// Settings shared with the field configs of the other converters, e.g. AllowMultipleValues and MapValue.
+	FieldOptions
+	// Allow prefix matching when a wildcard (`*`) is present at the end of a string.
+	// Only applicable for FilterToPostgresFieldColumnTypeText. Defaults to false.
+	AllowPrefixMatch bool
+	// Allow suffix matching when a wildcard (`*`) is present at the beginning of a string.
+	// Only applicable for FilterToPostgresFieldColumnTypeText. Defaults to false.
+	AllowSuffixMatch bool
+	// Use ILIKE instead of LIKE for prefix and suffix matching. Defaults to false.
+	AllowCaseInsensitiveMatch bool
+}
+
+// ToPostgresSQL turns a Filter into conditions for a PostgreSQL WHERE clause, using named arguments as supported by
+// pgx. It takes a map of fields that are allowed to be queried via this filter, and returns the conditions, which
+// must be joined by AND, along with the named arguments:
+//
+//	conditions, args, err := filter.ToPostgresSQL(fieldConfigs)
+//	rows, err := conn.Query(ctx, "SELECT * FROM users WHERE "+strings.Join(conditions, " AND "), pgx.NamedArgs(args))
+//
+// Given the filter `userId:12345 email:john* state:(active OR frozen)` and matching field configs, the conditions are
+//
+//	["user_id = @KQL0", "email LIKE @KQL1", "state = ANY(@KQL2)"]
+//
+// with arguments
+//
+//	{"KQL0": int64(12345), "KQL1": "john%", "KQL2": []string{"active", "frozen"}}
+//
+// Negated multiple values (see FieldOptions.AllowNegation) are matched with `<> ALL(...)`.
+//
+// Equality on a tstzrange column checks that the range contains the timestamp (`period @> @KQL0::timestamptz`).
+// Range operators on a tstzrange column compare the lower bound for `>` and `>=`, and the upper bound for `<` and `<=`,
+// so `period >= a and period <= b` matches ranges within [a, b].
+//
+// TIMESTAMPTZ and TSTZRANGE fields accept RFC3339 values, values in the layouts set with WithTimeLayouts, as well as
+// relative time keywords such as `today` (see RelativeTimeToday), which are resolved using the clock and location set
+// with WithClock and WithDefaultLocation.
+func (f Filter) ToPostgresSQL(fieldConfigs map[string]FilterToPostgresFieldConfig, options ...ConverterOption) ([]string, map[string]any, error) {
+	o := newConverterOptions(options)
+	var conditions []string
+	args := make(map[string]any)
+
+	f, includeDeleted, err := o.extractIncludeDeleted(f)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	convertClause := func(clause Clause) (string, error) {
+		argName := fmt.Sprintf("KQL%d", len(args))
+		condition, arg, err := convertPostgresClause(clause, argName, fieldConfigs, o)
+		if err != nil {
+			return "", err
+		}
+		args[argName] = arg
+		return condition, nil
+	}
+	for _, clause := range f.Clauses {
+		condition, err := convertClause(clause)
+		if err != nil {
+			return nil, nil, err
+		}
+		conditions = append(conditions, condition)
+	}
+	if len(f.Groups) > 0 {
+		condition, err := convertGroups(f.Groups, convertClause)
+		if err != nil {
+			return nil, nil, err
+		}
+		conditions = append(conditions, condition)
+	}
+
+	if err := checkRequiredFields(fieldConfigs, f); err != nil {
+		return nil, nil, err
+	}
+
+	if o.softDeleteColumn != "" && !includeDeleted {
+		argName := fmt.Sprintf("KQL%d", len(args))
+		conditions = append(conditions, fmt.Sprintf("%s = @%s", o.softDeleteColumn, argName))
+		args[argName] = false
+	}
+
+	conditions, err = o.appendConditions(conditions, args)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return conditions, args, nil
+}
+
+// convertPostgresClause returns the condition of a clause and the value of its argument, named argName.
+func convertPostgresClause(clause Clause, argName string, fieldConfigs map[string]FilterToPostgresFieldConfig, o converterOptions) (string, any, error) {
+	name, fieldConfig, ok := lookupField(fieldConfigs, clause.Field)
+	if !ok {
+		if clause.Field == "1" && clause.Operator == "=" && len(clause.Values) == 1 && (clause.Values[0] == "1" || clause.Values[0] == "0") {
+			// Special case for boolean literals
+			value, _ := strconv.ParseInt(clause.Values[0], 10, 64)
+			return "1 = @" + argName, value, nil
+		}
+		return "", nil, newUnknownFieldError(fieldConfigs, clause.Field)
+	}
+
+	if clause.Operator == "~" {
+		return "", nil, fmt.Errorf("field %s: fuzzy matching is not supported by PostgreSQL", clause.Field)
+	}
+
+	columnName := fieldConfig.ColumnName
+	if columnName == "" {
+		columnName = name
+	}
+
+	if len(clause.Values) > 1 && !fieldConfig.AllowMultipleValues {
+		return "", nil, fmt.Errorf("field %s: multiple values are not allowed", clause.Field)
+	}
+	values, err := fieldConfig.mapValues(clause.Values, o, fieldConfig.convertValue)
+	if err != nil {
+		return "", nil, fmt.Errorf("field %s: %w", clause.Field, err)
+	}
+
+	var condition string
+	var arg any
+	switch clause.Operator {
+	case "IN", "NOT IN":
+		if clause.Operator == "NOT IN" && !(fieldConfig.AllowNegation && fieldConfig.AllowMultipleValues) {
+			return "", nil, fmt.Errorf("operator %s not supported for field: %s", clause.Operator, clause.Field)
+		}
+		if fieldConfig.ColumnType == FilterToPostgresFieldColumnTypeTstzRange {
+			return "", nil, fmt.Errorf("operator %s not supported for field type %s", clause.Operator, fieldConfig.ColumnType)
+		}
+		if clause.Operator == "IN" {
+			condition = fmt.Sprintf("%s = ANY(@%s)", columnName, argName)
+		} else {
+			condition = fmt.Sprintf("%s <> ALL(@%s)", columnName, argName)
+		}
+		for i, v := range values {
+			if s, ok := v.(string); ok {
+				values[i] = UnescapeValue(s)
+			}
+		}
+		arg = postgresArray(values)
+	case "=", "!=":
+		value := values[0]
+		if s, ok := value.(string); ok {
+			text, needsPrefixMatch, needsSuffixMatch := SplitWildcards(s, fieldConfig.AllowPrefixMatch && clause.Operator == "=", fieldConfig.AllowSuffixMatch && clause.Operator == "=")
+			if needsPrefixMatch || needsSuffixMatch {
+				pattern := escapePrefixSuffixSpecialChars(text)
+				if needsPrefixMatch {
+					pattern += "%"
+				}
+				if needsSuffixMatch {
+					pattern = "%" + pattern
+				}
+				like := "LIKE"
+				if fieldConfig.AllowCaseInsensitiveMatch {
+					like = "ILIKE"
+				}
+				return fmt.Sprintf("%s %s @%s", columnName, like, argName), pattern, nil
+			}
+			value = text
+		}
+		switch {
+		case fieldConfig.ColumnType == FilterToPostgresFieldColumnTypeTstzRange && clause.Operator == "=":
+			condition = fmt.Sprintf("%s @> @%s::timestamptz", columnName, argName)
+		case fieldConfig.ColumnType == FilterToPostgresFieldColumnTypeTstzRange:
+			condition = fmt.Sprintf("NOT %s @> @%s::timestamptz", columnName, argName)
+		case clause.Operator == "=":
+			condition = fmt.Sprintf("%s = @%s", columnName, argName)
+		default:
+			condition = fmt.Sprintf("%s <> @%s", columnName, argName)
+		}
+		arg = value
+	case ">=", "<=", ">", "<":
+		if !fieldConfig.AllowRanges {
+			return "", nil, fmt.Errorf("operator %s not supported for field: %s", clause.Operator, clause.Field)
+		}
+		switch fieldConfig.ColumnType {
+		case FilterToPostgresFieldColumnTypeBigInt, FilterToPostgresFieldColumnTypeDouble, FilterToPostgresFieldColumnTypeTimestampTZ:
+			condition = fmt.Sprintf("%s %s @%s", columnName, clause.Operator, argName)
+		case FilterToPostgresFieldColumnTypeTstzRange:
+			bound := "lower"
+			if clause.Operator == "<" || clause.Operator == "<=" {
+				bound = "upper"
+			}
+			condition = fmt.Sprintf("%s(%s) %s @%s", bound, columnName, clause.Operator, argName)
+		default:
+			return "", nil, fmt.Errorf("operator %s not supported for field type %s", clause.Operator, fieldConfig.ColumnType)
+		}
+		arg = values[0]
+	default:
+		return "", nil, fmt.Errorf("operator %s not supported for field: %s", clause.Operator, clause.Field)
+	}
+	return condition, arg, nil
+}
+
+func (f FilterToPostgresFieldConfig) convertValue(value string, o converterOptions) (any, error) {
+	switch f.ColumnType {
+	case FilterToPostgresFieldColumnTypeBigInt:
+		intVal, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bigint value: %w", err)
+		}
+		return intVal, nil
+	case FilterToPostgresFieldColumnTypeDouble:
+		doubleVal, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid double precision value: %w", err)
+		}
+		return doubleVal, nil
+	case FilterToPostgresFieldColumnTypeBoolean:
+		boolVal, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid boolean value: %w", err)
+		}
+		return boolVal, nil
+	case FilterToPostgresFieldColumnTypeTimestampTZ, FilterToPostgresFieldColumnTypeTstzRange:
+		if relative, ok := o.resolveRelativeTime(value); ok {
+			return relative, nil
+		}
+		t, err := o.parseTime(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timestamptz value: %w", err)
+		}
+		return t, nil
+	default:
+		return value, nil
+	}
+}
+
+// postgresArray returns the values as a typed slice, e.g. []int64, so that pgx can encode them as an array argument.
+// Values of mixed types, which can only be returned by MapValue, are returned as-is.
+func postgresArray(values []any) any {
+	switch values[0].(type) {
+	case string:
+		return typedSlice[string](values)
+	case int64:
+		return typedSlice[int64](values)
+	case float64:
+		return typedSlice[float64](values)
+	case bool:
+		return typedSlice[bool](values)
+	case time.Time:
+		return typedSlice[time.Time](values)
+	default:
+		return values
+	}
+}
+
+func typedSlice[T any](values []any) any {
+	typed := make([]T, 0, len(values))
+	for _, v := range values {
+		t, ok := v.(T)
+		if !ok {
+			return values
+		}
+		typed = append(typed, t)
+	}
+	return typed
+}
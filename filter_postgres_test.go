@@ -0,0 +1,366 @@
+package kqlfilter
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToPostgresSQL(t *testing.T) {
+	testCases := []struct {
+		name          string
+		input         string
+		columnMap     map[string]FilterToPostgresFieldConfig
+		expectedError bool
+		expectedSQL   string
+		expectedArgs  map[string]any
+	}{
+		{
+			"one integer field",
+			"userId:12345",
+			map[string]FilterToPostgresFieldConfig{
+				"userId": {
+					ColumnName: "user_id",
+					ColumnType: FilterToPostgresFieldColumnTypeBigInt,
+				},
+			},
+			false,
+			"(user_id = @KQL0)",
+			map[string]any{
+				"KQL0": int64(12345),
+			},
+		},
+		{
+			"boolean literal",
+			"false",
+			map[string]FilterToPostgresFieldConfig{},
+			false,
+			"(1 = @KQL0)",
+			map[string]any{
+				"KQL0": int64(0),
+			},
+		},
+		{
+			"alias",
+			"user_id:12",
+			map[string]FilterToPostgresFieldConfig{
+				"userId": {
+					ColumnName:   "u.user_id",
+					ColumnType:   FilterToPostgresFieldColumnTypeBigInt,
+					FieldOptions: FieldOptions{Aliases: []string{"user_id"}},
+				},
+			},
+			false,
+			"(u.user_id = @KQL0)",
+			map[string]any{
+				"KQL0": int64(12),
+			},
+		},
+		{
+			"unknown field",
+			"userId:12",
+			map[string]FilterToPostgresFieldConfig{
+				"email": {},
+			},
+			true,
+			"",
+			nil,
+		},
+		{
+			"invalid integer",
+			"userId:abc",
+			map[string]FilterToPostgresFieldConfig{
+				"userId": {ColumnType: FilterToPostgresFieldColumnTypeBigInt},
+			},
+			true,
+			"",
+			nil,
+		},
+		{
+			"negated single value",
+			"not state:active",
+			map[string]FilterToPostgresFieldConfig{
+				"state": {},
+			},
+			false,
+			"(state <> @KQL0)",
+			map[string]any{
+				"KQL0": "active",
+			},
+		},
+		{
+			"prefix match",
+			"email:john_*",
+			map[string]FilterToPostgresFieldConfig{
+				"email": {AllowPrefixMatch: true},
+			},
+			false,
+			"(email LIKE @KQL0)",
+			map[string]any{
+				"KQL0": `john\_%`,
+			},
+		},
+		{
+			"case insensitive prefix and suffix match",
+			"email:*john*",
+			map[string]FilterToPostgresFieldConfig{
+				"email": {AllowPrefixMatch: true, AllowSuffixMatch: true, AllowCaseInsensitiveMatch: true},
+			},
+			false,
+			"(email ILIKE @KQL0)",
+			map[string]any{
+				"KQL0": "%john%",
+			},
+		},
+		{
+			"escaped asterisk is matched literally",
+			`email:*john\*`,
+			map[string]FilterToPostgresFieldConfig{
+				"email": {AllowPrefixMatch: true, AllowSuffixMatch: true},
+			},
+			false,
+			"(email LIKE @KQL0)",
+			map[string]any{
+				"KQL0": "%john*",
+			},
+		},
+		{
+			"wildcard without prefix match is matched literally",
+			"email:john*",
+			map[string]FilterToPostgresFieldConfig{
+				"email": {},
+			},
+			false,
+			"(email = @KQL0)",
+			map[string]any{
+				"KQL0": "john*",
+			},
+		},
+		{
+			"multiple values",
+			"state:(active OR frozen)",
+			map[string]FilterToPostgresFieldConfig{
+				"state": {FieldOptions: FieldOptions{AllowMultipleValues: true}},
+			},
+			false,
+			"(state = ANY(@KQL0))",
+			map[string]any{
+				"KQL0": []string{"active", "frozen"},
+			},
+		},
+		{
+			"multiple integer values",
+			"userId:(1 OR 2)",
+			map[string]FilterToPostgresFieldConfig{
+				"userId": {ColumnType: FilterToPostgresFieldColumnTypeBigInt, FieldOptions: FieldOptions{AllowMultipleValues: true}},
+			},
+			false,
+			"(userId = ANY(@KQL0))",
+			map[string]any{
+				"KQL0": []int64{1, 2},
+			},
+		},
+		{
+			"multiple values not allowed",
+			"state:(active OR frozen)",
+			map[string]FilterToPostgresFieldConfig{
+				"state": {},
+			},
+			true,
+			"",
+			nil,
+		},
+		{
+			"negated multiple values",
+			"not state:(active OR frozen)",
+			map[string]FilterToPostgresFieldConfig{
+				"state": {FieldOptions: FieldOptions{AllowMultipleValues: true, AllowNegation: true}},
+			},
+			false,
+			"(state <> ALL(@KQL0))",
+			map[string]any{
+				"KQL0": []string{"active", "frozen"},
+			},
+		},
+		{
+			"negated multiple values not allowed",
+			"not state:(active OR frozen)",
+			map[string]FilterToPostgresFieldConfig{
+				"state": {FieldOptions: FieldOptions{AllowMultipleValues: true}},
+			},
+			true,
+			"",
+			nil,
+		},
+		{
+			"range",
+			"price>=10 and price<20.5",
+			map[string]FilterToPostgresFieldConfig{
+				"price": {ColumnType: FilterToPostgresFieldColumnTypeDouble, FieldOptions: FieldOptions{AllowRanges: true}},
+			},
+			false,
+			"(price >= @KQL0 AND price < @KQL1)",
+			map[string]any{
+				"KQL0": float64(10),
+				"KQL1": 20.5,
+			},
+		},
+		{
+			"range not allowed",
+			"price>=10",
+			map[string]FilterToPostgresFieldConfig{
+				"price": {ColumnType: FilterToPostgresFieldColumnTypeDouble},
+			},
+			true,
+			"",
+			nil,
+		},
+		{
+			"range on text",
+			"name>=a",
+			map[string]FilterToPostgresFieldConfig{
+				"name": {FieldOptions: FieldOptions{AllowRanges: true}},
+			},
+			true,
+			"",
+			nil,
+		},
+		{
+			"tstzrange contains",
+			`period:"2023-01-01T00:00:00Z"`,
+			map[string]FilterToPostgresFieldConfig{
+				"period": {ColumnType: FilterToPostgresFieldColumnTypeTstzRange},
+			},
+			false,
+			"(period @> @KQL0::timestamptz)",
+			map[string]any{
+				"KQL0": time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			"tstzrange bounds",
+			`period>="2023-01-01T00:00:00Z" and period<"2023-02-01T00:00:00Z"`,
+			map[string]FilterToPostgresFieldConfig{
+				"period": {ColumnType: FilterToPostgresFieldColumnTypeTstzRange, FieldOptions: FieldOptions{AllowRanges: true}},
+			},
+			false,
+			"(lower(period) >= @KQL0 AND upper(period) < @KQL1)",
+			map[string]any{
+				"KQL0": time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+				"KQL1": time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			"required field missing",
+			"state:active",
+			map[string]FilterToPostgresFieldConfig{
+				"state":  {},
+				"userId": {FieldOptions: FieldOptions{Required: true}},
+			},
+			true,
+			"",
+			nil,
+		},
+		{
+			"allowed values",
+			"state:actve",
+			map[string]FilterToPostgresFieldConfig{
+				"state": {FieldOptions: FieldOptions{AllowedValues: []string{"active", "frozen"}}},
+			},
+			true,
+			"",
+			nil,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			f, errParse := Parse(test.input)
+			conditions, args, err := f.ToPostgresSQL(test.columnMap)
+			if test.expectedError {
+				if errParse == nil && err == nil {
+					t.Errorf("expected error, but got none")
+				}
+				return
+			}
+			require.NoError(t, errParse)
+			require.NoError(t, err)
+
+			sql := ""
+			if len(conditions) > 0 {
+				sql = "(" + strings.Join(conditions, " AND ") + ")"
+			}
+			assert.Equal(t, test.expectedSQL, sql)
+			assert.Equal(t, test.expectedArgs, args)
+		})
+	}
+}
+
+func TestToPostgresSQLSoftDelete(t *testing.T) {
+	configs := map[string]FilterToPostgresFieldConfig{"state": {}}
+
+	f, err := Parse("state:active")
+	require.NoError(t, err)
+	conditions, args, err := f.ToPostgresSQL(configs, WithSoftDelete("deleted", true))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"state = @KQL0", "deleted = @KQL1"}, conditions)
+	assert.Equal(t, map[string]any{"KQL0": "active", "KQL1": false}, args)
+
+	f, err = Parse("state:active _include_deleted:true")
+	require.NoError(t, err)
+	conditions, _, err = f.ToPostgresSQL(configs, WithSoftDelete("deleted", true))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"state = @KQL0"}, conditions)
+}
+
+func TestToPostgresSQLWithCondition(t *testing.T) {
+	configs := map[string]FilterToPostgresFieldConfig{"state": {}}
+
+	f, err := Parse("state:active")
+	require.NoError(t, err)
+	conditions, args, err := f.ToPostgresSQL(configs, WithCondition("tenant_id = @tenant", map[string]any{"tenant": "t1"}))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"state = @KQL0", "tenant_id = @tenant"}, conditions)
+	assert.Equal(t, map[string]any{"KQL0": "active", "tenant": "t1"}, args)
+}
+
+func TestPostgresBackend(t *testing.T) {
+	schema := Schema{
+		"user_id": {Type: FieldTypeInt64, Aliases: []string{"userId"}},
+		"state":   {AllowMultipleValues: true},
+	}
+
+	query, err := QueryFromKQL("userId:12 state:(active OR paused)", schema, PostgresBackend())
+	require.NoError(t, err)
+	assert.Equal(t, "user_id = @KQL0 AND state = ANY(@KQL1)", query.Where())
+	assert.Equal(t, map[string]any{"KQL0": int64(12), "KQL1": []string{"active", "paused"}}, query.Args)
+}
+
+func TestToPostgresSQLGroups(t *testing.T) {
+	configs := map[string]FilterToPostgresFieldConfig{
+		"state": {},
+		"a":     {},
+		"b":     {ColumnType: FilterToPostgresFieldColumnTypeBigInt, FieldOptions: FieldOptions{AllowRanges: true}},
+		"c":     {FieldOptions: FieldOptions{AllowMultipleValues: true}},
+	}
+	f := Filter{
+		Clauses: []Clause{{Field: "state", Operator: "=", Values: []string{"active"}}},
+		Groups: [][]Clause{
+			{{Field: "a", Operator: "=", Values: []string{"1"}}, {Field: "b", Operator: ">", Values: []string{"2"}}},
+			{{Field: "c", Operator: "IN", Values: []string{"x", "y"}}},
+		},
+	}
+
+	conditions, args, err := f.ToPostgresSQL(configs, WithSoftDelete("deleted", true))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"state = @KQL0", "((a = @KQL1 AND b > @KQL2) OR (c = ANY(@KQL3)))", "deleted = @KQL4"}, conditions)
+	assert.Equal(t, map[string]any{"KQL0": "active", "KQL1": "1", "KQL2": int64(2), "KQL3": []string{"x", "y"}, "KQL4": false}, args)
+
+	f.Groups[1][0].Field = "d"
+	_, _, err = f.ToPostgresSQL(configs)
+	var unknownField *UnknownFieldError
+	assert.ErrorAs(t, err, &unknownField)
+}
@@ -1,12 +1,21 @@
 package kqlfilter
 
 import (
+	"errors"
 	"fmt"
+	"math/big"
 	"reflect"
+	"regexp"
 	"slices"
 	"strconv"
 	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"cloud.google.com/go/civil"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
 )
 
 type FilterToSpannerFieldColumnType int
@@ -18,6 +27,8 @@ const (
 	FilterToSpannerFieldColumnTypeFloat64
 	FilterToSpannerFieldColumnTypeBool
 	FilterToSpannerFieldColumnTypeTimestamp
+	FilterToSpannerFieldColumnTypeDate
+	FilterToSpannerFieldColumnTypeNumeric
 )
 
 func (c FilterToSpannerFieldColumnType) String() string {
@@ -32,6 +43,61 @@ func (c FilterToSpannerFieldColumnType) String() string {
 		return "BOOL"
 	case FilterToSpannerFieldColumnTypeTimestamp:
 		return "TIMESTAMP"
+	case FilterToSpannerFieldColumnTypeDate:
+		return "DATE"
+	case FilterToSpannerFieldColumnTypeNumeric:
+		return "NUMERIC"
+	default:
+		return "???"
+	}
+}
+
+// FilterToSpannerSearchFunction is the Spanner full-text search function used to match a TOKENLIST column.
+type FilterToSpannerSearchFunction int
+
+const (
+	// FilterToSpannerSearchFunctionNone matches the column with the standard operators.
+	FilterToSpannerSearchFunctionNone FilterToSpannerSearchFunction = iota
+	// FilterToSpannerSearchFunctionSearch matches the column with `SEARCH(column, @param)`, for a TOKENLIST created
+	// with TOKENIZE_FULLTEXT.
+	FilterToSpannerSearchFunctionSearch
+	// FilterToSpannerSearchFunctionSearchSubstring matches the column with `SEARCH_SUBSTRING(column, @param)`, for a
+	// TOKENLIST created with TOKENIZE_SUBSTRING.
+	FilterToSpannerSearchFunctionSearchSubstring
+)
+
+func (f FilterToSpannerSearchFunction) String() string {
+	switch f {
+	case FilterToSpannerSearchFunctionSearch:
+		return "SEARCH"
+	case FilterToSpannerSearchFunctionSearchSubstring:
+		return "SEARCH_SUBSTRING"
+	default:
+		return "???"
+	}
+}
+
+// FilterToSpannerNormalization is the Unicode normalization form that string values are converted to, so that values
+// written with different code points for the same characters, like a precomposed `é` and an `e` with a combining
+// accent, compare equal.
+type FilterToSpannerNormalization int
+
+const (
+	// FilterToSpannerNormalizationNone binds values as written.
+	FilterToSpannerNormalizationNone FilterToSpannerNormalization = iota
+	// FilterToSpannerNormalizationNFC converts values to canonical composition.
+	FilterToSpannerNormalizationNFC
+	// FilterToSpannerNormalizationNFKC converts values to compatibility composition, which also folds compatibility
+	// characters like ligatures (`ﬁ`) and full-width letters to their plain forms.
+	FilterToSpannerNormalizationNFKC
+)
+
+func (n FilterToSpannerNormalization) String() string {
+	switch n {
+	case FilterToSpannerNormalizationNFC:
+		return "NFC"
+	case FilterToSpannerNormalizationNFKC:
+		return "NFKC"
 	default:
 		return "???"
 	}
@@ -42,6 +108,14 @@ type FilterToSpannerFieldConfig struct {
 	ColumnName string
 	// SQL column type. Defaults to FilterToSpannerFieldColumnTypeString.
 	ColumnType FilterToSpannerFieldColumnType
+	// A SQL expression that is used instead of a column on the left side of the conditions of this field, e.g.
+	// `TIMESTAMP_TRUNC(created_at, DAY)` or `JSON_VALUE(meta, ?)`. Values in the expression are written as `?`
+	// placeholders (use `??` for a literal question mark), and are bound as params from ColumnExprArgs, so they are
+	// never interpolated into the SQL. Expressions with operators, like `a || b`, should be put in parentheses.
+	// ColumnType is the type of the expression. Takes precedence over ColumnName. Defaults to using the column.
+	ColumnExpr string
+	// The values of the placeholders in ColumnExpr, in order.
+	ColumnExprArgs []any
 	// If true, the filter must at least contain this field. Will not apply to empty filters. Defaults to false.
 	Required bool
 	// A list of other fields that must be present in the filter for this field to be allowed in the filter.
@@ -63,10 +137,41 @@ type FilterToSpannerFieldConfig struct {
 	// This currently only works for string columns in combination with `AllowPrefixMatch` and `AllowSuffixMatch`.
 	// Important: this can have a negative impact on performance, as it will prevent the use of an index on the column.
 	AllowCaseInsensitiveMatch bool
+	// The Unicode normalization form string values are converted to before they are bound, which should match how the
+	// column is stored, or the column must be normalized as well (see NormalizeColumn). Regular expressions are not
+	// normalized. Only applicable for FilterToSpannerFieldColumnTypeString. Defaults to FilterToSpannerNormalizationNone.
+	Normalization FilterToSpannerNormalization
+	// Fold the case of string values before they are bound, like Spanner's NORMALIZE_AND_CASEFOLD, for
+	// case-insensitive matching of columns stored case-folded. Implies FilterToSpannerNormalizationNFC if
+	// Normalization isn't set. Only applicable for FilterToSpannerFieldColumnTypeString. Defaults to false.
+	CaseFold bool
+	// Normalize the column in the same way as the values in all but range comparisons, with
+	// `NORMALIZE(column, NFC)` or `NORMALIZE_AND_CASEFOLD(column, NFC)`, for columns that are not stored normalized.
+	// Only applicable in combination with Normalization or CaseFold.
+	// Important: like AllowCaseInsensitiveMatch, this prevents the use of an index on the column.
+	NormalizeColumn bool
+	// Allow matching string values against a regular expression by writing the value between slashes, e.g.
+	// `name:/^jo.*n$/`, which is emitted as `REGEXP_CONTAINS(column, @param)`, or `NOT REGEXP_CONTAINS` when negated.
+	// Patterns use the RE2 syntax, which matches in linear time, and are validated before they are sent to Spanner.
+	// AllowedValues and MapValue do not apply to patterns.
+	// Only applicable for FilterToSpannerFieldColumnTypeString. Defaults to false.
+	AllowRegex bool
+	// The maximum length of a regular expression in bytes. Only applicable in combination with AllowRegex.
+	// Defaults to 256.
+	MaxRegexLength int
 	// Allow multiple values for this field. Defaults to false.
 	AllowMultipleValues bool
+	// Allow negated matching of multiple values (e.g. `not state:(active OR canceled)`), which is emitted as
+	// `NOT IN UNNEST(...)`. Only applicable in combination with AllowMultipleValues. Defaults to false.
+	AllowNegation bool
+	// The maximum number of values of a clause. Only applicable in combination with AllowMultipleValues.
+	// Defaults to the value set with WithMaxValues, or no limit.
+	MaxValues int
 	// Allow this field to be queried with one or more range operators. Defaults to false.
 	AllowRanges bool
+	// Allow range operators on a STRING column, which compare values lexicographically, e.g. to page through ULID or
+	// UUIDv7 keys with `id>01HZX3M5K2`. Only applicable in combination with AllowRanges. Defaults to false.
+	AllowStringRanges bool
 	// A list of aliases for this field. Can be used if you want to allow users to use different field names to filter
 	// on the same column. Useful e.g. to allow different naming conventions, like `type_id` and `typeId`.
 	Aliases []string
@@ -74,22 +179,58 @@ type FilterToSpannerFieldConfig struct {
 	// stored in the database. This should return an error when the user is providing a value that is illegal for this
 	// particular field. Defaults to using the provided value as-is.
 	MapValue func(string) (any, error)
+	// The values that are allowed for this field, e.g. the values of an enum. When set, any other value is rejected
+	// with an InvalidValueError that lists the allowed values and suggests the closest ones. This is checked before
+	// calling MapValue; errors returned by MapValue are reported the same way. Defaults to allowing any value.
+	AllowedValues []string
+	// Which of the AllowedValues are revealed when a value is rejected, also for InvalidValueErrors returned by MapValue,
+	// e.g. ValueSuggestionsNone for fields whose values are internal. Defaults to ValueSuggestionsAll.
+	ValueSuggestions ValueSuggestions
+	// The maximum length of a value in characters, not counting escapes and the wildcards of prefix and suffix matches.
+	// Longer values are rejected with a ValueConstraintError before calling MapValue. Regular expressions (see
+	// AllowRegex) are not checked. Defaults to no limit.
+	MaxLength int
+	// A regular expression that values must match, e.g. `^[a-z0-9-]+$`. It is matched against the value without escapes
+	// and the wildcards of prefix and suffix matches. Other values are rejected with a ValueConstraintError before
+	// calling MapValue. Regular expressions (see AllowRegex) are not checked. Defaults to allowing any value.
+	Pattern *regexp.Regexp
 	// When set to true, the field will be ignored in the generated where conditions. This can be useful when you want
 	// to manually process some fields after calling `ToSpannerSQL` (and want to ignore them in the initial filter).
 	// An example of this would when a field would require a complex join that is not auto-generateable by `ToSpannerSQL`.
 	// Defaults to false.
 	Ignore bool
+	// A function that converts a clause on this field by itself, for columns that can't be expressed with the standard
+	// column types, e.g. geography or tokenized search columns. It receives the column name, the operator and the raw
+	// values of the clause, and returns a SQL condition in which params are written as `?` (use `??` for a literal
+	// question mark), and the values of these params in order. The params are named like all other params of the filter.
+	// The column name is ColumnExpr, if set.
+	// If set, all other fields in the config except ColumnName, ColumnExpr, Aliases and Requires will be ignored.
+	// It is the only way to support fuzzy matches (`name:jon~1`, operator `~`).
+	CustomBuilder func(column string, operator string, values []string) (string, []any, error)
+	// The full-text search function used to match this field, in which case ColumnName is the name of the TOKENLIST
+	// column, e.g. `Title_Tokens`. Values are sanitized so that their words are searched for as plain terms: search
+	// query operators, like `OR`, `-` and quotes, are removed. Multiple values are OR'ed. Ranges, AllowedValues and
+	// MapValue do not apply. Defaults to FilterToSpannerSearchFunctionNone.
+	SearchFunction FilterToSpannerSearchFunction
 }
 
-func (f FilterToSpannerFieldConfig) mapValues(values []string) (any, error) {
+func (f FilterToSpannerFieldConfig) mapValues(values []string, o converterOptions) (any, error) {
 	var outputValue any
 	var err error
+	for _, value := range values {
+		if err := checkAllowedValue(value, f.AllowedValues); err != nil {
+			return nil, f.ValueSuggestions.apply(err)
+		}
+	}
 	if f.MapValue != nil {
 		outputValue = make([]any, 0, len(values))
 		for _, value := range values {
 			mappedValue, err := f.MapValue(value)
 			if err != nil {
-				return nil, err
+				if len(f.AllowedValues) > 0 {
+					err = NewInvalidValueError(value, err, f.AllowedValues)
+				}
+				return nil, f.ValueSuggestions.apply(err)
 			}
 			outputValue = append(outputValue.([]any), mappedValue)
 		}
@@ -101,13 +242,13 @@ func (f FilterToSpannerFieldConfig) mapValues(values []string) (any, error) {
 	outputValue = unwrapSlice(outputValue)
 
 	if !f.AllowMultipleValues && reflect.TypeOf(outputValue).Kind() == reflect.Slice {
-		return nil, fmt.Errorf("multiple values are not allowed")
+		return nil, ErrMultipleValuesNotAllowed
 	}
 
 	switch ov := outputValue.(type) {
 	// convert single string value if needed
 	case string:
-		outputValue, err = f.convertValue(ov)
+		outputValue, err = f.convertValue(ov, o)
 		if err != nil {
 			return nil, err
 		}
@@ -118,7 +259,7 @@ func (f FilterToSpannerFieldConfig) mapValues(values []string) (any, error) {
 		case FilterToSpannerFieldColumnTypeInt64:
 			outSlice := make([]int64, len(ov))
 			for i, v := range ov {
-				val, err := f.convertValue(v)
+				val, err := f.convertValue(v, o)
 				if err != nil {
 					return nil, err
 				}
@@ -128,7 +269,7 @@ func (f FilterToSpannerFieldConfig) mapValues(values []string) (any, error) {
 		case FilterToSpannerFieldColumnTypeFloat64:
 			outSlice := make([]float64, len(ov))
 			for i, v := range ov {
-				val, err := f.convertValue(v)
+				val, err := f.convertValue(v, o)
 				if err != nil {
 					return nil, err
 				}
@@ -138,7 +279,7 @@ func (f FilterToSpannerFieldConfig) mapValues(values []string) (any, error) {
 		case FilterToSpannerFieldColumnTypeBool:
 			outSlice := make([]bool, len(ov))
 			for i, v := range ov {
-				val, err := f.convertValue(v)
+				val, err := f.convertValue(v, o)
 				if err != nil {
 					return nil, err
 				}
@@ -148,20 +289,40 @@ func (f FilterToSpannerFieldConfig) mapValues(values []string) (any, error) {
 		case FilterToSpannerFieldColumnTypeTimestamp:
 			outSlice := make([]time.Time, len(ov))
 			for i, v := range ov {
-				val, err := f.convertValue(v)
+				val, err := f.convertValue(v, o)
 				if err != nil {
 					return nil, err
 				}
 				outSlice[i] = val.(time.Time)
 			}
 			outputValue = outSlice
+		case FilterToSpannerFieldColumnTypeDate:
+			outSlice := make([]civil.Date, len(ov))
+			for i, v := range ov {
+				val, err := f.convertValue(v, o)
+				if err != nil {
+					return nil, err
+				}
+				outSlice[i] = val.(civil.Date)
+			}
+			outputValue = outSlice
+		case FilterToSpannerFieldColumnTypeNumeric:
+			outSlice := make([]*big.Rat, len(ov))
+			for i, v := range ov {
+				val, err := f.convertValue(v, o)
+				if err != nil {
+					return nil, err
+				}
+				outSlice[i] = val.(*big.Rat)
+			}
+			outputValue = outSlice
 		}
 	}
 
 	return outputValue, nil
 }
 
-func (f FilterToSpannerFieldConfig) convertValue(value string) (any, error) {
+func (f FilterToSpannerFieldConfig) convertValue(value string, o converterOptions) (any, error) {
 	switch f.ColumnType {
 	case FilterToSpannerFieldColumnTypeInt64:
 		intVal, err := strconv.ParseInt(value, 10, 64)
@@ -185,12 +346,32 @@ func (f FilterToSpannerFieldConfig) convertValue(value string) (any, error) {
 		return boolVal, nil
 
 	case FilterToSpannerFieldColumnTypeTimestamp:
-		t, err := time.Parse(time.RFC3339, value)
+		t, err := o.parseTime(value)
 		if err != nil {
+			if relative, ok := o.resolveRelativeTime(value); ok {
+				return relative, nil
+			}
 			return nil, fmt.Errorf("invalid TIMESTAMP value: %w", err)
 		}
 		return t, nil
 
+	case FilterToSpannerFieldColumnTypeDate:
+		d, err := civil.ParseDate(value)
+		if err != nil {
+			if relative, ok := o.resolveRelativeTime(value); ok {
+				return civil.DateOf(relative), nil
+			}
+			return nil, fmt.Errorf("invalid DATE value: %w", err)
+		}
+		return d, nil
+
+	case FilterToSpannerFieldColumnTypeNumeric:
+		r, err := parseNumeric(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid NUMERIC value: %w", err)
+		}
+		return r, nil
+
 	case FilterToSpannerFieldColumnTypeString:
 		return value, nil
 
@@ -265,168 +446,637 @@ func unwrapSlice(v any) any {
 //		"@KQL1": "T2"
 //	}
 //
+// TIMESTAMP fields accept RFC3339 values as well as relative time keywords such as `today` (see RelativeTimeToday),
+// which are resolved using the clock and location set with WithClock and WithDefaultLocation.
+//
+// Params are numbered in the order of the clauses, so `ts >= a AND ts < b` results in `ts>=@KQL0` and `ts<@KQL1`.
+// With WithCollapseRanges, an inclusive lower and upper bound on the same column (`ts >= a AND ts <= b`) are combined
+// into a single `ts BETWEEN @KQL0 AND @KQL1` condition, at the position of the first of the two clauses.
+// With WithParamDeduplication, identical values share the param of their first occurrence.
+//
+// Filter.Groups are converted to a single condition, `((a AND b) OR (c))`, after those of the clauses. Ranges are
+// collapsed within each group, and a group that only has ignored fields returns an error, as it would match every row.
+//
 // Note: The Clause Operator is contextually used/ignored. It only works with INT64, FLOAT64 and TIMESTAMP types currently.
-func (f Filter) ToSpannerSQL(fieldConfigs map[string]FilterToSpannerFieldConfig) ([]string, map[string]any, error) {
-	var condAnds []string
-	params := make(map[string]any)
+func (f Filter) ToSpannerSQL(fieldConfigs map[string]FilterToSpannerFieldConfig, options ...ConverterOption) ([]string, map[string]any, error) {
+	// Indexing the field configs does not pay off for a single conversion.
+	c := SpannerConverter{fieldConfigs: fieldConfigs, options: newConverterOptions(options)}
+	return c.Convert(f)
+}
 
-	paramIndex := 0
+// SpannerConverter converts filters to Spanner SQL like Filter.ToSpannerSQL, for a fixed set of field configs.
+// The field configs are validated and indexed once, which makes it cheaper than Filter.ToSpannerSQL when converting
+// many filters. A SpannerConverter is safe for concurrent use.
+type SpannerConverter struct {
+	fieldConfigs map[string]FilterToSpannerFieldConfig
+	// Canonical field name by field name or alias. If nil, aliases are looked up by scanning the field configs.
+	fields     map[string]string
+	fieldNames []string
+	required   []string
+	options    converterOptions
+}
 
-	for _, clause := range f.Clauses {
-		fieldConfig, ok := fieldConfigs[clause.Field]
-		if !ok {
-			// There may be an alias defined on one of the other fieldConfigs
-			for _, fc := range fieldConfigs {
-				for _, alias := range fc.Aliases {
-					if alias == clause.Field {
-						fieldConfig = fc
-						ok = true
-						break
-					}
-				}
-				if ok {
-					break
+// NewSpannerConverter returns a SpannerConverter for the given field configs. It returns an error if an alias is used
+// by more than one field or shadows a field name, or if a field requires an unknown field.
+func NewSpannerConverter(fieldConfigs map[string]FilterToSpannerFieldConfig, options ...ConverterOption) (*SpannerConverter, error) {
+	for name, fc := range fieldConfigs {
+		for _, alias := range fc.Aliases {
+			if _, ok := fieldConfigs[alias]; ok && alias != name {
+				return nil, fmt.Errorf("alias %s of field %s shadows field %s", alias, name, alias)
+			}
+			for other, otherConfig := range fieldConfigs {
+				if other != name && slices.Contains(otherConfig.Aliases, alias) {
+					return nil, fmt.Errorf("alias %s is used by fields %s and %s", alias, name, other)
 				}
 			}
+		}
+		for _, requiredField := range fc.Requires {
+			if _, ok := fieldConfigs[requiredField]; !ok {
+				return nil, fmt.Errorf("field %s requires unknown field %s", name, requiredField)
+			}
+		}
+		if fc.ColumnExpr != "" {
+			s := &spannerConversion{params: make(map[string]any)}
+			if _, err := s.bindPlaceholders(fc.ColumnExpr, fc.ColumnExprArgs); err != nil {
+				return nil, fmt.Errorf("field %s: column expression: %w", name, err)
+			}
+		}
+	}
+	c := &SpannerConverter{
+		fieldConfigs: fieldConfigs,
+		fields:       make(map[string]string, len(fieldConfigs)),
+		fieldNames:   spannerFieldNames(fieldConfigs),
+		required:     requiredSpannerFields(fieldConfigs),
+		options:      newConverterOptions(options),
+	}
+	for name, fc := range fieldConfigs {
+		for _, alias := range fc.Aliases {
+			c.fields[alias] = name
+		}
+	}
+	// Field names take precedence over aliases.
+	for name := range fieldConfigs {
+		c.fields[name] = name
+	}
+	return c, nil
+}
 
-			if !ok {
-				if clause.Field == "1" && clause.Operator == "=" && len(clause.Values) == 1 && (clause.Values[0] == "1" || clause.Values[0] == "0") {
-					// Special case for boolean literals
-				} else {
-					return nil, nil, fmt.Errorf("unknown field: %s", clause.Field)
+// lookup returns the name of the field config of the given field name or alias.
+func (c *SpannerConverter) lookup(field string) (string, bool) {
+	if c.fields != nil {
+		name, ok := c.fields[field]
+		return name, ok
+	}
+	if _, ok := c.fieldConfigs[field]; ok {
+		return field, true
+	}
+	// There may be an alias defined on one of the other fieldConfigs
+	for name, fc := range c.fieldConfigs {
+		if slices.Contains(fc.Aliases, field) {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// Convert turns a Filter into a partial StandardSQL statement; see Filter.ToSpannerSQL.
+func (c *SpannerConverter) Convert(f Filter) ([]string, map[string]any, error) {
+	o := c.options
+
+	f, includeDeleted, err := o.extractIncludeDeleted(f)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s := &spannerConversion{params: make(map[string]any), dedup: o.dedupParams}
+	errs := errorCollector{all: o.allErrors}
+	for _, clause := range f.Clauses {
+		if err := c.convertClause(s, clause, f, o); err != nil && errs.add(err) {
+			return nil, nil, err
+		}
+	}
+	condAnds, params := s.condAnds, s.params
+
+	if len(s.bounds) > 1 {
+		condAnds = collapseRanges(condAnds, s.bounds)
+	}
+	if len(f.Groups) > 0 {
+		groups, err := c.convertGroups(s, f, o, &errs)
+		if err != nil {
+			return nil, nil, err
+		}
+		if groups != "" {
+			condAnds = append(condAnds, groups)
+		}
+	}
+
+	required := c.required
+	if c.fields == nil {
+		required = requiredSpannerFields(c.fieldConfigs)
+	}
+	for _, field := range required {
+		found := f.hasClause(func(clause Clause) bool {
+			return clause.Field == field || slices.Contains(c.fieldConfigs[field].Aliases, clause.Field)
+		})
+		if !found {
+			err := newFieldError(field, ErrRequiredFieldMissing, "required field %s missing", field)
+			if errs.add(err) {
+				return nil, nil, err
+			}
+		}
+	}
+	if err := errs.err(); err != nil {
+		return nil, nil, err
+	}
+
+	if o.softDeleteColumn != "" && !includeDeleted {
+		condAnds = append(condAnds, fmt.Sprintf("%s=@%s", o.softDeleteColumn, s.bind(false)))
+	}
+
+	condAnds, err = o.appendConditions(condAnds, params)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return condAnds, params, nil
+}
+
+// convertGroups returns the condition of the groups of a filter, `((a AND b) OR (c))`, converting the clauses of each
+// group like those of the filter. It returns an empty condition if errors are collected in errs.
+func (c *SpannerConverter) convertGroups(s *spannerConversion, f Filter, o converterOptions, errs *errorCollector) (string, error) {
+	condAnds, bounds := s.condAnds, s.bounds
+	defer func() {
+		s.condAnds, s.bounds = condAnds, bounds
+	}()
+
+	failed := false
+	alternatives := make([][]string, 0, len(f.Groups))
+	for i, group := range f.Groups {
+		s.condAnds, s.bounds = nil, nil
+		// Fields required by a clause of the group may be in the group or in the clauses of the filter.
+		withGroup := Filter{Clauses: append(slices.Clone(f.Clauses), group...)}
+		groupFailed := false
+		for _, clause := range group {
+			if err := c.convertClause(s, clause, withGroup, o); err != nil {
+				groupFailed = true
+				if errs.add(err) {
+					return "", err
 				}
 			}
 		}
+		if groupFailed {
+			failed = true
+			continue
+		}
+		if len(s.condAnds) == 0 {
+			failed = true
+			err := fmt.Errorf("group %d only has ignored fields and would match every row", i)
+			if errs.add(err) {
+				return "", err
+			}
+			continue
+		}
+		conditions := s.condAnds
+		if len(s.bounds) > 1 {
+			conditions = collapseRanges(conditions, s.bounds)
+		}
+		alternatives = append(alternatives, conditions)
+	}
+	if failed {
+		return "", nil
+	}
+	return disjunction(alternatives)
+}
+
+// spannerConversion holds the conditions and params of a filter while its clauses are converted.
+type spannerConversion struct {
+	condAnds   []string
+	params     map[string]any
+	paramIndex int
+	bounds     []rangeBound
+	// Whether identical values share a param; see WithParamDeduplication.
+	dedup bool
+	// Param names in the order they were bound, to look up identical values deterministically.
+	paramNames []string
+	// Bound column expressions by field name, so that the params of an expression are bound once per filter.
+	columnExprs map[string]string
+}
+
+// bind adds the value to the params and returns the name of its param. With deduplication, the param of an identical
+// value bound before is returned instead.
+func (s *spannerConversion) bind(value any) string {
+	if s.dedup {
+		for _, name := range s.paramNames {
+			if reflect.DeepEqual(s.params[name], value) {
+				return name
+			}
+		}
+	}
+	name := fmt.Sprintf("%s%d", "KQL", s.paramIndex)
+	s.params[name] = value
+	s.paramNames = append(s.paramNames, name)
+	s.paramIndex++
+	return name
+}
+
+// bindColumnExpr returns the ColumnExpr of the field with its placeholders replaced by named params.
+func (s *spannerConversion) bindColumnExpr(name string, fieldConfig FilterToSpannerFieldConfig) (string, error) {
+	if expr, ok := s.columnExprs[name]; ok {
+		return expr, nil
+	}
+	expr, err := s.bindPlaceholders(fieldConfig.ColumnExpr, fieldConfig.ColumnExprArgs)
+	if err != nil {
+		return "", err
+	}
+	if s.columnExprs == nil {
+		s.columnExprs = make(map[string]string)
+	}
+	s.columnExprs[name] = expr
+	return expr, nil
+}
 
-		if fieldConfig.Ignore {
+// bindPlaceholders replaces the `?` placeholders of a condition returned by a CustomBuilder or of a ColumnExpr with
+// named params, adding the args to the params in order. `??` is replaced with a literal question mark.
+func (s *spannerConversion) bindPlaceholders(condition string, args []any) (string, error) {
+	var sb strings.Builder
+	n := 0
+	for i := 0; i < len(condition); i++ {
+		if condition[i] != '?' {
+			sb.WriteByte(condition[i])
+			continue
+		}
+		if i+1 < len(condition) && condition[i+1] == '?' {
+			sb.WriteByte('?')
+			i++
 			continue
 		}
+		if n == len(args) {
+			return "", fmt.Errorf("condition %q has more placeholders than the %d params", condition, len(args))
+		}
+		sb.WriteString("@" + s.bind(args[n]))
+		n++
+	}
+	if n != len(args) {
+		return "", fmt.Errorf("condition %q has %d placeholders for %d params", condition, n, len(args))
+	}
+	return sb.String(), nil
+}
 
-		if len(fieldConfig.Requires) > 0 {
-			for _, requiredField := range fieldConfig.Requires {
-				found := false
-				for _, c := range f.Clauses {
-					if c.Field == requiredField || (slices.Contains(fieldConfig.Aliases, c.Field)) {
-						found = true
-						break
-					}
-				}
-				if !found {
-					return nil, nil, fmt.Errorf("%s can only be used in this filter in combination with %s", clause.Field, requiredField)
-				}
+// convertSearchClause converts a clause on a field with a SearchFunction, adding its condition and params to s.
+func (s *spannerConversion) convertSearchClause(clause Clause, fieldConfig FilterToSpannerFieldConfig, columnName string) error {
+	switch clause.Operator {
+	case "=", "!=":
+	case "IN", "NOT IN":
+		if !fieldConfig.AllowMultipleValues {
+			return newFieldError(clause.Field, ErrMultipleValuesNotAllowed, "field %s: %w", clause.Field, ErrMultipleValuesNotAllowed)
+		}
+		if clause.Operator == "NOT IN" && !fieldConfig.AllowNegation {
+			return newFieldError(clause.Field, ErrOperatorNotAllowed, "operator %s not supported for field: %s", clause.Operator, clause.Field)
+		}
+	default:
+		return newFieldError(clause.Field, ErrOperatorNotAllowed, "operator %s not supported for field: %s", clause.Operator, clause.Field)
+	}
+
+	queries := make([]string, 0, len(clause.Values))
+	for _, value := range clause.Values {
+		query := sanitizeSearchQuery(UnescapeValue(value))
+		if query == "" {
+			return newFieldError(clause.Field, ErrValueInvalid, "field %s: search value %q contains no words", clause.Field, value)
+		}
+		queries = append(queries, query)
+	}
+
+	conditions := make([]string, 0, len(queries))
+	for _, query := range queries {
+		conditions = append(conditions, fmt.Sprintf("%s(%s, @%s)", fieldConfig.SearchFunction, columnName, s.bind(query)))
+	}
+	condition := conditions[0]
+	if len(conditions) > 1 {
+		condition = "(" + strings.Join(conditions, " OR ") + ")"
+	}
+	if clause.Operator == "!=" || clause.Operator == "NOT IN" {
+		condition = "NOT " + condition
+	}
+	s.condAnds = append(s.condAnds, condition)
+	return nil
+}
+
+// convertClause converts a clause of the filter f, adding its conditions and params to s.
+func (c *SpannerConverter) convertClause(s *spannerConversion, clause Clause, f Filter, o converterOptions) error {
+	name, ok := c.lookup(clause.Field)
+	fieldConfig := c.fieldConfigs[name]
+	if !ok {
+		if clause.Field == "1" && clause.Operator == "=" && len(clause.Values) == 1 && (clause.Values[0] == "1" || clause.Values[0] == "0") {
+			// Special case for boolean literals
+		} else {
+			fieldNames := c.fieldNames
+			if fieldNames == nil {
+				fieldNames = spannerFieldNames(c.fieldConfigs)
+			}
+			return NewUnknownFieldError(clause.Field, fieldNames)
+		}
+	}
+
+	if fieldConfig.Ignore {
+		return nil
+	}
+
+	if clause.Operator == "~" && fieldConfig.CustomBuilder == nil {
+		return newFieldError(clause.Field, ErrOperatorNotAllowed, "field %s: fuzzy matching is not supported by Spanner", clause.Field)
+	}
+
+	if len(fieldConfig.Requires) > 0 {
+		for _, requiredField := range fieldConfig.Requires {
+			found := f.hasClause(func(other Clause) bool {
+				return other.Field == requiredField || slices.Contains(fieldConfig.Aliases, other.Field)
+			})
+			if !found {
+				return newFieldError(clause.Field, ErrRequiredFieldMissing, "%s can only be used in this filter in combination with %s", clause.Field, requiredField)
 			}
 		}
+	}
 
-		columnName := fieldConfig.ColumnName
-		if columnName == "" {
-			columnName = clause.Field
+	columnName := fieldConfig.ColumnName
+	if columnName == "" {
+		columnName = clause.Field
+	}
+	if fieldConfig.ColumnExpr != "" {
+		expr, err := s.bindColumnExpr(name, fieldConfig)
+		if err != nil {
+			return fmt.Errorf("field %s: column expression: %w", clause.Field, err)
 		}
-		mappedValue, err := fieldConfig.mapValues(clause.Values)
+		columnName = expr
+	}
+	if fieldConfig.CustomBuilder != nil {
+		condition, args, err := fieldConfig.CustomBuilder(columnName, clause.Operator, clause.Values)
 		if err != nil {
-			return nil, nil, fmt.Errorf("field %s: %w", clause.Field, err)
+			return newFieldError(clause.Field, ErrValueInvalid, "field %s: %w", clause.Field, err)
 		}
+		condition, err = s.bindPlaceholders(condition, args)
+		if err != nil {
+			return newFieldError(clause.Field, ErrValueInvalid, "field %s: %w", clause.Field, err)
+		}
+		s.condAnds = append(s.condAnds, condition)
+		return nil
+	}
+	maxValues := fieldConfig.MaxValues
+	if maxValues <= 0 {
+		maxValues = o.maxValues
+	}
+	if maxValues > 0 && len(clause.Values) > maxValues {
+		return newFieldError(clause.Field, ErrMultipleValuesNotAllowed, "field %s: %d values exceed the maximum of %d", clause.Field, len(clause.Values), maxValues)
+	}
 
-		operator := clause.Operator
+	if _, ok := regexPattern(clause); !ok || !fieldConfig.AllowRegex {
+		if err := fieldConfig.checkValueConstraints(clause.Values); err != nil {
+			return newFieldError(clause.Field, ErrValueInvalid, "field %s: %w", clause.Field, err)
+		}
+	}
 
-		if len(clause.Values) > 1 && operator != "IN" {
-			return nil, nil, fmt.Errorf("operator %s doesn't support multiple values in field: %s", operator, clause.Field)
+	if fieldConfig.SearchFunction != FilterToSpannerSearchFunctionNone {
+		return s.convertSearchClause(clause, fieldConfig, columnName)
+	}
+	if pattern, ok := regexPattern(clause); ok && fieldConfig.AllowRegex {
+		if fieldConfig.ColumnType != FilterToSpannerFieldColumnTypeUnspecified && fieldConfig.ColumnType != FilterToSpannerFieldColumnTypeString {
+			return newFieldError(clause.Field, ErrOperatorNotAllowed, "regular expressions not supported for field type %s", fieldConfig.ColumnType)
+		}
+		if err := fieldConfig.checkRegex(pattern); err != nil {
+			return newFieldError(clause.Field, ErrValueInvalid, "field %s: %w", clause.Field, err)
+		}
+		condition := fmt.Sprintf("REGEXP_CONTAINS(%s, @%s)", columnName, s.bind(pattern))
+		if clause.Operator == "!=" {
+			condition = "NOT " + condition
 		}
+		s.condAnds = append(s.condAnds, condition)
+		return nil
+	}
 
-		forceLowercase := false
-		whereClauseFormat := "%s%s@%s"
-		switch operator {
-		case "IN":
-			switch fieldConfig.ColumnType {
-			case FilterToSpannerFieldColumnTypeString:
-				mappedValue, err = parseAnyToSlice[string](mappedValue)
-				if err == nil {
-					mappedValue = uniqueSliceElements(mappedValue.([]string))
-				}
-			case FilterToSpannerFieldColumnTypeInt64:
-				mappedValue, err = parseAnyToSlice[int64](mappedValue)
-				if err == nil {
-					mappedValue = uniqueSliceElements(mappedValue.([]int64))
-				}
-			case FilterToSpannerFieldColumnTypeFloat64:
-				mappedValue, err = parseAnyToSlice[float64](mappedValue)
-				if err == nil {
-					mappedValue = uniqueSliceElements(mappedValue.([]float64))
-				}
-			case FilterToSpannerFieldColumnTypeTimestamp:
-				mappedValue, err = parseAnyToSlice[time.Time](mappedValue)
-				if err == nil {
-					mappedValue = uniqueSliceElements(mappedValue.([]time.Time))
-				}
-			default:
-				return nil, nil, fmt.Errorf("operator %s not supported for field type %s", operator, fieldConfig.ColumnType)
+	// A MapValue function converts the value itself, so its result is never expanded to a day.
+	if fieldConfig.ColumnType == FilterToSpannerFieldColumnTypeTimestamp && clause.Operator == "=" && len(clause.Values) == 1 && fieldConfig.MapValue == nil {
+		if start, end, ok := o.dateRange(clause.Values[0]); ok {
+			if err := checkAllowedValue(clause.Values[0], fieldConfig.AllowedValues); err != nil {
+				return newFieldError(clause.Field, ErrValueInvalid, "field %s: %w", clause.Field, fieldConfig.ValueSuggestions.apply(err))
 			}
+			startParam := s.bind(start)
+			endParam := s.bind(end)
+			s.condAnds = append(s.condAnds, fmt.Sprintf("%s>=@%s", columnName, startParam), fmt.Sprintf("%s<@%s", columnName, endParam))
+			return nil
+		}
+	}
+
+	mappedValue, err := fieldConfig.mapValues(clause.Values, o)
+	if err != nil {
+		kind := ErrValueInvalid
+		if errors.Is(err, ErrMultipleValuesNotAllowed) {
+			kind = ErrMultipleValuesNotAllowed
+		}
+		return newFieldError(clause.Field, kind, "field %s: %w", clause.Field, err)
+	}
+
+	operator := clause.Operator
+
+	if len(clause.Values) > 1 && operator != "IN" && operator != "NOT IN" {
+		return newFieldError(clause.Field, ErrMultipleValuesNotAllowed, "operator %s doesn't support multiple values in field: %s", operator, clause.Field)
+	}
+
+	forceLowercase := false
+	whereClauseFormat := "%s%s@%s"
+	// STARTS_WITH or ENDS_WITH, if the clause is converted to a function call, see WithStartsWith.
+	function := ""
+	switch operator {
+	case "IN", "NOT IN":
+		if operator == "NOT IN" && !(fieldConfig.AllowNegation && fieldConfig.AllowMultipleValues) {
+			return newFieldError(clause.Field, ErrOperatorNotAllowed, "operator %s not supported for field: %s", operator, clause.Field)
+		}
+		switch fieldConfig.ColumnType {
+		case FilterToSpannerFieldColumnTypeBool:
+			// Booleans are matched with an equality per value, like in the other converters.
+			values, err := parseAnyToSlice[bool](mappedValue)
 			if err != nil {
-				return nil, nil, err
+				return newFieldError(clause.Field, ErrValueInvalid, "%w", err)
+			}
+			values = uniqueSliceElements(values)
+			conditions := make([]string, len(values))
+			for i, v := range values {
+				conditions[i] = fmt.Sprintf("%s=@%s", columnName, s.bind(v))
+			}
+			condition := "(" + strings.Join(conditions, " OR ") + ")"
+			if operator == "NOT IN" {
+				condition = "NOT " + condition
+			}
+			s.condAnds = append(s.condAnds, condition)
+			return nil
+		case FilterToSpannerFieldColumnTypeString:
+			mappedValue, err = parseAnyToSlice[string](mappedValue)
+			if err == nil {
+				mappedValue = uniqueSliceElements(unescapeValues(mappedValue.([]string)))
+			}
+		case FilterToSpannerFieldColumnTypeInt64:
+			mappedValue, err = parseAnyToSlice[int64](mappedValue)
+			if err == nil {
+				mappedValue = uniqueSliceElements(mappedValue.([]int64))
+			}
+		case FilterToSpannerFieldColumnTypeFloat64:
+			mappedValue, err = parseAnyToSlice[float64](mappedValue)
+			if err == nil {
+				mappedValue = uniqueSliceElements(mappedValue.([]float64))
 			}
+		case FilterToSpannerFieldColumnTypeTimestamp:
+			mappedValue, err = parseAnyToSlice[time.Time](mappedValue)
+			if err == nil {
+				mappedValue = uniqueSliceElements(mappedValue.([]time.Time))
+			}
+		case FilterToSpannerFieldColumnTypeDate:
+			mappedValue, err = parseAnyToSlice[civil.Date](mappedValue)
+			if err == nil {
+				mappedValue = uniqueSliceElements(mappedValue.([]civil.Date))
+			}
+		case FilterToSpannerFieldColumnTypeNumeric:
+			mappedValue, err = parseAnyToSlice[*big.Rat](mappedValue)
+			if err == nil {
+				mappedValue = uniqueNumerics(mappedValue.([]*big.Rat))
+			}
+		default:
+			return newFieldError(clause.Field, ErrOperatorNotAllowed, "operator %s not supported for field type %s", operator, fieldConfig.ColumnType)
+		}
+		if err != nil {
+			return &FieldError{Field: clause.Field, Kind: ErrValueInvalid, Err: err}
+		}
 
-			whereClauseFormat = "%s %s UNNEST(@%s)"
-		case "=":
-			// Prefix and suffix matching is supported only for single strings
-			mappedString, isString := mappedValue.(string)
-			if isString {
-				needsPrefixMatch := fieldConfig.AllowPrefixMatch && strings.HasSuffix(mappedString, "*") && !strings.HasSuffix(mappedString, "\\*")
-				needsSuffixMatch := fieldConfig.AllowSuffixMatch && strings.HasPrefix(mappedString, "*")
-
-				if needsPrefixMatch && needsSuffixMatch {
-					operator = " LIKE "
-					forceLowercase = true
-					mappedString = escapePrefixSuffixSpecialChars(mappedString)
-					mappedValue = "%" + mappedString[1:len(mappedString)-1] + "%"
-				} else if needsPrefixMatch {
-					operator = " LIKE "
-					forceLowercase = true
-					mappedString = escapePrefixSuffixSpecialChars(mappedString)
-					mappedValue = mappedString[:len(mappedString)-1] + "%"
-				} else if needsSuffixMatch {
-					operator = " LIKE "
-					forceLowercase = true
-					mappedString = escapePrefixSuffixSpecialChars(mappedString)
-					mappedValue = "%" + mappedString[1:]
+		whereClauseFormat = "%s %s UNNEST(@%s)"
+	case "=":
+		// Prefix and suffix matching is supported only for single strings
+		mappedString, isString := mappedValue.(string)
+		if isString {
+			text, needsPrefixMatch, needsSuffixMatch := SplitWildcards(mappedString, fieldConfig.AllowPrefixMatch, fieldConfig.AllowSuffixMatch)
+			if needsPrefixMatch != needsSuffixMatch && o.startsWith {
+				function = "STARTS_WITH"
+				if needsSuffixMatch {
+					function = "ENDS_WITH"
+				}
+				forceLowercase = true
+			} else if needsPrefixMatch || needsSuffixMatch {
+				operator = " LIKE "
+				forceLowercase = true
+				text = escapePrefixSuffixSpecialChars(text)
+				if needsPrefixMatch {
+					text += "%"
+				}
+				if needsSuffixMatch {
+					text = "%" + text
 				}
 			}
-		case ">=", "<=", ">", "<":
-			if !fieldConfig.AllowRanges {
-				return nil, nil, fmt.Errorf("operator %s not supported for field: %s", operator, clause.Field)
-			}
+			mappedValue = text
+		}
+	case "!=":
+		if mappedString, isString := mappedValue.(string); isString {
+			mappedValue = UnescapeValue(mappedString)
+		}
+	case ">=", "<=", ">", "<":
+		if !fieldConfig.AllowRanges {
+			return newFieldError(clause.Field, ErrOperatorNotAllowed, "operator %s not supported for field: %s", operator, clause.Field)
+		}
 
-			switch fieldConfig.ColumnType {
-			case FilterToSpannerFieldColumnTypeInt64, FilterToSpannerFieldColumnTypeFloat64, FilterToSpannerFieldColumnTypeTimestamp:
-				break
-			default:
-				return nil, nil, fmt.Errorf("operator %s not supported for field type %s", operator, fieldConfig.ColumnType)
+		switch fieldConfig.ColumnType {
+		case FilterToSpannerFieldColumnTypeInt64, FilterToSpannerFieldColumnTypeFloat64, FilterToSpannerFieldColumnTypeTimestamp, FilterToSpannerFieldColumnTypeDate, FilterToSpannerFieldColumnTypeNumeric:
+			break
+		case FilterToSpannerFieldColumnTypeString:
+			if !fieldConfig.AllowStringRanges {
+				return newFieldError(clause.Field, ErrOperatorNotAllowed, "operator %s not supported for field type %s", operator, fieldConfig.ColumnType)
 			}
+			if mappedString, isString := mappedValue.(string); isString {
+				mappedValue = UnescapeValue(mappedString)
+			}
+		default:
+			return newFieldError(clause.Field, ErrOperatorNotAllowed, "operator %s not supported for field type %s", operator, fieldConfig.ColumnType)
 		}
+	}
 
-		paramName := fmt.Sprintf("%s%d", "KQL", paramIndex)
+	if fieldConfig.normalizes() {
+		mappedValue = fieldConfig.normalizeValue(mappedValue)
+		if fieldConfig.NormalizeColumn && !isRangeOperator(operator) {
+			columnName = fieldConfig.normalizeColumn(columnName)
+		}
+	}
+
+	paramName := s.bind(mappedValue)
+	if function != "" {
 		if forceLowercase && fieldConfig.AllowCaseInsensitiveMatch {
-			whereClauseFormat = "LOWER(%s)%sLOWER(@%s)"
+			s.condAnds = append(s.condAnds, fmt.Sprintf("%s(LOWER(%s), LOWER(@%s))", function, columnName, paramName))
+		} else {
+			s.condAnds = append(s.condAnds, fmt.Sprintf("%s(%s, @%s)", function, columnName, paramName))
 		}
-		condAnds = append(condAnds, fmt.Sprintf(whereClauseFormat, columnName, operator, paramName))
-		params[paramName] = mappedValue
-		paramIndex++
+		return nil
+	}
+	if forceLowercase && fieldConfig.AllowCaseInsensitiveMatch {
+		whereClauseFormat = "LOWER(%s)%sLOWER(@%s)"
+	}
+	if o.collapseRanges && (operator == ">=" || operator == "<=") {
+		s.bounds = append(s.bounds, rangeBound{column: columnName, operator: operator, param: paramName, index: len(s.condAnds)})
 	}
+	s.condAnds = append(s.condAnds, fmt.Sprintf(whereClauseFormat, columnName, operator, paramName))
+	return nil
+}
 
-	for field, fieldConfig := range fieldConfigs {
-		if fieldConfig.Required {
-			found := false
-			for _, clause := range f.Clauses {
-				if clause.Field == field || (slices.Contains(fieldConfig.Aliases, clause.Field)) {
-					found = true
-					break
-				}
-			}
-			if !found {
-				return nil, nil, fmt.Errorf("required field %s missing", field)
-			}
+// rangeBound is an inclusive range condition that may be collapsed into a BETWEEN condition.
+type rangeBound struct {
+	column   string
+	operator string
+	param    string
+	// Index of the condition.
+	index int
+}
+
+// collapseRanges replaces each pair of conditions that are the only inclusive lower and upper bound of a column by a
+// single BETWEEN condition.
+func collapseRanges(condAnds []string, bounds []rangeBound) []string {
+	byColumn := make(map[string][]rangeBound)
+	for _, b := range bounds {
+		byColumn[b.column] = append(byColumn[b.column], b)
+	}
+	removed := make(map[int]bool)
+	for column, bs := range byColumn {
+		if len(bs) != 2 || bs[0].operator == bs[1].operator {
+			continue
+		}
+		lower, upper := bs[0], bs[1]
+		if lower.operator == "<=" {
+			lower, upper = upper, lower
+		}
+		condAnds[bs[0].index] = fmt.Sprintf("%s BETWEEN @%s AND @%s", column, lower.param, upper.param)
+		removed[bs[1].index] = true
+	}
+	if len(removed) == 0 {
+		return condAnds
+	}
+	result := make([]string, 0, len(condAnds)-len(removed))
+	for i, cond := range condAnds {
+		if !removed[i] {
+			result = append(result, cond)
 		}
 	}
+	return result
+}
 
-	return condAnds, params, nil
+// spannerFieldNames returns all field names and aliases that can be used with the field configs.
+func spannerFieldNames(fieldConfigs map[string]FilterToSpannerFieldConfig) []string {
+	names := make([]string, 0, len(fieldConfigs))
+	for name, fc := range fieldConfigs {
+		names = append(names, name)
+		names = append(names, fc.Aliases...)
+	}
+	return names
+}
+
+// requiredSpannerFields returns the names of the fields that must be present in the filter.
+func requiredSpannerFields(fieldConfigs map[string]FilterToSpannerFieldConfig) []string {
+	var required []string
+	for name, fc := range fieldConfigs {
+		if fc.Required {
+			required = append(required, name)
+		}
+	}
+	return required
 }
 
 func parseAnyToSlice[T any](s any) ([]T, error) {
@@ -466,6 +1116,169 @@ func uniqueSliceElements[T comparable](inputSlice []T) []T {
 	return uniqueSlice
 }
 
+// uniqueNumerics removes duplicate numbers, which uniqueSliceElements can't compare.
+func uniqueNumerics(values []*big.Rat) []*big.Rat {
+	unique := make([]*big.Rat, 0, len(values))
+	seen := make(map[string]bool, len(values))
+	for _, v := range values {
+		key := v.RatString()
+		if !seen[key] {
+			unique = append(unique, v)
+			seen[key] = true
+		}
+	}
+	return unique
+}
+
+// parseNumeric parses a decimal number into an exact value that fits in a Spanner NUMERIC column,
+// i.e. with at most 29 digits before and 9 digits after the decimal point.
+func parseNumeric(value string) (*big.Rat, error) {
+	digits := strings.TrimPrefix(strings.TrimPrefix(value, "-"), "+")
+	integer, fraction, _ := strings.Cut(digits, ".")
+	if integer == "" && fraction == "" || strings.Trim(integer+fraction, "0123456789") != "" {
+		return nil, fmt.Errorf("%q is not a decimal number", value)
+	}
+	if len(strings.TrimLeft(integer, "0")) > 29 || len(fraction) > 9 {
+		return nil, fmt.Errorf("%q exceeds the precision of 29 integer and 9 fractional digits", value)
+	}
+	r, ok := new(big.Rat).SetString(value)
+	if !ok {
+		return nil, fmt.Errorf("%q is not a decimal number", value)
+	}
+	return r, nil
+}
+
+// unescapeValues returns the literal text of the values, see UnescapeValue.
+func unescapeValues(values []string) []string {
+	unescaped := make([]string, len(values))
+	for i, v := range values {
+		unescaped[i] = UnescapeValue(v)
+	}
+	return unescaped
+}
+
+// regexPattern returns the regular expression of an equality clause with a value between slashes (`/pattern/`).
+func regexPattern(clause Clause) (string, bool) {
+	if (clause.Operator != "=" && clause.Operator != "!=") || len(clause.Values) != 1 {
+		return "", false
+	}
+	value := UnescapeValue(clause.Values[0])
+	if len(value) < 2 || !strings.HasPrefix(value, "/") || !strings.HasSuffix(value, "/") {
+		return "", false
+	}
+	return value[1 : len(value)-1], true
+}
+
+// normalizes reports whether string values of the field are normalized or case-folded.
+func (f FilterToSpannerFieldConfig) normalizes() bool {
+	if f.ColumnType != FilterToSpannerFieldColumnTypeUnspecified && f.ColumnType != FilterToSpannerFieldColumnTypeString {
+		return false
+	}
+	return f.Normalization != FilterToSpannerNormalizationNone || f.CaseFold
+}
+
+// normalizationForm returns the normalization form of the field, which defaults to NFC when only case folding.
+func (f FilterToSpannerFieldConfig) normalizationForm() FilterToSpannerNormalization {
+	if f.Normalization == FilterToSpannerNormalizationNone {
+		return FilterToSpannerNormalizationNFC
+	}
+	return f.Normalization
+}
+
+// normalizeValue normalizes and case-folds a string or a slice of strings. Values of other types, as returned by
+// MapValue, are returned as is.
+func (f FilterToSpannerFieldConfig) normalizeValue(value any) any {
+	normalize := func(s string) string {
+		if f.CaseFold {
+			s = cases.Fold().String(s)
+		}
+		if f.normalizationForm() == FilterToSpannerNormalizationNFKC {
+			return norm.NFKC.String(s)
+		}
+		return norm.NFC.String(s)
+	}
+	switch v := value.(type) {
+	case string:
+		return normalize(v)
+	case []string:
+		normalized := make([]string, len(v))
+		for i, s := range v {
+			normalized[i] = normalize(s)
+		}
+		return uniqueSliceElements(normalized)
+	default:
+		return value
+	}
+}
+
+// normalizeColumn wraps the column in the Spanner function that normalizes it like normalizeValue.
+func (f FilterToSpannerFieldConfig) normalizeColumn(column string) string {
+	if f.CaseFold {
+		return fmt.Sprintf("NORMALIZE_AND_CASEFOLD(%s, %s)", column, f.normalizationForm())
+	}
+	return fmt.Sprintf("NORMALIZE(%s, %s)", column, f.normalizationForm())
+}
+
+// isRangeOperator reports whether the operator is one of the range operators of a clause.
+func isRangeOperator(operator string) bool {
+	switch operator {
+	case ">", ">=", "<", "<=":
+		return true
+	default:
+		return false
+	}
+}
+
+// defaultMaxRegexLength is the default of FilterToSpannerFieldConfig.MaxRegexLength.
+const defaultMaxRegexLength = 256
+
+// checkRegex returns an error if the pattern is too long or not a valid RE2 regular expression.
+func (f FilterToSpannerFieldConfig) checkRegex(pattern string) error {
+	maxLength := f.MaxRegexLength
+	if maxLength <= 0 {
+		maxLength = defaultMaxRegexLength
+	}
+	if len(pattern) > maxLength {
+		return fmt.Errorf("regular expression exceeds maximum length of %d", maxLength)
+	}
+	if _, err := regexp.Compile(pattern); err != nil {
+		return fmt.Errorf("invalid regular expression: %w", err)
+	}
+	return nil
+}
+
+// checkValueConstraints returns a ValueConstraintError for the first value that exceeds MaxLength or doesn't match
+// Pattern.
+func (f FilterToSpannerFieldConfig) checkValueConstraints(values []string) error {
+	if f.MaxLength <= 0 && f.Pattern == nil {
+		return nil
+	}
+	for _, value := range values {
+		text, _, _ := SplitWildcards(value, f.AllowPrefixMatch, f.AllowSuffixMatch)
+		if f.MaxLength > 0 && utf8.RuneCountInString(text) > f.MaxLength {
+			return &ValueConstraintError{Value: text, MaxLength: f.MaxLength}
+		}
+		if f.Pattern != nil && !f.Pattern.MatchString(text) {
+			return &ValueConstraintError{Value: text, Pattern: f.Pattern}
+		}
+	}
+	return nil
+}
+
+// sanitizeSearchQuery removes the operators of the Spanner search query syntax from a value, so that its words are
+// searched for as plain terms. Tokenization is case-insensitive, so the value is lowercased to turn the `OR` and
+// `AROUND` operators into plain words.
+func sanitizeSearchQuery(value string) string {
+	words := strings.FieldsFunc(strings.ToLower(value), func(r rune) bool {
+		return unicode.IsSpace(r) || r == '"' || r == '(' || r == ')'
+	})
+	for i, word := range words {
+		words[i] = strings.TrimLeft(word, "-")
+	}
+	words = slices.DeleteFunc(words, func(word string) bool { return word == "" })
+	return strings.Join(words, " ")
+}
+
 func escapePrefixSuffixSpecialChars(s string) string {
 	s = strings.ReplaceAll(s, `\`, `\\`)
 	s = strings.ReplaceAll(s, `_`, `\_`)
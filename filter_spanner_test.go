@@ -2,10 +2,15 @@ package kqlfilter
 
 import (
 	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
 
+	"cloud.google.com/go/civil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -131,6 +136,49 @@ func TestToSpannerSQL(t *testing.T) {
 				"KQL0": "70\\%%",
 			},
 		},
+		{
+			"escaped asterisk with wildcard suffix allowed",
+			`discount_string:70\*`, map[string]FilterToSpannerFieldConfig{
+				"discount_string": {
+					ColumnType:       FilterToSpannerFieldColumnTypeString,
+					AllowPrefixMatch: true,
+				},
+			},
+			false,
+			"(discount_string=@KQL0)",
+			map[string]any{
+				"KQL0": "70*",
+			},
+		},
+		{
+			"escaped asterisk in list of values",
+			`discount_string:(team\* or 70\*)`, map[string]FilterToSpannerFieldConfig{
+				"discount_string": {
+					ColumnType:          FilterToSpannerFieldColumnTypeString,
+					AllowPrefixMatch:    true,
+					AllowMultipleValues: true,
+				},
+			},
+			false,
+			"(discount_string IN UNNEST(@KQL0))",
+			map[string]any{
+				"KQL0": []string{"team*", "70*"},
+			},
+		},
+		{
+			"escaped asterisk followed by wildcard",
+			`discount_string:70\**`, map[string]FilterToSpannerFieldConfig{
+				"discount_string": {
+					ColumnType:       FilterToSpannerFieldColumnTypeString,
+					AllowPrefixMatch: true,
+				},
+			},
+			false,
+			"(discount_string LIKE @KQL0)",
+			map[string]any{
+				"KQL0": "70*%",
+			},
+		},
 		{
 			"one integer field and one string field with wildcards allowed, illegal wildcard in middle",
 			"userId:12345 email:*example*com", map[string]FilterToSpannerFieldConfig{
@@ -436,6 +484,92 @@ func TestToSpannerSQL(t *testing.T) {
 				"KQL3": time.Date(2023, time.June, 1, 23, 0, 0, 200000000, time.UTC),
 			},
 		},
+		{
+			"negated range operator",
+			"not userId>=12345", map[string]FilterToSpannerFieldConfig{
+				"userId": {
+					ColumnName:  "user_id",
+					ColumnType:  FilterToSpannerFieldColumnTypeInt64,
+					AllowRanges: true,
+				},
+			},
+			false,
+			"(user_id<@KQL0)",
+			map[string]any{
+				"KQL0": int64(12345),
+			},
+		},
+		{
+			"date field with range and multiple values",
+			"day>=2024-05-01 and release:(2024-05-01 OR 2024-06-01)", map[string]FilterToSpannerFieldConfig{
+				"day": {
+					ColumnType:  FilterToSpannerFieldColumnTypeDate,
+					AllowRanges: true,
+				},
+				"release": {
+					ColumnType:          FilterToSpannerFieldColumnTypeDate,
+					AllowMultipleValues: true,
+				},
+			},
+			false,
+			"(day>=@KQL0 AND release IN UNNEST(@KQL1))",
+			map[string]any{
+				"KQL0": civil.Date{Year: 2024, Month: time.May, Day: 1},
+				"KQL1": []civil.Date{{Year: 2024, Month: time.May, Day: 1}, {Year: 2024, Month: time.June, Day: 1}},
+			},
+		},
+		{
+			"invalid date value",
+			"day:2024-13-01", map[string]FilterToSpannerFieldConfig{
+				"day": {
+					ColumnType: FilterToSpannerFieldColumnTypeDate,
+				},
+			},
+			true,
+			"",
+			map[string]any{},
+		},
+		{
+			"numeric field with range and multiple values",
+			"amount>=10.25 and price:(0.1 OR 0.10 OR -3)", map[string]FilterToSpannerFieldConfig{
+				"amount": {
+					ColumnType:  FilterToSpannerFieldColumnTypeNumeric,
+					AllowRanges: true,
+				},
+				"price": {
+					ColumnType:          FilterToSpannerFieldColumnTypeNumeric,
+					AllowMultipleValues: true,
+				},
+			},
+			false,
+			"(amount>=@KQL0 AND price IN UNNEST(@KQL1))",
+			map[string]any{
+				"KQL0": big.NewRat(41, 4),
+				"KQL1": []*big.Rat{big.NewRat(1, 10), big.NewRat(-3, 1)},
+			},
+		},
+		{
+			"numeric value with too many fractional digits",
+			"amount:0.0000000001", map[string]FilterToSpannerFieldConfig{
+				"amount": {
+					ColumnType: FilterToSpannerFieldColumnTypeNumeric,
+				},
+			},
+			true,
+			"",
+			map[string]any{},
+		},
+		{
+			"numeric value as a fraction",
+			"amount:1/3", map[string]FilterToSpannerFieldConfig{
+				"amount": {
+					ColumnType: FilterToSpannerFieldColumnTypeNumeric,
+				},
+			},
+			true,
+			"",
+			map[string]any{},
+		},
 		{
 			"try a range operator on a field that does not support it",
 			"userId>=12345 date<=\"2023-06-01T23:00:00.20Z\"", map[string]FilterToSpannerFieldConfig{
@@ -515,6 +649,62 @@ func TestToSpannerSQL(t *testing.T) {
 				"KQL0": []string{"active", "Active"},
 			},
 		},
+		{
+			"not in query",
+			"not state:(active OR canceled OR active)", map[string]FilterToSpannerFieldConfig{
+				"state": {
+					ColumnType:          FilterToSpannerFieldColumnTypeString,
+					AllowMultipleValues: true,
+					AllowNegation:       true,
+				},
+			},
+			false,
+			"(state NOT IN UNNEST(@KQL0))",
+			map[string]any{
+				"KQL0": []string{"active", "canceled"},
+			},
+		},
+		{
+			"not in query with int64 values",
+			"not team_id:(1 OR 2) and state:active", map[string]FilterToSpannerFieldConfig{
+				"team_id": {
+					ColumnType:          FilterToSpannerFieldColumnTypeInt64,
+					AllowMultipleValues: true,
+					AllowNegation:       true,
+				},
+				"state": {},
+			},
+			false,
+			"(team_id NOT IN UNNEST(@KQL0) AND state=@KQL1)",
+			map[string]any{
+				"KQL0": []int64{1, 2},
+				"KQL1": "active",
+			},
+		},
+		{
+			"not in query - negation disabled",
+			"not state:(active OR canceled)", map[string]FilterToSpannerFieldConfig{
+				"state": {
+					ColumnType:          FilterToSpannerFieldColumnTypeString,
+					AllowMultipleValues: true,
+				},
+			},
+			true,
+			"",
+			nil,
+		},
+		{
+			"not in query - multiple values disabled",
+			"not state:(active OR canceled)", map[string]FilterToSpannerFieldConfig{
+				"state": {
+					ColumnType:    FilterToSpannerFieldColumnTypeString,
+					AllowNegation: true,
+				},
+			},
+			true,
+			"",
+			nil,
+		},
 		{
 			"in query - disabled",
 			"state:(active OR canceled)", map[string]FilterToSpannerFieldConfig{
@@ -561,9 +751,28 @@ func TestToSpannerSQL(t *testing.T) {
 					AllowMultipleValues: true,
 				},
 			},
-			true, // operator IN not supported for field type BOOL
-			"",
-			map[string]any{},
+			false,
+			"((UserID=@KQL0 OR UserID=@KQL1))",
+			map[string]any{
+				"KQL0": true,
+				"KQL1": false,
+			},
+		},
+		{
+			"not in query - bool",
+			"not user_id:(true OR true)", map[string]FilterToSpannerFieldConfig{
+				"user_id": {
+					ColumnName:          "UserID",
+					ColumnType:          FilterToSpannerFieldColumnTypeBool,
+					AllowMultipleValues: true,
+					AllowNegation:       true,
+				},
+			},
+			false,
+			"(NOT (UserID=@KQL0))",
+			map[string]any{
+				"KQL0": true,
+			},
 		},
 		{
 			"required field - field present",
@@ -683,3 +892,852 @@ func TestToSpannerSQL(t *testing.T) {
 		})
 	}
 }
+
+func TestNewSpannerConverter(t *testing.T) {
+	configs := map[string]FilterToSpannerFieldConfig{
+		"user_id": {ColumnType: FilterToSpannerFieldColumnTypeInt64, Aliases: []string{"userId"}},
+		"email":   {AllowPrefixMatch: true, Requires: []string{"user_id"}},
+	}
+	c, err := NewSpannerConverter(configs)
+	require.NoError(t, err)
+
+	for _, input := range []string{"userId:1 email:john*", "user_id:2", "true", "email:john"} {
+		f, err := Parse(input)
+		require.NoError(t, err)
+
+		expectedConds, expectedParams, expectedErr := f.ToSpannerSQL(configs)
+		conds, params, err := c.Convert(f)
+		assert.Equal(t, expectedErr, err, input)
+		assert.Equal(t, expectedConds, conds, input)
+		assert.Equal(t, expectedParams, params, input)
+	}
+
+	_, err = NewSpannerConverter(map[string]FilterToSpannerFieldConfig{
+		"a": {Aliases: []string{"x"}},
+		"b": {Aliases: []string{"x"}},
+	})
+	assert.ErrorContains(t, err, "alias x is used by fields")
+
+	_, err = NewSpannerConverter(map[string]FilterToSpannerFieldConfig{
+		"a": {Aliases: []string{"b"}},
+		"b": {},
+	})
+	assert.EqualError(t, err, "alias b of field a shadows field b")
+
+	_, err = NewSpannerConverter(map[string]FilterToSpannerFieldConfig{
+		"a": {Requires: []string{"c"}},
+	})
+	assert.EqualError(t, err, "field a requires unknown field c")
+
+	_, err = NewSpannerConverter(map[string]FilterToSpannerFieldConfig{
+		"sku": {ColumnExpr: "JSON_VALUE(meta, ?)"},
+	})
+	assert.EqualError(t, err, `field sku: column expression: condition "JSON_VALUE(meta, ?)" has more placeholders than the 0 params`)
+}
+
+func BenchmarkSpannerConverter(b *testing.B) {
+	configs := make(map[string]FilterToSpannerFieldConfig)
+	for i := 0; i < 50; i++ {
+		name := fmt.Sprintf("field_%d", i)
+		configs[name] = FilterToSpannerFieldConfig{Aliases: []string{name + "_alias"}}
+	}
+	f, err := Parse("field_1_alias:a field_49_alias:b")
+	require.NoError(b, err)
+
+	b.Run("ToSpannerSQL", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_, _, _ = f.ToSpannerSQL(configs)
+		}
+	})
+	b.Run("SpannerConverter", func(b *testing.B) {
+		c, err := NewSpannerConverter(configs)
+		require.NoError(b, err)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			_, _, _ = c.Convert(f)
+		}
+	})
+}
+
+func TestToSpannerSQLCollapseRanges(t *testing.T) {
+	configs := map[string]FilterToSpannerFieldConfig{
+		"ts":    {ColumnName: "create_time", ColumnType: FilterToSpannerFieldColumnTypeInt64, AllowRanges: true},
+		"price": {ColumnType: FilterToSpannerFieldColumnTypeFloat64, AllowRanges: true},
+		"state": {},
+	}
+	testCases := []struct {
+		input    string
+		expected []string
+	}{
+		{"ts >= 1 and state:a and ts <= 5", []string{"create_time BETWEEN @KQL0 AND @KQL2", "state=@KQL1"}},
+		{"ts <= 5 and ts >= 1", []string{"create_time BETWEEN @KQL1 AND @KQL0"}},
+		{"ts >= 1 and ts < 5", []string{"create_time>=@KQL0", "create_time<@KQL1"}},
+		{"ts >= 1 and price <= 5", []string{"create_time>=@KQL0", "price<=@KQL1"}},
+		{"ts >= 1 and ts >= 2", []string{"create_time>=@KQL0", "create_time>=@KQL1"}},
+	}
+	for _, test := range testCases {
+		f, err := Parse(test.input)
+		require.NoError(t, err)
+		conditions, params, err := f.ToSpannerSQL(configs, WithCollapseRanges())
+		require.NoError(t, err)
+		assert.Equal(t, test.expected, conditions, test.input)
+		assert.Len(t, params, len(f.Clauses), test.input)
+	}
+}
+
+func TestToSpannerSQLParamDeduplication(t *testing.T) {
+	configs := map[string]FilterToSpannerFieldConfig{
+		"owner":    {},
+		"assignee": {},
+		"state":    {ColumnType: FilterToSpannerFieldColumnTypeString, AllowMultipleValues: true},
+		"from":     {ColumnType: FilterToSpannerFieldColumnTypeInt64, AllowRanges: true},
+		"to":       {ColumnType: FilterToSpannerFieldColumnTypeInt64, AllowRanges: true},
+		"custom": {CustomBuilder: func(columnName string, operator string, values []string) (string, []any, error) {
+			return "(owner=? OR assignee=?)", []any{values[0], values[0]}, nil
+		}},
+	}
+	f, err := Parse("owner:jon assignee:jon state:(a or b) from>=5 to<=5 custom:jon")
+	require.NoError(t, err)
+
+	conditions, params, err := f.ToSpannerSQL(configs, WithParamDeduplication(), WithSoftDelete("deleted", false))
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"owner=@KQL0", "assignee=@KQL0", "state IN UNNEST(@KQL1)", "from>=@KQL2", "to<=@KQL2",
+		"(owner=@KQL0 OR assignee=@KQL0)", "deleted=@KQL3",
+	}, conditions)
+	assert.Equal(t, map[string]any{"KQL0": "jon", "KQL1": []string{"a", "b"}, "KQL2": int64(5), "KQL3": false}, params)
+
+	// Values of different types are not shared.
+	f, err = Parse("owner:5 from:5")
+	require.NoError(t, err)
+	conditions, params, err = f.ToSpannerSQL(configs, WithParamDeduplication())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"owner=@KQL0", "from=@KQL1"}, conditions)
+	assert.Len(t, params, 2)
+
+	_, params, err = f.ToSpannerSQL(configs)
+	require.NoError(t, err)
+	assert.Len(t, params, 2)
+}
+
+func TestToSpannerSQLStartsWith(t *testing.T) {
+	configs := map[string]FilterToSpannerFieldConfig{
+		"email": {AllowPrefixMatch: true, AllowSuffixMatch: true},
+		"name":  {AllowPrefixMatch: true, AllowCaseInsensitiveMatch: true},
+		"code":  {AllowPrefixMatch: true, AllowSuffixMatch: true},
+	}
+	f, err := Parse(`email:*@example.com name:jo* code:100%_* code:*a_b*`)
+	require.NoError(t, err)
+
+	conditions, params, err := f.ToSpannerSQL(configs, WithStartsWith())
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"ENDS_WITH(email, @KQL0)",
+		"STARTS_WITH(LOWER(name), LOWER(@KQL1))",
+		"STARTS_WITH(code, @KQL2)",
+		"code LIKE @KQL3",
+	}, conditions)
+	assert.Equal(t, map[string]any{"KQL0": "@example.com", "KQL1": "jo", "KQL2": "100%_", "KQL3": `%a\_b%`}, params)
+}
+
+func TestToSpannerSQLErrors(t *testing.T) {
+	configs := map[string]FilterToSpannerFieldConfig{
+		"user_id": {Required: true},
+		"age":     {ColumnType: FilterToSpannerFieldColumnTypeInt64},
+		"state":   {AllowedValues: []string{"active", "inactive"}},
+		"team":    {Requires: []string{"league"}},
+		"league":  {},
+	}
+	testCases := []struct {
+		input string
+		field string
+		kind  error
+	}{
+		{"user_id:1 usr:2", "usr", ErrUnknownField},
+		{"user_id:1 age > 18", "age", ErrOperatorNotAllowed},
+		{"user_id:1 age:abc", "age", ErrValueInvalid},
+		{"user_id:1 state:deleted", "state", ErrValueInvalid},
+		{"user_id:1 state:(active or inactive)", "state", ErrMultipleValuesNotAllowed},
+		{"user_id:1 team:ajax", "team", ErrRequiredFieldMissing},
+		{"age:18", "user_id", ErrRequiredFieldMissing},
+	}
+	for _, test := range testCases {
+		f, err := Parse(test.input)
+		require.NoError(t, err)
+		_, _, err = f.ToSpannerSQL(configs)
+		require.ErrorIs(t, err, test.kind, test.input)
+
+		if test.kind == ErrUnknownField {
+			var unknownFieldErr *UnknownFieldError
+			require.ErrorAs(t, err, &unknownFieldErr, test.input)
+			assert.Equal(t, test.field, unknownFieldErr.Field, test.input)
+			continue
+		}
+		var fieldErr *FieldError
+		require.ErrorAs(t, err, &fieldErr, test.input)
+		assert.Equal(t, test.field, fieldErr.Field, test.input)
+	}
+
+	f, err := Parse("user_id:1 age:abc")
+	require.NoError(t, err)
+	_, _, err = f.ToSpannerSQL(configs)
+	assert.EqualError(t, err, `field age: invalid INT64 value: strconv.ParseInt: parsing "abc": invalid syntax`)
+	var numErr *strconv.NumError
+	assert.ErrorAs(t, err, &numErr)
+}
+
+func TestToSpannerSQLWithCondition(t *testing.T) {
+	configs := map[string]FilterToSpannerFieldConfig{
+		"state": {},
+	}
+	options := []ConverterOption{
+		WithCondition("tenant_id=@tenant", map[string]any{"tenant": "t1"}),
+		WithCondition("deleted_at IS NULL", nil),
+	}
+
+	f, err := Parse("state:active")
+	require.NoError(t, err)
+	conditions, params, err := f.ToSpannerSQL(configs, options...)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"state=@KQL0", "tenant_id=@tenant", "deleted_at IS NULL"}, conditions)
+	assert.Equal(t, map[string]any{"KQL0": "active", "tenant": "t1"}, params)
+
+	conditions, params, err = Filter{}.ToSpannerSQL(configs, options...)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"tenant_id=@tenant", "deleted_at IS NULL"}, conditions)
+	assert.Equal(t, map[string]any{"tenant": "t1"}, params)
+
+	_, _, err = f.ToSpannerSQL(configs, WithCondition("id=@KQL0", map[string]any{"KQL0": 1}))
+	assert.EqualError(t, err, `param KQL0 of condition "id=@KQL0" conflicts with the params of the filter`)
+}
+
+func TestToSpannerSQLRegex(t *testing.T) {
+	configs := map[string]FilterToSpannerFieldConfig{
+		"name":   {AllowRegex: true, MaxRegexLength: 20},
+		"email":  {},
+		"age":    {ColumnType: FilterToSpannerFieldColumnTypeInt64, AllowRegex: true},
+		"status": {AllowRegex: true, AllowedValues: []string{"active"}},
+	}
+
+	testCases := []struct {
+		name           string
+		input          string
+		expectedSQL    []string
+		expectedParams map[string]any
+		expectedError  string
+		expectedKind   error
+	}{
+		{
+			name:           "regex",
+			input:          `name:/^jo.*n$/`,
+			expectedSQL:    []string{"REGEXP_CONTAINS(name, @KQL0)"},
+			expectedParams: map[string]any{"KQL0": "^jo.*n$"},
+		},
+		{
+			name:           "quoted regex with escapes",
+			input:          `name:"/^(a|b)\\d+$/"`,
+			expectedSQL:    []string{"REGEXP_CONTAINS(name, @KQL0)"},
+			expectedParams: map[string]any{"KQL0": `^(a|b)\d+$`},
+		},
+		{
+			name:           "negated regex",
+			input:          `not name:/^a/ status:active`,
+			expectedSQL:    []string{"NOT REGEXP_CONTAINS(name, @KQL0)", "status=@KQL1"},
+			expectedParams: map[string]any{"KQL0": "^a", "KQL1": "active"},
+		},
+		{
+			name:           "allowed values do not apply to patterns",
+			input:          `status:/^act/`,
+			expectedSQL:    []string{"REGEXP_CONTAINS(status, @KQL0)"},
+			expectedParams: map[string]any{"KQL0": "^act"},
+		},
+		{
+			name:           "regex not allowed",
+			input:          `email:/^a/`,
+			expectedSQL:    []string{"email=@KQL0"},
+			expectedParams: map[string]any{"KQL0": "/^a/"},
+		},
+		{
+			name:          "invalid regex",
+			input:         `name:"/^(a/"`,
+			expectedError: "field name: invalid regular expression: error parsing regexp: missing closing ): `^(a`",
+			expectedKind:  ErrValueInvalid,
+		},
+		{
+			name:          "regex too long",
+			input:         `name:/aaaaaaaaaaaaaaaaaaaaa/`,
+			expectedError: "field name: regular expression exceeds maximum length of 20",
+			expectedKind:  ErrValueInvalid,
+		},
+		{
+			name:          "regex on non-string field",
+			input:         `age:/^1/`,
+			expectedError: "regular expressions not supported for field type INT64",
+			expectedKind:  ErrOperatorNotAllowed,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			f, err := Parse(test.input)
+			require.NoError(t, err)
+			sql, params, err := f.ToSpannerSQL(configs)
+			if test.expectedError != "" {
+				assert.EqualError(t, err, test.expectedError)
+				assert.ErrorIs(t, err, test.expectedKind)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedSQL, sql)
+			assert.Equal(t, test.expectedParams, params)
+		})
+	}
+}
+
+func TestToSpannerSQLCustomBuilder(t *testing.T) {
+	configs := map[string]FilterToSpannerFieldConfig{
+		"title": {
+			ColumnName: "title_tokens",
+			CustomBuilder: func(column string, operator string, values []string) (string, []any, error) {
+				if operator != "=" && operator != "~" {
+					return "", nil, fmt.Errorf("operator %s not supported", operator)
+				}
+				return fmt.Sprintf("SEARCH(%s, ?)", column), []any{values[0]}, nil
+			},
+		},
+		"location": {
+			CustomBuilder: func(column string, operator string, values []string) (string, []any, error) {
+				return fmt.Sprintf("ST_DWITHIN(%s, ST_GEOGPOINT(?, ?), 1000) AND name != '??'", column), []any{1.5, 2.5}, nil
+			},
+		},
+		"broken": {
+			CustomBuilder: func(column string, operator string, values []string) (string, []any, error) {
+				return column + "=?", nil, nil
+			},
+		},
+		"state": {},
+	}
+
+	testCases := []struct {
+		name           string
+		input          string
+		expectedSQL    []string
+		expectedParams map[string]any
+		expectedError  string
+	}{
+		{
+			name:           "search",
+			input:          `title:"hello world" state:active`,
+			expectedSQL:    []string{"SEARCH(title_tokens, @KQL0)", "state=@KQL1"},
+			expectedParams: map[string]any{"KQL0": "hello world", "KQL1": "active"},
+		},
+		{
+			name:           "fuzzy",
+			input:          `title:helo~1`,
+			expectedSQL:    []string{"SEARCH(title_tokens, @KQL0)"},
+			expectedParams: map[string]any{"KQL0": "helo"},
+		},
+		{
+			name:           "multiple params and literal question mark",
+			input:          `state:active location:here`,
+			expectedSQL:    []string{"state=@KQL0", "ST_DWITHIN(location, ST_GEOGPOINT(@KQL1, @KQL2), 1000) AND name != '?'"},
+			expectedParams: map[string]any{"KQL0": "active", "KQL1": 1.5, "KQL2": 2.5},
+		},
+		{
+			name:          "builder error",
+			input:         `title>a`,
+			expectedError: "field title: operator > not supported",
+		},
+		{
+			name:          "placeholders without params",
+			input:         `broken:a`,
+			expectedError: `field broken: condition "broken=?" has more placeholders than the 0 params`,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			ast, err := ParseAST(test.input, WithFuzzyMatching())
+			require.NoError(t, err)
+			f, err := convertToFilter(ast)
+			require.NoError(t, err)
+			sql, params, err := f.ToSpannerSQL(configs)
+			if test.expectedError != "" {
+				assert.EqualError(t, err, test.expectedError)
+				assert.ErrorIs(t, err, ErrValueInvalid)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedSQL, sql)
+			assert.Equal(t, test.expectedParams, params)
+		})
+	}
+}
+
+func TestToSpannerSQLSearch(t *testing.T) {
+	configs := map[string]FilterToSpannerFieldConfig{
+		"title": {
+			ColumnName:          "Title_Tokens",
+			SearchFunction:      FilterToSpannerSearchFunctionSearch,
+			AllowMultipleValues: true,
+		},
+		"name": {
+			ColumnName:     "Name_Substring_Tokens",
+			SearchFunction: FilterToSpannerSearchFunctionSearchSubstring,
+		},
+		"state": {},
+	}
+
+	testCases := []struct {
+		name           string
+		input          string
+		expectedSQL    []string
+		expectedParams map[string]any
+		expectedError  string
+		expectedKind   error
+	}{
+		{
+			name:           "search",
+			input:          `title:"Hello World" state:active`,
+			expectedSQL:    []string{"SEARCH(Title_Tokens, @KQL0)", "state=@KQL1"},
+			expectedParams: map[string]any{"KQL0": "hello world", "KQL1": "active"},
+		},
+		{
+			name:           "search substring",
+			input:          `name:jon`,
+			expectedSQL:    []string{"SEARCH_SUBSTRING(Name_Substring_Tokens, @KQL0)"},
+			expectedParams: map[string]any{"KQL0": "jon"},
+		},
+		{
+			name:           "negated",
+			input:          `not name:jon`,
+			expectedSQL:    []string{"NOT SEARCH_SUBSTRING(Name_Substring_Tokens, @KQL0)"},
+			expectedParams: map[string]any{"KQL0": "jon"},
+		},
+		{
+			name:           "multiple values",
+			input:          `title:(foo or bar)`,
+			expectedSQL:    []string{"(SEARCH(Title_Tokens, @KQL0) OR SEARCH(Title_Tokens, @KQL1))"},
+			expectedParams: map[string]any{"KQL0": "foo", "KQL1": "bar"},
+		},
+		{
+			name:           "search operators are removed",
+			input:          `title:"\"a b\" OR -c AROUND(3) (d)"`,
+			expectedSQL:    []string{"SEARCH(Title_Tokens, @KQL0)"},
+			expectedParams: map[string]any{"KQL0": "a b or c around 3 d"},
+		},
+		{
+			name:          "no words",
+			input:         `title:"- ()"`,
+			expectedError: `field title: search value "- ()" contains no words`,
+			expectedKind:  ErrValueInvalid,
+		},
+		{
+			name:          "multiple values not allowed",
+			input:         `name:(a or b)`,
+			expectedError: "field name: multiple values are not allowed",
+			expectedKind:  ErrMultipleValuesNotAllowed,
+		},
+		{
+			name:          "negated multiple values not allowed",
+			input:         `not title:(a or b)`,
+			expectedError: "operator NOT IN not supported for field: title",
+			expectedKind:  ErrOperatorNotAllowed,
+		},
+		{
+			name:          "range",
+			input:         `title>a`,
+			expectedError: "operator > not supported for field: title",
+			expectedKind:  ErrOperatorNotAllowed,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			f, err := Parse(test.input)
+			require.NoError(t, err)
+			sql, params, err := f.ToSpannerSQL(configs)
+			if test.expectedError != "" {
+				assert.EqualError(t, err, test.expectedError)
+				assert.ErrorIs(t, err, test.expectedKind)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedSQL, sql)
+			assert.Equal(t, test.expectedParams, params)
+		})
+	}
+}
+
+func TestToSpannerSQLMaxValues(t *testing.T) {
+	configs := map[string]FilterToSpannerFieldConfig{
+		"state": {ColumnType: FilterToSpannerFieldColumnTypeString, AllowMultipleValues: true, MaxValues: 2},
+		"type":  {ColumnType: FilterToSpannerFieldColumnTypeString, AllowMultipleValues: true},
+	}
+
+	testCases := []struct {
+		name          string
+		input         string
+		options       []ConverterOption
+		expectedSQL   []string
+		expectedError string
+	}{
+		{
+			name:        "within maximum",
+			input:       "state:(a or b)",
+			expectedSQL: []string{"state IN UNNEST(@KQL0)"},
+		},
+		{
+			name:          "field maximum exceeded",
+			input:         "state:(a or b or c)",
+			expectedError: "field state: 3 values exceed the maximum of 2",
+		},
+		{
+			name:        "no default maximum",
+			input:       "type:(a or b or c)",
+			expectedSQL: []string{"type IN UNNEST(@KQL0)"},
+		},
+		{
+			name:          "default maximum exceeded",
+			input:         "type:(a or b or c)",
+			options:       []ConverterOption{WithMaxValues(2)},
+			expectedError: "field type: 3 values exceed the maximum of 2",
+		},
+		{
+			name:        "field maximum overrides default",
+			input:       "state:(a or b)",
+			options:     []ConverterOption{WithMaxValues(1)},
+			expectedSQL: []string{"state IN UNNEST(@KQL0)"},
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			f, err := Parse(test.input)
+			require.NoError(t, err)
+			sql, _, err := f.ToSpannerSQL(configs, test.options...)
+			if test.expectedError != "" {
+				assert.EqualError(t, err, test.expectedError)
+				assert.ErrorIs(t, err, ErrMultipleValuesNotAllowed)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedSQL, sql)
+		})
+	}
+}
+
+func TestToSpannerSQLStringRanges(t *testing.T) {
+	configs := map[string]FilterToSpannerFieldConfig{
+		"id":   {ColumnType: FilterToSpannerFieldColumnTypeString, AllowRanges: true, AllowStringRanges: true},
+		"name": {ColumnType: FilterToSpannerFieldColumnTypeString, AllowRanges: true},
+	}
+
+	testCases := []struct {
+		name           string
+		input          string
+		expectedSQL    []string
+		expectedParams map[string]any
+		expectedError  string
+	}{
+		{
+			name:           "cursor",
+			input:          "id>01HZX3M5K2",
+			expectedSQL:    []string{"id>@KQL0"},
+			expectedParams: map[string]any{"KQL0": "01HZX3M5K2"},
+		},
+		{
+			name:           "range",
+			input:          `id>=01HZX3M5K2 and id<"01HZX3M5K2\*"`,
+			expectedSQL:    []string{"id>=@KQL0", "id<@KQL1"},
+			expectedParams: map[string]any{"KQL0": "01HZX3M5K2", "KQL1": "01HZX3M5K2*"},
+		},
+		{
+			name:          "string ranges not allowed",
+			input:         "name>b",
+			expectedError: "operator > not supported for field type STRING",
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			f, err := Parse(test.input)
+			require.NoError(t, err)
+			sql, params, err := f.ToSpannerSQL(configs)
+			if test.expectedError != "" {
+				assert.EqualError(t, err, test.expectedError)
+				assert.ErrorIs(t, err, ErrOperatorNotAllowed)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedSQL, sql)
+			assert.Equal(t, test.expectedParams, params)
+		})
+	}
+}
+
+func TestToSpannerSQLValueConstraints(t *testing.T) {
+	configs := map[string]FilterToSpannerFieldConfig{
+		"slug":  {ColumnType: FilterToSpannerFieldColumnTypeString, AllowMultipleValues: true, AllowPrefixMatch: true, Pattern: regexp.MustCompile(`^[a-z0-9-]+$`)},
+		"name":  {ColumnType: FilterToSpannerFieldColumnTypeString, MaxLength: 5},
+		"title": {ColumnType: FilterToSpannerFieldColumnTypeString, MaxLength: 3, AllowRegex: true},
+	}
+
+	testCases := []struct {
+		name          string
+		input         string
+		expectedSQL   []string
+		expectedError string
+	}{
+		{
+			name:        "matching pattern",
+			input:       "slug:(my-post or other)",
+			expectedSQL: []string{"slug IN UNNEST(@KQL0)"},
+		},
+		{
+			name:        "prefix match",
+			input:       "slug:my-*",
+			expectedSQL: []string{"slug LIKE @KQL0"},
+		},
+		{
+			name:          "not matching pattern",
+			input:         "slug:(my-post or My_Post)",
+			expectedError: `field slug: value "My_Post" does not match pattern ^[a-z0-9-]+$`,
+		},
+		{
+			name:        "within maximum length",
+			input:       `name:"jöñ d"`,
+			expectedSQL: []string{"name=@KQL0"},
+		},
+		{
+			name:          "maximum length exceeded",
+			input:         "name:johnny",
+			expectedError: `field name: value "johnny" exceeds maximum length of 5`,
+		},
+		{
+			name:        "regular expressions are not checked",
+			input:       "title:/ab+c/",
+			expectedSQL: []string{"REGEXP_CONTAINS(title, @KQL0)"},
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			f, err := Parse(test.input)
+			require.NoError(t, err)
+			sql, _, err := f.ToSpannerSQL(configs)
+			if test.expectedError != "" {
+				assert.EqualError(t, err, test.expectedError)
+				assert.ErrorIs(t, err, ErrValueInvalid)
+				var constraintErr *ValueConstraintError
+				assert.ErrorAs(t, err, &constraintErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedSQL, sql)
+		})
+	}
+}
+
+func TestToSpannerSQLAllErrors(t *testing.T) {
+	configs := map[string]FilterToSpannerFieldConfig{
+		"user_id": {ColumnType: FilterToSpannerFieldColumnTypeInt64, Required: true},
+		"state":   {},
+		"age":     {ColumnType: FilterToSpannerFieldColumnTypeInt64},
+	}
+
+	f, err := Parse("state:active foo:1 age:abc age>3")
+	require.NoError(t, err)
+
+	_, _, err = f.ToSpannerSQL(configs)
+	assert.EqualError(t, err, "unknown field: foo")
+
+	_, _, err = f.ToSpannerSQL(configs, WithAllErrors())
+	assert.EqualError(t, err, "unknown field: foo\n"+
+		"field age: invalid INT64 value: strconv.ParseInt: parsing \"abc\": invalid syntax\n"+
+		"operator > not supported for field: age\n"+
+		"required field user_id missing")
+	assert.ErrorIs(t, err, ErrUnknownField)
+	assert.ErrorIs(t, err, ErrValueInvalid)
+	assert.ErrorIs(t, err, ErrOperatorNotAllowed)
+	assert.ErrorIs(t, err, ErrRequiredFieldMissing)
+
+	f, err = Parse("user_id:1 state:active")
+	require.NoError(t, err)
+	conditions, _, err := f.ToSpannerSQL(configs, WithAllErrors())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"user_id=@KQL0", "state=@KQL1"}, conditions)
+}
+
+func TestToSpannerSQLNormalization(t *testing.T) {
+	configs := map[string]FilterToSpannerFieldConfig{
+		"name":  {ColumnType: FilterToSpannerFieldColumnTypeString, Normalization: FilterToSpannerNormalizationNFC, AllowMultipleValues: true},
+		"title": {Normalization: FilterToSpannerNormalizationNFKC, CaseFold: true, NormalizeColumn: true, AllowPrefixMatch: true},
+		"city":  {ColumnType: FilterToSpannerFieldColumnTypeString, CaseFold: true, NormalizeColumn: true, AllowRanges: true, AllowStringRanges: true},
+		"age":   {ColumnType: FilterToSpannerFieldColumnTypeInt64, CaseFold: true, NormalizeColumn: true},
+	}
+
+	testCases := []struct {
+		name           string
+		input          string
+		expectedSQL    []string
+		expectedParams map[string]any
+	}{
+		{
+			name:           "composed",
+			input:          "name:\"Jose\u0301\"",
+			expectedSQL:    []string{"name=@KQL0"},
+			expectedParams: map[string]any{"KQL0": "José"},
+		},
+		{
+			name:           "list of values",
+			input:          "name:(\"Jose\u0301\" or José or Zoe\u0308)",
+			expectedSQL:    []string{"name IN UNNEST(@KQL0)"},
+			expectedParams: map[string]any{"KQL0": []string{"José", "Zoë"}},
+		},
+		{
+			name:           "compatibility and case folding",
+			input:          "title:\"ﬁnal STRASSE\" not city:Zürich",
+			expectedSQL:    []string{"NORMALIZE_AND_CASEFOLD(title, NFKC)=@KQL0", "NORMALIZE_AND_CASEFOLD(city, NFC)!=@KQL1"},
+			expectedParams: map[string]any{"KQL0": "final strasse", "KQL1": "zürich"},
+		},
+		{
+			name:           "prefix match",
+			input:          "title:ÉT*",
+			expectedSQL:    []string{"NORMALIZE_AND_CASEFOLD(title, NFKC) LIKE @KQL0"},
+			expectedParams: map[string]any{"KQL0": "ét%"},
+		},
+		{
+			name:           "ranges compare the column as stored",
+			input:          "city>=Zürich",
+			expectedSQL:    []string{"city>=@KQL0"},
+			expectedParams: map[string]any{"KQL0": "zürich"},
+		},
+		{
+			name:           "other column types are not normalized",
+			input:          "age:42",
+			expectedSQL:    []string{"age=@KQL0"},
+			expectedParams: map[string]any{"KQL0": int64(42)},
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			f, err := Parse(test.input)
+			require.NoError(t, err)
+			sql, params, err := f.ToSpannerSQL(configs)
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedSQL, sql)
+			assert.Equal(t, test.expectedParams, params)
+		})
+	}
+}
+
+func TestToSpannerSQLColumnExpr(t *testing.T) {
+	configs := map[string]FilterToSpannerFieldConfig{
+		"day": {
+			ColumnExpr:  "TIMESTAMP_TRUNC(created_at, DAY)",
+			ColumnType:  FilterToSpannerFieldColumnTypeTimestamp,
+			AllowRanges: true,
+		},
+		"sku": {
+			ColumnExpr:          "JSON_VALUE(meta, ?)",
+			ColumnExprArgs:      []any{"$.sku"},
+			ColumnType:          FilterToSpannerFieldColumnTypeString,
+			AllowMultipleValues: true,
+			AllowPrefixMatch:    true,
+		},
+	}
+
+	f, err := Parse(`day>="2024-05-01T00:00:00Z" day<"2024-06-01T00:00:00Z" sku:(a or b) not sku:c*`)
+	require.NoError(t, err)
+	sql, params, err := f.ToSpannerSQL(configs)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"TIMESTAMP_TRUNC(created_at, DAY)>=@KQL0",
+		"TIMESTAMP_TRUNC(created_at, DAY)<@KQL1",
+		"JSON_VALUE(meta, @KQL2) IN UNNEST(@KQL3)",
+		"JSON_VALUE(meta, @KQL2)!=@KQL4",
+	}, sql)
+	assert.Equal(t, map[string]any{
+		"KQL0": time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+		"KQL1": time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+		"KQL2": "$.sku",
+		"KQL3": []string{"a", "b"},
+		"KQL4": "c*",
+	}, params)
+
+	configs["sku"] = FilterToSpannerFieldConfig{ColumnExpr: "JSON_VALUE(meta, ?)"}
+	f, err = Parse(`sku:a`)
+	require.NoError(t, err)
+	_, _, err = f.ToSpannerSQL(configs)
+	assert.EqualError(t, err, `field sku: column expression: condition "JSON_VALUE(meta, ?)" has more placeholders than the 0 params`)
+}
+
+func TestToSpannerSQLEpochTimestamps(t *testing.T) {
+	configs := map[string]FilterToSpannerFieldConfig{
+		"created": {ColumnType: FilterToSpannerFieldColumnTypeTimestamp, AllowRanges: true},
+	}
+	expected := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name  string
+		input string
+		unit  EpochUnit
+	}{
+		{name: "seconds", input: "created>1714521600", unit: EpochSeconds},
+		{name: "milliseconds", input: "created>1714521600000", unit: EpochMilliseconds},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			f, err := Parse(test.input)
+			require.NoError(t, err)
+			sql, params, err := f.ToSpannerSQL(configs, WithEpochTimestamps(test.unit))
+			require.NoError(t, err)
+			assert.Equal(t, []string{"created>@KQL0"}, sql)
+			assert.Equal(t, map[string]any{"KQL0": expected}, params)
+		})
+	}
+}
+
+func TestToSpannerSQLGroups(t *testing.T) {
+	configs := map[string]FilterToSpannerFieldConfig{
+		"state":   {Required: true},
+		"ts":      {ColumnName: "create_time", ColumnType: FilterToSpannerFieldColumnTypeInt64, AllowRanges: true},
+		"owner":   {Requires: []string{"state"}},
+		"session": {Ignore: true},
+	}
+	f := Filter{
+		Clauses: []Clause{{Field: "owner", Operator: "=", Values: []string{"jon"}}},
+		Groups: [][]Clause{
+			{
+				{Field: "state", Operator: "=", Values: []string{"active"}},
+				{Field: "ts", Operator: ">=", Values: []string{"1"}},
+				{Field: "ts", Operator: "<=", Values: []string{"5"}},
+				{Field: "session", Operator: "=", Values: []string{"x"}},
+			},
+			{{Field: "state", Operator: "=", Values: []string{"frozen"}}},
+		},
+	}
+
+	conditions, params, err := f.ToSpannerSQL(configs, WithCollapseRanges(), WithSoftDelete("deleted", false))
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"owner=@KQL0", "((state=@KQL1 AND create_time BETWEEN @KQL2 AND @KQL3) OR (state=@KQL4))", "deleted=@KQL5",
+	}, conditions)
+	assert.Equal(t, map[string]any{
+		"KQL0": "jon", "KQL1": "active", "KQL2": int64(1), "KQL3": int64(5), "KQL4": "frozen", "KQL5": false,
+	}, params)
+
+	// A required field must be in every group if it is not in the clauses.
+	f.Groups[1][0].Field = "ts"
+	_, _, err = f.ToSpannerSQL(configs)
+	assert.ErrorIs(t, err, ErrRequiredFieldMissing)
+
+	// A group that only has ignored fields would match every row.
+	f.Clauses = nil
+	f.Groups[1] = []Clause{{Field: "session", Operator: "=", Values: []string{"x"}}}
+	_, _, err = f.ToSpannerSQL(configs)
+	assert.EqualError(t, err, "group 1 only has ignored fields and would match every row")
+}
@@ -0,0 +1,299 @@
+package kqlfilter
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type FilterToSQLiteFieldColumnType int
+
+const (
+	FilterToSQLiteFieldColumnTypeUnspecified FilterToSQLiteFieldColumnType = iota
+	FilterToSQLiteFieldColumnTypeText
+	FilterToSQLiteFieldColumnTypeInteger
+	FilterToSQLiteFieldColumnTypeReal
+	// A boolean stored as an integer, 0 for false and 1 for true.
+	FilterToSQLiteFieldColumnTypeBoolean
+	// A timestamp stored as text in UTC, in the format used by the SQLite date and time functions, e.g.
+	// `2024-05-01 13:45:00` or `2024-05-01 13:45:00.123`.
+	FilterToSQLiteFieldColumnTypeDatetime
+	// A timestamp stored as an integer number of seconds since the Unix epoch.
+	FilterToSQLiteFieldColumnTypeUnixTime
+)
+
+func (c FilterToSQLiteFieldColumnType) String() string {
+	switch c {
+	case FilterToSQLiteFieldColumnTypeText:
+		return "TEXT"
+	case FilterToSQLiteFieldColumnTypeInteger:
+		return "INTEGER"
+	case FilterToSQLiteFieldColumnTypeReal:
+		return "REAL"
+	case FilterToSQLiteFieldColumnTypeBoolean:
+		return "BOOLEAN"
+	case FilterToSQLiteFieldColumnTypeDatetime:
+		return "DATETIME"
+	case FilterToSQLiteFieldColumnTypeUnixTime:
+		return "UNIXTIME"
+	default:
+		return "???"
+	}
+}
+
+// sqliteDatetimeLayout is the layout of FilterToSQLiteFieldColumnTypeDatetime columns.
+const sqliteDatetimeLayout = "2006-01-02 15:04:05.999"
+
+type FilterToSQLiteFieldConfig struct {
+	// SQL table column name. Can be omitted if the column name is equal to the key in the fieldConfigs map.
+	ColumnName string
+	// SQL column type. Defaults to FilterToSQLiteFieldColumnTypeText.
+	ColumnType FilterToSQLiteFieldColumnType
+	// Settings shared with the field configs of the other converters, e.g. AllowMultipleValues and MapValue.
+	FieldOptions
+	// Allow prefix matching when a wildcard (`*`) is present at the end of a string.
+	// Only applicable for FilterToSQLiteFieldColumnTypeText. Defaults to false.
+	AllowPrefixMatch bool
+	// Allow suffix matching when a wildcard (`*`) is present at the beginning of a string.
+	// Only applicable for FilterToSQLiteFieldColumnTypeText and not for FullTextSearch. Defaults to false.
+	AllowSuffixMatch bool
+	// Match this field with an FTS5 full-text query (`column MATCH ?`), in which case ColumnName is the name of the FTS5
+	// table, to search all of its columns, or of one of its columns. The words of a value must all be present; they are
+	// quoted, so FTS5 query operators are searched for as plain words. A trailing wildcard is a prefix query if
+	// AllowPrefixMatch is set. Multiple values are OR'ed into a single query. Negation, ranges and MapValue do not
+	// apply. Defaults to false.
+	FullTextSearch bool
+}
+
+// ToSQLiteSQL turns a Filter into conditions for a SQLite WHERE clause, using `?` placeholders. It takes a map of
+// fields that are allowed to be queried via this filter, and returns the conditions, which must be joined by AND,
+// along with the arguments in the order of their placeholders:
+//
+//	conditions, args, err := filter.ToSQLiteSQL(fieldConfigs)
+//	rows, err := db.QueryContext(ctx, "SELECT * FROM users WHERE "+strings.Join(conditions, " AND "), args...)
+//
+// Given the filter `userId:12345 email:john* state:(active OR frozen)` and matching field configs, the conditions are
+//
+//	["user_id = ?", "email LIKE ? ESCAPE '\'", "state IN (?, ?)"]
+//
+// with arguments
+//
+//	[int64(12345), "john%", "active", "frozen"]
+//
+// Note that LIKE is case-insensitive for ASCII characters in SQLite, unless `PRAGMA case_sensitive_like` is enabled.
+//
+// DATETIME and UNIXTIME fields accept RFC3339 values, values in the layouts set with WithTimeLayouts, as well as
+// relative time keywords such as `today` (see RelativeTimeToday), which are resolved using the clock and location set
+// with WithClock and WithDefaultLocation.
+//
+// Conditions added with WithCondition are appended as-is; they must not have params, as SQLite placeholders are
+// positional.
+func (f Filter) ToSQLiteSQL(fieldConfigs map[string]FilterToSQLiteFieldConfig, options ...ConverterOption) ([]string, []any, error) {
+	o := newConverterOptions(options)
+	var conditions []string
+	var args []any
+
+	f, includeDeleted, err := o.extractIncludeDeleted(f)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, clause := range f.Clauses {
+		condition, clauseArgs, err := convertSQLiteClause(clause, fieldConfigs, o)
+		if err != nil {
+			return nil, nil, err
+		}
+		conditions = append(conditions, condition)
+		args = append(args, clauseArgs...)
+	}
+	if len(f.Groups) > 0 {
+		condition, err := convertGroups(f.Groups, func(clause Clause) (string, error) {
+			condition, clauseArgs, err := convertSQLiteClause(clause, fieldConfigs, o)
+			args = append(args, clauseArgs...)
+			return condition, err
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		conditions = append(conditions, condition)
+	}
+
+	if err := checkRequiredFields(fieldConfigs, f); err != nil {
+		return nil, nil, err
+	}
+
+	if o.softDeleteColumn != "" && !includeDeleted {
+		conditions = append(conditions, o.softDeleteColumn+" = ?")
+		args = append(args, false)
+	}
+
+	for _, c := range o.conditions {
+		if len(c.params) > 0 {
+			return nil, nil, fmt.Errorf("condition %q: params are not supported by SQLite", c.sql)
+		}
+		conditions = append(conditions, c.sql)
+	}
+
+	return conditions, args, nil
+}
+
+// convertSQLiteClause returns the condition of a clause along with the arguments of its placeholders.
+func convertSQLiteClause(clause Clause, fieldConfigs map[string]FilterToSQLiteFieldConfig, o converterOptions) (string, []any, error) {
+	name, fieldConfig, ok := lookupField(fieldConfigs, clause.Field)
+	if !ok {
+		if clause.Field == "1" && clause.Operator == "=" && len(clause.Values) == 1 && (clause.Values[0] == "1" || clause.Values[0] == "0") {
+			// Special case for boolean literals
+			value, _ := strconv.ParseInt(clause.Values[0], 10, 64)
+			return "1 = ?", []any{value}, nil
+		}
+		return "", nil, newUnknownFieldError(fieldConfigs, clause.Field)
+	}
+
+	if clause.Operator == "~" {
+		return "", nil, fmt.Errorf("field %s: fuzzy matching is not supported by SQLite", clause.Field)
+	}
+
+	columnName := fieldConfig.ColumnName
+	if columnName == "" {
+		columnName = name
+	}
+
+	if len(clause.Values) > 1 && !fieldConfig.AllowMultipleValues {
+		return "", nil, fmt.Errorf("field %s: multiple values are not allowed", clause.Field)
+	}
+
+	if fieldConfig.FullTextSearch {
+		if clause.Operator != "=" && clause.Operator != "IN" {
+			return "", nil, fmt.Errorf("operator %s not supported for field: %s", clause.Operator, clause.Field)
+		}
+		query, err := fieldConfig.matchQuery(clause.Values)
+		if err != nil {
+			return "", nil, fmt.Errorf("field %s: %w", clause.Field, err)
+		}
+		return columnName + " MATCH ?", []any{query}, nil
+	}
+
+	values, err := fieldConfig.mapValues(clause.Values, o, fieldConfig.convertValue)
+	if err != nil {
+		return "", nil, fmt.Errorf("field %s: %w", clause.Field, err)
+	}
+
+	var condition string
+	var args []any
+	switch clause.Operator {
+	case "IN", "NOT IN":
+		if clause.Operator == "NOT IN" && !(fieldConfig.AllowNegation && fieldConfig.AllowMultipleValues) {
+			return "", nil, fmt.Errorf("operator %s not supported for field: %s", clause.Operator, clause.Field)
+		}
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(values)), ", ")
+		condition = fmt.Sprintf("%s %s (%s)", columnName, clause.Operator, placeholders)
+		for _, v := range values {
+			if s, ok := v.(string); ok {
+				v = UnescapeValue(s)
+			}
+			args = append(args, v)
+		}
+	case "=", "!=":
+		value := values[0]
+		if s, ok := value.(string); ok {
+			text, needsPrefixMatch, needsSuffixMatch := SplitWildcards(s, fieldConfig.AllowPrefixMatch && clause.Operator == "=", fieldConfig.AllowSuffixMatch && clause.Operator == "=")
+			if needsPrefixMatch || needsSuffixMatch {
+				pattern := escapePrefixSuffixSpecialChars(text)
+				if needsPrefixMatch {
+					pattern += "%"
+				}
+				if needsSuffixMatch {
+					pattern = "%" + pattern
+				}
+				return columnName + ` LIKE ? ESCAPE '\'`, []any{pattern}, nil
+			}
+			value = text
+		}
+		if clause.Operator == "=" {
+			condition = columnName + " = ?"
+		} else {
+			condition = columnName + " <> ?"
+		}
+		args = append(args, value)
+	case ">=", "<=", ">", "<":
+		if !fieldConfig.AllowRanges {
+			return "", nil, fmt.Errorf("operator %s not supported for field: %s", clause.Operator, clause.Field)
+		}
+		switch fieldConfig.ColumnType {
+		case FilterToSQLiteFieldColumnTypeInteger, FilterToSQLiteFieldColumnTypeReal, FilterToSQLiteFieldColumnTypeDatetime, FilterToSQLiteFieldColumnTypeUnixTime:
+			condition = fmt.Sprintf("%s %s ?", columnName, clause.Operator)
+		default:
+			return "", nil, fmt.Errorf("operator %s not supported for field type %s", clause.Operator, fieldConfig.ColumnType)
+		}
+		args = append(args, values[0])
+	default:
+		return "", nil, fmt.Errorf("operator %s not supported for field: %s", clause.Operator, clause.Field)
+	}
+	return condition, args, nil
+}
+
+func (f FilterToSQLiteFieldConfig) convertValue(value string, o converterOptions) (any, error) {
+	switch f.ColumnType {
+	case FilterToSQLiteFieldColumnTypeInteger:
+		intVal, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid integer value: %w", err)
+		}
+		return intVal, nil
+	case FilterToSQLiteFieldColumnTypeReal:
+		realVal, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid real value: %w", err)
+		}
+		return realVal, nil
+	case FilterToSQLiteFieldColumnTypeBoolean:
+		boolVal, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid boolean value: %w", err)
+		}
+		return boolVal, nil
+	case FilterToSQLiteFieldColumnTypeDatetime, FilterToSQLiteFieldColumnTypeUnixTime:
+		t, ok := o.resolveRelativeTime(value)
+		if !ok {
+			var err error
+			t, err = o.parseTime(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid timestamp value: %w", err)
+			}
+		}
+		if f.ColumnType == FilterToSQLiteFieldColumnTypeUnixTime {
+			return t.Unix(), nil
+		}
+		return t.UTC().Format(sqliteDatetimeLayout), nil
+	default:
+		return value, nil
+	}
+}
+
+// matchQuery returns the FTS5 query matching any of the values, each of which must contain all of its words.
+func (f FilterToSQLiteFieldConfig) matchQuery(values []string) (string, error) {
+	queries := make([]string, 0, len(values))
+	for _, value := range values {
+		if err := checkAllowedValue(value, f.AllowedValues); err != nil {
+			return "", err
+		}
+		text, needsPrefixMatch, _ := SplitWildcards(value, f.AllowPrefixMatch, false)
+		words := strings.Fields(text)
+		if len(words) == 0 {
+			return "", errors.New("full-text search value contains no words")
+		}
+		for i, word := range words {
+			words[i] = `"` + strings.ReplaceAll(word, `"`, `""`) + `"`
+		}
+		if needsPrefixMatch {
+			words[len(words)-1] += "*"
+		}
+		query := strings.Join(words, " ")
+		if len(values) > 1 && len(words) > 1 {
+			query = "(" + query + ")"
+		}
+		queries = append(queries, query)
+	}
+	return strings.Join(queries, " OR "), nil
+}
@@ -0,0 +1,230 @@
+package kqlfilter
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToSQLiteSQL(t *testing.T) {
+	testCases := []struct {
+		name          string
+		input         string
+		columnMap     map[string]FilterToSQLiteFieldConfig
+		expectedError string
+		expectedSQL   string
+		expectedArgs  []any
+	}{
+		{
+			name:  "one integer field",
+			input: "userId:12345",
+			columnMap: map[string]FilterToSQLiteFieldConfig{
+				"userId": {ColumnName: "user_id", ColumnType: FilterToSQLiteFieldColumnTypeInteger},
+			},
+			expectedSQL:  "(user_id = ?)",
+			expectedArgs: []any{int64(12345)},
+		},
+		{
+			name:         "boolean literal",
+			input:        "false",
+			columnMap:    map[string]FilterToSQLiteFieldConfig{},
+			expectedSQL:  "(1 = ?)",
+			expectedArgs: []any{int64(0)},
+		},
+		{
+			name:  "alias and negation",
+			input: "not type_id:team",
+			columnMap: map[string]FilterToSQLiteFieldConfig{
+				"typeId": {ColumnName: "t.type_id", FieldOptions: FieldOptions{Aliases: []string{"type_id"}}},
+			},
+			expectedSQL:  "(t.type_id <> ?)",
+			expectedArgs: []any{"team"},
+		},
+		{
+			name:  "prefix and suffix match",
+			input: `email:john_* name:*son`,
+			columnMap: map[string]FilterToSQLiteFieldConfig{
+				"email": {AllowPrefixMatch: true},
+				"name":  {AllowSuffixMatch: true},
+			},
+			expectedSQL:  `(email LIKE ? ESCAPE '\' AND name LIKE ? ESCAPE '\')`,
+			expectedArgs: []any{`john\_%`, `%son`},
+		},
+		{
+			name:  "multiple values",
+			input: "state:(active or frozen) not kind:(a or b)",
+			columnMap: map[string]FilterToSQLiteFieldConfig{
+				"state": {FieldOptions: FieldOptions{AllowMultipleValues: true}},
+				"kind":  {FieldOptions: FieldOptions{AllowMultipleValues: true, AllowNegation: true}},
+			},
+			expectedSQL:  "(state IN (?, ?) AND kind NOT IN (?, ?))",
+			expectedArgs: []any{"active", "frozen", "a", "b"},
+		},
+		{
+			name:  "negated multiple values not allowed",
+			input: "not state:(active or frozen)",
+			columnMap: map[string]FilterToSQLiteFieldConfig{
+				"state": {FieldOptions: FieldOptions{AllowMultipleValues: true}},
+			},
+			expectedError: "operator NOT IN not supported for field: state",
+		},
+		{
+			name:  "ranges",
+			input: `price>=1.5 created<"2024-05-01T12:00:00.25Z" updated>"2024-05-01T00:00:00+02:00"`,
+			columnMap: map[string]FilterToSQLiteFieldConfig{
+				"price":   {ColumnType: FilterToSQLiteFieldColumnTypeReal, FieldOptions: FieldOptions{AllowRanges: true}},
+				"created": {ColumnType: FilterToSQLiteFieldColumnTypeDatetime, FieldOptions: FieldOptions{AllowRanges: true}},
+				"updated": {ColumnType: FilterToSQLiteFieldColumnTypeUnixTime, FieldOptions: FieldOptions{AllowRanges: true}},
+			},
+			expectedSQL:  "(price >= ? AND created < ? AND updated > ?)",
+			expectedArgs: []any{1.5, "2024-05-01 12:00:00.25", int64(1714514400)},
+		},
+		{
+			name:  "range on text field",
+			input: "name>a",
+			columnMap: map[string]FilterToSQLiteFieldConfig{
+				"name": {ColumnType: FilterToSQLiteFieldColumnTypeText, FieldOptions: FieldOptions{AllowRanges: true}},
+			},
+			expectedError: "operator > not supported for field type TEXT",
+		},
+		{
+			name:  "boolean",
+			input: "active:true",
+			columnMap: map[string]FilterToSQLiteFieldConfig{
+				"active": {ColumnType: FilterToSQLiteFieldColumnTypeBoolean},
+			},
+			expectedSQL:  "(active = ?)",
+			expectedArgs: []any{true},
+		},
+		{
+			name:  "full-text search",
+			input: `title:"hello OR world" state:active`,
+			columnMap: map[string]FilterToSQLiteFieldConfig{
+				"title": {ColumnName: "posts_fts", FullTextSearch: true},
+				"state": {},
+			},
+			expectedSQL:  "(posts_fts MATCH ? AND state = ?)",
+			expectedArgs: []any{`"hello" "OR" "world"`, "active"},
+		},
+		{
+			name:  "full-text search with prefix",
+			input: `body:"hello wor*"`,
+			columnMap: map[string]FilterToSQLiteFieldConfig{
+				"body": {ColumnName: "posts_fts.body", FullTextSearch: true, AllowPrefixMatch: true},
+			},
+			expectedSQL:  "(posts_fts.body MATCH ?)",
+			expectedArgs: []any{`"hello" "wor"*`},
+		},
+		{
+			name:  "full-text search with multiple values",
+			input: `body:(go or "say \"hi\" there")`,
+			columnMap: map[string]FilterToSQLiteFieldConfig{
+				"body": {ColumnName: "posts_fts.body", FullTextSearch: true, FieldOptions: FieldOptions{AllowMultipleValues: true}},
+			},
+			expectedSQL:  "(posts_fts.body MATCH ?)",
+			expectedArgs: []any{`"go" OR ("say" """hi""" "there")`},
+		},
+		{
+			name:  "negated full-text search",
+			input: `not title:hello`,
+			columnMap: map[string]FilterToSQLiteFieldConfig{
+				"title": {FullTextSearch: true},
+			},
+			expectedError: "operator != not supported for field: title",
+		},
+		{
+			name:  "full-text search without words",
+			input: `title:" "`,
+			columnMap: map[string]FilterToSQLiteFieldConfig{
+				"title": {FullTextSearch: true},
+			},
+			expectedError: "field title: full-text search value contains no words",
+		},
+		{
+			name:  "required field missing",
+			input: "state:active",
+			columnMap: map[string]FilterToSQLiteFieldConfig{
+				"state":  {},
+				"tenant": {FieldOptions: FieldOptions{Required: true}},
+			},
+			expectedError: "required field tenant missing",
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			f, err := Parse(test.input)
+			require.NoError(t, err)
+			conditions, args, err := f.ToSQLiteSQL(test.columnMap)
+			if test.expectedError != "" {
+				assert.EqualError(t, err, test.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedSQL, "("+strings.Join(conditions, " AND ")+")")
+			assert.Equal(t, test.expectedArgs, args)
+		})
+	}
+}
+
+func TestToSQLiteSQLOptions(t *testing.T) {
+	configs := map[string]FilterToSQLiteFieldConfig{
+		"state":   {},
+		"created": {ColumnType: FilterToSQLiteFieldColumnTypeDatetime},
+	}
+
+	f, err := Parse("state:active created:today")
+	require.NoError(t, err)
+	now := time.Date(2024, 5, 1, 13, 45, 0, 0, time.UTC)
+	conditions, args, err := f.ToSQLiteSQL(configs,
+		WithClock(func() time.Time { return now }),
+		WithSoftDelete("deleted", true),
+		WithCondition("tenant_id = 1", nil),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"state = ?", "created = ?", "deleted = ?", "tenant_id = 1"}, conditions)
+	assert.Equal(t, []any{"active", "2024-05-01 00:00:00", false}, args)
+
+	_, _, err = f.ToSQLiteSQL(configs, WithCondition("tenant_id = @tenant", map[string]any{"tenant": "t1"}))
+	assert.EqualError(t, err, `condition "tenant_id = @tenant": params are not supported by SQLite`)
+}
+
+func TestSQLiteBackend(t *testing.T) {
+	schema := Schema{
+		"user_id": {Type: FieldTypeInt64, Aliases: []string{"userId"}},
+		"state":   {AllowMultipleValues: true},
+	}
+
+	query, err := QueryFromKQL("userId:12 state:(active OR paused)", schema, SQLiteBackend())
+	require.NoError(t, err)
+	assert.Equal(t, "user_id = ? AND state IN (?, ?)", query.Where())
+	assert.Equal(t, []any{int64(12), "active", "paused"}, query.Args)
+}
+
+func TestToSQLiteSQLGroups(t *testing.T) {
+	configs := map[string]FilterToSQLiteFieldConfig{
+		"state": {FieldOptions: FieldOptions{Required: true}},
+		"a":     {},
+		"b":     {ColumnType: FilterToSQLiteFieldColumnTypeInteger, FieldOptions: FieldOptions{AllowRanges: true}},
+	}
+	f := Filter{
+		Clauses: []Clause{{Field: "a", Operator: "=", Values: []string{"1"}}},
+		Groups: [][]Clause{
+			{{Field: "state", Operator: "=", Values: []string{"active"}}, {Field: "b", Operator: ">", Values: []string{"2"}}},
+			{{Field: "state", Operator: "=", Values: []string{"frozen"}}},
+		},
+	}
+
+	conditions, args, err := f.ToSQLiteSQL(configs)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a = ?", "((state = ? AND b > ?) OR (state = ?))"}, conditions)
+	assert.Equal(t, []any{"1", "active", int64(2), "frozen"}, args)
+
+	// A required field must be in every group if it is not in the clauses.
+	f.Groups[1][0].Field = "a"
+	_, _, err = f.ToSQLiteSQL(configs)
+	assert.EqualError(t, err, "required field state missing")
+}
@@ -3,6 +3,7 @@ package kqlfilter
 import (
 	"fmt"
 	"reflect"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -30,6 +31,10 @@ type FilterToSquirrelSqlFieldConfig struct {
 	// Allow prefix matching when a wildcard (`*`) is present at the end of a string.
 	// Only applicable for FilterToSpannerFieldColumnTypeString. Defaults to false.
 	AllowPrefixMatch bool
+	// Match string values case-insensitively, for equality as well as prefix matching. This emits
+	// `LOWER(column) = LOWER(?)`, or ILIKE when the WithILike option is set. Defaults to false.
+	// Important: this can have a negative impact on performance, as it will prevent the use of an index on the column.
+	AllowCaseInsensitiveMatch bool
 	// Allow multiple values for this field. Defaults to false.
 	AllowMultipleValues bool
 	// Allow this field to be queried with one or more range operators. Defaults to false.
@@ -38,8 +43,29 @@ type FilterToSquirrelSqlFieldConfig struct {
 	// should be as users' input. This should return an error when the user is providing a value that is illegal or unexpected
 	// for this particular field. Defaults to using the provided value as-is.
 	MapValue func(string) (any, error)
+	// The values that are allowed for this field, e.g. the values of an enum. When set, any other value is rejected
+	// with an InvalidValueError that lists the allowed values and suggests the closest ones. This is checked before
+	// calling MapValue; errors returned by MapValue are reported the same way. Defaults to allowing any value.
+	AllowedValues []string
+	// Which of the AllowedValues are revealed when a value is rejected, also for InvalidValueErrors returned by MapValue,
+	// e.g. ValueSuggestionsNone for fields whose values are internal. Defaults to ValueSuggestionsAll.
+	ValueSuggestions ValueSuggestions
+	// A list of aliases for this field. Can be used if you want to allow users to use different field names to filter
+	// on the same column. Useful e.g. to allow different naming conventions, like `type_id` and `typeId`.
+	Aliases []string
+	// Allow matching NULL: `field:null` is emitted as `column IS NULL` and `not field:null` as `column IS NOT NULL`.
+	// Existence checks are emitted the same way: `field:*` as `column IS NOT NULL`, and `not field:*` (or
+	// `field IS NULL`, see WithExtendedSyntax) as `column IS NULL`. As quotes are not kept in a Filter, `field:"null"`
+	// matches NULL as well. AllowedValues and MapValue do not apply. Defaults to false.
+	AllowNull bool
+	// When set to true, clauses on this field are dropped by Filter.ToSquirrelSql and Filter.ToSquirrelPredicate instead
+	// of being converted, e.g. for fields that only affect the UI, or that you want to process manually after the
+	// conversion. Other fields in the config are not checked. A group of Filter.Groups that only has clauses on ignored
+	// fields is rejected, as it would match every row. Defaults to false.
+	Ignore bool
 	// A function that handle parsing the sql statement by itself.
 	// If set, all other fields in the config will be ignored
+	// It is the only way to support fuzzy matches (`name:jon~1`, operator `~`), e.g. with a trigram similarity function.
 	CustomBuilder func(stmt sq.SelectBuilder, operator string, values []string) (sq.SelectBuilder, error)
 }
 
@@ -70,27 +96,228 @@ type FilterToSquirrelSqlFieldConfig struct {
 //
 // ...... WHERE user_id = 123456 AND status in ("active","frozen","deleted") .....
 //
-// Note: the input timestamp format should always be time.RFC3339Nano
+// Note: the input timestamp format should always be time.RFC3339Nano, or one of the relative time keywords such as
+// `today` (see RelativeTimeToday), which are resolved using the clock and location set with WithClock and
+// WithDefaultLocation.
 var unknownFieldErr = errors.Errorf("unknown field")
 
-func (f Filter) ToSquirrelSql(stmt sq.SelectBuilder, fieldConfigs map[string]FilterToSquirrelSqlFieldConfig) (sq.SelectBuilder, error) {
-	var err error
+func (f Filter) ToSquirrelSql(stmt sq.SelectBuilder, fieldConfigs map[string]FilterToSquirrelSqlFieldConfig, options ...ConverterOption) (sq.SelectBuilder, error) {
+	return newSquirrelConverter(fieldConfigs, options).Convert(stmt, f)
+}
+
+// SquirrelConverter attaches filters to squirrel select builders like Filter.ToSquirrelSql, for a fixed set of field
+// configs. The field configs are validated once, which makes it cheaper than Filter.ToSquirrelSql when converting
+// many filters. A SquirrelConverter is safe for concurrent use.
+type SquirrelConverter struct {
+	fieldConfigs map[string]FilterToSquirrelSqlFieldConfig
+	// Canonical field name by field name or alias.
+	fields     map[string]string
+	fieldNames []string
+	options    converterOptions
+}
+
+// NewSquirrelConverter returns a SquirrelConverter for the given field configs.
+// It returns an error if a field config has an unknown column type, or if an alias is used by more than one field or
+// shadows a field name.
+func NewSquirrelConverter(fieldConfigs map[string]FilterToSquirrelSqlFieldConfig, options ...ConverterOption) (*SquirrelConverter, error) {
+	for name, fc := range fieldConfigs {
+		if fc.ColumnType < FilterToSquirrelSqlFieldColumnTypeUnspecified || fc.ColumnType > FilterToSquirrelSqlFieldColumnTypeTimestamp {
+			return nil, fmt.Errorf("field %s: unknown column type %d", name, fc.ColumnType)
+		}
+		for _, alias := range fc.Aliases {
+			if _, ok := fieldConfigs[alias]; ok && alias != name {
+				return nil, fmt.Errorf("alias %s of field %s shadows field %s", alias, name, alias)
+			}
+			for other, otherConfig := range fieldConfigs {
+				if other != name && slices.Contains(otherConfig.Aliases, alias) {
+					return nil, fmt.Errorf("alias %s is used by fields %s and %s", alias, name, other)
+				}
+			}
+		}
+	}
+	return newSquirrelConverter(fieldConfigs, options), nil
+}
+
+func newSquirrelConverter(fieldConfigs map[string]FilterToSquirrelSqlFieldConfig, options []ConverterOption) *SquirrelConverter {
+	names := make([]string, 0, len(fieldConfigs))
+	fields := make(map[string]string, len(fieldConfigs))
+	for name, fc := range fieldConfigs {
+		names = append(names, name)
+		names = append(names, fc.Aliases...)
+		for _, alias := range fc.Aliases {
+			fields[alias] = name
+		}
+	}
+	// Field names take precedence over aliases.
+	for name := range fieldConfigs {
+		fields[name] = name
+	}
+	return &SquirrelConverter{fieldConfigs: fieldConfigs, fields: fields, fieldNames: names, options: newConverterOptions(options)}
+}
+
+// lookup returns the config of the given field name or alias. The column name of the config defaults to the field
+// name, so that an alias refers to the same column as its field.
+func (c *SquirrelConverter) lookup(field string) (FilterToSquirrelSqlFieldConfig, bool) {
+	name, ok := c.fields[field]
+	if !ok {
+		return FilterToSquirrelSqlFieldConfig{}, false
+	}
+	fc := c.fieldConfigs[name]
+	if fc.ColumnName == "" {
+		fc.ColumnName = name
+	}
+	return fc, true
+}
 
+// Convert attaches the filter to the given squirrel select builder; see Filter.ToSquirrelSql.
+func (c *SquirrelConverter) Convert(stmt sq.SelectBuilder, f Filter) (sq.SelectBuilder, error) {
+	o := c.options
+	f, includeDeleted, err := o.extractIncludeDeleted(f)
+	if err != nil {
+		return stmt, err
+	}
+
+	errs := errorCollector{all: o.allErrors}
 	for i, clause := range f.Clauses {
-		fieldConfig, ok := fieldConfigs[clause.Field]
+		fieldConfig, ok := c.lookup(clause.Field)
 		if !ok {
-			return stmt, errors.Wrapf(unknownFieldErr, "unknown field: %s", clause.Field)
+			if err := NewUnknownFieldError(clause.Field, c.fieldNames); errs.add(err) {
+				return stmt, err
+			}
+			continue
+		}
+		if fieldConfig.Ignore {
+			continue
 		}
 
-		stmt, err = clause.ToSquirrelSql(stmt, fieldConfig)
+		next, err := clause.toSquirrelSql(stmt, fieldConfig, o)
 		if err != nil {
-			return stmt, errors.Wrapf(err, "failed to parse clause %d to squirrel sql statement", i)
+			if err := errors.Wrapf(err, "failed to parse clause %d to squirrel sql statement", i); errs.add(err) {
+				return stmt, err
+			}
+			continue
+		}
+		stmt = next
+	}
+	if len(f.Groups) > 0 {
+		if groups := c.groupsCondition(f.Groups, &errs); groups != nil {
+			stmt = stmt.Where(groups)
 		}
 	}
+	if err := errs.err(); err != nil {
+		return stmt, err
+	}
+	if o.softDeleteColumn != "" && !includeDeleted {
+		stmt = stmt.Where(sq.Eq{o.softDeleteColumn: false})
+	}
 	return stmt, nil
 }
 
-func (c *Clause) ToSquirrelSql(stmt sq.SelectBuilder, config FilterToSquirrelSqlFieldConfig) (sq.SelectBuilder, error) {
+// ToSquirrelPredicate converts the filter to a single squirrel condition, the conjunction of its clauses, which can be
+// attached to UPDATE or DELETE builders or combined with other conditions. It applies the same field configs and
+// options as Filter.ToSquirrelSql, but fields with a CustomBuilder are not supported and result in an error.
+func (f Filter) ToSquirrelPredicate(fieldConfigs map[string]FilterToSquirrelSqlFieldConfig, options ...ConverterOption) (sq.Sqlizer, error) {
+	return newSquirrelConverter(fieldConfigs, options).Predicate(f)
+}
+
+// Predicate converts the filter to a single squirrel condition; see Filter.ToSquirrelPredicate.
+func (c *SquirrelConverter) Predicate(f Filter) (sq.Sqlizer, error) {
+	o := c.options
+	f, includeDeleted, err := o.extractIncludeDeleted(f)
+	if err != nil {
+		return nil, err
+	}
+
+	conds := make(sq.And, 0, len(f.Clauses)+1)
+	errs := errorCollector{all: o.allErrors}
+	for i, clause := range f.Clauses {
+		fieldConfig, ok := c.lookup(clause.Field)
+		if !ok {
+			if err := NewUnknownFieldError(clause.Field, c.fieldNames); errs.add(err) {
+				return nil, err
+			}
+			continue
+		}
+		if fieldConfig.Ignore {
+			continue
+		}
+
+		cond, err := clause.toSquirrelCondition(fieldConfig, o)
+		if err != nil {
+			if err := errors.Wrapf(err, "failed to parse clause %d to squirrel condition", i); errs.add(err) {
+				return nil, err
+			}
+			continue
+		}
+		conds = append(conds, cond)
+	}
+	if len(f.Groups) > 0 {
+		if groups := c.groupsCondition(f.Groups, &errs); groups != nil {
+			conds = append(conds, groups)
+		}
+	}
+	if err := errs.err(); err != nil {
+		return nil, err
+	}
+	if o.softDeleteColumn != "" && !includeDeleted {
+		conds = append(conds, sq.Eq{o.softDeleteColumn: false})
+	}
+	return conds, nil
+}
+
+// groupsCondition converts the groups of a filter to the disjunction of their conjunctions. A group whose clauses are
+// all ignored is rejected, as it would match every row. It returns nil if a group cannot be converted, after recording
+// the error.
+func (c *SquirrelConverter) groupsCondition(groups [][]Clause, errs *errorCollector) sq.Sqlizer {
+	failed := false
+	or := make(sq.Or, 0, len(groups))
+	for i, group := range groups {
+		conds := make(sq.And, 0, len(group))
+		ignored := 0
+		for j, clause := range group {
+			fieldConfig, ok := c.lookup(clause.Field)
+			if !ok {
+				failed = true
+				if errs.add(NewUnknownFieldError(clause.Field, c.fieldNames)) {
+					return nil
+				}
+				continue
+			}
+			if fieldConfig.Ignore {
+				ignored++
+				continue
+			}
+
+			cond, err := clause.toSquirrelCondition(fieldConfig, c.options)
+			if err != nil {
+				failed = true
+				if errs.add(errors.Wrapf(err, "failed to parse clause %d of group %d to squirrel condition", j, i)) {
+					return nil
+				}
+				continue
+			}
+			conds = append(conds, cond)
+		}
+		if ignored > 0 && ignored == len(group) {
+			failed = true
+			if errs.add(errors.Errorf("group %d only has ignored fields and would match every row", i)) {
+				return nil
+			}
+			continue
+		}
+		or = append(or, conds)
+	}
+	if failed {
+		return nil
+	}
+	return or
+}
+
+func (c *Clause) ToSquirrelSql(stmt sq.SelectBuilder, config FilterToSquirrelSqlFieldConfig, options ...ConverterOption) (sq.SelectBuilder, error) {
+	return c.toSquirrelSql(stmt, config, newConverterOptions(options))
+}
+
+func (c *Clause) toSquirrelSql(stmt sq.SelectBuilder, config FilterToSquirrelSqlFieldConfig, o converterOptions) (sq.SelectBuilder, error) {
 	var err error
 	// use customer parser if provided
 	if config.CustomBuilder != nil {
@@ -101,20 +328,58 @@ func (c *Clause) ToSquirrelSql(stmt sq.SelectBuilder, config FilterToSquirrelSql
 		return stmt, nil
 	}
 
+	cond, err := c.toSquirrelCondition(config, o)
+	if err != nil {
+		return stmt, err
+	}
+	return stmt.Where(cond), nil
+}
+
+// ToSquirrelCondition converts the clause to a squirrel condition using `?` placeholders, which can be attached to
+// any statement or rendered with ToSql, e.g. to pass it to another query builder.
+// The CustomBuilder of the config is not supported and results in an error.
+func (c *Clause) ToSquirrelCondition(config FilterToSquirrelSqlFieldConfig, options ...ConverterOption) (sq.Sqlizer, error) {
+	return c.toSquirrelCondition(config, newConverterOptions(options))
+}
+
+func (c *Clause) toSquirrelCondition(config FilterToSquirrelSqlFieldConfig, o converterOptions) (sq.Sqlizer, error) {
+	if config.CustomBuilder != nil {
+		return nil, errors.Errorf("custom builder of field %s cannot be converted to a condition", c.Field)
+	}
+	if c.Operator == "~" {
+		return nil, errors.Errorf("field %s: fuzzy matching requires a custom builder", c.Field)
+	}
+	var cond sq.Sqlizer
+	var err error
+
 	// get field name
 	columnName := config.ColumnName
 	if columnName == "" {
 		columnName = c.Field
 	}
 
+	if config.AllowNull {
+		if cond, ok := nullCondition(columnName, c); ok {
+			return cond, nil
+		}
+	}
+
 	// use MapValue function in config if provided
 	rawValues := make([]any, 0, len(c.Values))
+	for _, value := range c.Values {
+		if err := checkAllowedValue(value, config.AllowedValues); err != nil {
+			return nil, config.ValueSuggestions.apply(err)
+		}
+	}
 	if config.MapValue != nil {
 		mappedValues := make([]any, 0, len(rawValues))
 		for i := range c.Values {
 			mappedValue, err := config.MapValue(c.Values[i])
 			if err != nil {
-				return stmt, err
+				if len(config.AllowedValues) > 0 {
+					err = NewInvalidValueError(c.Values[i], err, config.AllowedValues)
+				}
+				return nil, config.ValueSuggestions.apply(err)
 			}
 			mappedValues = append(mappedValues, mappedValue)
 		}
@@ -131,104 +396,173 @@ func (c *Clause) ToSquirrelSql(stmt sq.SelectBuilder, config FilterToSquirrelSql
 		for i, v := range rawValues {
 			nativeValue, err := any2Int64(v)
 			if err != nil {
-				return stmt, errors.Wrapf(err, "failed to convert value %+v at index %d to int64", v, i)
+				return nil, errors.Wrapf(err, "failed to convert value %+v at index %d to int64", v, i)
 			}
 			nativeValues = append(nativeValues, nativeValue)
 		}
-		stmt, err = buildStmtByOperator[int64](stmt, columnName, c.Operator, nativeValues, config)
+		cond, err = buildCondition[int64](columnName, c.Operator, nativeValues, config, o)
 	case FilterToSquirrelSqlFieldColumnTypeFloat64:
 		nativeValues := make([]float64, 0, len(rawValues))
 		for i, v := range rawValues {
 			nativeValue, err := any2Float64(v)
 			if err != nil {
-				return stmt, errors.Wrapf(valueConvertErr, "failed to convert value %s (index %d in filter c values) to float64", v, i)
+				return nil, errors.Wrapf(valueConvertErr, "failed to convert value %s (index %d in filter c values) to float64", v, i)
 			}
 			nativeValues = append(nativeValues, nativeValue)
 		}
-		stmt, err = buildStmtByOperator[float64](stmt, columnName, c.Operator, nativeValues, config)
+		cond, err = buildCondition[float64](columnName, c.Operator, nativeValues, config, o)
 	case FilterToSquirrelSqlFieldColumnTypeBool:
 		nativeValues := make([]bool, 0, len(rawValues))
 		for i, v := range rawValues {
 			nativeValue, err := any2Bool(v)
 			if err != nil {
-				return stmt, errors.Wrapf(valueConvertErr, "failed to convert value %s (index %d in filter c values) to bool", v, i)
+				return nil, errors.Wrapf(valueConvertErr, "failed to convert value %s (index %d in filter c values) to bool", v, i)
 			}
 			nativeValues = append(nativeValues, nativeValue)
 		}
-		stmt, err = buildStmtByOperator[bool](stmt, columnName, c.Operator, nativeValues, config)
+		cond, err = buildCondition[bool](columnName, c.Operator, nativeValues, config, o)
 	case FilterToSquirrelSqlFieldColumnTypeTimestamp:
+		if c.Operator == "=" && len(rawValues) == 1 && config.MapValue == nil {
+			s, _ := rawValues[0].(string)
+			if start, end, ok := o.dateRange(s); ok {
+				// A date only matches the whole day.
+				cond = sq.And{sq.GtOrEq{columnName: start}, sq.Lt{columnName: end}}
+				break
+			}
+		}
 		nativeValues := make([]time.Time, 0, len(rawValues))
 		for i, v := range rawValues {
+			if s, ok := v.(string); ok {
+				if relative, ok := o.resolveRelativeTime(s); ok {
+					v = relative
+				} else if t, err := o.parseTime(s); err == nil {
+					v = t
+				}
+			}
 			nativeValue, err := any2Time(v)
 			if err != nil {
-				return stmt, errors.Wrapf(valueConvertErr, "failed to convert value %s (index %d in filter c values) to time.Time", v, i)
+				return nil, errors.Wrapf(valueConvertErr, "failed to convert value %s (index %d in filter c values) to time.Time", v, i)
 			}
 			nativeValues = append(nativeValues, nativeValue)
 		}
-		stmt, err = buildStmtByOperator[time.Time](stmt, columnName, c.Operator, nativeValues, config)
+		cond, err = buildCondition[time.Time](columnName, c.Operator, nativeValues, config, o)
 	default:
 		nativeValues := make([]string, 0, len(rawValues))
 		for i, v := range rawValues {
 			nativeValue := any2Str(v)
 			if err != nil {
-				return stmt, errors.Wrapf(valueConvertErr, "failed to convert value %s (index %d in filter c values) to time.Time", v, i)
+				return nil, errors.Wrapf(valueConvertErr, "failed to convert value %s (index %d in filter c values) to time.Time", v, i)
 			}
 			nativeValues = append(nativeValues, nativeValue)
 		}
-		stmt, err = buildStmtByOperator[string](stmt, columnName, c.Operator, nativeValues, config)
+		cond, err = buildCondition[string](columnName, c.Operator, nativeValues, config, o)
 	}
 
 	if err != nil {
-		return stmt, errors.Wrapf(err, "failed to build statement by operator")
+		return nil, errors.Wrapf(err, "failed to build statement by operator")
 	}
-	return stmt, nil
+	return cond, nil
+}
+
+// nullCondition returns the IS NULL or IS NOT NULL condition of a clause matching `null` or `*`, see AllowNull.
+func nullCondition(columnName string, c *Clause) (sq.Sqlizer, bool) {
+	if len(c.Values) != 1 || (c.Operator != "=" && c.Operator != "!=") {
+		return nil, false
+	}
+	var isNull bool
+	switch c.Values[0] {
+	case "null":
+		isNull = c.Operator == "="
+	case "*":
+		isNull = c.Operator == "!="
+	default:
+		return nil, false
+	}
+	if isNull {
+		return sq.Eq{columnName: nil}, true
+	}
+	return sq.NotEq{columnName: nil}, true
 }
 
 var emptyValuesErr = errors.Errorf("no values provided")
 var valuesNumError = errors.Errorf("wrong values num")
 var operatorError = errors.Errorf("unsupported operator")
 
-func buildStmtByOperator[T string | int64 | float64 | bool | time.Time](stmt sq.SelectBuilder, columnName string, op string, values []T, config FilterToSquirrelSqlFieldConfig) (sq.SelectBuilder, error) {
+func buildCondition[T string | int64 | float64 | bool | time.Time](columnName string, op string, values []T, config FilterToSquirrelSqlFieldConfig, o converterOptions) (sq.Sqlizer, error) {
 	switch op {
 	case "IN":
 		if len(values) == 0 {
-			return stmt, emptyValuesErr
+			return nil, emptyValuesErr
 		}
 		if len(values) > 1 && !config.AllowMultipleValues {
-			return stmt, errors.Wrapf(valuesNumError, "values num %d doesn't match the operator %s", len(values), op)
+			return nil, errors.Wrapf(valuesNumError, "values num %d doesn't match the operator %s", len(values), op)
+		}
+		if strs, ok := any(values).([]string); ok {
+			return sq.Eq{columnName: unescapeValues(strs)}, nil
+		}
+		if bools, ok := any(values).([]bool); ok {
+			// Booleans are matched with an equality per value, like in the other converters.
+			or := sq.Or{}
+			for _, b := range uniqueSliceElements(bools) {
+				or = append(or, sq.Eq{columnName: b})
+			}
+			return or, nil
 		}
-		stmt = stmt.Where(sq.Eq{columnName: values})
+		return sq.Eq{columnName: values}, nil
 	case "=", ">", ">=", "<", "<=":
 		if !config.AllowRanges && (op == ">" || op == ">=" || op == "<" || op == "<=") {
-			return stmt, errors.Wrapf(operatorError, "operator %s not supported", op)
+			return nil, errors.Wrapf(operatorError, "operator %s not supported", op)
 		}
 		if len(values) != 1 {
-			return stmt, errors.Wrapf(valuesNumError, "values num %d doesn't match the operator %s", len(values), op)
+			return nil, errors.Wrapf(valuesNumError, "values num %d doesn't match the operator %s", len(values), op)
 		}
 		switch op {
 		case "=":
-			if vStr, ok := any(values[0]).(string); ok && config.AllowPrefixMatch && strings.HasSuffix(vStr, "*") && !strings.HasSuffix(vStr, `\*`) {
-				vStr = vStr[:len(vStr)-1]                  // trim the suffix * ( don't use the TrimRightFunc because it'll also remove the first start from suffix "**"
+			vStr, ok := any(values[0]).(string)
+			var prefix bool
+			if ok {
+				// trim the suffix * and unescape literal asterisks
+				vStr, prefix, _ = SplitWildcards(vStr, config.AllowPrefixMatch, false)
+			}
+			if prefix {
 				vStr = strings.ReplaceAll(vStr, `\`, `\\`) // escape all `\`
 				vStr = strings.ReplaceAll(vStr, `%`, `\%`) // escape all `%`
 				vStr = strings.ReplaceAll(vStr, `_`, `\_`) // escape all `_`
-				stmt = stmt.Where(sq.Like{columnName: vStr + "%"})
+				if config.AllowCaseInsensitiveMatch {
+					if o.ilike {
+						return sq.ILike{columnName: vStr + "%"}, nil
+					}
+					return sq.Expr(fmt.Sprintf("LOWER(%s) LIKE LOWER(?)", columnName), vStr+"%"), nil
+				}
+				return sq.Like{columnName: vStr + "%"}, nil
+			} else if ok && config.AllowCaseInsensitiveMatch {
+				if o.ilike {
+					return sq.ILike{columnName: escapePrefixSuffixSpecialChars(vStr)}, nil
+				}
+				return sq.Expr(fmt.Sprintf("LOWER(%s) = LOWER(?)", columnName), vStr), nil
+			} else if ok {
+				return sq.Eq{columnName: vStr}, nil
 			} else {
-				stmt = stmt.Where(sq.Eq{columnName: values[0]})
+				return sq.Eq{columnName: values[0]}, nil
 			}
+		}
+		value := any(values[0])
+		if vStr, ok := value.(string); ok {
+			value = UnescapeValue(vStr)
+		}
+		switch op {
 		case ">":
-			stmt = stmt.Where(sq.Gt{columnName: values[0]})
+			return sq.Gt{columnName: value}, nil
 		case ">=":
-			stmt = stmt.Where(sq.GtOrEq{columnName: values[0]})
+			return sq.GtOrEq{columnName: value}, nil
 		case "<":
-			stmt = stmt.Where(sq.Lt{columnName: values[0]})
-		case "<=":
-			stmt = stmt.Where(sq.LtOrEq{columnName: values[0]})
+			return sq.Lt{columnName: value}, nil
+		default:
+			return sq.LtOrEq{columnName: value}, nil
 		}
 	default:
-		return stmt, errors.Wrapf(operatorError, "unsupported operator %s", op)
+		return nil, errors.Wrapf(operatorError, "unsupported operator %s", op)
 	}
-	return stmt, nil
 }
 
 var valueConvertErr = errors.Errorf("value convert error") // used in test cases
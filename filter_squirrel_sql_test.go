@@ -165,6 +165,65 @@ func TestToSquirrelSql(t *testing.T) {
 			"SELECT * FROM users WHERE self_intro LIKE ?",
 			[]any{`Monday\_\%a\\\_\\\%\\*%`},
 		},
+		{
+			"one string field with escaped asterisk",
+			`self_intro:Monday\*`,
+			map[string]FilterToSquirrelSqlFieldConfig{
+				"self_intro": {
+					ColumnName:       "self_intro",
+					ColumnType:       FilterToSquirrelSqlFieldColumnTypeString,
+					AllowPrefixMatch: true,
+				},
+			},
+			nil,
+			"SELECT * FROM users WHERE self_intro = ?",
+			[]any{"Monday*"},
+		},
+		{
+			"escaped asterisk in parentheses",
+			`type:(team\*)`,
+			map[string]FilterToSquirrelSqlFieldConfig{
+				"type": {
+					ColumnName:          "type",
+					ColumnType:          FilterToSquirrelSqlFieldColumnTypeString,
+					AllowPrefixMatch:    true,
+					AllowMultipleValues: true,
+				},
+			},
+			nil,
+			"SELECT * FROM users WHERE type = ?",
+			[]any{"team*"},
+		},
+		{
+			"escaped asterisk in list of values",
+			`type:(a or b\*)`,
+			map[string]FilterToSquirrelSqlFieldConfig{
+				"type": {
+					ColumnName:          "type",
+					ColumnType:          FilterToSquirrelSqlFieldColumnTypeString,
+					AllowPrefixMatch:    true,
+					AllowMultipleValues: true,
+				},
+			},
+			nil,
+			"SELECT * FROM users WHERE type IN (?,?)",
+			[]any{"a", "b*"},
+		},
+		{
+			"escaped asterisk in comma-separated list of values",
+			`type:(a, b\*)`,
+			map[string]FilterToSquirrelSqlFieldConfig{
+				"type": {
+					ColumnName:          "type",
+					ColumnType:          FilterToSquirrelSqlFieldColumnTypeString,
+					AllowPrefixMatch:    true,
+					AllowMultipleValues: true,
+				},
+			},
+			nil,
+			"SELECT * FROM users WHERE type IN (?,?)",
+			[]any{"a", "b*"},
+		},
 		{
 			"one string field with values map 1",
 			"favorite_day:(Monday OR Tuesday)",
@@ -212,6 +271,57 @@ func TestToSquirrelSql(t *testing.T) {
 			"SELECT * FROM users WHERE create_time < ?",
 			[]any{time.Date(2023, 01, 01, 00, 00, 00, 00, time.UTC)},
 		},
+		{
+			"case insensitive equality",
+			"name:Beau", map[string]FilterToSquirrelSqlFieldConfig{
+				"name": {
+					AllowCaseInsensitiveMatch: true,
+				},
+			},
+			nil,
+			"SELECT * FROM users WHERE LOWER(name) = LOWER(?)",
+			[]any{"Beau"},
+		},
+		{
+			"case insensitive prefix match",
+			"name:Be_*", map[string]FilterToSquirrelSqlFieldConfig{
+				"name": {
+					AllowPrefixMatch:          true,
+					AllowCaseInsensitiveMatch: true,
+				},
+			},
+			nil,
+			"SELECT * FROM users WHERE LOWER(name) LIKE LOWER(?)",
+			[]any{`Be\_%`},
+		},
+		{
+			"string range operator",
+			`id>=01HZX3M5K2 and id<"01HZX3M5K2\*"`,
+			map[string]FilterToSquirrelSqlFieldConfig{
+				"id": {
+					ColumnName:  "id",
+					ColumnType:  FilterToSquirrelSqlFieldColumnTypeString,
+					AllowRanges: true,
+				},
+			},
+			nil,
+			"SELECT * FROM users WHERE id >= ? AND id < ?",
+			[]any{"01HZX3M5K2", "01HZX3M5K2*"},
+		},
+		{
+			"negated range operator",
+			"not age>30",
+			map[string]FilterToSquirrelSqlFieldConfig{
+				"age": {
+					ColumnName:  "age",
+					ColumnType:  FilterToSquirrelSqlFieldColumnTypeInt64,
+					AllowRanges: true,
+				},
+			},
+			nil,
+			"SELECT * FROM users WHERE age <= ?",
+			[]any{int64(30)},
+		},
 		{
 			"unknown field",
 			"name:Beau age:30",
@@ -454,3 +564,323 @@ func TestAny2Str(t *testing.T) {
 		require.Equalf(t, "1", i, "%d: %+v\n", index, reflect.TypeOf(c))
 	}
 }
+
+func TestNewSquirrelConverter(t *testing.T) {
+	configs := map[string]FilterToSquirrelSqlFieldConfig{
+		"userId": {ColumnName: "user_id", ColumnType: FilterToSquirrelSqlFieldColumnTypeInt64},
+		"state":  {AllowMultipleValues: true},
+	}
+	c, err := NewSquirrelConverter(configs)
+	require.NoError(t, err)
+
+	f, err := Parse("userId:1 state:(a OR b)")
+	require.NoError(t, err)
+	stmt, err := c.Convert(sq.Select("*").From("users"), f)
+	require.NoError(t, err)
+	sql, args, err := stmt.ToSql()
+	require.NoError(t, err)
+	require.Equal(t, "SELECT * FROM users WHERE user_id = ? AND state IN (?,?)", sql)
+	require.Equal(t, []any{int64(1), "a", "b"}, args)
+
+	_, err = NewSquirrelConverter(map[string]FilterToSquirrelSqlFieldConfig{
+		"userId": {ColumnType: FilterToSquirrelSqlFieldColumnType(42)},
+	})
+	require.EqualError(t, err, "field userId: unknown column type 42")
+
+	_, err = NewSquirrelConverter(map[string]FilterToSquirrelSqlFieldConfig{
+		"a": {Aliases: []string{"b"}},
+		"b": {},
+	})
+	require.EqualError(t, err, "alias b of field a shadows field b")
+
+	_, err = NewSquirrelConverter(map[string]FilterToSquirrelSqlFieldConfig{
+		"a": {Aliases: []string{"x"}},
+		"b": {Aliases: []string{"x"}},
+	})
+	require.ErrorContains(t, err, "alias x is used by fields")
+}
+
+func TestToSquirrelSqlILike(t *testing.T) {
+	columnMap := map[string]FilterToSquirrelSqlFieldConfig{
+		"name": {
+			AllowPrefixMatch:          true,
+			AllowCaseInsensitiveMatch: true,
+		},
+	}
+
+	testCases := []struct {
+		input        string
+		expectedArgs []any
+	}{
+		{"name:Beau", []any{"Beau"}},
+		{"name:50%", []any{`50\%`}},
+		{"name:Be*", []any{"Be%"}},
+	}
+	for _, test := range testCases {
+		t.Run(test.input, func(t *testing.T) {
+			f, err := Parse(test.input)
+			require.NoError(t, err)
+			stmt, err := f.ToSquirrelSql(sq.Select("*").From("users"), columnMap, WithILike())
+			require.NoError(t, err)
+			sql, args, err := stmt.ToSql()
+			require.NoError(t, err)
+			require.Equal(t, "SELECT * FROM users WHERE name ILIKE ?", sql)
+			require.Equal(t, test.expectedArgs, args)
+		})
+	}
+}
+
+func TestToSquirrelPredicate(t *testing.T) {
+	columnMap := map[string]FilterToSquirrelSqlFieldConfig{
+		"userId": {ColumnName: "user_id", ColumnType: FilterToSquirrelSqlFieldColumnTypeInt64},
+		"state":  {AllowMultipleValues: true},
+		"custom": {CustomBuilder: func(stmt sq.SelectBuilder, operator string, values []string) (sq.SelectBuilder, error) {
+			return stmt, nil
+		}},
+	}
+
+	f, err := Parse("userId:1 state:(a OR b)")
+	require.NoError(t, err)
+	pred, err := f.ToSquirrelPredicate(columnMap, WithSoftDelete("deleted", false))
+	require.NoError(t, err)
+
+	sql, args, err := sq.Delete("users").Where(pred).ToSql()
+	require.NoError(t, err)
+	require.Equal(t, "DELETE FROM users WHERE (user_id = ? AND state IN (?,?) AND deleted = ?)", sql)
+	require.Equal(t, []any{int64(1), "a", "b", false}, args)
+
+	sql, args, err = sq.Update("users").Set("state", "c").Where(sq.Or{pred, sq.Eq{"owner": "x"}}).ToSql()
+	require.NoError(t, err)
+	require.Equal(t, "UPDATE users SET state = ? WHERE ((user_id = ? AND state IN (?,?) AND deleted = ?) OR owner = ?)", sql)
+	require.Equal(t, []any{"c", int64(1), "a", "b", false, "x"}, args)
+
+	f, err = Parse("custom:x")
+	require.NoError(t, err)
+	_, err = f.ToSquirrelPredicate(columnMap)
+	require.Error(t, err)
+
+	f, err = Parse("unknown:x")
+	require.NoError(t, err)
+	_, err = f.ToSquirrelPredicate(columnMap)
+	require.ErrorIs(t, err, ErrUnknownField)
+}
+
+func TestToSquirrelSqlAllErrors(t *testing.T) {
+	columnMap := map[string]FilterToSquirrelSqlFieldConfig{
+		"userId": {ColumnName: "user_id", ColumnType: FilterToSquirrelSqlFieldColumnTypeInt64},
+		"state":  {},
+	}
+
+	f, err := Parse("foo:1 userId:abc state:(a OR b) state:c")
+	require.NoError(t, err)
+
+	_, err = f.ToSquirrelSql(sq.Select("*").From("users"), columnMap)
+	require.EqualError(t, err, "unknown field: foo")
+
+	_, err = f.ToSquirrelSql(sq.Select("*").From("users"), columnMap, WithAllErrors())
+	require.Error(t, err)
+	require.ErrorIs(t, err, ErrUnknownField)
+	require.ErrorIs(t, err, valuesNumError)
+	require.Len(t, err.(interface{ Unwrap() []error }).Unwrap(), 3)
+
+	_, err = f.ToSquirrelPredicate(columnMap, WithAllErrors())
+	require.Error(t, err)
+	require.Len(t, err.(interface{ Unwrap() []error }).Unwrap(), 3)
+}
+
+func TestToSquirrelSqlIgnore(t *testing.T) {
+	columnMap := map[string]FilterToSquirrelSqlFieldConfig{
+		"state": {},
+		"view":  {Ignore: true, ColumnType: FilterToSquirrelSqlFieldColumnTypeInt64},
+	}
+
+	f, err := Parse("state:active view:(grid OR list) view>x")
+	require.NoError(t, err)
+
+	stmt, err := f.ToSquirrelSql(sq.Select("*").From("users"), columnMap)
+	require.NoError(t, err)
+	sql, args, err := stmt.ToSql()
+	require.NoError(t, err)
+	require.Equal(t, "SELECT * FROM users WHERE state = ?", sql)
+	require.Equal(t, []any{"active"}, args)
+
+	pred, err := f.ToSquirrelPredicate(columnMap)
+	require.NoError(t, err)
+	sql, args, err = sq.Delete("users").Where(pred).ToSql()
+	require.NoError(t, err)
+	require.Equal(t, "DELETE FROM users WHERE (state = ?)", sql)
+	require.Equal(t, []any{"active"}, args)
+}
+
+func TestToSquirrelSqlAliases(t *testing.T) {
+	columnMap := map[string]FilterToSquirrelSqlFieldConfig{
+		"user_id":    {ColumnType: FilterToSquirrelSqlFieldColumnTypeInt64, Aliases: []string{"userId"}},
+		"created_at": {ColumnName: "create_time", ColumnType: FilterToSquirrelSqlFieldColumnTypeTimestamp, AllowRanges: true, Aliases: []string{"createdAt"}},
+	}
+
+	f, err := Parse(`userId:1 user_id:2 createdAt>="2024-01-01T00:00:00Z"`)
+	require.NoError(t, err)
+
+	stmt, err := f.ToSquirrelSql(sq.Select("*").From("users"), columnMap)
+	require.NoError(t, err)
+	sql, args, err := stmt.ToSql()
+	require.NoError(t, err)
+	require.Equal(t, "SELECT * FROM users WHERE user_id = ? AND user_id = ? AND create_time >= ?", sql)
+	require.Equal(t, []any{int64(1), int64(2), time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}, args)
+
+	pred, err := f.ToSquirrelPredicate(columnMap)
+	require.NoError(t, err)
+	sql, _, err = sq.Delete("users").Where(pred).ToSql()
+	require.NoError(t, err)
+	require.Equal(t, "DELETE FROM users WHERE (user_id = ? AND user_id = ? AND create_time >= ?)", sql)
+
+	f, err = Parse(`user:1`)
+	require.NoError(t, err)
+	_, err = f.ToSquirrelSql(sq.Select("*").From("users"), columnMap)
+	require.EqualError(t, err, "unknown field: user; did you mean userId?")
+}
+
+func TestToSquirrelSqlGroups(t *testing.T) {
+	columnMap := map[string]FilterToSquirrelSqlFieldConfig{
+		"userId":  {ColumnName: "user_id", ColumnType: FilterToSquirrelSqlFieldColumnTypeInt64},
+		"state":   {AllowMultipleValues: true},
+		"trace":   {Ignore: true},
+		"ownerId": {ColumnName: "owner_id"},
+	}
+
+	ast, err := ParseAST("(userId:1 and state:(a or b)) or ownerId:x")
+	require.NoError(t, err)
+	f, err := ConvertToFilter(ast)
+	require.NoError(t, err)
+	f = NewFilterFrom(f).Eq("state", "c").Filter()
+
+	stmt, err := f.ToSquirrelSql(sq.Select("*").From("users"), columnMap)
+	require.NoError(t, err)
+	sql, args, err := stmt.ToSql()
+	require.NoError(t, err)
+	require.Equal(t, "SELECT * FROM users WHERE state = ? AND ((user_id = ? AND state IN (?,?)) OR (owner_id = ?))", sql)
+	require.Equal(t, []any{"c", int64(1), "a", "b", "x"}, args)
+
+	pred, err := f.ToSquirrelPredicate(columnMap)
+	require.NoError(t, err)
+	sql, args, err = sq.Delete("users").Where(pred).ToSql()
+	require.NoError(t, err)
+	require.Equal(t, "DELETE FROM users WHERE (state = ? AND ((user_id = ? AND state IN (?,?)) OR (owner_id = ?)))", sql)
+	require.Equal(t, []any{"c", int64(1), "a", "b", "x"}, args)
+
+	// Ignored clauses are dropped from a group.
+	ast, err = ParseAST("(userId:1 and trace:abc) or ownerId:x")
+	require.NoError(t, err)
+	f, err = ConvertToFilter(ast)
+	require.NoError(t, err)
+	stmt, err = f.ToSquirrelSql(sq.Select("*").From("users"), columnMap)
+	require.NoError(t, err)
+	sql, _, err = stmt.ToSql()
+	require.NoError(t, err)
+	require.Equal(t, "SELECT * FROM users WHERE ((user_id = ?) OR (owner_id = ?))", sql)
+
+	// A group of ignored clauses would match every row.
+	ast, err = ParseAST("userId:1 or trace:abc")
+	require.NoError(t, err)
+	f, err = ConvertToFilter(ast)
+	require.NoError(t, err)
+	_, err = f.ToSquirrelSql(sq.Select("*").From("users"), columnMap)
+	require.EqualError(t, err, "group 1 only has ignored fields and would match every row")
+	_, err = f.ToSquirrelPredicate(columnMap)
+	require.EqualError(t, err, "group 1 only has ignored fields and would match every row")
+
+	ast, err = ParseAST("userId:1 or unknown:x")
+	require.NoError(t, err)
+	f, err = ConvertToFilter(ast)
+	require.NoError(t, err)
+	_, err = f.ToSquirrelSql(sq.Select("*").From("users"), columnMap)
+	require.ErrorIs(t, err, ErrUnknownField)
+}
+
+func TestToSquirrelSqlAllowNull(t *testing.T) {
+	columnMap := map[string]FilterToSquirrelSqlFieldConfig{
+		"deletedAt": {ColumnName: "deleted_at", ColumnType: FilterToSquirrelSqlFieldColumnTypeTimestamp, AllowNull: true},
+		"parentId":  {ColumnName: "parent_id", ColumnType: FilterToSquirrelSqlFieldColumnTypeInt64, AllowNull: true},
+		"email":     {AllowNull: true, AllowedValues: []string{"a@example.com"}},
+		"name":      {},
+	}
+
+	testCases := []struct {
+		input        string
+		options      []ParserOption
+		expectedSQL  string
+		expectedArgs []any
+	}{
+		{"deletedAt:null", nil, "SELECT * FROM users WHERE deleted_at IS NULL", nil},
+		{"not deletedAt:null", nil, "SELECT * FROM users WHERE deleted_at IS NOT NULL", nil},
+		{"parentId:* email:null", nil, "SELECT * FROM users WHERE parent_id IS NOT NULL AND email IS NULL", nil},
+		{"not parentId:*", nil, "SELECT * FROM users WHERE parent_id IS NULL", nil},
+		{"parentId IS NULL", []ParserOption{WithExtendedSyntax()}, "SELECT * FROM users WHERE parent_id IS NULL", nil},
+		{"parentId:1", nil, "SELECT * FROM users WHERE parent_id = ?", []any{int64(1)}},
+		{"name:null", nil, "SELECT * FROM users WHERE name = ?", []any{"null"}},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.input, func(t *testing.T) {
+			ast, err := ParseAST(test.input, test.options...)
+			require.NoError(t, err)
+			f, err := ConvertToFilter(ast)
+			require.NoError(t, err)
+			stmt, err := f.ToSquirrelSql(sq.Select("*").From("users"), columnMap)
+			require.NoError(t, err)
+			sql, args, err := stmt.ToSql()
+			require.NoError(t, err)
+			require.Equal(t, test.expectedSQL, sql)
+			require.Equal(t, test.expectedArgs, args)
+		})
+	}
+}
+
+func TestToSquirrelSqlBoolValues(t *testing.T) {
+	columnMap := map[string]FilterToSquirrelSqlFieldConfig{
+		"active": {ColumnType: FilterToSquirrelSqlFieldColumnTypeBool, AllowMultipleValues: true},
+	}
+
+	f, err := Parse("active:(true or false or true)")
+	require.NoError(t, err)
+	stmt, err := f.ToSquirrelSql(sq.Select("*").From("users"), columnMap)
+	require.NoError(t, err)
+	sql, args, err := stmt.ToSql()
+	require.NoError(t, err)
+	require.Equal(t, "SELECT * FROM users WHERE (active = ? OR active = ?)", sql)
+	require.Equal(t, []any{true, false}, args)
+
+	f, err = Parse("active:(true or yes)")
+	require.NoError(t, err)
+	_, err = f.ToSquirrelSql(sq.Select("*").From("users"), columnMap)
+	require.Error(t, err)
+}
+
+func TestToSquirrelSqlEpochTimestamps(t *testing.T) {
+	columnMap := map[string]FilterToSquirrelSqlFieldConfig{
+		"created": {ColumnType: FilterToSquirrelSqlFieldColumnTypeTimestamp, AllowRanges: true},
+	}
+	expected := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name  string
+		input string
+		unit  EpochUnit
+	}{
+		{name: "seconds", input: "created>1714521600", unit: EpochSeconds},
+		{name: "milliseconds", input: "created>1714521600000", unit: EpochMilliseconds},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			f, err := Parse(test.input)
+			require.NoError(t, err)
+			stmt, err := f.ToSquirrelSql(sq.Select("*").From("users"), columnMap, WithEpochTimestamps(test.unit))
+			require.NoError(t, err)
+			sql, args, err := stmt.ToSql()
+			require.NoError(t, err)
+			require.Equal(t, "SELECT * FROM users WHERE created > ?", sql)
+			require.Equal(t, []any{expected}, args)
+		})
+	}
+}
@@ -1,8 +1,12 @@
 package kqlfilter
 
 import (
+	"context"
+	"strings"
 	"testing"
+	"time"
 
+	sq "github.com/Masterminds/squirrel"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -93,6 +97,20 @@ func TestParse(t *testing.T) {
 				},
 			},
 		},
+		{
+			"comma-separated values are supported",
+			"field:(value, second)",
+			false,
+			Filter{
+				Clauses: []Clause{
+					{
+						Field:    "field",
+						Operator: "IN",
+						Values:   []string{"value", "second"},
+					},
+				},
+			},
+		},
 		{
 			"one field with range operator",
 			"field>=value",
@@ -121,6 +139,25 @@ func TestParse(t *testing.T) {
 				},
 			},
 		},
+		{
+			"negated range",
+			"not price >= 100 and not created_at < 2023-01-01",
+			false,
+			Filter{
+				Clauses: []Clause{
+					{
+						Field:    "price",
+						Operator: "<",
+						Values:   []string{"100"},
+					},
+					{
+						Field:    "created_at",
+						Operator: ">=",
+						Values:   []string{"2023-01-01"},
+					},
+				},
+			},
+		},
 		{
 			"one field with not operator and an empty value",
 			`not field:""`,
@@ -154,6 +191,20 @@ func TestParse(t *testing.T) {
 				},
 			},
 		},
+		{
+			"one field with not operator and multiple values",
+			"not field:(value OR second)",
+			false,
+			Filter{
+				Clauses: []Clause{
+					{
+						Field:    "field",
+						Operator: "NOT IN",
+						Values:   []string{"value", "second"},
+					},
+				},
+			},
+		},
 		{
 			"negation applied to an and node - not supported due to implicit resulting OR clause",
 			"not (field:value and another:second)",
@@ -227,3 +278,137 @@ func TestParse(t *testing.T) {
 		})
 	}
 }
+
+func TestParseRangeBracketsToFilter(t *testing.T) {
+	f, err := Parse("state:active price:[10 TO 20}")
+	require.NoError(t, err)
+	assert.Equal(t, []Clause{
+		{Field: "state", Operator: "=", Values: []string{"active"}},
+		{Field: "price", Operator: ">=", Values: []string{"10"}},
+		{Field: "price", Operator: "<", Values: []string{"20"}},
+	}, f.Clauses)
+
+	conditions, params, err := f.ToSpannerSQL(map[string]FilterToSpannerFieldConfig{
+		"state": {},
+		"price": {ColumnType: FilterToSpannerFieldColumnTypeInt64, AllowRanges: true},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"state=@KQL0", "price>=@KQL1", "price<@KQL2"}, conditions)
+	assert.Equal(t, map[string]any{"KQL0": "active", "KQL1": int64(10), "KQL2": int64(20)}, params)
+
+	stmt, err := f.ToSquirrelSql(sq.Select("*").From("items"), map[string]FilterToSquirrelSqlFieldConfig{
+		"state": {},
+		"price": {ColumnType: FilterToSquirrelSqlFieldColumnTypeInt64, AllowRanges: true},
+	})
+	require.NoError(t, err)
+	sql, args, err := stmt.ToSql()
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT * FROM items WHERE state = ? AND price >= ? AND price < ?", sql)
+	assert.Equal(t, []any{"active", int64(10), int64(20)}, args)
+}
+
+func TestParseFuzzyToFilter(t *testing.T) {
+	ast, err := ParseAST("name:jon~1", WithFuzzyMatching())
+	require.NoError(t, err)
+	f, err := convertToFilter(ast)
+	require.NoError(t, err)
+	assert.Equal(t, []Clause{{Field: "name", Operator: "~", Values: []string{"jon", "1"}}}, f.Clauses)
+
+	_, _, err = f.ToSpannerSQL(map[string]FilterToSpannerFieldConfig{"name": {}})
+	assert.EqualError(t, err, "field name: fuzzy matching is not supported by Spanner")
+	_, _, err = f.ToPostgresSQL(map[string]FilterToPostgresFieldConfig{"name": {}})
+	assert.EqualError(t, err, "field name: fuzzy matching is not supported by PostgreSQL")
+	_, err = f.ToSquirrelSql(sq.Select("*").From("users"), map[string]FilterToSquirrelSqlFieldConfig{"name": {}})
+	assert.ErrorContains(t, err, "field name: fuzzy matching requires a custom builder")
+
+	stmt, err := f.ToSquirrelSql(sq.Select("*").From("users"), map[string]FilterToSquirrelSqlFieldConfig{
+		"name": {
+			CustomBuilder: func(stmt sq.SelectBuilder, operator string, values []string) (sq.SelectBuilder, error) {
+				return stmt.Where("levenshtein(name, ?) <= ?", values[0], values[1]), nil
+			},
+		},
+	})
+	require.NoError(t, err)
+	sql, args, err := stmt.ToSql()
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT * FROM users WHERE levenshtein(name, ?) <= ?", sql)
+	assert.Equal(t, []any{"jon", "1"}, args)
+
+	ast, err = ParseAST("not name:jon~1", WithFuzzyMatching())
+	require.NoError(t, err)
+	_, err = convertToFilter(ast)
+	assert.EqualError(t, err, "cannot support negation on operator ~")
+}
+
+func TestParseASTContext(t *testing.T) {
+	ast, err := ParseASTContext(context.Background(), "a:1 or b:2")
+	require.NoError(t, err)
+	assert.Equal(t, "(a=1 OR b=2)", ast.String())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = ParseASTContext(ctx, "a:1 or b:2")
+	assert.EqualError(t, err, "parser error: context canceled at pos 0")
+	assert.ErrorIs(t, err, context.Canceled)
+
+	ctx, cancel = context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	_, err = ParseASTContext(ctx, "a:1")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestParseASTTimeBudget(t *testing.T) {
+	input := strings.Repeat("a:1 ", 100)
+
+	_, err := ParseAST(input, WithMaxComplexity(100), WithTimeBudget(time.Minute))
+	require.NoError(t, err)
+
+	_, err = ParseAST(input, WithMaxComplexity(100), WithTimeBudget(time.Nanosecond))
+	assert.ErrorContains(t, err, "parser error: time budget of 1ns exceeded: context deadline exceeded")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestConvertToFilter(t *testing.T) {
+	ast, err := ParseAST("(a:1 and b>2) or c:(x or y) or not d:3")
+	require.NoError(t, err)
+	f, err := ConvertToFilter(ast)
+	require.NoError(t, err)
+	assert.Empty(t, f.Clauses)
+	assert.Equal(t, [][]Clause{
+		{{Field: "a", Operator: "=", Values: []string{"1"}}, {Field: "b", Operator: ">", Values: []string{"2"}}},
+		{{Field: "c", Operator: "IN", Values: []string{"x", "y"}}},
+		{{Field: "d", Operator: "!=", Values: []string{"3"}}},
+	}, f.Groups)
+
+	ast, err = ParseAST("a:1 and b:2")
+	require.NoError(t, err)
+	f, err = ConvertToFilter(ast)
+	require.NoError(t, err)
+	assert.Len(t, f.Clauses, 2)
+	assert.Empty(t, f.Groups)
+
+	// Disjunctions can only be at the top level.
+	ast, err = ParseAST("a:1 and (b:2 or c:3)")
+	require.NoError(t, err)
+	_, err = ConvertToFilter(ast)
+	require.Error(t, err)
+	ast, err = ParseAST("a:1 or (b:2 and (c:3 or d:4))")
+	require.NoError(t, err)
+	_, err = ConvertToFilter(ast)
+	require.Error(t, err)
+
+	// The SQL converters render groups as a single condition.
+	ast, err = ParseAST("a:1 or b:2")
+	require.NoError(t, err)
+	f, err = ConvertToFilter(ast)
+	require.NoError(t, err)
+	conditions, _, err := f.ToSpannerSQL(map[string]FilterToSpannerFieldConfig{"a": {}, "b": {}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"((a=@KQL0) OR (b=@KQL1))"}, conditions)
+
+	// Converters that cannot express the disjunction reject groups, e.g. DynamoDB if a group is on a key attribute of a
+	// Query.
+	f.Clauses = []Clause{{Field: "a", Operator: "=", Values: []string{"1"}}}
+	_, err = f.ToDynamoDBExpression(map[string]FilterToDynamoDBFieldConfig{"a": {Key: FilterToDynamoDBKeyTypePartition}, "b": {}})
+	require.ErrorIs(t, err, ErrGroupsNotSupported)
+}
@@ -0,0 +1,297 @@
+package kqlfilter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type FilterToTrinoFieldColumnType int
+
+const (
+	FilterToTrinoFieldColumnTypeUnspecified FilterToTrinoFieldColumnType = iota
+	FilterToTrinoFieldColumnTypeVarchar
+	FilterToTrinoFieldColumnTypeBigint
+	FilterToTrinoFieldColumnTypeDouble
+	FilterToTrinoFieldColumnTypeBoolean
+	// A TIMESTAMP or TIMESTAMP WITH TIME ZONE column, compared with `from_iso8601_timestamp(value)`.
+	FilterToTrinoFieldColumnTypeTimestamp
+	// A DATE column, compared with `from_iso8601_date(value)`.
+	FilterToTrinoFieldColumnTypeDate
+)
+
+func (c FilterToTrinoFieldColumnType) String() string {
+	switch c {
+	case FilterToTrinoFieldColumnTypeUnspecified, FilterToTrinoFieldColumnTypeVarchar:
+		return "VARCHAR"
+	case FilterToTrinoFieldColumnTypeBigint:
+		return "BIGINT"
+	case FilterToTrinoFieldColumnTypeDouble:
+		return "DOUBLE"
+	case FilterToTrinoFieldColumnTypeBoolean:
+		return "BOOLEAN"
+	case FilterToTrinoFieldColumnTypeTimestamp:
+		return "TIMESTAMP"
+	case FilterToTrinoFieldColumnTypeDate:
+		return "DATE"
+	default:
+		return "???"
+	}
+}
+
+type FilterToTrinoFieldConfig struct {
+	// SQL column name. Can be omitted if the column name is equal to the key in the fieldConfigs map.
+	ColumnName string
+	// SQL column type, or the element type of an ARRAY column. Defaults to FilterToTrinoFieldColumnTypeVarchar.
+	ColumnType FilterToTrinoFieldColumnType
+	// The column is an ARRAY of ColumnType. A value matches if the array contains it, with `contains(column, value)`,
+	// and multiple values match if the array contains any of them, with `arrays_overlap(column, ARRAY[values])`.
+	// Wildcards and ranges do not apply. Defaults to false.
+	Array bool
+	// Settings shared with the field configs of the other converters, e.g. AllowMultipleValues and MapValue.
+	FieldOptions
+	// Allow prefix matching when a wildcard (`*`) is present at the end of a string.
+	// Only applicable for FilterToTrinoFieldColumnTypeVarchar. Defaults to false.
+	AllowPrefixMatch bool
+	// Allow suffix matching when a wildcard (`*`) is present at the beginning of a string.
+	// Only applicable for FilterToTrinoFieldColumnTypeVarchar. Defaults to false.
+	AllowSuffixMatch bool
+}
+
+// ToTrinoSQL turns a Filter into predicates for a Trino (or Presto) WHERE clause, using positional `?` placeholders as
+// supported by the Trino Go client. It takes a map of fields that are allowed to be queried via this filter, and
+// returns the predicates, which must be joined by AND, along with the arguments in the order of their placeholders:
+//
+//	predicates, args, err := filter.ToTrinoSQL(fieldConfigs)
+//	rows, err := db.QueryContext(ctx, "SELECT * FROM events WHERE "+strings.Join(predicates, " AND "), args...)
+//
+// Given the filter `userId:12345 email:john* tags:(a OR b) time>="2024-05-01T00:00:00Z"` and matching field configs,
+// with `tags` an ARRAY column, the predicates are
+//
+//	["user_id = ?", "email LIKE ? ESCAPE '\'", "arrays_overlap(tags, ARRAY[?, ?])", "time >= from_iso8601_timestamp(?)"]
+//
+// with arguments
+//
+//	[int64(12345), "john%", "a", "b", "2024-05-01T00:00:00Z"]
+//
+// Arguments are typed according to the column type: BIGINT values as int64, DOUBLE values as float64 and BOOLEAN
+// values as bool. Timestamps and dates are passed as ISO 8601 strings and converted with from_iso8601_timestamp and
+// from_iso8601_date, so they keep their time zone. TIMESTAMP and DATE fields accept RFC3339 values, values in the
+// layouts set with WithTimeLayouts, as well as relative time keywords such as `today` (see RelativeTimeToday), which are
+// resolved using the clock and location set with WithClock and WithDefaultLocation.
+//
+// Conditions added with WithCondition are appended as-is; they must not have params, as the placeholders are
+// positional.
+func (f Filter) ToTrinoSQL(fieldConfigs map[string]FilterToTrinoFieldConfig, options ...ConverterOption) ([]string, []any, error) {
+	o := newConverterOptions(options)
+	var predicates []string
+	var args []any
+	bind := func(values ...any) string {
+		args = append(args, values...)
+		return strings.TrimSuffix(strings.Repeat("?, ", len(values)), ", ")
+	}
+
+	f, includeDeleted, err := o.extractIncludeDeleted(f)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, clause := range f.Clauses {
+		predicate, err := convertTrinoClause(clause, fieldConfigs, o, bind)
+		if err != nil {
+			return nil, nil, err
+		}
+		predicates = append(predicates, predicate)
+	}
+	if len(f.Groups) > 0 {
+		predicate, err := convertGroups(f.Groups, func(clause Clause) (string, error) {
+			return convertTrinoClause(clause, fieldConfigs, o, bind)
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		predicates = append(predicates, predicate)
+	}
+
+	if err := checkRequiredFields(fieldConfigs, f); err != nil {
+		return nil, nil, err
+	}
+
+	if o.softDeleteColumn != "" && !includeDeleted {
+		predicates = append(predicates, o.softDeleteColumn+" = "+bind(false))
+	}
+
+	for _, condition := range o.conditions {
+		if len(condition.params) > 0 {
+			return nil, nil, fmt.Errorf("condition %q: params are not supported with positional placeholders", condition.sql)
+		}
+		predicates = append(predicates, condition.sql)
+	}
+	return predicates, args, nil
+}
+
+// convertTrinoClause returns the predicate of a clause, binding its values with bind.
+func convertTrinoClause(clause Clause, fieldConfigs map[string]FilterToTrinoFieldConfig, o converterOptions, bind func(...any) string) (string, error) {
+	name, fieldConfig, ok := lookupField(fieldConfigs, clause.Field)
+	if !ok {
+		if clause.Field == "1" && clause.Operator == "=" && len(clause.Values) == 1 && (clause.Values[0] == "1" || clause.Values[0] == "0") {
+			// Special case for boolean literals
+			value, _ := strconv.ParseInt(clause.Values[0], 10, 64)
+			return "1 = " + bind(value), nil
+		}
+		return "", newUnknownFieldError(fieldConfigs, clause.Field)
+	}
+
+	if clause.Operator == "~" {
+		return "", fmt.Errorf("field %s: fuzzy matching is not supported by Trino", clause.Field)
+	}
+
+	columnName := fieldConfig.ColumnName
+	if columnName == "" {
+		columnName = name
+	}
+
+	if len(clause.Values) > 1 && !fieldConfig.AllowMultipleValues {
+		return "", fmt.Errorf("field %s: multiple values are not allowed", clause.Field)
+	}
+	values, err := fieldConfig.mapValues(clause.Values, o, fieldConfig.convertValue)
+	if err != nil {
+		return "", fmt.Errorf("field %s: %w", clause.Field, err)
+	}
+
+	if fieldConfig.Array {
+		return convertTrinoArrayClause(clause, columnName, fieldConfig, values, bind)
+	}
+
+	var predicate string
+	switch clause.Operator {
+	case "IN", "NOT IN":
+		if clause.Operator == "NOT IN" && !(fieldConfig.AllowNegation && fieldConfig.AllowMultipleValues) {
+			return "", fmt.Errorf("operator %s not supported for field: %s", clause.Operator, clause.Field)
+		}
+		placeholders := make([]string, len(values))
+		for i, v := range values {
+			placeholders[i] = fieldConfig.convertPlaceholder(bind(unescapeStringValue(v)))
+		}
+		predicate = fmt.Sprintf("%s %s (%s)", columnName, clause.Operator, strings.Join(placeholders, ", "))
+	case "=", "!=":
+		value := values[0]
+		if s, ok := value.(string); ok {
+			text, needsPrefixMatch, needsSuffixMatch := SplitWildcards(s, fieldConfig.AllowPrefixMatch && clause.Operator == "=", fieldConfig.AllowSuffixMatch && clause.Operator == "=")
+			if needsPrefixMatch || needsSuffixMatch {
+				pattern := escapePrefixSuffixSpecialChars(text)
+				if needsPrefixMatch {
+					pattern += "%"
+				}
+				if needsSuffixMatch {
+					pattern = "%" + pattern
+				}
+				return columnName + " LIKE " + bind(pattern) + ` ESCAPE '\'`, nil
+			}
+			value = text
+		}
+		operator := "="
+		if clause.Operator == "!=" {
+			operator = "<>"
+		}
+		predicate = fmt.Sprintf("%s %s %s", columnName, operator, fieldConfig.convertPlaceholder(bind(value)))
+	case ">=", "<=", ">", "<":
+		if !fieldConfig.AllowRanges {
+			return "", fmt.Errorf("operator %s not supported for field: %s", clause.Operator, clause.Field)
+		}
+		switch fieldConfig.ColumnType {
+		case FilterToTrinoFieldColumnTypeBigint, FilterToTrinoFieldColumnTypeDouble, FilterToTrinoFieldColumnTypeTimestamp, FilterToTrinoFieldColumnTypeDate:
+			predicate = fmt.Sprintf("%s %s %s", columnName, clause.Operator, fieldConfig.convertPlaceholder(bind(values[0])))
+		default:
+			return "", fmt.Errorf("operator %s not supported for field type %s", clause.Operator, fieldConfig.ColumnType)
+		}
+	default:
+		return "", fmt.Errorf("operator %s not supported for field: %s", clause.Operator, clause.Field)
+	}
+	return predicate, nil
+}
+
+// convertTrinoArrayClause returns the predicate of a clause on an ARRAY column.
+func convertTrinoArrayClause(clause Clause, columnName string, fieldConfig FilterToTrinoFieldConfig, values []any, bind func(...any) string) (string, error) {
+	placeholders := make([]string, len(values))
+	for i, v := range values {
+		placeholders[i] = fieldConfig.convertPlaceholder(bind(unescapeStringValue(v)))
+	}
+	switch clause.Operator {
+	case "=":
+		return fmt.Sprintf("contains(%s, %s)", columnName, placeholders[0]), nil
+	case "!=":
+		return fmt.Sprintf("NOT contains(%s, %s)", columnName, placeholders[0]), nil
+	case "IN":
+		return fmt.Sprintf("arrays_overlap(%s, ARRAY[%s])", columnName, strings.Join(placeholders, ", ")), nil
+	case "NOT IN":
+		if !(fieldConfig.AllowNegation && fieldConfig.AllowMultipleValues) {
+			return "", fmt.Errorf("operator %s not supported for field: %s", clause.Operator, clause.Field)
+		}
+		return fmt.Sprintf("NOT arrays_overlap(%s, ARRAY[%s])", columnName, strings.Join(placeholders, ", ")), nil
+	default:
+		return "", fmt.Errorf("operator %s not supported for field: %s", clause.Operator, clause.Field)
+	}
+}
+
+// unescapeStringValue returns the literal text of a string value, see UnescapeValue. Other values are returned as is.
+func unescapeStringValue(v any) any {
+	if s, ok := v.(string); ok {
+		return UnescapeValue(s)
+	}
+	return v
+}
+
+// convertPlaceholder wraps the placeholder of a value in the function converting it to the column type, if needed.
+func (f FilterToTrinoFieldConfig) convertPlaceholder(placeholder string) string {
+	if f.MapValue != nil {
+		return placeholder
+	}
+	switch f.ColumnType {
+	case FilterToTrinoFieldColumnTypeTimestamp:
+		return fmt.Sprintf("from_iso8601_timestamp(%s)", placeholder)
+	case FilterToTrinoFieldColumnTypeDate:
+		return fmt.Sprintf("from_iso8601_date(%s)", placeholder)
+	default:
+		return placeholder
+	}
+}
+
+func (f FilterToTrinoFieldConfig) convertValue(value string, o converterOptions) (any, error) {
+	switch f.ColumnType {
+	case FilterToTrinoFieldColumnTypeBigint:
+		intVal, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid BIGINT value: %w", err)
+		}
+		return intVal, nil
+	case FilterToTrinoFieldColumnTypeDouble:
+		floatVal, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid DOUBLE value: %w", err)
+		}
+		return floatVal, nil
+	case FilterToTrinoFieldColumnTypeBoolean:
+		boolVal, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid BOOLEAN value: %w", err)
+		}
+		return boolVal, nil
+	case FilterToTrinoFieldColumnTypeTimestamp, FilterToTrinoFieldColumnTypeDate:
+		t, ok := o.resolveRelativeTime(value)
+		if !ok {
+			var err error
+			t, err = o.parseTime(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s value: %w", f.ColumnType, err)
+			}
+		}
+		if f.ColumnType == FilterToTrinoFieldColumnTypeDate {
+			return t.In(o.location).Format(time.DateOnly), nil
+		}
+		return t.Format(time.RFC3339Nano), nil
+	default:
+		return value, nil
+	}
+}
@@ -0,0 +1,184 @@
+package kqlfilter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilterToTrinoSQL(t *testing.T) {
+	fieldConfigs := map[string]FilterToTrinoFieldConfig{
+		"userId": {
+			ColumnName: "user_id",
+			ColumnType: FilterToTrinoFieldColumnTypeBigint,
+		},
+		"email": {
+			AllowPrefixMatch: true,
+			AllowSuffixMatch: true,
+		},
+		"state": {
+			FieldOptions: FieldOptions{AllowMultipleValues: true, AllowNegation: true},
+		},
+		"tags": {
+			Array:        true,
+			FieldOptions: FieldOptions{AllowMultipleValues: true, AllowNegation: true},
+		},
+		"ids": {
+			Array:      true,
+			ColumnType: FilterToTrinoFieldColumnTypeBigint,
+		},
+		"time": {
+			ColumnType:   FilterToTrinoFieldColumnTypeTimestamp,
+			FieldOptions: FieldOptions{AllowRanges: true},
+		},
+		"day": {
+			ColumnType:   FilterToTrinoFieldColumnTypeDate,
+			FieldOptions: FieldOptions{AllowRanges: true},
+		},
+		"score": {
+			ColumnType:   FilterToTrinoFieldColumnTypeDouble,
+			FieldOptions: FieldOptions{AllowRanges: true},
+		},
+		"active": {
+			ColumnType: FilterToTrinoFieldColumnTypeBoolean,
+		},
+		"name": {
+			FieldOptions: FieldOptions{AllowRanges: true},
+		},
+	}
+
+	testCases := []struct {
+		name               string
+		input              string
+		expectedPredicates []string
+		expectedArgs       []any
+		expectedError      string
+	}{
+		{
+			name:               "example",
+			input:              `userId:12345 email:john* tags:(a OR b) time>="2024-05-01T00:00:00Z"`,
+			expectedPredicates: []string{"user_id = ?", `email LIKE ? ESCAPE '\'`, "arrays_overlap(tags, ARRAY[?, ?])", "time >= from_iso8601_timestamp(?)"},
+			expectedArgs:       []any{int64(12345), "john%", "a", "b", "2024-05-01T00:00:00Z"},
+		},
+		{
+			name:               "suffix match with special characters",
+			input:              `email:*_1%`,
+			expectedPredicates: []string{`email LIKE ? ESCAPE '\'`},
+			expectedArgs:       []any{`%\_1\%`},
+		},
+		{
+			name:               "negated values",
+			input:              `not state:(active or paused) not userId:1`,
+			expectedPredicates: []string{"state NOT IN (?, ?)", "user_id <> ?"},
+			expectedArgs:       []any{"active", "paused", int64(1)},
+		},
+		{
+			name:               "array contains",
+			input:              `tags:a not tags:(c or d) not ids:3`,
+			expectedPredicates: []string{"contains(tags, ?)", "NOT arrays_overlap(tags, ARRAY[?, ?])", "NOT contains(ids, ?)"},
+			expectedArgs:       []any{"a", "c", "d", int64(3)},
+		},
+		{
+			name:               "timestamps",
+			input:              `time<"2024-05-01T12:00:00.123456+02:00" day>="2024-05-01T23:00:00Z"`,
+			expectedPredicates: []string{"time < from_iso8601_timestamp(?)", "day >= from_iso8601_date(?)"},
+			expectedArgs:       []any{"2024-05-01T12:00:00.123456+02:00", "2024-05-01"},
+		},
+		{
+			name:               "relative time",
+			input:              `time>=today`,
+			expectedPredicates: []string{"time >= from_iso8601_timestamp(?)"},
+			expectedArgs:       []any{"2024-05-01T00:00:00Z"},
+		},
+		{
+			name:               "numbers and booleans",
+			input:              `score>0.5 active:true`,
+			expectedPredicates: []string{"score > ?", "active = ?"},
+			expectedArgs:       []any{0.5, true},
+		},
+		{
+			name:               "boolean literal",
+			input:              `1:0`,
+			expectedPredicates: []string{"1 = ?"},
+			expectedArgs:       []any{int64(0)},
+		},
+		{
+			name:          "range on string column",
+			input:         `name>a`,
+			expectedError: "operator > not supported for field type VARCHAR",
+		},
+		{
+			name:          "negated values not allowed on array column",
+			input:         `not ids:(1 or 2)`,
+			expectedError: "field ids: multiple values are not allowed",
+		},
+		{
+			name:          "unknown field",
+			input:         `foo:bar`,
+			expectedError: "unknown field: foo",
+		},
+		{
+			name:          "invalid number",
+			input:         `userId:abc`,
+			expectedError: `field userId: invalid BIGINT value: strconv.ParseInt: parsing "abc": invalid syntax`,
+		},
+		{
+			name:          "multiple values not allowed",
+			input:         `email:(a or b)`,
+			expectedError: "field email: multiple values are not allowed",
+		},
+	}
+
+	now := time.Date(2024, 5, 1, 15, 0, 0, 0, time.UTC)
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			f, err := Parse(test.input)
+			require.NoError(t, err)
+			predicates, args, err := f.ToTrinoSQL(fieldConfigs, WithClock(func() time.Time { return now }))
+			if test.expectedError != "" {
+				assert.EqualError(t, err, test.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedPredicates, predicates)
+			assert.Equal(t, test.expectedArgs, args)
+		})
+	}
+}
+
+func TestFilterToTrinoSQLOptions(t *testing.T) {
+	f, err := Parse("state:active")
+	require.NoError(t, err)
+	fieldConfigs := map[string]FilterToTrinoFieldConfig{"state": {}}
+
+	predicates, args, err := f.ToTrinoSQL(fieldConfigs, WithSoftDelete("deleted", false), WithCondition("tenant_id = 7", nil))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"state = ?", "deleted = ?", "tenant_id = 7"}, predicates)
+	assert.Equal(t, []any{"active", false}, args)
+
+	_, _, err = f.ToTrinoSQL(fieldConfigs, WithCondition("tenant_id = @tenant", map[string]any{"tenant": 7}))
+	assert.EqualError(t, err, `condition "tenant_id = @tenant": params are not supported with positional placeholders`)
+}
+
+func TestFilterToTrinoSQLGroups(t *testing.T) {
+	f := Filter{
+		Groups: [][]Clause{
+			{{Field: "state", Operator: "=", Values: []string{"active"}}, {Field: "age", Operator: ">=", Values: []string{"18"}}},
+			{{Field: "state", Operator: "=", Values: []string{"frozen"}}},
+		},
+	}
+	fieldConfigs := map[string]FilterToTrinoFieldConfig{
+		"state": {},
+		"age":   {ColumnType: FilterToTrinoFieldColumnTypeBigint, FieldOptions: FieldOptions{AllowRanges: true}},
+	}
+
+	predicates, args, err := f.ToTrinoSQL(fieldConfigs)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"((state = ? AND age >= ?) OR (state = ?))"}, predicates)
+	assert.Equal(t, []any{"active", int64(18), "frozen"}, args)
+
+	_, _, err = Filter{Groups: [][]Clause{{}}}.ToTrinoSQL(fieldConfigs)
+	assert.EqualError(t, err, "empty clause group")
+}
@@ -0,0 +1,116 @@
+package kqlfilter
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Format returns the KQL of the AST, so that a parsed or rewritten filter can be stored again. Parsing the result
+// yields the same AST, except for positions: conjunctions are joined with `and`, values are quoted only when needed and
+// ranges in brackets are written as two range operators, e.g. `price:[10 TO 20]` as `price>=10 and price<=20`.
+// Format returns an empty string for a nil AST.
+func Format(ast Node) string {
+	var sb strings.Builder
+	formatNode(&sb, ast)
+	return sb.String()
+}
+
+// formatNode writes the KQL of a node.
+func formatNode(sb *strings.Builder, ast Node) {
+	switch n := ast.(type) {
+	case *AndNode:
+		for i, child := range n.Nodes {
+			if i > 0 {
+				sb.WriteString(" and ")
+			}
+			formatOperand(sb, child, NodeOr)
+		}
+	case *OrNode:
+		for i, child := range n.Nodes {
+			if i > 0 {
+				sb.WriteString(" or ")
+			}
+			formatOperand(sb, child, NodeOr)
+		}
+	case *NotNode:
+		sb.WriteString("not ")
+		formatOperand(sb, n.Expr, NodeAnd, NodeOr)
+	case *IsNode:
+		sb.WriteString(quoteIfNeeded(n.Identifier))
+		sb.WriteString(":")
+		if nested, ok := n.Value.(*NestedNode); ok {
+			sb.WriteString("{")
+			formatNode(sb, nested.Expr)
+			sb.WriteString("}")
+		} else {
+			formatValues(sb, n.Value)
+		}
+	case *RangeNode:
+		sb.WriteString(quoteIfNeeded(n.Identifier))
+		sb.WriteString(n.Operator.String())
+		formatValues(sb, n.Value)
+	case *ExistsNode:
+		sb.WriteString(quoteIfNeeded(n.Identifier))
+		sb.WriteString(":*")
+	case *FuzzyNode:
+		sb.WriteString(quoteIfNeeded(n.Identifier))
+		sb.WriteString(":")
+		sb.WriteString(quoteIfNeeded(n.Value))
+		sb.WriteString("~")
+		sb.WriteString(strconv.Itoa(n.Fuzziness))
+	case *LiteralNode:
+		// A value without a field.
+		sb.WriteString(formatValue(n.Value))
+	}
+}
+
+// formatOperand writes the KQL of an operand, in parentheses if it is one of the given node types.
+func formatOperand(sb *strings.Builder, ast Node, parenthesize ...NodeType) {
+	for _, t := range parenthesize {
+		if ast != nil && ast.Type() == t {
+			sb.WriteString("(")
+			formatNode(sb, ast)
+			sb.WriteString(")")
+			return
+		}
+	}
+	formatNode(sb, ast)
+}
+
+// formatValues writes the value of a clause. Multiple values are always written in parentheses.
+func formatValues(sb *strings.Builder, ast Node) {
+	switch n := ast.(type) {
+	case *OrNode:
+		formatValueList(sb, n.Nodes, " or ")
+	case *AndNode:
+		formatValueList(sb, n.Nodes, " and ")
+	case *NotNode:
+		sb.WriteString("not ")
+		formatValues(sb, n.Expr)
+	case *LiteralNode:
+		sb.WriteString(formatValue(n.Value))
+	}
+}
+
+func formatValueList(sb *strings.Builder, nodes []Node, separator string) {
+	sb.WriteString("(")
+	for i, child := range nodes {
+		if i > 0 {
+			sb.WriteString(separator)
+		}
+		formatValues(sb, child)
+	}
+	sb.WriteString(")")
+}
+
+// formatValue returns the KQL of a literal value. Values containing an asterisk are already escaped (see
+// Clause.Values), so their backslashes are kept as is and only quotes are escaped when quoting them.
+func formatValue(value string) string {
+	if !strings.Contains(value, "*") {
+		return quoteIfNeeded(value)
+	}
+	if text := strings.NewReplacer(`\`, "", "*", "").Replace(value); text == "" || quoteIfNeeded(text) == text {
+		return value
+	}
+	return `"` + strings.ReplaceAll(value, `"`, `\"`) + `"`
+}
@@ -0,0 +1,91 @@
+package kqlfilter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormat(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		options  []ParserOption
+		expected string
+	}{
+		{
+			name:     "conjunction",
+			input:    "state:active   price >= 10",
+			expected: "state:active and price>=10",
+		},
+		{
+			name:     "disjunction in conjunction",
+			input:    "(state:active or state:paused) and name:jon",
+			expected: "(state:active or state:paused) and name:jon",
+		},
+		{
+			name:     "conjunction in disjunction",
+			input:    "state:active and name:jon or state:paused",
+			expected: "state:active and name:jon or state:paused",
+		},
+		{
+			name:     "negation",
+			input:    "not (state:active or name:jon) and not price<10",
+			expected: "not (state:active or name:jon) and not price<10",
+		},
+		{
+			name:     "value list",
+			input:    "state:(active or not paused)",
+			expected: "state:(active or not paused)",
+		},
+		{
+			name:     "quoted values",
+			input:    `name:"jon doe" state:"or" url:"a:b" text:"say \"hi\""`,
+			expected: `name:"jon doe" and state:"or" and url:"a:b" and text:"say \"hi\""`,
+		},
+		{
+			name:     "wildcards",
+			input:    `name:jo* title:"big *" path:a\*b*`,
+			expected: `name:jo* and title:"big *" and path:a\*b*`,
+		},
+		{
+			name:     "range brackets",
+			input:    "price:[10 TO 20]",
+			expected: "price>=10 and price<=20",
+		},
+		{
+			name:     "nested query",
+			input:    "user:{name:jon and age>18} email:*",
+			expected: "user:{name:jon and age>18} and email:*",
+		},
+		{
+			name:     "fuzzy match",
+			input:    "name:jon~2",
+			options:  []ParserOption{WithFuzzyMatching()},
+			expected: "name:jon~2",
+		},
+		{
+			name:     "free text",
+			input:    `"jon doe" true`,
+			expected: `"jon doe" and true`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ast, err := ParseAST(tc.input, tc.options...)
+			require.NoError(t, err)
+			formatted := Format(ast)
+			assert.Equal(t, tc.expected, formatted)
+
+			reparsed, err := ParseAST(formatted, tc.options...)
+			require.NoError(t, err)
+			assert.Equal(t, ast.String(), reparsed.String())
+		})
+	}
+}
+
+func TestFormatNil(t *testing.T) {
+	assert.Equal(t, "", Format(nil))
+}
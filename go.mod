@@ -3,9 +3,11 @@ module github.com/MottoStreaming/kqlfilter.go
 go 1.21
 
 require (
+	cloud.google.com/go v0.112.0
 	github.com/Masterminds/squirrel v1.5.4
 	github.com/pkg/errors v0.9.1
 	github.com/stretchr/testify v1.9.0
+	golang.org/x/text v0.14.0
 )
 
 require (
@@ -0,0 +1,25 @@
+module github.com/MottoStreaming/kqlfilter.go/gorm
+
+go 1.21
+
+require (
+	github.com/MottoStreaming/kqlfilter.go v0.0.0-20240423214149-cdc2d3eb4e84
+	github.com/stretchr/testify v1.9.0
+	gorm.io/gorm v1.25.10
+)
+
+require (
+	cloud.google.com/go v0.112.0 // indirect
+	github.com/Masterminds/squirrel v1.5.4 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/jinzhu/inflection v1.0.0 // indirect
+	github.com/jinzhu/now v1.1.5 // indirect
+	github.com/lann/builder v0.0.0-20180802200727-47ae307949d0 // indirect
+	github.com/lann/ps v0.0.0-20150810152359-62de8c46ede0 // indirect
+	github.com/pkg/errors v0.9.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/text v0.14.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/MottoStreaming/kqlfilter.go => ../
@@ -0,0 +1,150 @@
+// Package gorm applies kqlfilter filters to GORM queries.
+package gorm
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/MottoStreaming/kqlfilter.go"
+	"gorm.io/gorm"
+)
+
+// FieldConfig describes a field that is allowed to be queried via ApplyFilter.
+type FieldConfig struct {
+	// SQL table column name. Can be omitted if the column name is equal to the key in the configs map.
+	ColumnName string
+	// Type of the column. Defaults to kqlfilter.FieldTypeString.
+	ColumnType kqlfilter.FieldType
+	// Allow prefix matching when a wildcard (`*`) is present at the end of a string. Defaults to false.
+	AllowPrefixMatch bool
+	// Allow multiple values for this field. Defaults to false.
+	AllowMultipleValues bool
+	// Allow this field to be queried with one or more range operators. Defaults to false.
+	AllowRanges bool
+	// A function that takes a string value as provided by the user and converts it to the value stored in the column.
+	// It should return an error when the user provides an illegal value. Defaults to using the value as-is.
+	MapValue func(string) (any, error)
+	// The values that are allowed for this field, e.g. the values of an enum. Defaults to allowing any value.
+	AllowedValues []string
+}
+
+// FieldConfigs returns the field configs to use with ApplyFilter, derived from the schema.
+// Aliases are added as separate entries.
+func FieldConfigs(schema kqlfilter.Schema) map[string]FieldConfig {
+	configs := make(map[string]FieldConfig, len(schema))
+	for name, fs := range schema {
+		config := FieldConfig{
+			ColumnName:          schema.ColumnName(name),
+			ColumnType:          fs.Type,
+			AllowPrefixMatch:    fs.AllowPrefixMatch,
+			AllowMultipleValues: fs.AllowMultipleValues,
+			AllowRanges:         fs.AllowRanges,
+			MapValue:            fs.MapValue,
+			AllowedValues:       fs.AllowedValues,
+		}
+		configs[name] = config
+		for _, alias := range fs.Aliases {
+			configs[alias] = config
+		}
+	}
+	return configs
+}
+
+// ApplyFilter appends the clauses of the filter to db as Where conditions with placeholders.
+//
+// It takes a map of fields that are allowed to be queried via this filter, keyed by the field name used in the filter.
+// Values are converted the same way as by kqlfilter.Filter.ToSquirrelSql, so for example
+//
+//	userId:12345 state:(active OR frozen)
+//
+// with configs
+//
+//	{
+//		"userId": {ColumnName: "user_id", ColumnType: kqlfilter.FieldTypeInt64},
+//		"state":  {AllowMultipleValues: true},
+//	}
+//
+// results in
+//
+//	db.Where("user_id = ?", int64(12345)).Where("state IN (?,?)", "active", "frozen")
+//
+// The groups of the filter are appended as a single grouped condition, the disjunction of their conjunctions.
+func ApplyFilter(db *gorm.DB, f kqlfilter.Filter, configs map[string]FieldConfig, options ...kqlfilter.ConverterOption) (*gorm.DB, error) {
+	for _, clause := range f.Clauses {
+		query, args, err := clauseSQL(clause, configs, options)
+		if err != nil {
+			return db, err
+		}
+		db = db.Where(query, args...)
+	}
+	if len(f.Groups) == 0 {
+		return db, nil
+	}
+	var groups *gorm.DB
+	for _, group := range f.Groups {
+		if len(group) == 0 {
+			return db, errors.New("empty clause group")
+		}
+		conjunction := db.Session(&gorm.Session{NewDB: true})
+		for _, clause := range group {
+			query, args, err := clauseSQL(clause, configs, options)
+			if err != nil {
+				return db, err
+			}
+			conjunction = conjunction.Where(query, args...)
+		}
+		if groups == nil {
+			groups = db.Session(&gorm.Session{NewDB: true}).Where(conjunction)
+		} else {
+			groups = groups.Or(conjunction)
+		}
+	}
+	return db.Where(groups), nil
+}
+
+// clauseSQL returns the condition of a clause with placeholders, and its arguments.
+func clauseSQL(clause kqlfilter.Clause, configs map[string]FieldConfig, options []kqlfilter.ConverterOption) (string, []any, error) {
+	config, ok := configs[clause.Field]
+	if !ok {
+		names := make([]string, 0, len(configs))
+		for name := range configs {
+			names = append(names, name)
+		}
+		return "", nil, kqlfilter.NewUnknownFieldError(clause.Field, names)
+	}
+
+	cond, err := clause.ToSquirrelCondition(config.squirrelConfig(), options...)
+	if err != nil {
+		return "", nil, fmt.Errorf("field %s: %w", clause.Field, err)
+	}
+	query, args, err := cond.ToSql()
+	if err != nil {
+		return "", nil, fmt.Errorf("field %s: %w", clause.Field, err)
+	}
+	return query, args, nil
+}
+
+func (c FieldConfig) squirrelConfig() kqlfilter.FilterToSquirrelSqlFieldConfig {
+	var columnType kqlfilter.FilterToSquirrelSqlFieldColumnType
+	switch c.ColumnType {
+	case kqlfilter.FieldTypeInt64:
+		columnType = kqlfilter.FilterToSquirrelSqlFieldColumnTypeInt64
+	case kqlfilter.FieldTypeFloat64:
+		columnType = kqlfilter.FilterToSquirrelSqlFieldColumnTypeFloat64
+	case kqlfilter.FieldTypeBool:
+		columnType = kqlfilter.FilterToSquirrelSqlFieldColumnTypeBool
+	case kqlfilter.FieldTypeTimestamp:
+		columnType = kqlfilter.FilterToSquirrelSqlFieldColumnTypeTimestamp
+	default:
+		columnType = kqlfilter.FilterToSquirrelSqlFieldColumnTypeString
+	}
+	return kqlfilter.FilterToSquirrelSqlFieldConfig{
+		ColumnName:          c.ColumnName,
+		ColumnType:          columnType,
+		AllowPrefixMatch:    c.AllowPrefixMatch,
+		AllowMultipleValues: c.AllowMultipleValues,
+		AllowRanges:         c.AllowRanges,
+		MapValue:            c.MapValue,
+		AllowedValues:       c.AllowedValues,
+	}
+}
@@ -0,0 +1,118 @@
+package gorm
+
+import (
+	"testing"
+
+	"github.com/MottoStreaming/kqlfilter.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/utils/tests"
+)
+
+type user struct {
+	UserID int64
+	State  string
+	Email  string
+}
+
+func TestApplyFilter(t *testing.T) {
+	configs := map[string]FieldConfig{
+		"userId": {
+			ColumnName: "user_id",
+			ColumnType: kqlfilter.FieldTypeInt64,
+		},
+		"state": {
+			AllowMultipleValues: true,
+			AllowedValues:       []string{"active", "frozen", "deleted"},
+		},
+		"email": {
+			AllowPrefixMatch: true,
+		},
+	}
+
+	testCases := []struct {
+		name          string
+		input         string
+		expectedSQL   string
+		expectedVars  []any
+		expectedError string
+	}{
+		{
+			"equality",
+			"userId:12345",
+			"SELECT * FROM `users` WHERE user_id = ?",
+			[]any{int64(12345)},
+			"",
+		},
+		{
+			"multiple clauses",
+			"userId:12345 state:(active OR frozen) email:john*",
+			"SELECT * FROM `users` WHERE user_id = ? AND state IN (?,?) AND email LIKE ?",
+			[]any{int64(12345), "active", "frozen", "john%"},
+			"",
+		},
+		{
+			"unknown field",
+			"emial:john",
+			"",
+			nil,
+			"unknown field: emial; did you mean email?",
+		},
+		{
+			"invalid value",
+			"state:actve",
+			"",
+			nil,
+			`field state: invalid value "actve"; did you mean active? (allowed values: active, frozen, deleted)`,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			db, err := gorm.Open(tests.DummyDialector{}, &gorm.Config{DryRun: true})
+			require.NoError(t, err)
+
+			f, err := kqlfilter.Parse(test.input)
+			require.NoError(t, err)
+
+			tx, err := ApplyFilter(db.Model(&user{}), f, configs)
+			if test.expectedError != "" {
+				require.EqualError(t, err, test.expectedError)
+				return
+			}
+			require.NoError(t, err)
+
+			var users []user
+			stmt := tx.Find(&users).Statement
+			assert.Equal(t, test.expectedSQL, stmt.SQL.String())
+			assert.Equal(t, test.expectedVars, stmt.Vars)
+		})
+	}
+}
+
+func TestApplyFilterGroups(t *testing.T) {
+	configs := map[string]FieldConfig{
+		"userId": {ColumnName: "user_id", ColumnType: kqlfilter.FieldTypeInt64},
+		"state":  {},
+	}
+	db, err := gorm.Open(tests.DummyDialector{}, &gorm.Config{DryRun: true})
+	require.NoError(t, err)
+
+	ast, err := kqlfilter.ParseAST("(userId:1 and state:active) or state:frozen")
+	require.NoError(t, err)
+	f, err := kqlfilter.ConvertToFilter(ast)
+	require.NoError(t, err)
+	f.Clauses = []kqlfilter.Clause{{Field: "userId", Operator: "=", Values: []string{"2"}}}
+
+	tx, err := ApplyFilter(db.Model(&user{}), f, configs)
+	require.NoError(t, err)
+	var users []user
+	stmt := tx.Find(&users).Statement
+	assert.Equal(t, "SELECT * FROM `users` WHERE user_id = ? AND ((user_id = ? AND state = ?) OR state = ?)", stmt.SQL.String())
+	assert.Equal(t, []any{int64(2), int64(1), "active", "frozen"}, stmt.Vars)
+
+	f.Groups[1][0].Field = "title"
+	_, err = ApplyFilter(db.Model(&user{}), f, configs)
+	assert.EqualError(t, err, "unknown field: title")
+}
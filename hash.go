@@ -0,0 +1,86 @@
+package kqlfilter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/fnv"
+	"sort"
+)
+
+// Tags of the nodes in the encoding hashed by Hash. They are part of the hash, so they must never change.
+const (
+	hashTagNil byte = iota
+	hashTagAnd
+	hashTagOr
+	hashTagNot
+	hashTagIs
+	hashTagRange
+	hashTagNested
+	hashTagExists
+	hashTagFuzzy
+	hashTagLiteral
+)
+
+// Hash returns a hash of the normalized AST (see Normalize), so that filters that are Equal have the same hash, e.g.
+// to use as the key of cached query results. The hash is the 64-bit FNV-1a hash of a fixed binary encoding of the
+// AST, so it is stable across processes, platforms and releases of this package.
+// Different filters may have the same hash, although that is unlikely.
+func Hash(ast Node) uint64 {
+	h := fnv.New64a()
+	if ast != nil {
+		ast = Normalize(ast)
+	}
+	h.Write(appendHashEncoding(nil, ast))
+	return h.Sum64()
+}
+
+// appendHashEncoding appends the encoding of a node to b. Strings and lists are prefixed with their length, and the
+// nodes of conjunctions and disjunctions are sorted by their encoding, so the encoding doesn't depend on their order.
+func appendHashEncoding(b []byte, ast Node) []byte {
+	appendString := func(b []byte, s string) []byte {
+		b = binary.AppendUvarint(b, uint64(len(s)))
+		return append(b, s...)
+	}
+	appendNodes := func(b []byte, nodes []Node) []byte {
+		encoded := make([][]byte, len(nodes))
+		for i, n := range nodes {
+			encoded[i] = appendHashEncoding(nil, n)
+		}
+		sort.Slice(encoded, func(i, j int) bool {
+			return bytes.Compare(encoded[i], encoded[j]) < 0
+		})
+		b = binary.AppendUvarint(b, uint64(len(encoded)))
+		for _, e := range encoded {
+			b = append(b, e...)
+		}
+		return b
+	}
+
+	switch n := ast.(type) {
+	case *AndNode:
+		return appendNodes(append(b, hashTagAnd), n.Nodes)
+	case *OrNode:
+		return appendNodes(append(b, hashTagOr), n.Nodes)
+	case *NotNode:
+		return appendHashEncoding(append(b, hashTagNot), n.Expr)
+	case *IsNode:
+		b = appendString(append(b, hashTagIs), n.Identifier)
+		return appendHashEncoding(b, n.Value)
+	case *RangeNode:
+		b = appendString(append(b, hashTagRange), n.Identifier)
+		b = appendString(b, n.Operator.String())
+		return appendHashEncoding(b, n.Value)
+	case *NestedNode:
+		return appendHashEncoding(append(b, hashTagNested), n.Expr)
+	case *ExistsNode:
+		return appendString(append(b, hashTagExists), n.Identifier)
+	case *FuzzyNode:
+		b = appendString(append(b, hashTagFuzzy), n.Identifier)
+		b = appendString(b, n.Value)
+		return binary.AppendUvarint(b, uint64(n.Fuzziness))
+	case *LiteralNode:
+		return appendString(append(b, hashTagLiteral), n.Value)
+	default:
+		return append(b, hashTagNil)
+	}
+}
@@ -0,0 +1,45 @@
+package kqlfilter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHash(t *testing.T) {
+	testCases := []struct {
+		a, b     string
+		expected bool
+	}{
+		{"a:1 and b:2", "b:2 a:1", true},
+		{"state:(active or paused)", "state:(paused or active)", true},
+		{"user:{name:jon}", "user.name:jon", true},
+		{"not (not a:1)", "a:1", true},
+		{"a:1 or (b:2 or c:3)", "c:3 or b:2 or a:1", true},
+		{"a:1", "a:2", false},
+		{"a:1 and b:2", "a:1 or b:2", false},
+		{"a>=1", "a>1", false},
+		{`a:"x y"`, "a:x", false},
+		{`a:"bc"`, `ab:"c"`, false},
+		{"a:*", "a:1", false},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.a+" vs "+test.b, func(t *testing.T) {
+			a, err := ParseAST(test.a)
+			require.NoError(t, err)
+			b, err := ParseAST(test.b)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, Hash(a) == Hash(b))
+		})
+	}
+}
+
+func TestHashStable(t *testing.T) {
+	// The hashes must not change between releases, as they may be persisted, e.g. as cache keys.
+	ast, err := ParseAST("state:(active or paused) created>=2024-01-01 not user:{name:jo*}")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0x5703e59921bc3536), Hash(ast))
+	assert.Equal(t, uint64(0xaf63bd4c8601b7df), Hash(nil))
+}
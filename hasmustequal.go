@@ -4,57 +4,75 @@ package kqlfilter
 // and if so, will return the associated value(s). If not, it will return an empty string.
 // This is useful e.g. for determining if a KQL query contains a field that directly corresponds to an Elastic index,
 // and as such make it possible to reduce the space of ElasticSearch indexes to query.
+//
+// Parenthesized groups are taken into account as long as equality is still guaranteed, e.g. for
+// `(type_id:team and active:true) or type_id:player` the values are `team` and `player`.
 func HasMustEqual(ast Node, field string) []string {
+	return HasMustEqualFields(ast, field)[field]
+}
+
+// HasMustEqualFields is like HasMustEqual for multiple fields at once, walking the AST only once.
+// The returned map only contains the fields that must match for equality.
+func HasMustEqualFields(ast Node, fields ...string) map[string][]string {
+	wanted := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		wanted[field] = true
+	}
+	return hasMustEqual(ast, wanted)
+}
+
+func hasMustEqual(ast Node, fields map[string]bool) map[string][]string {
 	if ast == nil {
 		return nil
 	}
 	switch n := ast.(type) {
 	case *AndNode:
-		return hasMustEqualAndNode(n, field)
+		return hasMustEqualAndNode(n, fields)
 	case *IsNode:
-		return hasMustEqualIsNode(n, field)
+		return hasMustEqualIsNode(n, fields)
 	case *OrNode:
-		return hasMustEqualOrNode(n, field)
+		return hasMustEqualOrNode(n, fields)
 	default:
 		return nil
 	}
 }
 
-func hasMustEqualAndNode(ast *AndNode, field string) []string {
-	var values []string
+// hasMustEqualAndNode returns the values of all fields that must match in any of the nodes.
+func hasMustEqualAndNode(ast *AndNode, fields map[string]bool) map[string][]string {
+	var values map[string][]string
 	for _, node := range ast.Nodes {
-		var values_ []string
-		switch n := node.(type) {
-		case *IsNode:
-			values_ = hasMustEqualIsNode(n, field)
-		default:
-			continue
+		for field, values_ := range hasMustEqual(node, fields) {
+			if values == nil {
+				values = make(map[string][]string)
+			}
+			values[field] = append(values[field], values_...)
 		}
-		values = append(values, values_...)
 	}
 	return values
 }
 
-func hasMustEqualOrNode(ast *OrNode, field string) []string {
-	var values []string
-	for _, node := range ast.Nodes {
-		var values_ []string
-		switch n := node.(type) {
-		case *IsNode:
-			values_ = hasMustEqualIsNode(n, field)
-			if len(values_) == 0 {
-				return nil
-			}
-		default:
+// hasMustEqualOrNode returns the values of the fields that must match in every one of the nodes.
+func hasMustEqualOrNode(ast *OrNode, fields map[string]bool) map[string][]string {
+	var values map[string][]string
+	for i, node := range ast.Nodes {
+		values_ := hasMustEqual(node, fields)
+		if i == 0 {
+			values = values_
 			continue
 		}
-		values = append(values, values_...)
+		for field := range values {
+			if len(values_[field]) == 0 {
+				delete(values, field)
+				continue
+			}
+			values[field] = append(values[field], values_[field]...)
+		}
 	}
 	return values
 }
 
-func hasMustEqualIsNode(ast *IsNode, field string) []string {
-	if ast.Identifier != field {
+func hasMustEqualIsNode(ast *IsNode, fields map[string]bool) map[string][]string {
+	if !fields[ast.Identifier] {
 		return nil
 	}
 
@@ -72,5 +90,8 @@ func hasMustEqualIsNode(ast *IsNode, field string) []string {
 	default:
 		break
 	}
-	return values
+	if len(values) == 0 {
+		return nil
+	}
+	return map[string][]string{ast.Identifier: values}
 }
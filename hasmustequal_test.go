@@ -62,6 +62,21 @@ func TestHasMustEqual(t *testing.T) {
 			input:          "type_id>=team and type_id<=player",
 			expectedValues: nil,
 		},
+		{
+			name:           "and inside or",
+			input:          "(type_id:team and active:true) or type_id:player",
+			expectedValues: []string{"team", "player"},
+		},
+		{
+			name:           "or inside and",
+			input:          "(type_id:team or type_id:player) and active:true",
+			expectedValues: []string{"team", "player"},
+		},
+		{
+			name:           "and without the field inside or",
+			input:          "type_id:team or (active:true and disabled:false)",
+			expectedValues: nil,
+		},
 		{
 			name:           "not query",
 			input:          "not type_id:team",
@@ -79,3 +94,17 @@ func TestHasMustEqual(t *testing.T) {
 		})
 	}
 }
+
+func TestHasMustEqualFields(t *testing.T) {
+	n, err := ParseAST("tenant:a and (type_id:team and region:eu or type_id:player and region:us) and not region:asia")
+	require.NoError(t, err)
+
+	values := HasMustEqualFields(n, "tenant", "type_id", "region", "active")
+	assert.Equal(t, map[string][]string{
+		"tenant":  {"a"},
+		"type_id": {"team", "player"},
+		"region":  {"eu", "us"},
+	}, values)
+
+	assert.Nil(t, HasMustEqualFields(nil, "tenant"))
+}
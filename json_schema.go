@@ -0,0 +1,132 @@
+package kqlfilter
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+// jsonSchemaDocument is a JSON Schema describing the filterable fields as the properties of an object.
+type jsonSchemaDocument struct {
+	Schema               string                        `json:"$schema"`
+	Type                 string                        `json:"type"`
+	Properties           map[string]jsonSchemaProperty `json:"properties"`
+	Required             []string                      `json:"required,omitempty"`
+	AdditionalProperties bool                          `json:"additionalProperties"`
+}
+
+// jsonSchemaProperty describes a filterable field. Keywords starting with `x-kql-` describe how the field can be
+// filtered.
+type jsonSchemaProperty struct {
+	Type        string   `json:"type"`
+	Format      string   `json:"format,omitempty"`
+	Enum        []string `json:"enum,omitempty"`
+	Operators   []string `json:"x-kql-operators"`
+	Aliases     []string `json:"x-kql-aliases,omitempty"`
+	PrefixMatch bool     `json:"x-kql-prefix-match,omitempty"`
+	SuffixMatch bool     `json:"x-kql-suffix-match,omitempty"`
+}
+
+// JSONSchema returns a JSON Schema (draft 2020-12) document describing the fields of the schema, e.g. to drive a
+// filter builder UI. Every field is a property with the JSON Schema type of its values, and its allowed values as
+// `enum`. The following keywords describe how a field can be filtered:
+//
+//   - `x-kql-operators`: the allowed operators, as in Clause.Operator: `=`, `!=`, `IN`, `NOT IN`, `<`, `<=`, `>`
+//     and `>=`,
+//   - `x-kql-aliases`: the aliases of the field,
+//   - `x-kql-prefix-match` and `x-kql-suffix-match`: whether trailing and leading wildcards are allowed.
+//
+// Required fields are listed in `required`.
+func (s Schema) JSONSchema() ([]byte, error) {
+	doc := newJSONSchemaDocument()
+	for name, fs := range s {
+		property := jsonSchemaProperty{
+			Enum:        fs.AllowedValues,
+			Operators:   jsonSchemaOperators(fs.AllowMultipleValues, fs.AllowNegation, fs.AllowRanges),
+			Aliases:     fs.Aliases,
+			PrefixMatch: fs.AllowPrefixMatch,
+			SuffixMatch: fs.AllowSuffixMatch,
+		}
+		switch fs.Type {
+		case FieldTypeInt64:
+			property.Type, property.Format = "integer", "int64"
+		case FieldTypeFloat64:
+			property.Type, property.Format = "number", "double"
+		case FieldTypeBool:
+			property.Type = "boolean"
+		case FieldTypeTimestamp:
+			property.Type, property.Format = "string", "date-time"
+		default:
+			property.Type = "string"
+		}
+		doc.add(name, property, fs.Required)
+	}
+	return doc.marshal()
+}
+
+// SpannerJSONSchema returns a JSON Schema document describing the fields of the Spanner field configs, like
+// Schema.JSONSchema. DATE columns have the `date` format, and NUMERIC columns the `number` type.
+func SpannerJSONSchema(fieldConfigs map[string]FilterToSpannerFieldConfig) ([]byte, error) {
+	doc := newJSONSchemaDocument()
+	for name, fc := range fieldConfigs {
+		property := jsonSchemaProperty{
+			Enum:        fc.AllowedValues,
+			Operators:   jsonSchemaOperators(fc.AllowMultipleValues, fc.AllowNegation, fc.AllowRanges),
+			Aliases:     fc.Aliases,
+			PrefixMatch: fc.AllowPrefixMatch,
+			SuffixMatch: fc.AllowSuffixMatch,
+		}
+		switch fc.ColumnType {
+		case FilterToSpannerFieldColumnTypeInt64:
+			property.Type, property.Format = "integer", "int64"
+		case FilterToSpannerFieldColumnTypeFloat64:
+			property.Type, property.Format = "number", "double"
+		case FilterToSpannerFieldColumnTypeNumeric:
+			property.Type = "number"
+		case FilterToSpannerFieldColumnTypeBool:
+			property.Type = "boolean"
+		case FilterToSpannerFieldColumnTypeTimestamp:
+			property.Type, property.Format = "string", "date-time"
+		case FilterToSpannerFieldColumnTypeDate:
+			property.Type, property.Format = "string", "date"
+		default:
+			property.Type = "string"
+		}
+		doc.add(name, property, fc.Required)
+	}
+	return doc.marshal()
+}
+
+func newJSONSchemaDocument() *jsonSchemaDocument {
+	return &jsonSchemaDocument{
+		Schema:     "https://json-schema.org/draft/2020-12/schema",
+		Type:       "object",
+		Properties: make(map[string]jsonSchemaProperty),
+	}
+}
+
+func (d *jsonSchemaDocument) add(name string, property jsonSchemaProperty, required bool) {
+	d.Properties[name] = property
+	if required {
+		d.Required = append(d.Required, name)
+	}
+}
+
+func (d *jsonSchemaDocument) marshal() ([]byte, error) {
+	sort.Strings(d.Required)
+	return json.MarshalIndent(d, "", "  ")
+}
+
+// jsonSchemaOperators returns the operators allowed by the field options.
+func jsonSchemaOperators(allowMultipleValues, allowNegation, allowRanges bool) []string {
+	operators := []string{"=", "!="}
+	if allowMultipleValues {
+		operators = append(operators, "IN")
+		if allowNegation {
+			operators = append(operators, "NOT IN")
+		}
+	}
+	if allowRanges {
+		operators = append(operators, "<", "<=", ">", ">=")
+	}
+	return operators
+}
@@ -0,0 +1,61 @@
+package kqlfilter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchemaJSONSchema(t *testing.T) {
+	schema := Schema{
+		"state": {
+			AllowedValues:       []string{"active", "paused"},
+			AllowMultipleValues: true,
+			AllowNegation:       true,
+			Required:            true,
+		},
+		"user_id": {Type: FieldTypeInt64, Aliases: []string{"userId"}},
+		"created": {Type: FieldTypeTimestamp, AllowRanges: true},
+		"email":   {AllowPrefixMatch: true, AllowSuffixMatch: true},
+	}
+
+	doc, err := schema.JSONSchema()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"type": "object",
+		"properties": {
+			"created": {"type": "string", "format": "date-time", "x-kql-operators": ["=", "!=", "<", "<=", ">", ">="]},
+			"email": {"type": "string", "x-kql-operators": ["=", "!="], "x-kql-prefix-match": true, "x-kql-suffix-match": true},
+			"state": {"type": "string", "enum": ["active", "paused"], "x-kql-operators": ["=", "!=", "IN", "NOT IN"]},
+			"user_id": {"type": "integer", "format": "int64", "x-kql-operators": ["=", "!="], "x-kql-aliases": ["userId"]}
+		},
+		"required": ["state"],
+		"additionalProperties": false
+	}`, string(doc))
+}
+
+func TestSpannerJSONSchema(t *testing.T) {
+	configs := map[string]FilterToSpannerFieldConfig{
+		"birthday": {ColumnType: FilterToSpannerFieldColumnTypeDate, AllowRanges: true},
+		"price":    {ColumnType: FilterToSpannerFieldColumnTypeNumeric, AllowMultipleValues: true},
+		"active":   {ColumnType: FilterToSpannerFieldColumnTypeBool, Required: true},
+		"name":     {},
+	}
+
+	doc, err := SpannerJSONSchema(configs)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"type": "object",
+		"properties": {
+			"active": {"type": "boolean", "x-kql-operators": ["=", "!="]},
+			"birthday": {"type": "string", "format": "date", "x-kql-operators": ["=", "!=", "<", "<=", ">", ">="]},
+			"name": {"type": "string", "x-kql-operators": ["=", "!="]},
+			"price": {"type": "number", "x-kql-operators": ["=", "!=", "IN"]}
+		},
+		"required": ["active"],
+		"additionalProperties": false
+	}`, string(doc))
+}
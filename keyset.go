@@ -0,0 +1,185 @@
+package kqlfilter
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// SortKey is a field of the sort order of a list.
+type SortKey struct {
+	Field      string
+	Descending bool
+}
+
+// Keyset is a position in a list for keyset pagination: the sort order of the list, and the cursor, which holds the
+// values of the sort fields of the last row of the previous page. The last sort key should be unique, e.g. the primary
+// key, so that rows with equal values of the other sort keys are neither skipped nor repeated.
+//
+// The predicate of a keyset selects the rows after the cursor in the sort order. For the sort order
+// `create_time desc, id` and the cursor `2024-01-01T00:00:00Z, abc`, it is
+//
+//	create_time<"2024-01-01T00:00:00Z" or (create_time:"2024-01-01T00:00:00Z" and id>abc)
+//
+// Sort fields must not be NULL, as NULL values do not compare.
+type Keyset struct {
+	Sort []SortKey
+	// Values of the sort fields of the last row of the previous page, as strings. Nil for the first page.
+	Cursor []string
+}
+
+// AST returns the predicate of the keyset as an AST, which can be converted by any of the AST based converters.
+// It returns nil for the first page.
+func (k Keyset) AST() (Node, error) {
+	if err := k.validate(); err != nil || len(k.Cursor) == 0 {
+		return nil, err
+	}
+	literal := func(i int) Node {
+		return &LiteralNode{NodeType: NodeLiteral, Value: formatBuilderValue(k.Cursor[i])}
+	}
+	var disjuncts []Node
+	for i, key := range k.Sort {
+		nodes := make([]Node, 0, i+1)
+		for j := 0; j < i; j++ {
+			nodes = append(nodes, &IsNode{NodeType: NodeIs, Identifier: k.Sort[j].Field, Value: literal(j)})
+		}
+		op := RangeOperatorGt
+		if key.Descending {
+			op = RangeOperatorLt
+		}
+		nodes = append(nodes, &RangeNode{NodeType: NodeRange, Identifier: key.Field, Operator: op, Value: literal(i)})
+		disjuncts = append(disjuncts, And(nodes...))
+	}
+	return Or(disjuncts...), nil
+}
+
+// AppendTo returns an AST requiring both ast, typically the filter provided by the user, and the predicate of the
+// keyset, combined with And. The given AST is not modified.
+func (k Keyset) AppendTo(ast Node) (Node, error) {
+	predicate, err := k.AST()
+	if err != nil {
+		return nil, err
+	}
+	return And(ast, predicate), nil
+}
+
+// ToSpannerSQL converts the filter like Filter.ToSpannerSQL and appends the predicate of the keyset as one more
+// condition. The sort fields are looked up in the field configs, and the cursor values are converted according to
+// their column types; MapValue is not applied, as cursors hold values of the database.
+func (k Keyset) ToSpannerSQL(f Filter, fieldConfigs map[string]FilterToSpannerFieldConfig, options ...ConverterOption) ([]string, map[string]any, error) {
+	if err := k.validate(); err != nil {
+		return nil, nil, err
+	}
+	c := SpannerConverter{fieldConfigs: fieldConfigs, options: newConverterOptions(options)}
+	condAnds, params, err := c.Convert(f)
+	if err != nil || len(k.Cursor) == 0 {
+		return condAnds, params, err
+	}
+
+	paramIndex := len(params)
+	columns := make([]string, len(k.Sort))
+	paramNames := make([]string, len(k.Sort))
+	for i, key := range k.Sort {
+		name, ok := c.lookup(key.Field)
+		if !ok {
+			return nil, nil, NewUnknownFieldError(key.Field, spannerFieldNames(fieldConfigs))
+		}
+		fieldConfig := fieldConfigs[name]
+		columns[i] = fieldConfig.ColumnName
+		if columns[i] == "" {
+			columns[i] = name
+		}
+		value, err := fieldConfig.convertValue(k.Cursor[i], c.options)
+		if err != nil {
+			return nil, nil, newFieldError(key.Field, ErrValueInvalid, "cursor value of field %s: %w", key.Field, err)
+		}
+		for {
+			paramNames[i] = fmt.Sprintf("%s%d", "KQL", paramIndex)
+			paramIndex++
+			if _, exists := params[paramNames[i]]; !exists {
+				break
+			}
+		}
+		params[paramNames[i]] = value
+	}
+
+	disjuncts := make([]string, len(k.Sort))
+	for i, key := range k.Sort {
+		conjuncts := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			conjuncts = append(conjuncts, fmt.Sprintf("%s=@%s", columns[j], paramNames[j]))
+		}
+		operator := ">"
+		if key.Descending {
+			operator = "<"
+		}
+		conjuncts = append(conjuncts, fmt.Sprintf("%s%s@%s", columns[i], operator, paramNames[i]))
+		disjuncts[i] = strings.Join(conjuncts, " AND ")
+		if len(conjuncts) > 1 && len(k.Sort) > 1 {
+			disjuncts[i] = "(" + disjuncts[i] + ")"
+		}
+	}
+	condition := strings.Join(disjuncts, " OR ")
+	if len(disjuncts) > 1 {
+		condition = "(" + condition + ")"
+	}
+	return append(condAnds, condition), params, nil
+}
+
+// SpannerOrderBy returns the ORDER BY expressions of the sort order of the keyset, e.g. `create_time DESC, id ASC`,
+// which must be used with the predicate returned by ToSpannerSQL.
+func (k Keyset) SpannerOrderBy(fieldConfigs map[string]FilterToSpannerFieldConfig) (string, error) {
+	c := SpannerConverter{fieldConfigs: fieldConfigs}
+	expressions := make([]string, len(k.Sort))
+	for i, key := range k.Sort {
+		name, ok := c.lookup(key.Field)
+		if !ok {
+			return "", NewUnknownFieldError(key.Field, spannerFieldNames(fieldConfigs))
+		}
+		column := fieldConfigs[name].ColumnName
+		if column == "" {
+			column = name
+		}
+		direction := "ASC"
+		if key.Descending {
+			direction = "DESC"
+		}
+		expressions[i] = column + " " + direction
+	}
+	return strings.Join(expressions, ", "), nil
+}
+
+func (k Keyset) validate() error {
+	if len(k.Sort) == 0 {
+		return errors.New("keyset has no sort keys")
+	}
+	if len(k.Cursor) > 0 && len(k.Cursor) != len(k.Sort) {
+		return fmt.Errorf("cursor has %d values for %d sort keys", len(k.Cursor), len(k.Sort))
+	}
+	return nil
+}
+
+// EncodeCursor encodes the values of a cursor as an opaque, URL-safe token, e.g. to return it as the page token of a
+// list endpoint.
+func EncodeCursor(values ...string) string {
+	data, _ := json.Marshal(values)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor decodes the values of a cursor encoded by EncodeCursor. It returns nil for an empty token.
+func DecodeCursor(token string) ([]string, error) {
+	if token == "" {
+		return nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+	var values []string
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+	return values, nil
+}
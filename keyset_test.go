@@ -0,0 +1,151 @@
+package kqlfilter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeysetAST(t *testing.T) {
+	sort := []SortKey{{Field: "create_time", Descending: true}, {Field: "id"}}
+
+	testCases := []struct {
+		name     string
+		keyset   Keyset
+		expected string
+	}{
+		{
+			name:   "first page",
+			keyset: Keyset{Sort: sort},
+		},
+		{
+			name:     "single key",
+			keyset:   Keyset{Sort: []SortKey{{Field: "id"}}, Cursor: []string{"a*b"}},
+			expected: `id>a\*b`,
+		},
+		{
+			name:     "multiple keys",
+			keyset:   Keyset{Sort: sort, Cursor: []string{"2024-01-01T00:00:00Z", "abc"}},
+			expected: "(create_time<2024-01-01T00:00:00Z OR (create_time=2024-01-01T00:00:00Z AND id>abc))",
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			ast, err := test.keyset.AST()
+			require.NoError(t, err)
+			if test.expected == "" {
+				assert.Nil(t, ast)
+				return
+			}
+			assert.Equal(t, test.expected, ast.String())
+		})
+	}
+
+	_, err := Keyset{Sort: sort, Cursor: []string{"abc"}}.AST()
+	assert.EqualError(t, err, "cursor has 1 values for 2 sort keys")
+	_, err = Keyset{}.AST()
+	assert.EqualError(t, err, "keyset has no sort keys")
+}
+
+func TestKeysetAppendTo(t *testing.T) {
+	ast, err := ParseAST("state:active")
+	require.NoError(t, err)
+
+	keyset := Keyset{Sort: []SortKey{{Field: "name"}, {Field: "id"}}, Cursor: []string{"jon", "7"}}
+	merged, err := keyset.AppendTo(ast)
+	require.NoError(t, err)
+	assert.Equal(t, "(state=active AND (name>jon OR (name=jon AND id>7)))", merged.String())
+	assert.Equal(t, "state=active", ast.String())
+}
+
+func TestKeysetToSpannerSQL(t *testing.T) {
+	configs := map[string]FilterToSpannerFieldConfig{
+		"state":       {ColumnType: FilterToSpannerFieldColumnTypeString},
+		"create_time": {ColumnName: "CreateTime", ColumnType: FilterToSpannerFieldColumnTypeTimestamp, Aliases: []string{"createTime"}},
+		"id":          {ColumnName: "Id", ColumnType: FilterToSpannerFieldColumnTypeInt64},
+	}
+	sort := []SortKey{{Field: "createTime", Descending: true}, {Field: "id"}}
+
+	testCases := []struct {
+		name           string
+		input          string
+		keyset         Keyset
+		expectedSQL    []string
+		expectedParams map[string]any
+		expectedError  string
+	}{
+		{
+			name:           "first page",
+			input:          "state:active",
+			keyset:         Keyset{Sort: sort},
+			expectedSQL:    []string{"state=@KQL0"},
+			expectedParams: map[string]any{"KQL0": "active"},
+		},
+		{
+			name:        "next page",
+			input:       "state:active",
+			keyset:      Keyset{Sort: sort, Cursor: []string{"2024-01-01T00:00:00Z", "42"}},
+			expectedSQL: []string{"state=@KQL0", "(CreateTime<@KQL1 OR (CreateTime=@KQL1 AND Id>@KQL2))"},
+			expectedParams: map[string]any{
+				"KQL0": "active",
+				"KQL1": time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+				"KQL2": int64(42),
+			},
+		},
+		{
+			name:           "single key",
+			input:          "",
+			keyset:         Keyset{Sort: []SortKey{{Field: "id"}}, Cursor: []string{"42"}},
+			expectedSQL:    []string{"Id>@KQL0"},
+			expectedParams: map[string]any{"KQL0": int64(42)},
+		},
+		{
+			name:          "unknown sort field",
+			input:         "state:active",
+			keyset:        Keyset{Sort: []SortKey{{Field: "name"}}, Cursor: []string{"jon"}},
+			expectedError: "unknown field: name",
+		},
+		{
+			name:          "invalid cursor value",
+			input:         "state:active",
+			keyset:        Keyset{Sort: sort, Cursor: []string{"2024-01-01T00:00:00Z", "abc"}},
+			expectedError: `cursor value of field id: invalid INT64 value: strconv.ParseInt: parsing "abc": invalid syntax`,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			f, err := Parse(test.input)
+			require.NoError(t, err)
+			sql, params, err := test.keyset.ToSpannerSQL(f, configs)
+			if test.expectedError != "" {
+				assert.EqualError(t, err, test.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedSQL, sql)
+			assert.Equal(t, test.expectedParams, params)
+		})
+	}
+
+	orderBy, err := Keyset{Sort: sort}.SpannerOrderBy(configs)
+	require.NoError(t, err)
+	assert.Equal(t, "CreateTime DESC, Id ASC", orderBy)
+}
+
+func TestCursor(t *testing.T) {
+	token := EncodeCursor("2024-01-01T00:00:00Z", "a/b")
+	values, err := DecodeCursor(token)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2024-01-01T00:00:00Z", "a/b"}, values)
+
+	values, err = DecodeCursor("")
+	require.NoError(t, err)
+	assert.Nil(t, values)
+
+	_, err = DecodeCursor("not a cursor")
+	assert.ErrorContains(t, err, "invalid cursor")
+}
@@ -0,0 +1,233 @@
+package kqlfilter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ToKusto converts the AST to the predicate of a Kusto Query Language `where` operator, e.g. for querying events
+// mirrored into Azure Data Explorer with the same filters as the API.
+//
+// The schema is used to resolve aliases and column names, and to format the values according to the field type.
+// Fields that are not in the schema, or all fields if the schema is nil, are treated as strings with their identifier
+// as name. Fields in nested queries (`parent:{child:value}`) are joined with a dot (`parent.child`), which accesses
+// the property of a dynamic column.
+//
+// String comparisons are case-sensitive, like the other converters: equality uses `==`, and wildcards in string values
+// are converted to startswith_cs, endswith_cs or contains_cs. Existence checks (`field:*`) are converted to isnotempty
+// for strings and isnotnull for other types. An empty AST returns an empty string.
+func ToKusto(ast Node, schema Schema) (string, error) {
+	if ast == nil {
+		return "", nil
+	}
+	var sb strings.Builder
+	if err := writeKusto(&sb, ast, "", schema); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+func writeKusto(sb *strings.Builder, ast Node, prefix string, schema Schema) error {
+	switch n := ast.(type) {
+	case *AndNode:
+		return writeKustoNodes(sb, n.Nodes, " and ", prefix, schema)
+	case *OrNode:
+		return writeKustoNodes(sb, n.Nodes, " or ", prefix, schema)
+	case *NotNode:
+		sb.WriteString("not(")
+		if err := writeKusto(sb, n.Expr, prefix, schema); err != nil {
+			return err
+		}
+		sb.WriteString(")")
+	case *IsNode:
+		if nested, ok := n.Value.(*NestedNode); ok {
+			return writeKusto(sb, nested.Expr, prefix+n.Identifier+".", schema)
+		}
+		field := newKustoField(prefix+n.Identifier, schema)
+		return field.writeValue(sb, n.Value)
+	case *RangeNode:
+		field := newKustoField(prefix+n.Identifier, schema)
+		literal, ok := n.Value.(*LiteralNode)
+		if !ok {
+			return fmt.Errorf("unsupported node type %s", n.Value.Type())
+		}
+		operator := n.Operator.String()
+		if operator == "???" {
+			return fmt.Errorf("unsupported operator %s", n.Operator)
+		}
+		value, err := field.format(literal.Value)
+		if err != nil {
+			return err
+		}
+		sb.WriteString(field.path + " " + operator + " " + value)
+	case *ExistsNode:
+		field := newKustoField(prefix+n.Identifier, schema)
+		if field.fieldType == FieldTypeString {
+			sb.WriteString("isnotempty(" + field.path + ")")
+		} else {
+			sb.WriteString("isnotnull(" + field.path + ")")
+		}
+	case *FuzzyNode:
+		return fmt.Errorf("field %s: fuzzy matching is not supported by Kusto", prefix+n.Identifier)
+	case *LiteralNode:
+		if n.Value != "true" && n.Value != "false" {
+			return fmt.Errorf("only boolean literals are supported; %s", n.Value)
+		}
+		sb.WriteString(n.Value)
+	default:
+		return fmt.Errorf("unsupported node type %s", ast.Type())
+	}
+	return nil
+}
+
+func writeKustoNodes(sb *strings.Builder, nodes []Node, separator string, prefix string, schema Schema) error {
+	sb.WriteString("(")
+	for i, child := range nodes {
+		if i > 0 {
+			sb.WriteString(separator)
+		}
+		if err := writeKusto(sb, child, prefix, schema); err != nil {
+			return err
+		}
+	}
+	sb.WriteString(")")
+	return nil
+}
+
+// kustoField is a field as referenced in a Kusto expression.
+type kustoField struct {
+	path      string
+	fieldType FieldType
+}
+
+func newKustoField(identifier string, schema Schema) kustoField {
+	name, fs, ok := schema.Lookup(identifier)
+	if !ok {
+		return kustoField{path: kustoPath(identifier), fieldType: FieldTypeString}
+	}
+	return kustoField{path: kustoPath(schema.ColumnName(name)), fieldType: fs.Type}
+}
+
+// kustoPath returns the column name, or the path of a property of a dynamic column, quoting the names that are not
+// valid Kusto identifiers, e.g. `['event-type']`.
+func kustoPath(name string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		if !isKustoIdentifier(part) {
+			parts[i] = "['" + strings.ReplaceAll(strings.ReplaceAll(part, `\`, `\\`), "'", `\'`) + "']"
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+// isKustoIdentifier reports whether name consists of letters, digits and underscores and doesn't start with a digit.
+func isKustoIdentifier(name string) bool {
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		return false
+	}
+	for _, r := range name {
+		if !(r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')) {
+			return false
+		}
+	}
+	return true
+}
+
+// writeValue writes the comparison of the field with the value of an IsNode.
+func (f kustoField) writeValue(sb *strings.Builder, value Node) error {
+	switch v := value.(type) {
+	case *LiteralNode:
+		if f.fieldType == FieldTypeString {
+			text, prefix, suffix := SplitWildcards(v.Value, true, true)
+			switch {
+			case prefix && suffix:
+				sb.WriteString(f.path + " contains_cs " + quoteKustoString(text))
+				return nil
+			case prefix:
+				sb.WriteString(f.path + " startswith_cs " + quoteKustoString(text))
+				return nil
+			case suffix:
+				sb.WriteString(f.path + " endswith_cs " + quoteKustoString(text))
+				return nil
+			}
+		}
+		formatted, err := f.format(v.Value)
+		if err != nil {
+			return err
+		}
+		sb.WriteString(f.path + " == " + formatted)
+	case *OrNode:
+		values := make([]string, 0, len(v.Nodes))
+		for _, child := range v.Nodes {
+			literal, ok := child.(*LiteralNode)
+			if !ok {
+				return fmt.Errorf("unsupported node type %s", child.Type())
+			}
+			formatted, err := f.format(literal.Value)
+			if err != nil {
+				return err
+			}
+			values = append(values, formatted)
+		}
+		sb.WriteString(f.path + " in (" + strings.Join(values, ", ") + ")")
+	default:
+		return fmt.Errorf("unsupported node type %s", value.Type())
+	}
+	return nil
+}
+
+// format returns the value as a Kusto literal of the type of the field.
+func (f kustoField) format(value string) (string, error) {
+	switch f.fieldType {
+	case FieldTypeInt64:
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return "", fmt.Errorf("field %s: invalid int64 value: %w", f.path, err)
+		}
+		return value, nil
+	case FieldTypeFloat64:
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return "", fmt.Errorf("field %s: invalid float64 value: %w", f.path, err)
+		}
+		return "real(" + value + ")", nil
+	case FieldTypeBool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", fmt.Errorf("field %s: invalid bool value: %w", f.path, err)
+		}
+		return strconv.FormatBool(b), nil
+	case FieldTypeTimestamp:
+		t, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return "", fmt.Errorf("field %s: invalid timestamp value: %w", f.path, err)
+		}
+		return "datetime(" + t.UTC().Format(time.RFC3339Nano) + ")", nil
+	default:
+		return quoteKustoString(UnescapeValue(value)), nil
+	}
+}
+
+// quoteKustoString returns the value as a Kusto string literal, escaping backslashes, quotes and control characters.
+func quoteKustoString(value string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for _, r := range value {
+		switch r {
+		case '\\':
+			sb.WriteString(`\\`)
+		case '"':
+			sb.WriteString(`\"`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\t':
+			sb.WriteString(`\t`)
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
@@ -0,0 +1,76 @@
+package kqlfilter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToKusto(t *testing.T) {
+	schema := Schema{
+		"user_id":    {Type: FieldTypeInt64, Aliases: []string{"userId"}, Column: "UserId"},
+		"price":      {Type: FieldTypeFloat64},
+		"active":     {Type: FieldTypeBool},
+		"created_at": {Type: FieldTypeTimestamp},
+		"name":       {},
+		"state":      {},
+		"event_type": {Column: "event-type"},
+		"address.city": {
+			Column: "Address.City",
+		},
+	}
+
+	testCases := []struct {
+		name          string
+		input         string
+		expected      string
+		expectedError bool
+	}{
+		{"string equality", "name:john", `name == "john"`, false},
+		{"quote is escaped", `name:"say \"hi\""`, `name == "say \"hi\""`, false},
+		{"alias and column name", "userId:12", "UserId == 12", false},
+		{"quoted column name", "event_type:click", `['event-type'] == "click"`, false},
+		{"bool", "active:TRUE", "active == true", false},
+		{"timestamp range", `created_at>="2023-01-01T01:00:00+01:00"`, "created_at >= datetime(2023-01-01T00:00:00Z)", false},
+		{"ranges", "price>1 and price<=10.5", "(price > real(1) and price <= real(10.5))", false},
+		{"and, or and not", "name:a and (state:b or not state:c)", `(name == "a" and (state == "b" or not(state == "c")))`, false},
+		{"in", "state:(active or paused)", `state in ("active", "paused")`, false},
+		{"numbers in", "userId:(1 or 2)", "UserId in (1, 2)", false},
+		{"prefix", "name:jo*", `name startswith_cs "jo"`, false},
+		{"escaped asterisk", `name:jo\*`, `name == "jo*"`, false},
+		{"suffix", "name:*hn", `name endswith_cs "hn"`, false},
+		{"contains", "name:*oh*", `name contains_cs "oh"`, false},
+		{"exists", "name:* and userId:*", "(isnotempty(name) and isnotnull(UserId))", false},
+		{"nested", "address:{city:Amsterdam}", `Address.City == "Amsterdam"`, false},
+		{"boolean literal", "true", "true", false},
+		{"invalid int", "userId:abc", "", true},
+		{"invalid timestamp", "created_at>yesterday", "", true},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			ast, err := ParseAST(test.input)
+			require.NoError(t, err)
+			kusto, err := ToKusto(ast, schema)
+			if test.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, kusto)
+		})
+	}
+}
+
+func TestToKustoWithoutSchema(t *testing.T) {
+	ast, err := ParseAST("userId:12 state:(a or b)")
+	require.NoError(t, err)
+	kusto, err := ToKusto(ast, nil)
+	require.NoError(t, err)
+	assert.Equal(t, `(userId == "12" and state in ("a", "b"))`, kusto)
+
+	kusto, err = ToKusto(nil, nil)
+	require.NoError(t, err)
+	assert.Empty(t, kusto)
+}
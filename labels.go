@@ -0,0 +1,137 @@
+package kqlfilter
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ToLabelMatchers converts the AST to a PromQL or LogQL label selector, e.g. `{app="api", env=~"prod|staging"}`.
+//
+// Label selectors can only express a conjunction of label matchers, so only a subset of the AST is supported:
+// clauses joined by AND, each of which is an equality (`app:api`), a list of values (`env:(prod or staging)`),
+// an existence check (`app:*`) or the negation of one of these. Values with wildcards are converted to regular
+// expression matchers, e.g. `app:api*` becomes `app=~"api.*"`. Any other node, like OR between clauses or a range,
+// returns an error.
+//
+// The schema is used to resolve aliases and label names, see Schema.ColumnName. Field types are ignored, as label
+// values are always strings. An empty AST returns an empty string.
+func ToLabelMatchers(ast Node, schema Schema) (string, error) {
+	if ast == nil {
+		return "", nil
+	}
+	nodes := []Node{ast}
+	if and, ok := ast.(*AndNode); ok {
+		nodes = and.Nodes
+	}
+	matchers := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		matcher, err := labelMatcher(node, schema)
+		if err != nil {
+			return "", err
+		}
+		matchers = append(matchers, matcher)
+	}
+	return "{" + strings.Join(matchers, ", ") + "}", nil
+}
+
+// labelNamePattern matches valid Prometheus label names.
+var labelNamePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// labelMatcher returns the label matcher of a clause.
+func labelMatcher(ast Node, schema Schema) (string, error) {
+	negated := false
+	if not, ok := ast.(*NotNode); ok {
+		negated = true
+		ast = not.Expr
+	}
+	var identifier, operator, value string
+	switch n := ast.(type) {
+	case *IsNode:
+		identifier = n.Identifier
+		switch v := n.Value.(type) {
+		case *LiteralNode:
+			if !containsWildcard(v.Value) {
+				operator, value = "=", UnescapeValue(v.Value)
+			} else {
+				operator, value = "=~", wildcardRegexp(v.Value)
+			}
+		case *OrNode:
+			alternatives := make([]string, 0, len(v.Nodes))
+			for _, child := range v.Nodes {
+				literal, ok := child.(*LiteralNode)
+				if !ok {
+					return "", fmt.Errorf("field %s: unsupported node type %s", n.Identifier, child.Type())
+				}
+				alternatives = append(alternatives, wildcardRegexp(literal.Value))
+			}
+			operator, value = "=~", strings.Join(alternatives, "|")
+		default:
+			return "", fmt.Errorf("field %s: unsupported node type %s", n.Identifier, n.Value.Type())
+		}
+	case *ExistsNode:
+		// A label that is not set has the empty value.
+		identifier, operator, value = n.Identifier, "!=", ""
+	case *AndNode:
+		return "", fmt.Errorf("nested AND is not supported by label matchers")
+	case *OrNode:
+		return "", fmt.Errorf("OR between fields is not supported by label matchers")
+	case *RangeNode:
+		return "", fmt.Errorf("field %s: range operators are not supported by label matchers", n.Identifier)
+	case *FuzzyNode:
+		return "", fmt.Errorf("field %s: fuzzy matching is not supported by label matchers", n.Identifier)
+	default:
+		return "", fmt.Errorf("unsupported node type %s", ast.Type())
+	}
+
+	name := identifier
+	if canonical, _, ok := schema.Lookup(identifier); ok {
+		name = schema.ColumnName(canonical)
+	}
+	if !labelNamePattern.MatchString(name) {
+		return "", fmt.Errorf("field %s: invalid label name %q", identifier, name)
+	}
+	if negated {
+		operator = negateLabelOperator(operator)
+	}
+	return name + operator + strconv.Quote(value), nil
+}
+
+// negateLabelOperator returns the label matching operator with the opposite result.
+func negateLabelOperator(operator string) string {
+	switch operator {
+	case "=":
+		return "!="
+	case "!=":
+		return "="
+	case "=~":
+		return "!~"
+	default:
+		return "=~"
+	}
+}
+
+// wildcardRegexp returns a regular expression matching the value, in which unescaped asterisks match any text.
+// Label regular expressions are fully anchored, so no anchors are added.
+func wildcardRegexp(value string) string {
+	if !strings.Contains(value, "*") {
+		return regexp.QuoteMeta(value)
+	}
+	var sb, text strings.Builder
+	for i := 0; i < len(value); i++ {
+		switch {
+		case value[i] == '\\' && i+1 < len(value) && (value[i+1] == '\\' || value[i+1] == '*'):
+			i++
+			text.WriteByte(value[i])
+		case value[i] == '*':
+			sb.WriteString(regexp.QuoteMeta(text.String()))
+			sb.WriteString(".*")
+			text.Reset()
+		default:
+			text.WriteByte(value[i])
+		}
+	}
+	sb.WriteString(regexp.QuoteMeta(text.String()))
+	return sb.String()
+}
@@ -0,0 +1,61 @@
+package kqlfilter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToLabelMatchers(t *testing.T) {
+	schema := Schema{
+		"app":       {Aliases: []string{"application"}},
+		"env":       {},
+		"namespace": {Column: "k8s_namespace"},
+		"team":      {Column: "team-name"},
+	}
+
+	testCases := []struct {
+		name          string
+		input         string
+		expected      string
+		expectedError bool
+	}{
+		{"equality", "app:api", `{app="api"}`, false},
+		{"and", "app:api and env:prod", `{app="api", env="prod"}`, false},
+		{"implicit and", "app:api env:prod", `{app="api", env="prod"}`, false},
+		{"alias and label name", "application:api namespace:default", `{app="api", k8s_namespace="default"}`, false},
+		{"unknown field", "pod:api-1", `{pod="api-1"}`, false},
+		{"quote is escaped", `app:"say \"hi\""`, `{app="say \"hi\""}`, false},
+		{"negation", "not app:api", `{app!="api"}`, false},
+		{"in", "env:(prod or staging)", `{env=~"prod|staging"}`, false},
+		{"not in", "not env:(prod or staging)", `{env!~"prod|staging"}`, false},
+		{"regexp characters are quoted", "env:(a.b or c|d)", `{env=~"a\\.b|c\\|d"}`, false},
+		{"wildcard", "app:api*", `{app=~"api.*"}`, false},
+		{"negated wildcard", "not app:*api", `{app!~".*api"}`, false},
+		{"escaped asterisk", `app:api\*`, `{app="api*"}`, false},
+		{"wildcard and escaped asterisk", `app:a\*b*`, `{app=~"a\\*b.*"}`, false},
+		{"exists", "app:*", `{app!=""}`, false},
+		{"not exists", "not app:*", `{app=""}`, false},
+		{"or", "app:api or env:prod", "", true},
+		{"nested and", "app:api and not (env:prod and env:staging)", "", true},
+		{"range", "app>a", "", true},
+		{"literal", "api", "", true},
+		{"nested query", "app:{name:api}", "", true},
+		{"invalid label name", "team:core", "", true},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			ast, err := ParseAST(test.input)
+			require.NoError(t, err)
+			matchers, err := ToLabelMatchers(ast, schema)
+			if test.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, matchers)
+		})
+	}
+}
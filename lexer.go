@@ -3,6 +3,7 @@ package kqlfilter
 import (
 	"fmt"
 	"strings"
+	"unicode"
 	"unicode/utf8"
 )
 
@@ -43,8 +44,11 @@ const (
 	itemLeftBrace     // '{'
 	itemRightBrace    // '{'
 	itemColon         // ':'
+	itemComma         // ',' inside parentheses
 	itemWildcard      // '*'
+	itemPlaceholder   // '{{name}}', only lexed for templates
 	itemRangeOperator // '<=' or '<' or '>=' or '>'
+	itemRangeBrackets // '[a TO b]', with '{' or '}' for exclusive bounds
 )
 
 // Make the types pretty printable.
@@ -62,7 +66,10 @@ var itemName = map[itemType]string{
 	itemLeftBrace:     "{",
 	itemRightBrace:    "}",
 	itemColon:         ":",
+	itemComma:         ",",
 	itemRangeOperator: "range",
+	itemRangeBrackets: "range brackets",
+	itemPlaceholder:   "placeholder",
 }
 
 func (i itemType) String() string {
@@ -81,6 +88,55 @@ var key = map[string]itemType{
 	"false": itemBool,
 }
 
+// maxKeywordLength is the length of the longest keyword.
+const maxKeywordLength = len("false")
+
+// keyword returns the item type of the word if it is a keyword, ignoring case, and zero otherwise.
+// It lowercases the word in a stack buffer, so that looking up words doesn't allocate.
+func keyword(word string) itemType {
+	if len(word) > maxKeywordLength {
+		return 0
+	}
+	var buf [maxKeywordLength]byte
+	for i := 0; i < len(word); i++ {
+		c := word[i]
+		if 'A' <= c && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		buf[i] = c
+	}
+	return key[string(buf[:len(word)])]
+}
+
+// KeywordCase controls which spellings of the boolean operators `and`, `or` and `not` are recognized as keywords.
+// Other spellings are parsed as strings, e.g. with KeywordCaseUpper, `state:active or` matches the value `or`.
+type KeywordCase int
+
+const (
+	// KeywordCaseInsensitive recognizes the operators in any case, e.g. `and`, `AND` and `And`. This is the default.
+	KeywordCaseInsensitive KeywordCase = iota
+	// KeywordCaseLower only recognizes the lowercase operators `and`, `or` and `not`.
+	KeywordCaseLower
+	// KeywordCaseUpper only recognizes the uppercase operators `AND`, `OR` and `NOT`, as in Lucene.
+	KeywordCaseUpper
+)
+
+// isKeyword reports whether the word is spelled as a keyword of the given type according to the keyword case.
+// The boolean constants are always case-insensitive.
+func (c KeywordCase) isKeyword(word string, typ itemType) bool {
+	if typ == itemBool {
+		return true
+	}
+	switch c {
+	case KeywordCaseLower:
+		return word == strings.ToLower(word)
+	case KeywordCaseUpper:
+		return word == strings.ToUpper(word)
+	default:
+		return true
+	}
+}
+
 const eof = -1
 
 // stateFn represents the state of the scanner as a function that returns the next state.
@@ -97,6 +153,10 @@ type lexer struct {
 	line       int    // 1+number of newlines seen
 	startLine  int    // start line of this item
 	item       item   // item to return to parser
+	// Spellings of the boolean operators recognized as keywords.
+	keywordCase KeywordCase
+	// Whether `{{name}}` is lexed as a placeholder; see ParseTemplate.
+	placeholders bool
 }
 
 // next returns the next rune in the input.
@@ -202,12 +262,18 @@ func (l *lexer) nextItem() item {
 
 // lex creates a new scanner for the input string.
 func lex(input string) *lexer {
-	l := &lexer{
+	l := &lexer{}
+	l.reset(input)
+	return l
+}
+
+// reset prepares the scanner to scan the input string, so a scanner can be reused without allocating.
+func (l *lexer) reset(input string) {
+	*l = lexer{
 		input:     input,
 		line:      1,
 		startLine: 1,
 	}
-	return l
 }
 
 // state functions
@@ -230,12 +296,18 @@ func lexExpression(l *lexer) stateFn {
 		return lexSpace
 	case r == ':':
 		return l.emit(itemColon)
+	case r == ',' && l.parenDepth > 0:
+		return l.emit(itemComma)
 	case r == '"':
 		return lexQuote
 	case r == '<' || r == '>':
 		return lexRangeOperator
 	case r == '*':
 		return l.emit(itemWildcard)
+	case r == '\\':
+		// An escape sequence starts a string; let lexString scan it.
+		l.backup()
+		return lexString
 	case r == '(':
 		l.parenDepth++
 		return l.emit(itemLeftParen)
@@ -245,7 +317,17 @@ func lexExpression(l *lexer) stateFn {
 			return l.errorf("unexpected right parenthesis")
 		}
 		return l.emit(itemRightParen)
-	case r == '{':
+	case r == '{' && l.placeholders && l.peek() == '{':
+		return lexPlaceholder
+	case r == '[' || r == '{':
+		if _, n, ok := scanRangeBrackets(l.input[l.start:]); ok {
+			l.pos = l.start + Pos(n)
+			return l.emit(itemRangeBrackets)
+		}
+		if r == '[' {
+			l.backup()
+			return lexString
+		}
 		l.braceDepth++
 		return l.emit(itemLeftBrace)
 	case r == '}':
@@ -306,7 +388,7 @@ Loop:
 func lexString(l *lexer) stateFn {
 	for {
 		switch r := l.next(); {
-		case !isSpecialSymbol(r) && r != eof && !isSpace(r):
+		case !isSpecialSymbol(r) && r != eof && !isSpace(r) && !l.isComma(r):
 		// absorb.
 		case r == '\\':
 			switch l.next() {
@@ -341,14 +423,13 @@ func lexString(l *lexer) stateFn {
 			}
 		default:
 			l.backup()
-			word := strings.ToLower(l.input[l.start:l.pos])
+			word := l.input[l.start:l.pos]
 			if !l.atTerminator() {
 				return l.errorf("bad character %#U", r)
 			}
-			switch {
-			case key[word] > 0:
-				item := key[word]
-				return l.emit(item)
+			switch typ := keyword(word); {
+			case typ > 0 && l.keywordCase.isKeyword(word, typ):
+				return l.emit(typ)
 			default:
 				// Replace escaped characters.
 
@@ -368,13 +449,22 @@ func lexString(l *lexer) stateFn {
 }
 
 // replaceEscapes replaces escaped characters in the input string.
+// Escaped backslashes and asterisks are kept, so that the parser can tell literal asterisks from wildcards.
+// Strings without escapes are returned as is, without allocating.
 func replaceEscapes(s string) string {
+	if strings.IndexByte(s, '\\') < 0 {
+		return s
+	}
 	var b strings.Builder
+	b.Grow(len(s))
 	for i := 0; i < len(s); i++ {
 		if s[i] == '\\' {
 			i++
 			switch s[i] {
-			case '\\', '(', ')', '{', '}', ':', '<', '>', '"', '*':
+			case '\\', '*':
+				b.WriteByte('\\')
+				b.WriteByte(s[i])
+			case '(', ')', '{', '}', ':', '<', '>', '"':
 				b.WriteByte(s[i])
 			case 'a':
 				b.WriteString("and")
@@ -404,7 +494,146 @@ func (l *lexer) atTerminator() bool {
 	case eof, '*', '>', '<', ':', ')', '(', '}', '{':
 		return true
 	}
-	return false
+	return l.isComma(r)
+}
+
+// isComma reports whether r is a comma separating values, which it is inside parentheses, e.g. in `state:(a, b)`.
+// Elsewhere, commas are part of strings.
+func (l *lexer) isComma(r rune) bool {
+	return r == ',' && l.parenDepth > 0
+}
+
+// lexPlaceholder scans a placeholder, `{{name}}`. The first brace has been consumed.
+func lexPlaceholder(l *lexer) stateFn {
+	end := strings.Index(l.input[l.pos:], "}}")
+	if end < 0 {
+		return l.errorf("unterminated placeholder")
+	}
+	if name := l.input[int(l.pos)+1 : int(l.pos)+end]; !isPlaceholderName(strings.TrimSpace(name)) {
+		return l.errorf("invalid placeholder name %q", name)
+	}
+	l.pos += Pos(end + 2)
+	return l.emit(itemPlaceholder)
+}
+
+// placeholderName returns the name of a placeholder, i.e. the text between the braces without surrounding spaces.
+func placeholderName(placeholder string) string {
+	return strings.TrimSpace(placeholder[2 : len(placeholder)-2])
+}
+
+// isPlaceholderName reports whether name consists of letters, digits, underscores and dots only.
+func isPlaceholderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '.' {
+			return false
+		}
+	}
+	return true
+}
+
+// rangeBrackets holds the bounds of a Lucene-style range, e.g. `[10 TO 20}`.
+type rangeBrackets struct {
+	// Bounds with escapes replaced like in quoted strings. Empty if the range is open on that side, i.e. the bound is
+	// an unquoted `*`.
+	lower, upper string
+	// Whether the bounds are inclusive, i.e. written with `[` and `]` rather than `{` and `}`.
+	lowerInclusive, upperInclusive bool
+}
+
+// scanRangeBrackets scans a range in brackets at the start of s, `[a TO b]`, where each bracket is either square for an
+// inclusive bound or curly for an exclusive one, as in Lucene. It returns the range and its length in bytes, and
+// reports whether s starts with a range. The keyword `TO` must be uppercase.
+func scanRangeBrackets(s string) (r rangeBrackets, n int, ok bool) {
+	if s == "" || (s[0] != '[' && s[0] != '{') {
+		return r, 0, false
+	}
+	r.lowerInclusive = s[0] == '['
+	i := skipSpaces(s, 1)
+	var lowerOpen, upperOpen bool
+	if r.lower, lowerOpen, i, ok = scanRangeBound(s, i); !ok {
+		return r, 0, false
+	}
+	j := skipSpaces(s, i)
+	if j == i || !strings.HasPrefix(s[j:], "TO") {
+		return r, 0, false
+	}
+	i = skipSpaces(s, j+len("TO"))
+	if i == j+len("TO") {
+		return r, 0, false
+	}
+	if r.upper, upperOpen, i, ok = scanRangeBound(s, i); !ok {
+		return r, 0, false
+	}
+	i = skipSpaces(s, i)
+	if i >= len(s) || (s[i] != ']' && s[i] != '}') {
+		return r, 0, false
+	}
+	r.upperInclusive = s[i] == ']'
+	if lowerOpen {
+		r.lower = ""
+	}
+	if upperOpen {
+		r.upper = ""
+	}
+	return r, i + 1, true
+}
+
+// scanRangeBound scans a bound of a range starting at s[i], either a quoted string or a run of characters other than
+// spaces and brackets. Opening brackets are excluded so that a nested query such as `{name:[a TO b]}` isn't mistaken
+// for a range. It returns the bound, whether it is an unquoted `*`, and the position after it.
+func scanRangeBound(s string, i int) (bound string, open bool, end int, ok bool) {
+	start := i
+	if i < len(s) && s[i] == '"' {
+		for i++; i < len(s) && s[i] != '"'; i++ {
+			if s[i] == '\\' {
+				i++
+			}
+		}
+		if i >= len(s) {
+			return "", false, 0, false
+		}
+		i++
+		quoted := replaceRangeEscapes(s[start+1 : i-1])
+		return quoted, false, i, true
+	}
+	for i < len(s) && !isSpace(rune(s[i])) && !strings.ContainsRune("[]{}", rune(s[i])) {
+		if s[i] == '\\' {
+			i++
+		}
+		i++
+	}
+	if i == start || i > len(s) {
+		return "", false, 0, false
+	}
+	bound = s[start:i]
+	return replaceRangeEscapes(bound), bound == "*", i, true
+}
+
+// replaceRangeEscapes removes the backslashes of escape sequences in a range bound.
+func replaceRangeEscapes(s string) string {
+	if strings.IndexByte(s, '\\') < 0 {
+		return s
+	}
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// skipSpaces returns the position of the first non-space character of s at or after i.
+func skipSpaces(s string, i int) int {
+	for i < len(s) && isSpace(rune(s[i])) {
+		i++
+	}
+	return i
 }
 
 // lexRangeOperator scans a range operator.
@@ -99,6 +99,20 @@ func TestLexer(t *testing.T) {
 				tEOF,
 			},
 		},
+		{
+			"range brackets",
+			"price:[10 TO *} tag:[x",
+			[]item{
+				newItem(itemString, "price"),
+				tColon,
+				newItem(itemRangeBrackets, "[10 TO *}"),
+				tSpace,
+				newItem(itemString, "tag"),
+				tColon,
+				newItem(itemString, "[x"),
+				tEOF,
+			},
+		},
 		{
 			"quoted filter",
 			"field: \"value two\"*",
@@ -0,0 +1,27 @@
+package kqlfilter
+
+import "fmt"
+
+// Names of the limits reported in a LimitError.
+const (
+	// LimitInputLength is the limit set with WithMaxInputLength.
+	LimitInputLength = "input length"
+	// LimitValueLength is the limit set with WithMaxValueLength.
+	LimitValueLength = "value length"
+)
+
+// LimitError is returned by the parser when the input exceeds a limit set with a parser option.
+type LimitError struct {
+	// Name of the exceeded limit, e.g. LimitValueLength.
+	Limit string
+	// Maximum allowed by the limit.
+	Max int
+	// Actual size of the input or value.
+	Actual int
+	// Position of the offending part of the input.
+	Pos Pos
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("parser error: %s %d exceeds maximum of %d at pos %d", e.Limit, e.Actual, e.Max, e.Pos)
+}
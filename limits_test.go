@@ -0,0 +1,89 @@
+package kqlfilter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLimits(t *testing.T) {
+	testCases := []struct {
+		name          string
+		input         string
+		options       []ParserOption
+		expectedError *LimitError
+	}{
+		{
+			"input within limit",
+			"a:1 b:2",
+			[]ParserOption{WithMaxInputLength(7)},
+			nil,
+		},
+		{
+			"input too long",
+			"a:1 b:22",
+			[]ParserOption{WithMaxInputLength(7)},
+			&LimitError{Limit: LimitInputLength, Max: 7, Actual: 8},
+		},
+		{
+			"value within limit",
+			`a:"abcd" b:(x OR abcd)`,
+			[]ParserOption{WithMaxValueLength(4)},
+			nil,
+		},
+		{
+			"value too long",
+			"a:1 b:abcde",
+			[]ParserOption{WithMaxValueLength(4)},
+			&LimitError{Limit: LimitValueLength, Max: 4, Actual: 5, Pos: 6},
+		},
+		{
+			"value in list too long",
+			"b:(x OR abcde)",
+			[]ParserOption{WithMaxValueLength(4)},
+			&LimitError{Limit: LimitValueLength, Max: 4, Actual: 5, Pos: 8},
+		},
+		{
+			"range value too long",
+			"a >= 123456",
+			[]ParserOption{WithMaxValueLength(4)},
+			&LimitError{Limit: LimitValueLength, Max: 4, Actual: 6, Pos: 5},
+		},
+		{
+			"quoted literal too long",
+			`"abcde"`,
+			[]ParserOption{WithMaxValueLength(4)},
+			&LimitError{Limit: LimitValueLength, Max: 4, Actual: 5, Pos: 0},
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ParseAST(test.input, test.options...)
+			if test.expectedError == nil {
+				require.NoError(t, err)
+				return
+			}
+			var limitErr *LimitError
+			require.ErrorAs(t, err, &limitErr)
+			assert.Equal(t, test.expectedError, limitErr)
+		})
+	}
+}
+
+func TestParseIncrementalLimits(t *testing.T) {
+	r := ParseIncremental("a:1 b:2", WithMaxInputLength(10), WithMaxValueLength(3))
+	require.NoError(t, r.Err)
+
+	r = r.Apply(Edit{Offset: 7, Inserted: " c:3"})
+	var limitErr *LimitError
+	require.ErrorAs(t, r.Err, &limitErr)
+	assert.Equal(t, LimitInputLength, limitErr.Limit)
+
+	r = ParseIncremental("a:1 b:2", WithMaxValueLength(3))
+	r = r.Apply(Edit{Offset: 7, Inserted: strings.Repeat("x", 3)})
+	require.ErrorAs(t, r.Err, &limitErr)
+	assert.Equal(t, &LimitError{Limit: LimitValueLength, Max: 3, Actual: 4, Pos: 6}, limitErr)
+}
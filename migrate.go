@@ -0,0 +1,204 @@
+package kqlfilter
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Migration describes how filters are rewritten when a schema changes, e.g. when a field is renamed.
+// Fields in nested queries are named by their path, e.g. `user.email`.
+type Migration struct {
+	// New names of renamed fields, keyed by their old name. Renaming a parent field, e.g. `user`, also renames its
+	// nested fields. Nested fields must keep their parent: in `user:{email:jon@example.com}`, `user.email` can be
+	// renamed to `user.mail`, but not to `mail`.
+	Fields map[string]string
+	// New values of fields, keyed by the old name of the field and the old value. Values with wildcards are kept.
+	Values map[string]map[string]string
+}
+
+// Apply returns a copy of the AST with the fields and values renamed. The input AST is not modified.
+func (m Migration) Apply(ast Node) (Node, error) {
+	if ast == nil {
+		return nil, nil
+	}
+	return m.apply(ast, "", "")
+}
+
+// apply copies the node, renaming its fields and values. oldPrefix and newPrefix are the paths of the nested query the
+// node is part of, before and after renaming.
+func (m Migration) apply(ast Node, oldPrefix, newPrefix string) (Node, error) {
+	var err error
+	switch n := ast.(type) {
+	case *AndNode:
+		c := *n
+		c.Nodes = make([]Node, len(n.Nodes))
+		for i, child := range n.Nodes {
+			if c.Nodes[i], err = m.apply(child, oldPrefix, newPrefix); err != nil {
+				return nil, err
+			}
+		}
+		return &c, nil
+	case *OrNode:
+		c := *n
+		c.Nodes = make([]Node, len(n.Nodes))
+		for i, child := range n.Nodes {
+			if c.Nodes[i], err = m.apply(child, oldPrefix, newPrefix); err != nil {
+				return nil, err
+			}
+		}
+		return &c, nil
+	case *NotNode:
+		c := *n
+		if c.Expr, err = m.apply(n.Expr, oldPrefix, newPrefix); err != nil {
+			return nil, err
+		}
+		return &c, nil
+	case *IsNode:
+		c := *n
+		field := oldPrefix + n.Identifier
+		if c.Identifier, err = m.renameField(field, newPrefix); err != nil {
+			return nil, err
+		}
+		if nested, ok := n.Value.(*NestedNode); ok {
+			cn := *nested
+			if cn.Expr, err = m.apply(nested.Expr, field+".", newPrefix+c.Identifier+"."); err != nil {
+				return nil, err
+			}
+			c.Value = &cn
+		} else {
+			c.Value = m.renameValues(n.Value, m.Values[field])
+		}
+		return &c, nil
+	case *RangeNode:
+		c := *n
+		field := oldPrefix + n.Identifier
+		if c.Identifier, err = m.renameField(field, newPrefix); err != nil {
+			return nil, err
+		}
+		c.Value = m.renameValues(n.Value, m.Values[field])
+		return &c, nil
+	case *FuzzyNode:
+		c := *n
+		field := oldPrefix + n.Identifier
+		if c.Identifier, err = m.renameField(field, newPrefix); err != nil {
+			return nil, err
+		}
+		if value, ok := m.Values[field][n.Value]; ok {
+			c.Value = value
+		}
+		return &c, nil
+	case *ExistsNode:
+		c := *n
+		if c.Identifier, err = m.renameField(oldPrefix+n.Identifier, newPrefix); err != nil {
+			return nil, err
+		}
+		return &c, nil
+	case *LiteralNode:
+		c := *n
+		return &c, nil
+	default:
+		return ast, nil
+	}
+}
+
+// renameField returns the new name of a field relative to newPrefix, the new path of its nested query.
+// A field without a new name keeps its name, or is moved along with its renamed parent field.
+func (m Migration) renameField(field, newPrefix string) (string, error) {
+	renamed, ok := m.Fields[field]
+	if !ok {
+		// The closest renamed parent field renames its nested fields.
+		renamed = field
+		for parent := field; strings.Contains(parent, "."); {
+			parent = parent[:strings.LastIndex(parent, ".")]
+			if newParent, ok := m.Fields[parent]; ok {
+				renamed = newParent + field[len(parent):]
+				break
+			}
+		}
+	}
+	if !strings.HasPrefix(renamed, newPrefix) || len(renamed) == len(newPrefix) {
+		return "", fmt.Errorf("cannot rename nested field %s to %s outside of %s", field, renamed,
+			strings.TrimSuffix(newPrefix, "."))
+	}
+	return renamed[len(newPrefix):], nil
+}
+
+// renameValues copies the value of a clause, replacing the literals found in values.
+// Literals with wildcards are kept, as they are patterns rather than values.
+func (m Migration) renameValues(ast Node, values map[string]string) Node {
+	switch n := ast.(type) {
+	case *OrNode:
+		c := *n
+		c.Nodes = make([]Node, len(n.Nodes))
+		for i, child := range n.Nodes {
+			c.Nodes[i] = m.renameValues(child, values)
+		}
+		return &c
+	case *AndNode:
+		c := *n
+		c.Nodes = make([]Node, len(n.Nodes))
+		for i, child := range n.Nodes {
+			c.Nodes[i] = m.renameValues(child, values)
+		}
+		return &c
+	case *NotNode:
+		c := *n
+		c.Expr = m.renameValues(n.Expr, values)
+		return &c
+	case *LiteralNode:
+		c := *n
+		if value, ok := values[n.Value]; ok && !strings.Contains(n.Value, "*") {
+			c.Value = formatBuilderValue(value)
+		}
+		return &c
+	default:
+		return ast
+	}
+}
+
+// FilterMigrator rewrites stored filters when their schema changes. Stored filters are versioned by the number of
+// migrations that were applied to them: a filter stored at version v is brought up to date by applying
+// Migrations[v:] in order, and the current version is len(Migrations). Migrations must only be appended, so that the
+// versions of stored filters keep their meaning.
+type FilterMigrator struct {
+	// Migrations in the order in which the schema changed.
+	Migrations []Migration
+	// Schema of the current version, which migrated filters are validated against. Optional.
+	Schema Schema
+	// Options to parse stored filters with.
+	ParserOptions []ParserOption
+}
+
+// Version returns the current version of filters, to store along with migrated and new filters.
+func (m FilterMigrator) Version() int {
+	return len(m.Migrations)
+}
+
+// Migrate parses a filter stored at the given version, applies the migrations it is missing and returns the filter
+// as KQL, see Format. If a Schema is set, the migrated filter is validated against it and all violations are
+// returned, joined with errors.Join, so that filters that can't be migrated automatically can be reported.
+// Filters that are already at the current version are validated and returned unchanged.
+func (m FilterMigrator) Migrate(input string, version int) (string, error) {
+	if version < 0 || version > m.Version() {
+		return "", fmt.Errorf("unknown filter version %d, current version is %d", version, m.Version())
+	}
+	ast, err := ParseAST(input, m.ParserOptions...)
+	if err != nil {
+		return "", err
+	}
+	for _, migration := range m.Migrations[version:] {
+		if ast, err = migration.Apply(ast); err != nil {
+			return "", err
+		}
+	}
+	if m.Schema != nil {
+		if errs := Validate(ast, m.Schema); len(errs) > 0 {
+			return "", errors.Join(errs...)
+		}
+	}
+	if version == m.Version() {
+		return input, nil
+	}
+	return Format(ast), nil
+}
@@ -0,0 +1,137 @@
+package kqlfilter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrationApply(t *testing.T) {
+	testCases := []struct {
+		name      string
+		input     string
+		migration Migration
+		expected  string
+		err       string
+	}{
+		{
+			name:      "renamed field",
+			input:     "type:movie and not type:(series or episode) created>2024-01-01 type:*",
+			migration: Migration{Fields: map[string]string{"type": "kind"}},
+			expected:  "kind:movie and not kind:(series or episode) and created>2024-01-01 and kind:*",
+		},
+		{
+			name:  "renamed values",
+			input: "state:(active or paused) state:archived* title:active",
+			migration: Migration{
+				Fields: map[string]string{"state": "status"},
+				Values: map[string]map[string]string{"state": {"active": "ACTIVE", "paused": "on hold"}},
+			},
+			expected: `status:(ACTIVE or "on hold") and status:archived* and title:active`,
+		},
+		{
+			name:      "renamed nested field",
+			input:     "user:{name:jon and age>18} user.name:jane",
+			migration: Migration{Fields: map[string]string{"user.name": "user.full_name"}},
+			expected:  "user:{full_name:jon and age>18} and user.full_name:jane",
+		},
+		{
+			name:      "renamed parent field",
+			input:     "user:{name:jon} user.name:jane",
+			migration: Migration{Fields: map[string]string{"user": "account"}},
+			expected:  "account:{name:jon} and account.name:jane",
+		},
+		{
+			name:      "nested field moved out of its parent",
+			input:     "user:{name:jon}",
+			migration: Migration{Fields: map[string]string{"user.name": "name"}},
+			err:       "cannot rename nested field user.name to name outside of user",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ast, err := ParseAST(tc.input)
+			require.NoError(t, err)
+			before := ast.String()
+
+			migrated, err := tc.migration.Apply(ast)
+			if tc.err != "" {
+				require.EqualError(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, Format(migrated))
+			assert.Equal(t, before, ast.String(), "input AST must not be modified")
+		})
+	}
+}
+
+func TestFilterMigrator(t *testing.T) {
+	m := FilterMigrator{
+		Migrations: []Migration{
+			{Fields: map[string]string{"type": "kind"}},
+			{
+				Fields: map[string]string{"kind": "content_type"},
+				Values: map[string]map[string]string{"kind": {"movie": "film"}},
+			},
+		},
+		Schema: Schema{
+			"content_type": {AllowedValues: []string{"film", "series"}, AllowMultipleValues: true},
+			"title":        {},
+		},
+	}
+	require.Equal(t, 2, m.Version())
+
+	testCases := []struct {
+		name     string
+		input    string
+		version  int
+		expected string
+		err      string
+	}{
+		{
+			name:     "first version",
+			input:    "type:(movie or series) title:jaws",
+			version:  0,
+			expected: "content_type:(film or series) and title:jaws",
+		},
+		{
+			name:     "intermediate version",
+			input:    "kind:movie",
+			version:  1,
+			expected: "content_type:film",
+		},
+		{
+			name:     "current version",
+			input:    "content_type:series   title:jaws",
+			version:  2,
+			expected: "content_type:series   title:jaws",
+		},
+		{
+			name:    "invalid after migration",
+			input:   "type:episode rating>3",
+			version: 0,
+			err:     "invalid value \"episode\" (allowed values: film, series)\nunknown field: rating",
+		},
+		{
+			name:    "unknown version",
+			input:   "title:jaws",
+			version: 3,
+			err:     "unknown filter version 3, current version is 2",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			migrated, err := m.Migrate(tc.input, tc.version)
+			if tc.err != "" {
+				require.EqualError(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, migrated)
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package kqlfilter
 
 import (
+	"strconv"
 	"strings"
 )
 
@@ -9,6 +10,8 @@ type Node interface {
 	Type() NodeType
 	String() string
 	Position() Pos // byte position of start of node in full original input string
+	// Clone returns a deep copy of the node, which can be modified without affecting the original.
+	Clone() Node
 	// writeTo writes the String output to the builder.
 	writeTo(*strings.Builder)
 }
@@ -38,6 +41,8 @@ const (
 	NodeRange
 	NodeNested
 	NodeLiteral
+	NodeExists
+	NodeFuzzy
 )
 
 // Nodes.
@@ -46,12 +51,16 @@ const (
 type OrNode struct {
 	NodeType
 	Pos
-	p     *parser
 	Nodes []Node // The clauses nodes in lexical order.
 }
 
 func (p *parser) newOrNode(pos Pos) *OrNode {
-	return &OrNode{p: p, NodeType: NodeOr, Pos: pos}
+	if p.arena != nil {
+		n := p.arena.ors.alloc()
+		*n = OrNode{NodeType: NodeOr, Pos: pos}
+		return n
+	}
+	return &OrNode{NodeType: NodeOr, Pos: pos}
 }
 
 func (q *OrNode) append(n Node) {
@@ -64,6 +73,12 @@ func (q *OrNode) String() string {
 	return sb.String()
 }
 
+func (q *OrNode) Clone() Node {
+	c := *q
+	c.Nodes = cloneNodes(q.Nodes)
+	return &c
+}
+
 func (q *OrNode) writeTo(sb *strings.Builder) {
 	sb.WriteString("(")
 	for i, n := range q.Nodes {
@@ -79,28 +94,34 @@ func (q *OrNode) writeTo(sb *strings.Builder) {
 type AndNode struct {
 	NodeType
 	Pos
-	p     *parser
 	Nodes []Node // The clauses nodes in lexical order.
 }
 
 func (p *parser) newAndNode(pos Pos) *AndNode {
-	return &AndNode{p: p, NodeType: NodeAnd, Pos: pos}
+	if p.arena != nil {
+		n := p.arena.ands.alloc()
+		*n = AndNode{NodeType: NodeAnd, Pos: pos}
+		return n
+	}
+	return &AndNode{NodeType: NodeAnd, Pos: pos}
 }
 
 func (q *AndNode) append(n Node) {
 	q.Nodes = append(q.Nodes, n)
 }
 
-func (q *AndNode) tree() *parser {
-	return q.p
-}
-
 func (q *AndNode) String() string {
 	var sb strings.Builder
 	q.writeTo(&sb)
 	return sb.String()
 }
 
+func (q *AndNode) Clone() Node {
+	c := *q
+	c.Nodes = cloneNodes(q.Nodes)
+	return &c
+}
+
 func (q *AndNode) writeTo(sb *strings.Builder) {
 	sb.WriteString("(")
 	for i, n := range q.Nodes {
@@ -116,12 +137,16 @@ func (q *AndNode) writeTo(sb *strings.Builder) {
 type NotNode struct {
 	NodeType
 	Pos
-	p    *parser
 	Expr Node // Negated node.
 }
 
 func (p *parser) newNotNode(pos Pos, expr Node) *NotNode {
-	return &NotNode{p: p, NodeType: NodeNot, Pos: pos, Expr: expr}
+	if p.arena != nil {
+		n := p.arena.nots.alloc()
+		*n = NotNode{NodeType: NodeNot, Pos: pos, Expr: expr}
+		return n
+	}
+	return &NotNode{NodeType: NodeNot, Pos: pos, Expr: expr}
 }
 
 func (q *NotNode) String() string {
@@ -130,6 +155,12 @@ func (q *NotNode) String() string {
 	return sb.String()
 }
 
+func (q *NotNode) Clone() Node {
+	c := *q
+	c.Expr = cloneNode(q.Expr)
+	return &c
+}
+
 func (q *NotNode) writeTo(sb *strings.Builder) {
 	sb.WriteString("NOT ")
 	q.Expr.writeTo(sb)
@@ -139,13 +170,17 @@ func (q *NotNode) writeTo(sb *strings.Builder) {
 type IsNode struct {
 	NodeType
 	Pos
-	p          *parser
 	Identifier string
 	Value      Node // The clauses nodes in lexical order.
 }
 
 func (p *parser) newIsNode(pos Pos, identifier string, value Node) *IsNode {
-	return &IsNode{p: p, NodeType: NodeIs, Pos: pos, Identifier: identifier, Value: value}
+	if p.arena != nil {
+		n := p.arena.is.alloc()
+		*n = IsNode{NodeType: NodeIs, Pos: pos, Identifier: identifier, Value: value}
+		return n
+	}
+	return &IsNode{NodeType: NodeIs, Pos: pos, Identifier: identifier, Value: value}
 }
 
 func (q *IsNode) String() string {
@@ -154,6 +189,12 @@ func (q *IsNode) String() string {
 	return sb.String()
 }
 
+func (q *IsNode) Clone() Node {
+	c := *q
+	c.Value = cloneNode(q.Value)
+	return &c
+}
+
 func (q *IsNode) writeTo(sb *strings.Builder) {
 	sb.WriteString(q.Identifier)
 	sb.WriteString("=")
@@ -164,7 +205,6 @@ func (q *IsNode) writeTo(sb *strings.Builder) {
 type RangeNode struct {
 	NodeType
 	Pos
-	p          *parser
 	Identifier string
 	Operator   RangeOperator
 	Value      Node // The clauses nodes in lexical order.
@@ -195,7 +235,12 @@ func (o RangeOperator) String() string {
 }
 
 func (p *parser) newRangeNode(pos Pos, id string, op RangeOperator, value Node) *RangeNode {
-	return &RangeNode{p: p, NodeType: NodeRange, Pos: pos, Identifier: id, Operator: op, Value: value}
+	if p.arena != nil {
+		n := p.arena.ranges.alloc()
+		*n = RangeNode{NodeType: NodeRange, Pos: pos, Identifier: id, Operator: op, Value: value}
+		return n
+	}
+	return &RangeNode{NodeType: NodeRange, Pos: pos, Identifier: id, Operator: op, Value: value}
 }
 
 func (q *RangeNode) String() string {
@@ -204,6 +249,12 @@ func (q *RangeNode) String() string {
 	return sb.String()
 }
 
+func (q *RangeNode) Clone() Node {
+	c := *q
+	c.Value = cloneNode(q.Value)
+	return &c
+}
+
 func (q *RangeNode) writeTo(sb *strings.Builder) {
 	sb.WriteString(q.Identifier)
 	sb.WriteString(q.Operator.String())
@@ -214,12 +265,16 @@ func (q *RangeNode) writeTo(sb *strings.Builder) {
 type NestedNode struct {
 	NodeType
 	Pos
-	p    *parser
 	Expr Node // The clauses nodes in lexical order.
 }
 
 func (p *parser) newNestedNode(pos Pos, value Node) *NestedNode {
-	return &NestedNode{p: p, NodeType: NodeNested, Pos: pos, Expr: value}
+	if p.arena != nil {
+		n := p.arena.nesteds.alloc()
+		*n = NestedNode{NodeType: NodeNested, Pos: pos, Expr: value}
+		return n
+	}
+	return &NestedNode{NodeType: NodeNested, Pos: pos, Expr: value}
 }
 
 func (q *NestedNode) String() string {
@@ -228,22 +283,103 @@ func (q *NestedNode) String() string {
 	return sb.String()
 }
 
+func (q *NestedNode) Clone() Node {
+	c := *q
+	c.Expr = cloneNode(q.Expr)
+	return &c
+}
+
 func (q *NestedNode) writeTo(sb *strings.Builder) {
 	sb.WriteString("{")
 	q.Expr.writeTo(sb)
 	sb.WriteString("}")
 }
 
+// ExistsNode holds an existence check, written as `field:*`.
+type ExistsNode struct {
+	NodeType
+	Pos
+	Identifier string
+}
+
+func (p *parser) newExistsNode(pos Pos, identifier string) *ExistsNode {
+	if p.arena != nil {
+		n := p.arena.exists.alloc()
+		*n = ExistsNode{NodeType: NodeExists, Pos: pos, Identifier: identifier}
+		return n
+	}
+	return &ExistsNode{NodeType: NodeExists, Pos: pos, Identifier: identifier}
+}
+
+func (q *ExistsNode) String() string {
+	var sb strings.Builder
+	q.writeTo(&sb)
+	return sb.String()
+}
+
+func (q *ExistsNode) Clone() Node {
+	c := *q
+	return &c
+}
+
+func (q *ExistsNode) writeTo(sb *strings.Builder) {
+	sb.WriteString(q.Identifier)
+	sb.WriteString("=*")
+}
+
+// FuzzyNode holds a fuzzy match, written as `field:value~fuzziness`.
+// It is only produced when the parser is configured with WithFuzzyMatching.
+type FuzzyNode struct {
+	NodeType
+	Pos
+	Identifier string
+	Value      string
+	Fuzziness  int // Maximum edit distance, 0 to 2.
+}
+
+func (p *parser) newFuzzyNode(pos Pos, identifier string, value string, fuzziness int) *FuzzyNode {
+	if p.arena != nil {
+		n := p.arena.fuzzies.alloc()
+		*n = FuzzyNode{NodeType: NodeFuzzy, Pos: pos, Identifier: identifier, Value: value, Fuzziness: fuzziness}
+		return n
+	}
+	return &FuzzyNode{NodeType: NodeFuzzy, Pos: pos, Identifier: identifier, Value: value, Fuzziness: fuzziness}
+}
+
+func (q *FuzzyNode) String() string {
+	var sb strings.Builder
+	q.writeTo(&sb)
+	return sb.String()
+}
+
+func (q *FuzzyNode) Clone() Node {
+	c := *q
+	return &c
+}
+
+func (q *FuzzyNode) writeTo(sb *strings.Builder) {
+	sb.WriteString(q.Identifier)
+	sb.WriteString("=")
+	sb.WriteString(q.Value)
+	sb.WriteString("~")
+	sb.WriteString(strconv.Itoa(q.Fuzziness))
+}
+
 // LiteralNode holds literal value.
+// Values containing an asterisk keep escaped asterisks and backslashes, see Clause.Values.
 type LiteralNode struct {
 	NodeType
 	Pos
-	p     *parser
 	Value string
 }
 
 func (p *parser) newLiteralNode(pos Pos, value string) *LiteralNode {
-	return &LiteralNode{p: p, NodeType: NodeLiteral, Pos: pos, Value: value}
+	if p.arena != nil {
+		n := p.arena.literals.alloc()
+		*n = LiteralNode{NodeType: NodeLiteral, Pos: pos, Value: value}
+		return n
+	}
+	return &LiteralNode{NodeType: NodeLiteral, Pos: pos, Value: value}
 }
 
 func (q *LiteralNode) String() string {
@@ -252,6 +388,30 @@ func (q *LiteralNode) String() string {
 	return sb.String()
 }
 
+func (q *LiteralNode) Clone() Node {
+	c := *q
+	return &c
+}
+
 func (q *LiteralNode) writeTo(sb *strings.Builder) {
 	sb.WriteString(q.Value)
 }
+
+// cloneNode returns a deep copy of the node, or nil for a nil node.
+func cloneNode(n Node) Node {
+	if n == nil {
+		return nil
+	}
+	return n.Clone()
+}
+
+func cloneNodes(nodes []Node) []Node {
+	if nodes == nil {
+		return nil
+	}
+	c := make([]Node, len(nodes))
+	for i, n := range nodes {
+		c[i] = cloneNode(n)
+	}
+	return c
+}
@@ -0,0 +1,317 @@
+package kqlfilter
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Names of the node types as used in the JSON representation of an AST.
+var nodeTypeName = map[NodeType]string{
+	NodeOr:      "or",
+	NodeAnd:     "and",
+	NodeNot:     "not",
+	NodeIs:      "is",
+	NodeRange:   "range",
+	NodeNested:  "nested",
+	NodeLiteral: "literal",
+	NodeExists:  "exists",
+	NodeFuzzy:   "fuzzy",
+}
+
+// String returns the name of the node type, as used in the JSON representation of an AST.
+func (t NodeType) String() string {
+	s, ok := nodeTypeName[t]
+	if !ok {
+		return fmt.Sprintf("node%d", int(t))
+	}
+	return s
+}
+
+// parseNodeType returns the node type with the given name.
+func parseNodeType(name string) (NodeType, error) {
+	for typ, n := range nodeTypeName {
+		if n == name {
+			return typ, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown node type %q", name)
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (o RangeOperator) MarshalText() ([]byte, error) {
+	s := o.String()
+	if s == "???" {
+		return nil, fmt.Errorf("unknown range operator %d", int(o))
+	}
+	return []byte(s), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (o *RangeOperator) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case ">":
+		*o = RangeOperatorGt
+	case ">=":
+		*o = RangeOperatorGte
+	case "<":
+		*o = RangeOperatorLt
+	case "<=":
+		*o = RangeOperatorLte
+	default:
+		return fmt.Errorf("unknown range operator %q", text)
+	}
+	return nil
+}
+
+// jsonNode is the JSON representation of any node in the parse tree.
+// The Type field acts as the discriminator; only the fields relevant to that type are set.
+type jsonNode struct {
+	Type       string            `json:"type"`
+	Pos        Pos               `json:"pos"`
+	Identifier string            `json:"identifier,omitempty"`
+	Operator   *RangeOperator    `json:"operator,omitempty"`
+	Value      json.RawMessage   `json:"value,omitempty"`
+	Expr       json.RawMessage   `json:"expr,omitempty"`
+	Nodes      []json.RawMessage `json:"nodes,omitempty"`
+	Fuzziness  int               `json:"fuzziness,omitempty"`
+}
+
+// UnmarshalNode decodes a JSON document produced by json.Marshal of a Node back into a Node.
+func UnmarshalNode(data []byte) (Node, error) {
+	var jn jsonNode
+	if err := json.Unmarshal(data, &jn); err != nil {
+		return nil, err
+	}
+	return jn.node()
+}
+
+func (jn jsonNode) node() (Node, error) {
+	typ, err := parseNodeType(jn.Type)
+	if err != nil {
+		return nil, err
+	}
+	switch typ {
+	case NodeOr:
+		nodes, err := unmarshalNodes(jn.Nodes)
+		if err != nil {
+			return nil, err
+		}
+		return &OrNode{NodeType: NodeOr, Pos: jn.Pos, Nodes: nodes}, nil
+	case NodeAnd:
+		nodes, err := unmarshalNodes(jn.Nodes)
+		if err != nil {
+			return nil, err
+		}
+		return &AndNode{NodeType: NodeAnd, Pos: jn.Pos, Nodes: nodes}, nil
+	case NodeNot:
+		expr, err := unmarshalChild(jn.Expr, "expr")
+		if err != nil {
+			return nil, err
+		}
+		return &NotNode{NodeType: NodeNot, Pos: jn.Pos, Expr: expr}, nil
+	case NodeIs:
+		value, err := unmarshalChild(jn.Value, "value")
+		if err != nil {
+			return nil, err
+		}
+		return &IsNode{NodeType: NodeIs, Pos: jn.Pos, Identifier: jn.Identifier, Value: value}, nil
+	case NodeRange:
+		if jn.Operator == nil {
+			return nil, fmt.Errorf("range node: missing operator")
+		}
+		value, err := unmarshalChild(jn.Value, "value")
+		if err != nil {
+			return nil, err
+		}
+		return &RangeNode{NodeType: NodeRange, Pos: jn.Pos, Identifier: jn.Identifier, Operator: *jn.Operator, Value: value}, nil
+	case NodeNested:
+		expr, err := unmarshalChild(jn.Expr, "expr")
+		if err != nil {
+			return nil, err
+		}
+		return &NestedNode{NodeType: NodeNested, Pos: jn.Pos, Expr: expr}, nil
+	case NodeExists:
+		return &ExistsNode{NodeType: NodeExists, Pos: jn.Pos, Identifier: jn.Identifier}, nil
+	case NodeFuzzy:
+		var value string
+		if err := json.Unmarshal(jn.Value, &value); err != nil {
+			return nil, fmt.Errorf("fuzzy node: %w", err)
+		}
+		return &FuzzyNode{NodeType: NodeFuzzy, Pos: jn.Pos, Identifier: jn.Identifier, Value: value, Fuzziness: jn.Fuzziness}, nil
+	case NodeLiteral:
+		var value string
+		if err := json.Unmarshal(jn.Value, &value); err != nil {
+			return nil, fmt.Errorf("literal node: %w", err)
+		}
+		return &LiteralNode{NodeType: NodeLiteral, Pos: jn.Pos, Value: value}, nil
+	default:
+		return nil, fmt.Errorf("unsupported node type %s", typ)
+	}
+}
+
+func unmarshalChild(data json.RawMessage, name string) (Node, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("missing %s", name)
+	}
+	return UnmarshalNode(data)
+}
+
+func unmarshalNodes(data []json.RawMessage) ([]Node, error) {
+	nodes := make([]Node, 0, len(data))
+	for _, d := range data {
+		n, err := UnmarshalNode(d)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes, nil
+}
+
+func marshalNodes(nodes []Node) ([]json.RawMessage, error) {
+	out := make([]json.RawMessage, 0, len(nodes))
+	for _, n := range nodes {
+		b, err := json.Marshal(n)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, b)
+	}
+	return out, nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (q *OrNode) MarshalJSON() ([]byte, error) {
+	nodes, err := marshalNodes(q.Nodes)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(jsonNode{Type: NodeOr.String(), Pos: q.Pos, Nodes: nodes})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (q *OrNode) UnmarshalJSON(data []byte) error {
+	return unmarshalInto(data, NodeOr, q)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (q *AndNode) MarshalJSON() ([]byte, error) {
+	nodes, err := marshalNodes(q.Nodes)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(jsonNode{Type: NodeAnd.String(), Pos: q.Pos, Nodes: nodes})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (q *AndNode) UnmarshalJSON(data []byte) error {
+	return unmarshalInto(data, NodeAnd, q)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (q *NotNode) MarshalJSON() ([]byte, error) {
+	expr, err := json.Marshal(q.Expr)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(jsonNode{Type: NodeNot.String(), Pos: q.Pos, Expr: expr})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (q *NotNode) UnmarshalJSON(data []byte) error {
+	return unmarshalInto(data, NodeNot, q)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (q *IsNode) MarshalJSON() ([]byte, error) {
+	value, err := json.Marshal(q.Value)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(jsonNode{Type: NodeIs.String(), Pos: q.Pos, Identifier: q.Identifier, Value: value})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (q *IsNode) UnmarshalJSON(data []byte) error {
+	return unmarshalInto(data, NodeIs, q)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (q *RangeNode) MarshalJSON() ([]byte, error) {
+	value, err := json.Marshal(q.Value)
+	if err != nil {
+		return nil, err
+	}
+	op := q.Operator
+	return json.Marshal(jsonNode{Type: NodeRange.String(), Pos: q.Pos, Identifier: q.Identifier, Operator: &op, Value: value})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (q *RangeNode) UnmarshalJSON(data []byte) error {
+	return unmarshalInto(data, NodeRange, q)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (q *NestedNode) MarshalJSON() ([]byte, error) {
+	expr, err := json.Marshal(q.Expr)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(jsonNode{Type: NodeNested.String(), Pos: q.Pos, Expr: expr})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (q *NestedNode) UnmarshalJSON(data []byte) error {
+	return unmarshalInto(data, NodeNested, q)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (q *ExistsNode) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonNode{Type: NodeExists.String(), Pos: q.Pos, Identifier: q.Identifier})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (q *ExistsNode) UnmarshalJSON(data []byte) error {
+	return unmarshalInto(data, NodeExists, q)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (q *FuzzyNode) MarshalJSON() ([]byte, error) {
+	value, err := json.Marshal(q.Value)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(jsonNode{Type: NodeFuzzy.String(), Pos: q.Pos, Identifier: q.Identifier, Value: value, Fuzziness: q.Fuzziness})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (q *FuzzyNode) UnmarshalJSON(data []byte) error {
+	return unmarshalInto(data, NodeFuzzy, q)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (q *LiteralNode) MarshalJSON() ([]byte, error) {
+	value, err := json.Marshal(q.Value)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(jsonNode{Type: NodeLiteral.String(), Pos: q.Pos, Value: value})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (q *LiteralNode) UnmarshalJSON(data []byte) error {
+	return unmarshalInto(data, NodeLiteral, q)
+}
+
+// unmarshalInto decodes data into dst, which must be a pointer to a node of the expected type.
+func unmarshalInto[T any](data []byte, expected NodeType, dst *T) error {
+	n, err := UnmarshalNode(data)
+	if err != nil {
+		return err
+	}
+	if n.Type() != expected {
+		return fmt.Errorf("cannot unmarshal %s node into %s node", n.Type(), expected)
+	}
+	*dst = *any(n).(*T)
+	return nil
+}
@@ -0,0 +1,97 @@
+package kqlfilter
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNodeJSONRoundTrip(t *testing.T) {
+	testCases := []string{
+		"field:value",
+		"false",
+		`field:value1 AND field2:("value2" OR "value3")`,
+		"first:x OR second:y and NOT third:z",
+		"field:{nested:x or y:z}",
+		"field:* and not nested:{other:*}",
+		`start_time >= "2022-02-02T10:30:00.000Z" start_time < "2022-02-03T10:30:00.000Z"`,
+	}
+
+	for _, input := range testCases {
+		t.Run(input, func(t *testing.T) {
+			ast, err := ParseAST(input)
+			require.NoError(t, err)
+
+			data, err := json.Marshal(ast)
+			require.NoError(t, err)
+
+			decoded, err := UnmarshalNode(data)
+			require.NoError(t, err)
+			assert.Equal(t, ast.String(), decoded.String())
+			assert.Equal(t, ast.Position(), decoded.Position())
+
+			// Re-encoding the decoded tree must yield the same document.
+			data2, err := json.Marshal(decoded)
+			require.NoError(t, err)
+			assert.JSONEq(t, string(data), string(data2))
+		})
+	}
+}
+
+func TestNodeJSONFormat(t *testing.T) {
+	ast, err := ParseAST("a:1 and not b>=2")
+	require.NoError(t, err)
+
+	data, err := json.Marshal(ast)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"type": "and",
+		"pos": 0,
+		"nodes": [
+			{"type": "is", "pos": 0, "identifier": "a", "value": {"type": "literal", "pos": 2, "value": "1"}},
+			{"type": "not", "pos": 8, "expr": {
+				"type": "range", "pos": 12, "identifier": "b", "operator": ">=", "value": {"type": "literal", "pos": 15, "value": "2"}
+			}}
+		]
+	}`, string(data))
+}
+
+func TestNodeUnmarshalJSONConcreteType(t *testing.T) {
+	var n IsNode
+	err := json.Unmarshal([]byte(`{"type":"is","pos":0,"identifier":"a","value":{"type":"literal","pos":2,"value":"x"}}`), &n)
+	require.NoError(t, err)
+	assert.Equal(t, "a=x", n.String())
+
+	var and AndNode
+	err = json.Unmarshal([]byte(`{"type":"is","pos":0,"identifier":"a","value":{"type":"literal","pos":2,"value":"x"}}`), &and)
+	require.Error(t, err)
+}
+
+func TestUnmarshalNodeErrors(t *testing.T) {
+	testCases := []string{
+		`{"type":"unknown"}`,
+		`{"type":"is","identifier":"a"}`,
+		`{"type":"range","identifier":"a","value":{"type":"literal","value":"1"}}`,
+		`{"type":"range","identifier":"a","operator":"!","value":{"type":"literal","value":"1"}}`,
+		`{"type":"literal","value":1}`,
+		`not json`,
+	}
+	for _, input := range testCases {
+		_, err := UnmarshalNode([]byte(input))
+		assert.Error(t, err, input)
+	}
+}
+
+func TestNodeJSONFuzzy(t *testing.T) {
+	ast, err := ParseAST("name:jon~1 or not name:bob~", WithFuzzyMatching())
+	require.NoError(t, err)
+
+	data, err := json.Marshal(ast)
+	require.NoError(t, err)
+
+	decoded, err := UnmarshalNode(data)
+	require.NoError(t, err)
+	assert.Equal(t, "(name=jon~1 OR NOT name=bob~2)", decoded.String())
+}
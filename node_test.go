@@ -0,0 +1,36 @@
+package kqlfilter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNodeClone(t *testing.T) {
+	inputs := []string{
+		`a:1`,
+		`a:(1 or 2) and not b:"x y" c >= 3`,
+		`user:{name:john* and age < 30} email:*`,
+		`name:jon~1`,
+		`true`,
+	}
+	for _, input := range inputs {
+		t.Run(input, func(t *testing.T) {
+			ast, err := ParseAST(input, WithFuzzyMatching())
+			require.NoError(t, err)
+			original := ast.String()
+
+			clone := ast.Clone()
+			assert.Equal(t, ast, clone)
+
+			mapper := NodeMapper{
+				TransformIdentifierFunc: strings.ToUpper,
+				TransformValueFunc:      strings.ToUpper,
+			}
+			require.NoError(t, mapper.Map(clone))
+			assert.Equal(t, original, ast.String(), "modifying the clone must not modify the original")
+		})
+	}
+}
@@ -0,0 +1,207 @@
+package kqlfilter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ToOData converts the AST to an OData `$filter` expression, e.g. for use with partner APIs that only accept OData.
+//
+// The schema is used to resolve aliases and column names, and to format the values according to the field type.
+// Fields that are not in the schema, or all fields if the schema is nil, are treated as strings with their identifier
+// as name. Fields in nested queries (`parent:{child:value}`) are joined with a slash (`parent/child`).
+//
+// Multiple values are converted to the `in` operator of OData 4.01, e.g. `state:(a OR b)` becomes
+// `state in ('a', 'b')`. Wildcards in string values are converted to startswith, endswith or contains, and existence
+// checks (`field:*`) to a comparison with null. An empty AST returns an empty string.
+func ToOData(ast Node, schema Schema) (string, error) {
+	if ast == nil {
+		return "", nil
+	}
+	var sb strings.Builder
+	if err := writeOData(&sb, ast, "", schema); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+func writeOData(sb *strings.Builder, ast Node, prefix string, schema Schema) error {
+	switch n := ast.(type) {
+	case *AndNode:
+		return writeODataNodes(sb, n.Nodes, " and ", prefix, schema)
+	case *OrNode:
+		return writeODataNodes(sb, n.Nodes, " or ", prefix, schema)
+	case *NotNode:
+		switch n.Expr.(type) {
+		case *AndNode, *OrNode:
+			// already parenthesized
+			sb.WriteString("not ")
+			return writeOData(sb, n.Expr, prefix, schema)
+		default:
+			sb.WriteString("not (")
+			if err := writeOData(sb, n.Expr, prefix, schema); err != nil {
+				return err
+			}
+			sb.WriteString(")")
+		}
+	case *IsNode:
+		if nested, ok := n.Value.(*NestedNode); ok {
+			return writeOData(sb, nested.Expr, prefix+n.Identifier+".", schema)
+		}
+		field := newODataField(prefix+n.Identifier, schema)
+		return field.writeValue(sb, n.Value)
+	case *RangeNode:
+		field := newODataField(prefix+n.Identifier, schema)
+		literal, ok := n.Value.(*LiteralNode)
+		if !ok {
+			return fmt.Errorf("unsupported node type %s", n.Value.Type())
+		}
+		var operator string
+		switch n.Operator {
+		case RangeOperatorGt:
+			operator = "gt"
+		case RangeOperatorGte:
+			operator = "ge"
+		case RangeOperatorLt:
+			operator = "lt"
+		case RangeOperatorLte:
+			operator = "le"
+		default:
+			return fmt.Errorf("unsupported operator %s", n.Operator)
+		}
+		value, err := field.format(literal.Value)
+		if err != nil {
+			return err
+		}
+		sb.WriteString(field.path + " " + operator + " " + value)
+	case *ExistsNode:
+		field := newODataField(prefix+n.Identifier, schema)
+		sb.WriteString(field.path + " ne null")
+	case *FuzzyNode:
+		return fmt.Errorf("field %s: fuzzy matching is not supported by OData", prefix+n.Identifier)
+	case *LiteralNode:
+		if n.Value != "true" && n.Value != "false" {
+			return fmt.Errorf("only boolean literals are supported; %s", n.Value)
+		}
+		sb.WriteString(n.Value)
+	default:
+		return fmt.Errorf("unsupported node type %s", ast.Type())
+	}
+	return nil
+}
+
+func writeODataNodes(sb *strings.Builder, nodes []Node, separator string, prefix string, schema Schema) error {
+	sb.WriteString("(")
+	for i, child := range nodes {
+		if i > 0 {
+			sb.WriteString(separator)
+		}
+		if err := writeOData(sb, child, prefix, schema); err != nil {
+			return err
+		}
+	}
+	sb.WriteString(")")
+	return nil
+}
+
+// odataField is a field as referenced in an OData expression.
+type odataField struct {
+	path      string
+	fieldType FieldType
+}
+
+func newODataField(identifier string, schema Schema) odataField {
+	name, fs, ok := schema.Lookup(identifier)
+	if !ok {
+		return odataField{path: strings.ReplaceAll(identifier, ".", "/"), fieldType: FieldTypeString}
+	}
+	return odataField{path: strings.ReplaceAll(schema.ColumnName(name), ".", "/"), fieldType: fs.Type}
+}
+
+// writeValue writes the comparison of the field with the value of an IsNode.
+func (f odataField) writeValue(sb *strings.Builder, value Node) error {
+	switch v := value.(type) {
+	case *LiteralNode:
+		if f.fieldType == FieldTypeString {
+			if function, s, ok := odataWildcardFunction(v.Value); ok {
+				sb.WriteString(function + "(" + f.path + ", " + quoteODataString(s) + ")")
+				return nil
+			}
+		}
+		formatted, err := f.format(v.Value)
+		if err != nil {
+			return err
+		}
+		sb.WriteString(f.path + " eq " + formatted)
+	case *OrNode:
+		values := make([]string, 0, len(v.Nodes))
+		for _, child := range v.Nodes {
+			literal, ok := child.(*LiteralNode)
+			if !ok {
+				return fmt.Errorf("unsupported node type %s", child.Type())
+			}
+			formatted, err := f.format(literal.Value)
+			if err != nil {
+				return err
+			}
+			values = append(values, formatted)
+		}
+		sb.WriteString(f.path + " in (" + strings.Join(values, ", ") + ")")
+	default:
+		return fmt.Errorf("unsupported node type %s", value.Type())
+	}
+	return nil
+}
+
+// format returns the value as an OData literal of the type of the field.
+func (f odataField) format(value string) (string, error) {
+	switch f.fieldType {
+	case FieldTypeInt64:
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return "", fmt.Errorf("field %s: invalid int64 value: %w", f.path, err)
+		}
+		return value, nil
+	case FieldTypeFloat64:
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return "", fmt.Errorf("field %s: invalid float64 value: %w", f.path, err)
+		}
+		return value, nil
+	case FieldTypeBool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", fmt.Errorf("field %s: invalid bool value: %w", f.path, err)
+		}
+		return strconv.FormatBool(b), nil
+	case FieldTypeTimestamp:
+		t, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return "", fmt.Errorf("field %s: invalid timestamp value: %w", f.path, err)
+		}
+		return t.Format(time.RFC3339Nano), nil
+	default:
+		return quoteODataString(UnescapeValue(value)), nil
+	}
+}
+
+// odataWildcardFunction returns the OData string function and its argument for a value with a leading and/or trailing
+// wildcard.
+func odataWildcardFunction(value string) (string, string, bool) {
+	text, prefix, suffix := SplitWildcards(value, true, true)
+	switch {
+	case prefix && suffix:
+		return "contains", text, true
+	case prefix:
+		return "startswith", text, true
+	case suffix:
+		return "endswith", text, true
+	default:
+		return "", "", false
+	}
+}
+
+// quoteODataString returns the value as an OData string literal, escaping single quotes by doubling them.
+func quoteODataString(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
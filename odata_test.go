@@ -0,0 +1,77 @@
+package kqlfilter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToOData(t *testing.T) {
+	schema := Schema{
+		"user_id":    {Type: FieldTypeInt64, Aliases: []string{"userId"}, Column: "UserId"},
+		"price":      {Type: FieldTypeFloat64},
+		"active":     {Type: FieldTypeBool},
+		"created_at": {Type: FieldTypeTimestamp},
+		"name":       {},
+		"state":      {},
+		"address.city": {
+			Column: "Address/City",
+		},
+	}
+
+	testCases := []struct {
+		name          string
+		input         string
+		expected      string
+		expectedError bool
+	}{
+		{"string equality", "name:john", "name eq 'john'", false},
+		{"quote is escaped", `name:"o'neil"`, "name eq 'o''neil'", false},
+		{"alias and column name", "userId:12", "UserId eq 12", false},
+		{"bool", "active:TRUE", "active eq true", false},
+		{"timestamp range", `created_at>="2023-01-01T00:00:00Z"`, "created_at ge 2023-01-01T00:00:00Z", false},
+		{"ranges", "price>1 and price<=10.5", "(price gt 1 and price le 10.5)", false},
+		{"and, or and not", "name:a and (state:b or not state:c)", "(name eq 'a' and (state eq 'b' or not (state eq 'c')))", false},
+		{"not and", "not (name:a and state:b)", "not (name eq 'a' and state eq 'b')", false},
+		{"in", "state:(active or paused)", "state in ('active', 'paused')", false},
+		{"numbers in", "userId:(1 or 2)", "UserId in (1, 2)", false},
+		{"prefix", "name:jo*", "startswith(name, 'jo')", false},
+		{"escaped asterisk", `name:jo\*`, "name eq 'jo*'", false},
+		{"escaped asterisk before wildcard", `name:jo\**`, "startswith(name, 'jo*')", false},
+		{"suffix", "name:*hn", "endswith(name, 'hn')", false},
+		{"contains", "name:*oh*", "contains(name, 'oh')", false},
+		{"exists", "name:*", "name ne null", false},
+		{"nested", "address:{city:Amsterdam}", "Address/City eq 'Amsterdam'", false},
+		{"unknown nested field", "address:{street:Main}", "address/street eq 'Main'", false},
+		{"boolean literal", "true", "true", false},
+		{"invalid int", "userId:abc", "", true},
+		{"invalid timestamp", "created_at>yesterday", "", true},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			ast, err := ParseAST(test.input)
+			require.NoError(t, err)
+			odata, err := ToOData(ast, schema)
+			if test.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, odata)
+		})
+	}
+}
+
+func TestToODataWithoutSchema(t *testing.T) {
+	ast, err := ParseAST("userId:12 state:(a or b)")
+	require.NoError(t, err)
+	odata, err := ToOData(ast, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "(userId eq '12' and state in ('a', 'b'))", odata)
+
+	odata, err = ToOData(nil, nil)
+	require.NoError(t, err)
+	assert.Empty(t, odata)
+}
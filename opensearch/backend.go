@@ -0,0 +1,26 @@
+package opensearch
+
+import (
+	"github.com/MottoStreaming/kqlfilter.go"
+	"github.com/MottoStreaming/kqlfilter.go/elastic"
+)
+
+func init() {
+	kqlfilter.RegisterConverter("opensearch", func() kqlfilter.Backend[any] {
+		return kqlfilter.AnyBackend(Backend())
+	})
+}
+
+// Backend returns a kqlfilter.Backend producing OpenSearch queries for use with kqlfilter.QueryFromKQL. The queries
+// are those of elastic.Backend, which resolves field names, types and text fields against the schema, with the same
+// options.
+func Backend(options ...Option) kqlfilter.Backend[Query] {
+	backend := elastic.Backend(options...)
+	return kqlfilter.BackendFunc[Query](func(ast kqlfilter.Node, schema kqlfilter.Schema) (Query, error) {
+		query, err := backend.Convert(ast, schema)
+		if err != nil {
+			return nil, err
+		}
+		return elastic.QueryToMap(query)
+	})
+}
@@ -0,0 +1,35 @@
+// Package opensearch converts KQL filters to OpenSearch queries.
+//
+// OpenSearch accepts the query DSL of Elasticsearch, so queries are generated by the query generator of the elastic
+// module, and are returned as a Query, which encodes to the JSON request body taken by the official OpenSearch Go
+// client. All options of the elastic module apply, e.g. elastic.WithFilterContext.
+package opensearch
+
+import (
+	"github.com/MottoStreaming/kqlfilter.go"
+	"github.com/MottoStreaming/kqlfilter.go/elastic"
+)
+
+// Query is an OpenSearch query in its JSON representation, e.g. `{"term": {"type_id": {"value": "team"}}}`. Numbers
+// are json.Number values.
+type Query map[string]any
+
+// Option is an option of the elastic query generator.
+type Option = elastic.Option
+
+type QueryGenerator struct {
+	generator *elastic.QueryGenerator
+}
+
+func NewQueryGenerator(options ...Option) *QueryGenerator {
+	return &QueryGenerator{generator: elastic.NewQueryGenerator(options...)}
+}
+
+// ConvertAST converts a KQL AST to an OpenSearch query.
+func (q *QueryGenerator) ConvertAST(root kqlfilter.Node) (Query, error) {
+	m, err := q.generator.ConvertASTToMap(root)
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
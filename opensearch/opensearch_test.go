@@ -0,0 +1,183 @@
+package opensearch
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/MottoStreaming/kqlfilter.go"
+	"github.com/MottoStreaming/kqlfilter.go/elastic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertNodeToQuery(t *testing.T) {
+	testCases := []struct {
+		name              string
+		input             string
+		options           []Option
+		expectedError     string
+		expectedQueryJSON string
+	}{
+		{
+			name:              "simple equality",
+			input:             "type_id:team",
+			expectedQueryJSON: `{"term":{"type_id":{"value":"team"}}}`,
+		},
+		{
+			name:              "boolean literals",
+			input:             "true or false",
+			expectedQueryJSON: `{"bool":{"should":[{"match_all":{}},{"match_none":{}}],"minimum_should_match":1}}`,
+		},
+		{
+			name:              "multiple values for same field",
+			input:             "type_id:(team OR player)",
+			expectedQueryJSON: `{"terms":{"type_id":["team","player"]}}`,
+		},
+		{
+			name:  "and, or and not",
+			input: "type_id:team and (fields.active:true or not fields.name:*)",
+			expectedQueryJSON: `{"bool":{"must":[
+				{"term":{"type_id":{"value":"team"}}},
+				{"bool":{"should":[
+					{"term":{"fields.active":{"value":"true"}}},
+					{"bool":{"must_not":[{"exists":{"field":"fields.name"}}]}}
+				],"minimum_should_match":1}}
+			]}}`,
+		},
+		{
+			name:              "nested query",
+			input:             "fields:{established_year >= 2000}",
+			expectedQueryJSON: `{"range":{"fields.established_year":{"gte":2000}}}`,
+		},
+		{
+			name:              "date range",
+			input:             `time < "2024-01-01T00:00:00Z"`,
+			expectedQueryJSON: `{"range":{"time":{"lt":"2024-01-01T00:00:00Z"}}}`,
+		},
+		{
+			name:              "escaped asterisk",
+			input:             `name:a\*b*`,
+			expectedQueryJSON: `{"term":{"name":{"value":"a*b*"}}}`,
+		},
+		{
+			name:              "filter context",
+			input:             "type_id:team",
+			options:           []Option{elastic.WithFilterContext()},
+			expectedQueryJSON: `{"bool":{"filter":[{"term":{"type_id":{"value":"team"}}}]}}`,
+		},
+		{
+			name:              "filter context negation",
+			input:             "not type_id:team",
+			options:           []Option{elastic.WithFilterContext()},
+			expectedQueryJSON: `{"bool":{"must_not":[{"term":{"type_id":{"value":"team"}}}]}}`,
+		},
+		{
+			name:  "field types",
+			input: "active:true age:(18 or 21) name:true",
+			options: []Option{elastic.WithFieldTypes(map[string]kqlfilter.FieldType{
+				"active": kqlfilter.FieldTypeBool,
+				"age":    kqlfilter.FieldTypeInt64,
+			})},
+			expectedQueryJSON: `{"bool":{"must":[
+				{"term":{"active":{"value":true}}},
+				{"terms":{"age":[18,21]}},
+				{"term":{"name":{"value":"true"}}}
+			]}}`,
+		},
+		{
+			name:    "boosts",
+			input:   "title:go tags:(a or b)",
+			options: []Option{elastic.WithFieldBoosts(map[string]float32{"title": 2, "tags": 1.5})},
+			expectedQueryJSON: `{"bool":{"must":[
+				{"term":{"title":{"value":"go","boost":2}}},
+				{"terms":{"tags":["a","b"],"boost":1.5}}
+			]}}`,
+		},
+		{
+			name:    "text fields",
+			input:   `title:go body:"quick brown fox" body:(fox or "lazy dog")`,
+			options: []Option{elastic.WithTextFields("title", "body"), elastic.WithFieldBoosts(map[string]float32{"title": 2})},
+			expectedQueryJSON: `{"bool":{"must":[
+				{"match":{"title":{"query":"go","boost":2}}},
+				{"match_phrase":{"body":{"query":"quick brown fox"}}},
+				{"bool":{"should":[
+					{"match":{"body":{"query":"fox"}}},
+					{"match_phrase":{"body":{"query":"lazy dog"}}}
+				],"minimum_should_match":1}}
+			]}}`,
+		},
+		{
+			name:  "date ranges",
+			input: "created:2024-05-01",
+			options: []Option{
+				elastic.WithFieldTypes(map[string]kqlfilter.FieldType{"created": kqlfilter.FieldTypeTimestamp}),
+				elastic.WithDateRanges(time.UTC),
+			},
+			expectedQueryJSON: `{"range":{"created":{"gte":"2024-05-01T00:00:00Z","lt":"2024-05-02T00:00:00Z"}}}`,
+		},
+		{
+			name:          "invalid range value",
+			input:         "age > abc",
+			expectedError: "age: expected number or date literal",
+		},
+		{
+			name:              "minimum should match omitted",
+			input:             "true or false",
+			options:           []Option{elastic.WithMinimumShouldMatch(0)},
+			expectedQueryJSON: `{"bool":{"should":[{"match_all":{}},{"match_none":{}}]}}`,
+		},
+		{
+			name:          "unsupported literal",
+			input:         "team",
+			expectedError: "only boolean literals are supported; team",
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			n, err := kqlfilter.ParseAST(test.input)
+			require.NoError(t, err)
+
+			q, err := NewQueryGenerator(test.options...).ConvertAST(n)
+			if test.expectedError != "" {
+				require.EqualError(t, err, test.expectedError)
+				return
+			}
+			require.NoError(t, err)
+
+			data, err := json.Marshal(q)
+			require.NoError(t, err)
+			assert.JSONEq(t, test.expectedQueryJSON, string(data))
+		})
+	}
+}
+
+func TestConvertFuzzyNodeToQuery(t *testing.T) {
+	n, err := kqlfilter.ParseAST("name:jon~1", kqlfilter.WithFuzzyMatching())
+	require.NoError(t, err)
+
+	q, err := NewQueryGenerator().ConvertAST(n)
+	require.NoError(t, err)
+
+	data, err := json.Marshal(q)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"fuzzy":{"name":{"value":"jon","fuzziness":"1"}}}`, string(data))
+}
+
+func TestBackend(t *testing.T) {
+	schema := kqlfilter.Schema{
+		"active": {Type: kqlfilter.FieldTypeBool, Column: "is_active", Aliases: []string{"enabled"}},
+	}
+	q, err := kqlfilter.QueryFromKQL("enabled:false", schema, Backend())
+	require.NoError(t, err)
+
+	data, err := json.Marshal(q)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"term":{"is_active":{"value":false}}}`, string(data))
+
+	_, err = kqlfilter.QueryFromKQL("name:x", schema, Backend())
+	assert.ErrorIs(t, err, kqlfilter.ErrUnknownField)
+
+	assert.Contains(t, kqlfilter.Converters(), "opensearch")
+}
@@ -1,9 +1,12 @@
 package kqlfilter
 
 import (
+	"context"
 	"fmt"
 	"runtime"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // parser is the representation of a single parsed filter.
@@ -11,7 +14,7 @@ type parser struct {
 	Root Node   // top-level root of the tree.
 	text string // text parsed to create the filter
 	// Parsing only; cleared after parse.
-	lex       *lexer
+	lex       lexer
 	token     [3]item // three-token lookahead for parser.
 	peekCount int
 	// Disallow complex expressions:
@@ -21,18 +24,69 @@ type parser struct {
 	currentDepth              int
 	maxComplexity             int
 	currentComplexity         int
+	maxInputLength            int
+	maxValueLength            int
+	fuzzyMatching             bool
+	extendedSyntax            bool
+	keywordCase               KeywordCase
+	disabledFeatures          Feature
+	// Cancellation; checked every cancelCheckInterval tokens.
+	ctx        context.Context
+	timeBudget time.Duration
+	deadline   time.Time
+	tokenCount int
+	// Allocates the nodes if set; see ParseSession.
+	arena *nodeArena
+	// Placeholders of a template, in order of occurrence; only lexed when placeholders is set.
+	placeholders    bool
+	placeholderRefs []placeholderRef
+	// Top-level clauses joined by an implicit AND; only recorded when trackSegments is set.
+	trackSegments bool
+	segments      []segment
 }
 
+// segment is a top-level clause of the input, i.e. a clause that is joined to its siblings by whitespace only.
+type segment struct {
+	start      Pos
+	node       Node
+	complexity int
+}
+
+// cancelCheckInterval is the number of tokens after which the parser checks whether the context is done or the time
+// budget is exceeded.
+const cancelCheckInterval = 64
+
 // next returns the next token.
 func (p *parser) next() item {
 	if p.peekCount > 0 {
 		p.peekCount--
 	} else {
-		p.token[0] = p.lex.nextItem()
+		p.token[0] = p.nextItem()
 	}
 	return p.token[p.peekCount]
 }
 
+// nextItem returns the next token of the lexer, terminating processing if parsing was cancelled.
+func (p *parser) nextItem() item {
+	if p.tokenCount%cancelCheckInterval == 0 {
+		p.checkCancel()
+	}
+	p.tokenCount++
+	return p.lex.nextItem()
+}
+
+// checkCancel terminates processing if the context is done or the time budget is exceeded.
+func (p *parser) checkCancel() {
+	if p.ctx != nil {
+		if err := p.ctx.Err(); err != nil {
+			p.errorf("%w", err)
+		}
+	}
+	if p.timeBudget > 0 && time.Now().After(p.deadline) {
+		p.errorf("time budget of %s exceeded: %w", p.timeBudget, context.DeadlineExceeded)
+	}
+}
+
 // backup backs the input stream up one token.
 func (p *parser) backup() {
 	p.peekCount++
@@ -44,7 +98,7 @@ func (p *parser) peek() item {
 		return p.token[p.peekCount-1]
 	}
 	p.peekCount = 1
-	p.token[0] = p.lex.nextItem()
+	p.token[0] = p.nextItem()
 	return p.token[0]
 }
 
@@ -58,8 +112,13 @@ func (p *parser) eatSpace() {
 
 // errorf formats the error and terminates processing.
 func (p *parser) errorf(format string, args ...any) {
+	p.errorfAt(p.token[0].pos, format, args...)
+}
+
+// errorfAt is like errorf but reports the error at the given position.
+func (p *parser) errorfAt(pos Pos, format string, args ...any) {
 	p.Root = nil
-	format = fmt.Sprintf("parser error: %s at pos %d", format, p.token[0].pos)
+	format = fmt.Sprintf("parser error: %s at pos %d", format, pos)
 	panic(fmt.Errorf(format, args...))
 }
 
@@ -90,6 +149,10 @@ func (p *parser) unexpected(token item, context string) {
 		extra := ""
 		p.errorf("%s%s", token, extra)
 	}
+	switch token.typ {
+	case itemAnd, itemOr, itemNot:
+		p.errorf("unexpected %s in %s; quote it to use it as a field name or value, e.g. \"%s\"", token, context, token.val)
+	}
 	p.errorf("unexpected %s in %s", token, context)
 }
 
@@ -109,14 +172,14 @@ func (p *parser) parse() {
 	p.currentDepth = 0
 	p.eatSpace()
 
-	head := p.parseOr()
+	head := p.parseSegment()
 	// Handle implicit AND
 	if p.peek().typ != itemEOF {
 		andN := p.newAndNode(0)
 		andN.append(head)
 		for p.peek().typ != itemEOF {
 			p.eatSpace()
-			andN.append(p.parseOr())
+			andN.append(p.parseSegment())
 		}
 		p.Root = andN
 		return
@@ -124,10 +187,23 @@ func (p *parser) parse() {
 	p.Root = head
 }
 
+// parseSegment parses a top-level clause.
+func (p *parser) parseSegment() Node {
+	if !p.trackSegments {
+		return p.parseOr()
+	}
+	start := p.peek().pos
+	complexity := p.currentComplexity
+	n := p.parseOr()
+	p.segments = append(p.segments, segment{start: start, node: n, complexity: p.currentComplexity - complexity})
+	return n
+}
+
 func (p *parser) parseOr() Node {
-	n := p.newOrNode(p.peek().pos)
+	pos := p.peek().pos
 	and := p.parseAnd()
-	n.append(and)
+	// The OR node is only allocated once a second operand is found.
+	var n *OrNode
 	// optional space before OR
 	p.eatSpace()
 	for p.peek().typ == itemOr {
@@ -143,20 +219,24 @@ func (p *parser) parseOr() Node {
 		p.next()
 		p.eatSpace()
 
-		and = p.parseAnd()
-		n.append(and)
+		if n == nil {
+			n = p.newOrNode(pos)
+			n.append(and)
+		}
+		n.append(p.parseAnd())
 	}
 	// simplify if only one node
-	if len(n.Nodes) == 1 {
-		return n.Nodes[0]
+	if n == nil {
+		return and
 	}
 	return n
 }
 
 func (p *parser) parseAnd() Node {
-	n := p.newAndNode(p.peek().pos)
+	pos := p.peek().pos
 	not := p.parseNot()
-	n.append(not)
+	// The AND node is only allocated once a second operand is found.
+	var n *AndNode
 	p.eatSpace()
 	for p.peek().typ == itemAnd {
 		p.currentComplexity++
@@ -167,13 +247,16 @@ func (p *parser) parseAnd() Node {
 
 		p.next()
 		p.eatSpace()
-		not = p.parseNot()
+		if n == nil {
+			n = p.newAndNode(pos)
+			n.append(not)
+		}
+		n.append(p.parseNot())
 		p.eatSpace()
-		n.append(not)
 	}
 	// simplify if only one node
-	if len(n.Nodes) == 1 {
-		return n.Nodes[0]
+	if n == nil {
+		return not
 	}
 	return n
 }
@@ -181,6 +264,7 @@ func (p *parser) parseAnd() Node {
 func (p *parser) parseNot() Node {
 	if p.peek().typ == itemNot {
 		pos := p.peek().pos
+		p.requireFeature(pos, FeatureNegation)
 		p.next()
 		p.eatSpace()
 
@@ -215,16 +299,38 @@ func (p *parser) parseExpression() Node {
 	switch p.peek().typ {
 	case itemString:
 		idItem := p.next()
+		// Field names are unescaped, values only if they have no asterisks, see the default case.
+		raw := idItem.val
+		idItem.val = unescapeWildcards(idItem.val)
 		p.eatSpace()
 
 		op := p.next()
 
 		switch op.typ {
 		case itemColon:
+			idItem.val = unquoteField(idItem.val)
 			p.eatSpace()
+			if p.peek().typ == itemRangeBrackets {
+				p.requireFeature(p.peek().pos, FeatureRanges)
+				return p.parseRangeBrackets(idItem)
+			}
+			if t := p.peek().typ; t == itemWildcard || (p.fuzzyMatching && t == itemString) {
+				value, wildcardOnly, unquoted := p.parseLiteral()
+				if wildcardOnly {
+					return p.newExistsNode(idItem.pos, idItem.val)
+				}
+				if unquoted && p.fuzzyMatching {
+					if term, fuzziness, ok := p.parseFuzziness(value.Pos, value.Value); ok {
+						return p.newFuzzyNode(idItem.pos, idItem.val, term, fuzziness)
+					}
+				}
+				return p.newIsNode(idItem.pos, idItem.val, value)
+			}
 			value := p.parseListOfValues()
 			return p.newIsNode(idItem.pos, idItem.val, value)
 		case itemRangeOperator:
+			p.requireFeature(op.pos, FeatureRanges)
+			idItem.val = unquoteField(idItem.val)
 			p.eatSpace()
 			value := p.parseValue()
 			var rop RangeOperator
@@ -239,28 +345,122 @@ func (p *parser) parseExpression() Node {
 				rop = RangeOperatorGte
 			}
 			return p.newRangeNode(idItem.pos, idItem.val, rop, value)
+		case itemString:
+			if p.extendedSyntax && strings.EqualFold(op.val, "is") {
+				return p.parseNullCheck(idItem)
+			}
+			if p.extendedSyntax && strings.EqualFold(op.val, "in") {
+				return p.parseInList(idItem)
+			}
+			fallthrough
 		default:
 			p.backup()
+			value := raw
 			// Strip the quotes
-			if strings.HasPrefix(idItem.val, `"`) {
-				idItem.val = idItem.val[1 : len(idItem.val)-1]
+			if strings.HasPrefix(value, `"`) {
+				value = value[1 : len(value)-1]
 			}
-			return p.newLiteralNode(idItem.pos, idItem.val)
+			// Only values with asterisks keep their escaped asterisks and backslashes, see Clause.Values.
+			if !strings.Contains(value, "*") {
+				value = unescapeWildcards(value)
+			}
+			p.checkValueLength(idItem.pos, UnescapeValue(value))
+			return p.newLiteralNode(idItem.pos, value)
 		}
 
 	case itemBool:
 		value := p.next()
 		return p.newLiteralNode(value.pos, value.val)
 
+	case itemPlaceholder:
+		return p.parsePlaceholder(p.next())
+
 	default:
 		p.unexpected(p.peek(), "expression")
 		return nil
 	}
 }
 
+// parseNullCheck parses the rest of `field IS NULL` or `field IS NOT NULL`, after the IS keyword.
+// `IS NOT NULL` is an existence check, and `IS NULL` its negation.
+func (p *parser) parseNullCheck(idItem item) Node {
+	p.eatSpace()
+	negated := true
+	// NOT may be parsed as a string if it is spelled in a case that isn't a keyword, see WithKeywordCase.
+	if t := p.peek(); t.typ == itemNot || (t.typ == itemString && strings.EqualFold(t.val, "not")) {
+		p.next()
+		p.eatSpace()
+		negated = false
+	}
+	if token := p.next(); token.typ != itemString || !strings.EqualFold(token.val, "null") {
+		p.unexpected(token, "null check")
+	}
+	n := p.newExistsNode(idItem.pos, unquoteField(idItem.val))
+	if negated {
+		p.requireFeature(idItem.pos, FeatureNegation)
+		return p.newNotNode(idItem.pos, n)
+	}
+	return n
+}
+
+// parseInList parses the rest of `field IN (a, b)`, after the IN keyword, like `field:(a, b)`.
+func (p *parser) parseInList(idItem item) Node {
+	p.eatSpace()
+	if token := p.peek(); token.typ != itemLeftParen {
+		p.unexpected(token, "IN list")
+	}
+	value := p.parseListOfValues()
+	return p.newIsNode(idItem.pos, unquoteField(idItem.val), value)
+}
+
+// parseRangeBrackets parses a range in brackets after the colon, e.g. `price:[10 TO 20}`, into the range checks of
+// its bounds: `price>=10 and price<20`. A range that is open on one side results in a single range check, and a
+// range that is open on both sides, `field:[* TO *]`, in an existence check.
+func (p *parser) parseRangeBrackets(idItem item) Node {
+	token := p.next()
+	r, _, _ := scanRangeBrackets(token.val)
+	var nodes []Node
+	if r.lower != "" {
+		p.checkValueLength(token.pos, r.lower)
+		op := RangeOperatorGt
+		if r.lowerInclusive {
+			op = RangeOperatorGte
+		}
+		nodes = append(nodes, p.newRangeNode(idItem.pos, idItem.val, op, p.newLiteralNode(token.pos, r.lower)))
+	}
+	if r.upper != "" {
+		p.checkValueLength(token.pos, r.upper)
+		op := RangeOperatorLt
+		if r.upperInclusive {
+			op = RangeOperatorLte
+		}
+		nodes = append(nodes, p.newRangeNode(idItem.pos, idItem.val, op, p.newLiteralNode(token.pos, r.upper)))
+	}
+	switch len(nodes) {
+	case 0:
+		return p.newExistsNode(idItem.pos, idItem.val)
+	case 1:
+		return nodes[0]
+	default:
+		n := p.newAndNode(idItem.pos)
+		n.append(nodes[0])
+		n.append(nodes[1])
+		return n
+	}
+}
+
+// unquoteField strips the quotes of a quoted field name, e.g. of `"or"` in `"or":1`.
+func unquoteField(field string) string {
+	if len(field) >= 2 && strings.HasPrefix(field, `"`) {
+		return field[1 : len(field)-1]
+	}
+	return field
+}
+
 func (p *parser) parseListOfValues() Node {
 	peeked := p.peek()
 	if peeked.typ == itemLeftBrace {
+		p.requireFeature(peeked.pos, FeatureNesting)
 		if p.disableComplexExpressions {
 			p.errorf("complex expressions are not allowed")
 		}
@@ -297,6 +497,9 @@ func (p *parser) parseListOfValues() Node {
 
 		n := p.parseOr()
 		p.eatSpace()
+		if p.peek().typ == itemComma {
+			n = p.parseCommaSeparatedValues(n)
+		}
 		p.expect(itemRightParen, "list of values")
 
 		p.currentDepth--
@@ -305,11 +508,53 @@ func (p *parser) parseListOfValues() Node {
 	return p.parseValue()
 }
 
+// parseCommaSeparatedValues parses the rest of a comma-separated list of values, e.g. `, b, c` in `state:(a, b, c)`,
+// into a disjunction, like `state:(a or b or c)`. Each element is a single value.
+func (p *parser) parseCommaSeparatedValues(first Node) Node {
+	if _, ok := first.(*OrNode); ok {
+		p.errorf("unexpected %s in list of values; use either commas or OR to separate values", p.peek())
+	}
+	p.requireSingleValue(first)
+	n := p.newOrNode(first.Position())
+	n.append(first)
+	for p.peek().typ == itemComma {
+		p.currentComplexity++
+
+		if p.currentComplexity > p.maxComplexity {
+			p.errorf("maximum complexity exceeded")
+		}
+
+		p.next()
+		p.eatSpace()
+		n.append(p.requireSingleValue(p.parseExpression()))
+		p.eatSpace()
+	}
+	return n
+}
+
+// requireSingleValue terminates processing if an element of a comma-separated list of values is not a single value,
+// e.g. `a and b` in `state:(a and b, c)`.
+func (p *parser) requireSingleValue(n Node) Node {
+	if _, ok := n.(*LiteralNode); !ok {
+		p.errorf("unexpected %s in list of values; only values can be separated by commas", p.peek())
+	}
+	return n
+}
+
 func (p *parser) parseValue() Node {
+	n, _, _ := p.parseLiteral()
+	return n
+}
+
+// parseLiteral parses a value and reports whether it consists of a single unescaped wildcard,
+// and whether it consists of a single unquoted string.
+func (p *parser) parseLiteral() (n *LiteralNode, wildcardOnly bool, unquoted bool) {
 	var value string
 	pos := p.peek().pos
 
 	valueCount := 0
+	wildcardOnly = true
+	unquoted = true
 	for {
 		if p.atTerminator() {
 			break
@@ -319,7 +564,20 @@ func (p *parser) parseValue() Node {
 			itemString,
 			itemBool,
 			itemWildcard,
+			itemComma,
+			itemPlaceholder,
 		}, "value")
+		if item.typ == itemPlaceholder {
+			if valueCount > 1 || !p.atTerminator() {
+				p.errorf("placeholder %s must be the whole value", item)
+			}
+			return p.parsePlaceholder(item), false, false
+		}
+		if item.typ == itemWildcard {
+			p.requireFeature(item.pos, FeatureWildcards)
+		}
+		wildcardOnly = wildcardOnly && item.typ == itemWildcard
+		unquoted = unquoted && item.typ == itemString && !strings.HasPrefix(item.val, `"`)
 		if item.typ == itemString && strings.HasPrefix(item.val, `"`) {
 			// Strip the quotes
 			item.val = item.val[1 : len(item.val)-1]
@@ -330,8 +588,49 @@ func (p *parser) parseValue() Node {
 	if valueCount == 0 {
 		p.errorf("value expected")
 	}
+	// Only values with asterisks keep their escaped asterisks and backslashes, see Clause.Values.
+	if !strings.Contains(value, "*") {
+		value = unescapeWildcards(value)
+	}
+	p.checkValueLength(pos, UnescapeValue(value))
 
-	return p.newLiteralNode(pos, value)
+	return p.newLiteralNode(pos, value), wildcardOnly && valueCount == 1, unquoted && valueCount == 1
+}
+
+// parsePlaceholder returns the literal node of a placeholder token, recording it to be substituted by
+// Template.Execute. The value of the node is the placeholder itself, e.g. `{{me}}`.
+func (p *parser) parsePlaceholder(token item) *LiteralNode {
+	p.placeholderRefs = append(p.placeholderRefs, placeholderRef{pos: token.pos, name: placeholderName(token.val)})
+	return p.newLiteralNode(token.pos, token.val)
+}
+
+// parseFuzziness splits a value with a fuzziness suffix (`jon~1`) into the term and the maximum edit distance.
+// A suffix without a number (`jon~`) defaults to an edit distance of 2, as in Lucene.
+func (p *parser) parseFuzziness(pos Pos, value string) (string, int, bool) {
+	i := strings.LastIndexByte(value, '~')
+	if i <= 0 {
+		return "", 0, false
+	}
+	fuzziness := 2
+	if digits := value[i+1:]; digits != "" {
+		var err error
+		fuzziness, err = strconv.Atoi(digits)
+		if err != nil {
+			return "", 0, false
+		}
+		if fuzziness < 0 || fuzziness > 2 {
+			p.errorfAt(pos, "fuzziness %d must be between 0 and 2", fuzziness)
+		}
+	}
+	return value[:i], fuzziness, true
+}
+
+// checkValueLength terminates processing if the value exceeds the maximum value length.
+func (p *parser) checkValueLength(pos Pos, value string) {
+	if p.maxValueLength > 0 && len(value) > p.maxValueLength {
+		p.Root = nil
+		panic(&LimitError{Limit: LimitValueLength, Max: p.maxValueLength, Actual: len(value), Pos: pos})
+	}
 }
 
 func (p *parser) atTerminator() bool {
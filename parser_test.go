@@ -56,6 +56,12 @@ func TestParseAST(t *testing.T) {
 			false,
 			"field=*value",
 		},
+		{
+			"exists",
+			"field:* and not other:*",
+			false,
+			"(field=* AND NOT other=*)",
+		},
 		{
 			"multi filter",
 			"field:value second:filter",
@@ -284,3 +290,203 @@ func TestParseSimple(t *testing.T) {
 		})
 	}
 }
+
+func TestParseExists(t *testing.T) {
+	n, err := ParseAST("field:*")
+	require.NoError(t, err)
+	require.IsType(t, &ExistsNode{}, n)
+	assert.Equal(t, "field", n.(*ExistsNode).Identifier)
+
+	// Wildcards combined with a value are still literal values.
+	n, err = ParseAST("field:**")
+	require.NoError(t, err)
+	require.IsType(t, &IsNode{}, n)
+	assert.Equal(t, "**", n.(*IsNode).Value.(*LiteralNode).Value)
+}
+
+func TestParseFuzzy(t *testing.T) {
+	// Without the option, the suffix is part of the value.
+	n, err := ParseAST("name:jon~1")
+	require.NoError(t, err)
+	assert.Equal(t, "jon~1", n.(*IsNode).Value.(*LiteralNode).Value)
+
+	testCases := []struct {
+		input             string
+		expectedValue     string
+		expectedFuzziness int
+	}{
+		{"name:jon~1", "jon", 1},
+		{"name:jon~", "jon", 2},
+		{"name:jon~0", "jon", 0},
+		{"name: a~b~2", "a~b", 2},
+	}
+	for _, test := range testCases {
+		t.Run(test.input, func(t *testing.T) {
+			n, err := ParseAST(test.input, WithFuzzyMatching())
+			require.NoError(t, err)
+			require.IsType(t, &FuzzyNode{}, n)
+			fuzzy := n.(*FuzzyNode)
+			assert.Equal(t, "name", fuzzy.Identifier)
+			assert.Equal(t, test.expectedValue, fuzzy.Value)
+			assert.Equal(t, test.expectedFuzziness, fuzzy.Fuzziness)
+		})
+	}
+
+	// Quoted values and suffixes that are not a number are literal values.
+	for _, input := range []string{`name:"jon~1"`, "name:jon~x", "name:~1", "name:(jon~1 or bob)", "name>=jon~1"} {
+		n, err := ParseAST(input, WithFuzzyMatching())
+		require.NoError(t, err, input)
+		assert.NotEqual(t, NodeFuzzy, n.Type(), input)
+	}
+
+	_, err = ParseAST("name:jon~3", WithFuzzyMatching())
+	assert.EqualError(t, err, "parser error: fuzziness 3 must be between 0 and 2 at pos 5")
+	_, err = ParseAST("name:jon~-1", WithFuzzyMatching())
+	assert.EqualError(t, err, "parser error: fuzziness -1 must be between 0 and 2 at pos 5")
+}
+
+func TestParseNullCheck(t *testing.T) {
+	// Without the option, the keywords are values.
+	n, err := ParseAST("email is null")
+	require.NoError(t, err)
+	assert.Equal(t, "(email AND is AND null)", n.String())
+
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"email is not null", "email=*"},
+		{"email IS NULL", "NOT email=*"},
+		{"state:active and email is null", "(state=active AND NOT email=*)"},
+		{"email is null or not phone is not null", "(NOT email=* OR NOT phone=*)"},
+		{"user:{email is not null}", "user={email=*}"},
+	}
+	for _, test := range testCases {
+		t.Run(test.input, func(t *testing.T) {
+			n, err := ParseAST(test.input, WithExtendedSyntax())
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, n.String())
+		})
+	}
+
+	_, err = ParseAST("email is empty", WithExtendedSyntax())
+	assert.EqualError(t, err, `parser error: unexpected "empty" in null check at pos 9`)
+}
+
+func TestParseKeywordCase(t *testing.T) {
+	testCases := []struct {
+		input       string
+		keywordCase KeywordCase
+		expected    string
+	}{
+		{"a:1 Or b:2", KeywordCaseInsensitive, "(a=1 OR b=2)"},
+		{"a:1 or b:2", KeywordCaseLower, "(a=1 OR b=2)"},
+		{"a:1 OR b:2", KeywordCaseLower, "(a=1 AND OR AND b=2)"},
+		{"a:1 OR b:2", KeywordCaseUpper, "(a=1 OR b=2)"},
+		{"NOT a:1 and b:2", KeywordCaseUpper, "(NOT a=1 AND and AND b=2)"},
+		{"or:1", KeywordCaseUpper, "or=1"},
+		{"TRUE", KeywordCaseLower, "TRUE"},
+	}
+	for _, test := range testCases {
+		t.Run(test.input, func(t *testing.T) {
+			n, err := ParseAST(test.input, WithKeywordCase(test.keywordCase))
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, n.String())
+		})
+	}
+
+	n, err := ParseAST("email is NOT null", WithExtendedSyntax(), WithKeywordCase(KeywordCaseLower))
+	require.NoError(t, err)
+	assert.Equal(t, "email=*", n.String())
+}
+
+func TestParseKeywordFieldNames(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{`"or":1`, "or=1"},
+		{`\or:1`, "or=1"},
+		{`"and">=2 and "not":x`, "(and>=2 AND not=x)"},
+		{`"not" is null`, "NOT not=*"},
+		{`field:"or"`, "field=or"},
+	}
+	for _, test := range testCases {
+		t.Run(test.input, func(t *testing.T) {
+			n, err := ParseAST(test.input, WithExtendedSyntax())
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, n.String())
+		})
+	}
+
+	_, err := ParseAST("or:1")
+	assert.EqualError(t, err, `parser error: unexpected "or" in expression; quote it to use it as a field name or value, e.g. "or" at pos 0`)
+}
+
+func TestParseCommaSeparatedValues(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"state:(a, b, c)", "state=(a OR b OR c)"},
+		{"state:(a,b)", "state=(a OR b)"},
+		{`state:("a b" , c)`, "state=(a b OR c)"},
+		{"state:(a, b) and id:1", "(state=(a OR b) AND id=1)"},
+		{"state in (a, b)", "state=(a OR b)"},
+		{"state IN (a)", "state=a"},
+		// Outside of lists of values, commas are part of the value.
+		{"name:doe,john", "name=doe,john"},
+		{"(name:doe,john or id:1)", "(name=doe,john OR id=1)"},
+	}
+	for _, test := range testCases {
+		t.Run(test.input, func(t *testing.T) {
+			n, err := ParseAST(test.input, WithExtendedSyntax())
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, n.String())
+		})
+	}
+
+	_, err := ParseAST("state:(a or b, c)")
+	assert.EqualError(t, err, `parser error: unexpected "," in list of values; use either commas or OR to separate values at pos 13`)
+	_, err = ParseAST("state:(a and b, c)")
+	assert.EqualError(t, err, `parser error: unexpected "," in list of values; only values can be separated by commas at pos 14`)
+	_, err = ParseAST("state:(a, b and c)")
+	assert.Error(t, err)
+	_, err = ParseAST("state in a", WithExtendedSyntax())
+	assert.EqualError(t, err, `parser error: unexpected "a" in IN list at pos 9`)
+	_, err = ParseAST("state:(a, b, c)", WithMaxComplexity(1))
+	assert.EqualError(t, err, "parser error: maximum complexity exceeded at pos 11")
+}
+
+func TestParseRangeBrackets(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"price:[10 TO 20]", "(price>=10 AND price<=20)"},
+		{"price:{10 TO 20}", "(price>10 AND price<20)"},
+		{"price:[10 TO 20}", "(price>=10 AND price<20)"},
+		{"price: [ 10  TO 20 ]", "(price>=10 AND price<=20)"},
+		{"price:[10 TO *]", "price>=10"},
+		{"price:{* TO 20}", "price<20"},
+		{"price:[* TO *]", "price=*"},
+		{`created:[2024-01-01T00:00:00Z TO "2024-02-01T00:00:00Z"}`, "(created>=2024-01-01T00:00:00Z AND created<2024-02-01T00:00:00Z)"},
+		{`name:["a b" TO c\]d]`, "(name>=a b AND name<=c]d)"},
+		{"a:1 price:[10 TO 20] or b:2", "(a=1 AND ((price>=10 AND price<=20) OR b=2))"},
+		{"user:{name:[a TO b]}", "user={(name>=a AND name<=b)}"},
+	}
+	for _, test := range testCases {
+		t.Run(test.input, func(t *testing.T) {
+			n, err := ParseAST(test.input)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, n.String())
+		})
+	}
+
+	// Without the uppercase TO keyword, brackets are part of values and braces start nested queries.
+	n, err := ParseAST("tag:[10")
+	require.NoError(t, err)
+	assert.Equal(t, "tag=[10", n.String())
+	_, err = ParseAST("price:[10 TO 20]", WithMaxValueLength(1))
+	require.Error(t, err)
+}
@@ -0,0 +1,360 @@
+package kqlfilter
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// Backend converts a parsed and validated filter into a query of type T for a specific storage backend.
+// A nil ast means that the filter is empty and should match everything.
+type Backend[T any] interface {
+	Convert(ast Node, schema Schema) (T, error)
+}
+
+// BackendFunc is an adapter to allow the use of ordinary functions as a Backend.
+type BackendFunc[T any] func(ast Node, schema Schema) (T, error)
+
+// Convert calls f(ast, schema).
+func (f BackendFunc[T]) Convert(ast Node, schema Schema) (T, error) {
+	return f(ast, schema)
+}
+
+// Warning describes a non-fatal issue found in a filter.
+type Warning struct {
+	Pos     Pos
+	Message string
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("%s at pos %d", w.Message, w.Pos)
+}
+
+type queryOptions struct {
+	parserOptions []ParserOption
+	scope         func(Node) (Node, error)
+	onWarning     func(Warning)
+	tenantKey     string
+	quota         QuotaHook
+	telemetry     TelemetryHook
+}
+
+// QueryOption is a function that configures QueryFromKQL.
+type QueryOption func(*queryOptions)
+
+// WithParserOptions sets the options used to parse the input. By default, the defaults of ParseAST apply.
+func WithParserOptions(options ...ParserOption) QueryOption {
+	return func(o *queryOptions) {
+		o.parserOptions = append(o.parserOptions, options...)
+	}
+}
+
+// WithScope sets a hook that receives the validated user filter and returns the filter to convert.
+// It is typically used to add server-enforced conditions, such as tenant scoping, to the user filter.
+// The conditions added by the hook are not validated against the schema, but the fields must still be known to the
+// backend. The hook receives nil for empty filters.
+func WithScope(scope func(ast Node) (Node, error)) QueryOption {
+	return func(o *queryOptions) {
+		o.scope = scope
+	}
+}
+
+// WithWarningHandler sets a function that is called for every non-fatal issue found in the filter,
+// such as usage of a field alias instead of the canonical field name.
+func WithWarningHandler(handler func(Warning)) QueryOption {
+	return func(o *queryOptions) {
+		o.onWarning = handler
+	}
+}
+
+// QuotaHook is called with the complexity of the filter and the fields it uses, so that usage can be limited per
+// tenant. The complexity is the number of AND and OR operators, including implicit ones, as limited by
+// WithMaxComplexity. Returning an error rejects the filter.
+type QuotaHook func(tenantKey string, complexity int, fields []FieldUsage) error
+
+// WithQuota sets a hook that is called with the given tenant key before the filter is converted, e.g. to limit the
+// number of clauses or the use of ranges on a free tier. Only the user filter is accounted for, not the conditions
+// added by WithScope. The hook is also called for empty filters.
+func WithQuota(tenantKey string, hook QuotaHook) QueryOption {
+	return func(o *queryOptions) {
+		o.tenantKey = tenantKey
+		o.quota = hook
+	}
+}
+
+// QueryFromKQL parses the input, validates it against the schema and converts it using the given backend.
+// It is the common path for services exposing a filter parameter:
+//
+//	query, err := kqlfilter.QueryFromKQL(req.Filter, schema, kqlfilter.SpannerBackend(),
+//		kqlfilter.WithScope(func(ast kqlfilter.Node) (kqlfilter.Node, error) {
+//			// restrict results to the tenant of the caller
+//		}),
+//	)
+func QueryFromKQL[T any](input string, schema Schema, backend Backend[T], options ...QueryOption) (T, error) {
+	return QueryFromKQLContext(context.Background(), input, schema, backend, options...)
+}
+
+// QueryFromKQLContext is like QueryFromKQL, but stops parsing when the context is done, like ParseASTContext.
+// The context is passed to the hook set with WithTelemetry, e.g. to parent a span.
+func QueryFromKQLContext[T any](ctx context.Context, input string, schema Schema, backend Backend[T], options ...QueryOption) (T, error) {
+	o := queryOptions{}
+	for _, option := range options {
+		option(&o)
+	}
+	if o.telemetry == nil {
+		return queryFromKQL(ctx, input, schema, backend, o, nil)
+	}
+	stats := QueryStats{Start: time.Now()}
+	result, err := queryFromKQL(ctx, input, schema, backend, o, &stats)
+	stats.Err = err
+	stats.RejectedField = rejectedField(err)
+	o.telemetry(ctx, stats)
+	return result, err
+}
+
+// queryFromKQL implements QueryFromKQLContext, recording the stats if not nil.
+func queryFromKQL[T any](ctx context.Context, input string, schema Schema, backend Backend[T], o queryOptions, stats *QueryStats) (T, error) {
+	var zero T
+	var ast Node
+	if strings.TrimSpace(input) != "" {
+		var err error
+		ast, err = ParseASTContext(ctx, input, o.parserOptions...)
+		if err == nil {
+			err = validateAgainstSchema(ast, schema, "", o.onWarning)
+		}
+		if stats != nil {
+			stats.ParseDuration = time.Since(stats.Start)
+			if ast != nil {
+				stats.Complexity = countOperators(ast)
+				stats.Fields = ListFields(ast)
+			}
+		}
+		if err != nil {
+			return zero, err
+		}
+	}
+
+	if o.quota != nil {
+		if err := o.quota(o.tenantKey, countOperators(ast), ListFields(ast)); err != nil {
+			return zero, err
+		}
+	}
+
+	convertStart := time.Now()
+	if o.scope != nil {
+		var err error
+		ast, err = o.scope(ast)
+		if err != nil {
+			return zero, err
+		}
+	}
+
+	result, err := backend.Convert(ast, schema)
+	if stats != nil {
+		stats.ConvertDuration = time.Since(convertStart)
+		if err == nil {
+			for _, usage := range ListFields(ast) {
+				stats.Clauses += usage.Count
+			}
+		}
+	}
+	return result, err
+}
+
+// validateAgainstSchema checks that all fields referenced in the AST are known and used with allowed operators.
+func validateAgainstSchema(ast Node, schema Schema, prefix string, onWarning func(Warning)) error {
+	checkField := func(pos Pos, identifier string) (FieldSchema, error) {
+		name, fs, ok := schema.Lookup(prefix + identifier)
+		if !ok {
+			return FieldSchema{}, NewUnknownFieldError(prefix+identifier, schema.FieldNames())
+		}
+		if onWarning != nil && name != prefix+identifier {
+			onWarning(Warning{Pos: pos, Message: fmt.Sprintf("field %s is an alias of %s", prefix+identifier, name)})
+		}
+		return fs, nil
+	}
+
+	switch n := ast.(type) {
+	case *AndNode:
+		for _, child := range n.Nodes {
+			if err := validateAgainstSchema(child, schema, prefix, onWarning); err != nil {
+				return err
+			}
+		}
+	case *OrNode:
+		for _, child := range n.Nodes {
+			if err := validateAgainstSchema(child, schema, prefix, onWarning); err != nil {
+				return err
+			}
+		}
+	case *NotNode:
+		return validateAgainstSchema(n.Expr, schema, prefix, onWarning)
+	case *IsNode:
+		if nested, ok := n.Value.(*NestedNode); ok {
+			return validateAgainstSchema(nested.Expr, schema, prefix+n.Identifier+".", onWarning)
+		}
+		fs, err := checkField(n.Pos, n.Identifier)
+		if err != nil {
+			return err
+		}
+		if _, ok := n.Value.(*OrNode); ok && !fs.AllowMultipleValues {
+			return newFieldError(prefix+n.Identifier, ErrMultipleValuesNotAllowed, "field %s: multiple values are not allowed", prefix+n.Identifier)
+		}
+	case *ExistsNode:
+		if _, err := checkField(n.Pos, n.Identifier); err != nil {
+			return err
+		}
+	case *FuzzyNode:
+		if _, err := checkField(n.Pos, n.Identifier); err != nil {
+			return err
+		}
+	case *RangeNode:
+		fs, err := checkField(n.Pos, n.Identifier)
+		if err != nil {
+			return err
+		}
+		if !fs.AllowRanges {
+			return newFieldError(prefix+n.Identifier, ErrOperatorNotAllowed, "operator %s not supported for field: %s", n.Operator, prefix+n.Identifier)
+		}
+	}
+	return nil
+}
+
+// countOperators returns the number of AND and OR operators in the AST.
+func countOperators(ast Node) int {
+	switch n := ast.(type) {
+	case *AndNode:
+		return len(n.Nodes) - 1 + countOperatorsOf(n.Nodes)
+	case *OrNode:
+		return len(n.Nodes) - 1 + countOperatorsOf(n.Nodes)
+	case *NotNode:
+		return countOperators(n.Expr)
+	case *IsNode:
+		return countOperators(n.Value)
+	case *NestedNode:
+		return countOperators(n.Expr)
+	default:
+		return 0
+	}
+}
+
+func countOperatorsOf(nodes []Node) int {
+	count := 0
+	for _, n := range nodes {
+		count += countOperators(n)
+	}
+	return count
+}
+
+// SpannerQuery holds the result of converting a filter with SpannerBackend.
+type SpannerQuery struct {
+	// Conditions that must all hold; see Filter.ToSpannerSQL.
+	Conditions []string
+	// Params referenced by the conditions.
+	Params map[string]any
+	// Time requested with the AsOfField pseudo-field, to be used as the bound of a stale read. Zero if not requested.
+	AsOf time.Time
+}
+
+// Where returns the conditions joined by AND, or an empty string if there are no conditions.
+func (q SpannerQuery) Where() string {
+	return strings.Join(q.Conditions, " AND ")
+}
+
+// SpannerBackend returns a Backend producing Spanner SQL conditions, using the field configs derived from the schema.
+// A disjunction at the top level of the filter is converted to a single condition; see ConvertToFilter. The AsOfField
+// pseudo-field is extracted from the clauses of the filter into SpannerQuery.AsOf; to allow it in QueryFromKQL, add it
+// to the schema.
+func SpannerBackend(options ...ConverterOption) Backend[SpannerQuery] {
+	return BackendFunc[SpannerQuery](func(ast Node, schema Schema) (SpannerQuery, error) {
+		filter, err := ConvertToFilter(ast)
+		if err != nil {
+			return SpannerQuery{}, err
+		}
+		filter, asOf, err := filter.ExtractAsOf(options...)
+		if err != nil {
+			return SpannerQuery{}, err
+		}
+		conditions, params, err := filter.ToSpannerSQL(schema.SpannerFieldConfigs(), options...)
+		if err != nil {
+			return SpannerQuery{}, err
+		}
+		return SpannerQuery{Conditions: conditions, Params: params, AsOf: asOf}, nil
+	})
+}
+
+// PostgresQuery holds the result of converting a filter with PostgresBackend.
+type PostgresQuery struct {
+	// Conditions that must all hold; see Filter.ToPostgresSQL.
+	Conditions []string
+	// Named arguments referenced by the conditions, to be passed as pgx.NamedArgs.
+	Args map[string]any
+}
+
+// Where returns the conditions joined by AND, or an empty string if there are no conditions.
+func (q PostgresQuery) Where() string {
+	return strings.Join(q.Conditions, " AND ")
+}
+
+// PostgresBackend returns a Backend producing PostgreSQL conditions with named arguments, using the field configs
+// derived from the schema. A disjunction at the top level of the filter is converted to a single condition; see
+// ConvertToFilter.
+func PostgresBackend(options ...ConverterOption) Backend[PostgresQuery] {
+	return BackendFunc[PostgresQuery](func(ast Node, schema Schema) (PostgresQuery, error) {
+		filter, err := ConvertToFilter(ast)
+		if err != nil {
+			return PostgresQuery{}, err
+		}
+		conditions, args, err := filter.ToPostgresSQL(schema.PostgresFieldConfigs(), options...)
+		if err != nil {
+			return PostgresQuery{}, err
+		}
+		return PostgresQuery{Conditions: conditions, Args: args}, nil
+	})
+}
+
+// SQLiteQuery holds the result of converting a filter with SQLiteBackend.
+type SQLiteQuery struct {
+	// Conditions that must all hold; see Filter.ToSQLiteSQL.
+	Conditions []string
+	// Arguments of the `?` placeholders of the conditions, in order.
+	Args []any
+}
+
+// Where returns the conditions joined by AND, or an empty string if there are no conditions.
+func (q SQLiteQuery) Where() string {
+	return strings.Join(q.Conditions, " AND ")
+}
+
+// SQLiteBackend returns a Backend producing SQLite conditions with `?` placeholders, using the field configs derived
+// from the schema. A disjunction at the top level of the filter is converted to a single condition; see
+// ConvertToFilter.
+func SQLiteBackend(options ...ConverterOption) Backend[SQLiteQuery] {
+	return BackendFunc[SQLiteQuery](func(ast Node, schema Schema) (SQLiteQuery, error) {
+		filter, err := ConvertToFilter(ast)
+		if err != nil {
+			return SQLiteQuery{}, err
+		}
+		conditions, args, err := filter.ToSQLiteSQL(schema.SQLiteFieldConfigs(), options...)
+		if err != nil {
+			return SQLiteQuery{}, err
+		}
+		return SQLiteQuery{Conditions: conditions, Args: args}, nil
+	})
+}
+
+// SquirrelBackend returns a Backend attaching the filter to the given select builder,
+// using the field configs derived from the schema. A disjunction at the top level of the filter is converted to a
+// single condition; see ConvertToFilter.
+func SquirrelBackend(stmt sq.SelectBuilder, options ...ConverterOption) Backend[sq.SelectBuilder] {
+	return BackendFunc[sq.SelectBuilder](func(ast Node, schema Schema) (sq.SelectBuilder, error) {
+		filter, err := ConvertToFilter(ast)
+		if err != nil {
+			return stmt, err
+		}
+		return filter.ToSquirrelSql(stmt, schema.SquirrelFieldConfigs(), options...)
+	})
+}
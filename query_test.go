@@ -0,0 +1,147 @@
+package kqlfilter
+
+import (
+	"fmt"
+	"testing"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testQuerySchema = Schema{
+	"user_id": {
+		Type:    FieldTypeInt64,
+		Aliases: []string{"userId"},
+	},
+	"state": {
+		AllowMultipleValues: true,
+	},
+	"created_at": {
+		Column:      "create_time",
+		Type:        FieldTypeTimestamp,
+		AllowRanges: true,
+	},
+	"tenant_id": {},
+}
+
+func TestQueryFromKQLSpanner(t *testing.T) {
+	var warnings []Warning
+	query, err := QueryFromKQL(`userId:12 state:(active OR paused) created_at >= "2024-01-01T00:00:00Z"`, testQuerySchema, SpannerBackend(),
+		WithWarningHandler(func(w Warning) {
+			warnings = append(warnings, w)
+		}),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "user_id=@KQL0 AND state IN UNNEST(@KQL1) AND create_time>=@KQL2", query.Where())
+	assert.Equal(t, int64(12), query.Params["KQL0"])
+	assert.Equal(t, []string{"active", "paused"}, query.Params["KQL1"])
+	require.Len(t, warnings, 1)
+	assert.Equal(t, "field userId is an alias of user_id at pos 0", warnings[0].String())
+}
+
+func TestQueryFromKQLScope(t *testing.T) {
+	scope := WithScope(func(ast Node) (Node, error) {
+		tenant := &IsNode{NodeType: NodeIs, Identifier: "tenant_id", Value: &LiteralNode{NodeType: NodeLiteral, Value: "t1"}}
+		if ast == nil {
+			return tenant, nil
+		}
+		return &AndNode{NodeType: NodeAnd, Nodes: []Node{ast, tenant}}, nil
+	})
+
+	query, err := QueryFromKQL("user_id:1", testQuerySchema, SpannerBackend(), scope)
+	require.NoError(t, err)
+	assert.Equal(t, "user_id=@KQL0 AND tenant_id=@KQL1", query.Where())
+
+	query, err = QueryFromKQL("  ", testQuerySchema, SpannerBackend(), scope)
+	require.NoError(t, err)
+	assert.Equal(t, "tenant_id=@KQL0", query.Where())
+}
+
+func TestQueryFromKQLQuota(t *testing.T) {
+	freeTier := WithQuota("tenant-1", func(tenantKey string, complexity int, fields []FieldUsage) error {
+		assert.Equal(t, "tenant-1", tenantKey)
+		if complexity > 2 {
+			return fmt.Errorf("tenant %s: filter too complex", tenantKey)
+		}
+		for _, f := range fields {
+			for _, op := range f.Operators {
+				if op != "=" && op != "IN" {
+					return fmt.Errorf("tenant %s: operator %s not allowed on free tier", tenantKey, op)
+				}
+			}
+		}
+		return nil
+	})
+
+	_, err := QueryFromKQL("user_id:1 and state:(active OR paused)", testQuerySchema, SpannerBackend(), freeTier)
+	require.NoError(t, err)
+
+	_, err = QueryFromKQL("user_id:1 state:(active OR paused) tenant_id:x", testQuerySchema, SpannerBackend(), freeTier)
+	assert.EqualError(t, err, "tenant tenant-1: filter too complex")
+
+	_, err = QueryFromKQL("created_at >= 2024-01-01", testQuerySchema, SpannerBackend(), freeTier)
+	assert.EqualError(t, err, "tenant tenant-1: operator >= not allowed on free tier")
+
+	var calls int
+	_, err = QueryFromKQL("", testQuerySchema, SpannerBackend(), WithQuota("tenant-1", func(_ string, complexity int, fields []FieldUsage) error {
+		calls++
+		assert.Zero(t, complexity)
+		assert.Empty(t, fields)
+		return nil
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, 1, calls)
+}
+
+func TestQueryFromKQLSquirrel(t *testing.T) {
+	stmt, err := QueryFromKQL("userId:12 and created_at < \"2024-01-01T00:00:00Z\"", testQuerySchema, SquirrelBackend(sq.Select("*").From("users")))
+	require.NoError(t, err)
+	sql, args, err := stmt.ToSql()
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT * FROM users WHERE user_id = ? AND create_time < ?", sql)
+	assert.Len(t, args, 2)
+}
+
+func TestQueryFromKQLDisjunction(t *testing.T) {
+	input := "(user_id:1 and state:active) or tenant_id:t1"
+
+	spannerQuery, err := QueryFromKQL(input, testQuerySchema, SpannerBackend())
+	require.NoError(t, err)
+	assert.Equal(t, "((user_id=@KQL0 AND state=@KQL1) OR (tenant_id=@KQL2))", spannerQuery.Where())
+
+	postgresQuery, err := QueryFromKQL(input, testQuerySchema, PostgresBackend())
+	require.NoError(t, err)
+	assert.Equal(t, "((user_id = @KQL0 AND state = @KQL1) OR (tenant_id = @KQL2))", postgresQuery.Where())
+
+	sqliteQuery, err := QueryFromKQL(input, testQuerySchema, SQLiteBackend())
+	require.NoError(t, err)
+	assert.Equal(t, "((user_id = ? AND state = ?) OR (tenant_id = ?))", sqliteQuery.Where())
+	assert.Equal(t, []any{int64(1), "active", "t1"}, sqliteQuery.Args)
+
+	stmt, err := QueryFromKQL(input, testQuerySchema, SquirrelBackend(sq.Select("*").From("users")))
+	require.NoError(t, err)
+	sql, _, err := stmt.ToSql()
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT * FROM users WHERE ((user_id = ? AND state = ?) OR (tenant_id = ?))", sql)
+}
+
+func TestQueryFromKQLErrors(t *testing.T) {
+	testCases := []struct {
+		name  string
+		input string
+	}{
+		{"parse error", "user_id:("},
+		{"unknown field", "email:x"},
+		{"multiple values not allowed", "user_id:(1 OR 2)"},
+		{"ranges not allowed", "user_id > 1"},
+		{"unknown nested field", "user:{id:1}"},
+		{"parser limits", "state:(a OR b OR c)"},
+	}
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := QueryFromKQL(test.input, testQuerySchema, SpannerBackend(), WithParserOptions(WithMaxComplexity(1)))
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package kqlfilter
+
+// RedactedValue is the placeholder Redact replaces values with.
+const RedactedValue = "REDACTED"
+
+// Redact returns a copy of the AST in which the values of the given fields are replaced by RedactedValue, so the shape
+// of a filter can be logged without personal data. Fields in nested queries are named by their path, e.g.
+// `user.email`, and naming the parent field redacts all of its nested fields.
+// If no fields are given, all values are redacted. Values that are not associated with a field (`"john doe"`) are
+// always redacted, except for the boolean literals. The input AST is not modified.
+func Redact(ast Node, fields ...string) Node {
+	var sensitive map[string]bool
+	if len(fields) > 0 {
+		sensitive = make(map[string]bool, len(fields))
+		for _, field := range fields {
+			sensitive[field] = true
+		}
+	}
+	return redact(ast, "", sensitive)
+}
+
+// redact copies the node, redacting the values of sensitive fields. A nil sensitive set redacts all values.
+func redact(ast Node, prefix string, sensitive map[string]bool) Node {
+	isSensitive := func(field string) bool {
+		return sensitive == nil || sensitive[field]
+	}
+	switch n := ast.(type) {
+	case *AndNode:
+		c := *n
+		c.Nodes = make([]Node, len(n.Nodes))
+		for i, child := range n.Nodes {
+			c.Nodes[i] = redact(child, prefix, sensitive)
+		}
+		return &c
+	case *OrNode:
+		c := *n
+		c.Nodes = make([]Node, len(n.Nodes))
+		for i, child := range n.Nodes {
+			c.Nodes[i] = redact(child, prefix, sensitive)
+		}
+		return &c
+	case *NotNode:
+		c := *n
+		c.Expr = redact(n.Expr, prefix, sensitive)
+		return &c
+	case *IsNode:
+		c := *n
+		field := prefix + n.Identifier
+		if nested, ok := n.Value.(*NestedNode); ok {
+			cn := *nested
+			if isSensitive(field) {
+				// Naming the parent redacts all nested fields.
+				cn.Expr = redact(nested.Expr, field+".", nil)
+			} else {
+				cn.Expr = redact(nested.Expr, field+".", sensitive)
+			}
+			c.Value = &cn
+		} else {
+			c.Value = redactValues(n.Value, isSensitive(field))
+		}
+		return &c
+	case *RangeNode:
+		c := *n
+		c.Value = redactValues(n.Value, isSensitive(prefix+n.Identifier))
+		return &c
+	case *FuzzyNode:
+		c := *n
+		if isSensitive(prefix + n.Identifier) {
+			c.Value = RedactedValue
+		}
+		return &c
+	case *ExistsNode:
+		c := *n
+		return &c
+	case *LiteralNode:
+		// A value without a field.
+		return redactValues(n, n.Value != "true" && n.Value != "false")
+	default:
+		return ast
+	}
+}
+
+// redactValues copies the value of a clause, replacing the literals with RedactedValue if replace is set.
+func redactValues(ast Node, replace bool) Node {
+	switch n := ast.(type) {
+	case *OrNode:
+		c := *n
+		c.Nodes = make([]Node, len(n.Nodes))
+		for i, child := range n.Nodes {
+			c.Nodes[i] = redactValues(child, replace)
+		}
+		return &c
+	case *AndNode:
+		c := *n
+		c.Nodes = make([]Node, len(n.Nodes))
+		for i, child := range n.Nodes {
+			c.Nodes[i] = redactValues(child, replace)
+		}
+		return &c
+	case *NotNode:
+		c := *n
+		c.Expr = redactValues(n.Expr, replace)
+		return &c
+	case *LiteralNode:
+		c := *n
+		if replace {
+			c.Value = RedactedValue
+		}
+		return &c
+	default:
+		return ast
+	}
+}
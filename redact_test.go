@@ -0,0 +1,69 @@
+package kqlfilter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedact(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		fields   []string
+		expected string
+	}{
+		{
+			name:     "sensitive fields",
+			input:    "email:john@example.com state:(active or paused) not name:john",
+			fields:   []string{"email", "name"},
+			expected: "(email=REDACTED AND state=(active OR paused) AND NOT name=REDACTED)",
+		},
+		{
+			name:     "all fields",
+			input:    "email:john@example.com age >= 18 name:*",
+			expected: "(email=REDACTED AND age>=REDACTED AND name=*)",
+		},
+		{
+			name:     "value list",
+			input:    "email:(a@example.com or b@example.com)",
+			fields:   []string{"email"},
+			expected: "email=(REDACTED OR REDACTED)",
+		},
+		{
+			name:     "nested field",
+			input:    "user:{email:john@example.com and state:active}",
+			fields:   []string{"user.email"},
+			expected: "user={(email=REDACTED AND state=active)}",
+		},
+		{
+			name:     "nested parent",
+			input:    "user:{email:john@example.com and state:active}",
+			fields:   []string{"user"},
+			expected: "user={(email=REDACTED AND state=REDACTED)}",
+		},
+		{
+			name:     "values without field",
+			input:    `"john doe" and true`,
+			fields:   []string{"email"},
+			expected: "(REDACTED AND true)",
+		},
+	}
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			ast, err := ParseAST(test.input)
+			require.NoError(t, err)
+			original := ast.String()
+
+			assert.Equal(t, test.expected, Redact(ast, test.fields...).String())
+			assert.Equal(t, original, ast.String())
+		})
+	}
+}
+
+func TestRedactFuzzy(t *testing.T) {
+	ast, err := ParseAST("name:jon~1", WithFuzzyMatching())
+	require.NoError(t, err)
+	assert.Equal(t, "name=REDACTED~1", Redact(ast, "name").String())
+}
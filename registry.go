@@ -0,0 +1,74 @@
+package kqlfilter
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ConverterFactory creates a Backend. Results are returned as any, so that backends producing different query types
+// can be registered side by side; use AnyBackend to adapt a typed Backend.
+type ConverterFactory func() Backend[any]
+
+var (
+	convertersMu sync.RWMutex
+	converters   = make(map[string]ConverterFactory)
+)
+
+func init() {
+	RegisterConverter("spanner", func() Backend[any] {
+		return AnyBackend(SpannerBackend())
+	})
+	RegisterConverter("postgres", func() Backend[any] {
+		return AnyBackend(PostgresBackend())
+	})
+	RegisterConverter("sqlite", func() Backend[any] {
+		return AnyBackend(SQLiteBackend())
+	})
+}
+
+// RegisterConverter makes a backend available by the provided name, so that it can be selected at runtime with
+// NewConverter. It is typically called from the init function of the package implementing the backend.
+// If RegisterConverter is called twice with the same name or if factory is nil, it panics.
+func RegisterConverter(name string, factory ConverterFactory) {
+	convertersMu.Lock()
+	defer convertersMu.Unlock()
+	if factory == nil {
+		panic("kqlfilter: RegisterConverter factory is nil")
+	}
+	if _, dup := converters[name]; dup {
+		panic("kqlfilter: RegisterConverter called twice for converter " + name)
+	}
+	converters[name] = factory
+}
+
+// NewConverter returns a new Backend created by the factory registered with the given name, for use with QueryFromKQL.
+func NewConverter(name string) (Backend[any], error) {
+	convertersMu.RLock()
+	factory, ok := converters[name]
+	convertersMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown converter %q (forgotten import?)", name)
+	}
+	return factory(), nil
+}
+
+// Converters returns the sorted names of the registered converters.
+func Converters() []string {
+	convertersMu.RLock()
+	defer convertersMu.RUnlock()
+	names := make([]string, 0, len(converters))
+	for name := range converters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// AnyBackend adapts a Backend producing queries of type T to a Backend producing any, e.g. to register it with
+// RegisterConverter.
+func AnyBackend[T any](backend Backend[T]) Backend[any] {
+	return BackendFunc[any](func(ast Node, schema Schema) (any, error) {
+		return backend.Convert(ast, schema)
+	})
+}
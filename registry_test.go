@@ -0,0 +1,41 @@
+package kqlfilter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterConverter(t *testing.T) {
+	RegisterConverter("test-fields", func() Backend[any] {
+		return BackendFunc[any](func(ast Node, schema Schema) (any, error) {
+			return ListFields(ast), nil
+		})
+	})
+	assert.Contains(t, Converters(), "test-fields")
+	assert.Contains(t, Converters(), "spanner")
+
+	backend, err := NewConverter("test-fields")
+	require.NoError(t, err)
+	result, err := QueryFromKQL("user_id:1", testQuerySchema, backend)
+	require.NoError(t, err)
+	assert.Equal(t, []FieldUsage{{Field: "user_id", Count: 1, Operators: []string{"="}}}, result)
+
+	backend, err = NewConverter("spanner")
+	require.NoError(t, err)
+	result, err = QueryFromKQL("user_id:1", testQuerySchema, backend)
+	require.NoError(t, err)
+	require.IsType(t, SpannerQuery{}, result)
+	assert.Equal(t, "user_id=@KQL0", result.(SpannerQuery).Where())
+
+	_, err = NewConverter("graph")
+	assert.EqualError(t, err, `unknown converter "graph" (forgotten import?)`)
+
+	assert.Panics(t, func() {
+		RegisterConverter("test-fields", func() Backend[any] { return nil })
+	})
+	assert.Panics(t, func() {
+		RegisterConverter("test-nil", nil)
+	})
+}
@@ -0,0 +1,42 @@
+package kqlfilter
+
+import (
+	"strings"
+	"time"
+)
+
+// Relative time keywords that can be used as values of timestamp fields, e.g. `created_at >= today`.
+// They are resolved at conversion time, using the clock and location configured with WithClock and
+// WithDefaultLocation.
+const (
+	// RelativeTimeNow is the current time.
+	RelativeTimeNow = "now"
+	// RelativeTimeToday is the start of the current day.
+	RelativeTimeToday = "today"
+	// RelativeTimeYesterday is the start of the previous day.
+	RelativeTimeYesterday = "yesterday"
+	// RelativeTimeThisWeek is the start of the current week. Weeks start on Monday.
+	RelativeTimeThisWeek = "this_week"
+)
+
+// resolveRelativeTime returns the time that the relative time keyword refers to.
+// It returns false if the value is not a relative time keyword.
+func (o converterOptions) resolveRelativeTime(value string) (time.Time, bool) {
+	now := o.now().In(o.location)
+	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, o.location)
+
+	switch strings.ToLower(value) {
+	case RelativeTimeNow:
+		return now, true
+	case RelativeTimeToday:
+		return startOfDay, true
+	case RelativeTimeYesterday:
+		return startOfDay.AddDate(0, 0, -1), true
+	case RelativeTimeThisWeek:
+		// time.Sunday is 0, so shift the week day to make Monday the first day of the week.
+		daysSinceMonday := (int(now.Weekday()) + 6) % 7
+		return startOfDay.AddDate(0, 0, -daysSinceMonday), true
+	default:
+		return time.Time{}, false
+	}
+}
@@ -0,0 +1,94 @@
+package kqlfilter
+
+import (
+	"testing"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveRelativeTime(t *testing.T) {
+	amsterdam, err := time.LoadLocation("Europe/Amsterdam")
+	require.NoError(t, err)
+
+	// Thursday, shortly after midnight in Amsterdam but still Wednesday in UTC.
+	now := time.Date(2024, 5, 1, 22, 30, 0, 0, time.UTC)
+
+	testCases := []struct {
+		value    string
+		location *time.Location
+		expected time.Time
+	}{
+		{"now", time.UTC, now},
+		{"today", time.UTC, time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)},
+		{"TODAY", time.UTC, time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)},
+		{"yesterday", time.UTC, time.Date(2024, 4, 30, 0, 0, 0, 0, time.UTC)},
+		{"this_week", time.UTC, time.Date(2024, 4, 29, 0, 0, 0, 0, time.UTC)},
+		{"today", amsterdam, time.Date(2024, 5, 2, 0, 0, 0, 0, amsterdam)},
+		{"yesterday", amsterdam, time.Date(2024, 5, 1, 0, 0, 0, 0, amsterdam)},
+		{"this_week", amsterdam, time.Date(2024, 4, 29, 0, 0, 0, 0, amsterdam)},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.value+" "+test.location.String(), func(t *testing.T) {
+			o := newConverterOptions([]ConverterOption{
+				WithClock(func() time.Time { return now }),
+				WithDefaultLocation(test.location),
+			})
+			resolved, ok := o.resolveRelativeTime(test.value)
+			require.True(t, ok)
+			assert.True(t, test.expected.Equal(resolved), "expected %s, got %s", test.expected, resolved)
+		})
+	}
+
+	_, ok := newConverterOptions(nil).resolveRelativeTime("tomorrow")
+	assert.False(t, ok)
+}
+
+func TestRelativeTimeConversion(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	clock := WithClock(func() time.Time { return now })
+
+	f, err := Parse("created_at >= yesterday created_at < today")
+	require.NoError(t, err)
+
+	conditions, params, err := f.ToSpannerSQL(map[string]FilterToSpannerFieldConfig{
+		"created_at": {
+			ColumnType:  FilterToSpannerFieldColumnTypeTimestamp,
+			AllowRanges: true,
+		},
+	}, clock)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"created_at>=@KQL0", "created_at<@KQL1"}, conditions)
+	assert.Equal(t, map[string]any{
+		"KQL0": time.Date(2024, 4, 30, 0, 0, 0, 0, time.UTC),
+		"KQL1": time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+	}, params)
+
+	stmt, err := f.ToSquirrelSql(sq.Select("*").From("orders"), map[string]FilterToSquirrelSqlFieldConfig{
+		"created_at": {
+			ColumnType:  FilterToSquirrelSqlFieldColumnTypeTimestamp,
+			AllowRanges: true,
+		},
+	}, clock)
+	require.NoError(t, err)
+	sql, args, err := stmt.ToSql()
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT * FROM orders WHERE created_at >= ? AND created_at < ?", sql)
+	assert.Equal(t, []any{
+		time.Date(2024, 4, 30, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+	}, args)
+
+	f, err = Parse("created_at >= last_week")
+	require.NoError(t, err)
+	_, _, err = f.ToSpannerSQL(map[string]FilterToSpannerFieldConfig{
+		"created_at": {
+			ColumnType:  FilterToSpannerFieldColumnTypeTimestamp,
+			AllowRanges: true,
+		},
+	}, clock)
+	assert.Error(t, err)
+}
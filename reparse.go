@@ -0,0 +1,187 @@
+package kqlfilter
+
+import (
+	"math"
+	"strings"
+)
+
+// ParseResult holds the result of parsing a filter, and allows it to be updated incrementally while the input is
+// being edited, e.g. for live validation in a query bar.
+type ParseResult struct {
+	// Input is the parsed filter string.
+	Input string
+	// Root is the root of the parsed tree, or nil if the input is empty or could not be parsed.
+	Root Node
+	// Err is the parse error, if any.
+	Err error
+
+	options  []ParserOption
+	segments []segment
+}
+
+// Edit describes a change to the input of a filter: Deleted bytes starting at byte Offset are replaced by Inserted.
+type Edit struct {
+	Offset   int
+	Deleted  int
+	Inserted string
+}
+
+// ParseIncremental parses the input like ParseAST, and returns a result that can be updated with ParseResult.Apply.
+func ParseIncremental(input string, options ...ParserOption) *ParseResult {
+	r := &ParseResult{Input: input, options: options}
+	if strings.TrimSpace(input) == "" {
+		return r
+	}
+	p := newParser(options)
+	p.trackSegments = true
+	r.Root, r.Err = p.run(input)
+	if r.Err == nil {
+		r.segments = p.segments
+	}
+	return r
+}
+
+// Apply returns the result of parsing the input with the edit applied.
+//
+// Only the top-level clauses touched by the edit are re-parsed; the trees of all other clauses are reused. Top-level
+// clauses are the clauses joined by whitespace only, so filters like `a:1 b:2 c:3` benefit the most. When the edit
+// changes the structure of the filter, or the previous input could not be parsed, the whole input is parsed again.
+//
+// Apply reuses the nodes of r and updates their positions, so r must not be used anymore after calling Apply.
+func (r *ParseResult) Apply(edit Edit) *ParseResult {
+	if edit.Offset < 0 || edit.Deleted < 0 || edit.Offset+edit.Deleted > len(r.Input) {
+		// Invalid edit; let the caller see the error of an input that does not match what it expects.
+		return ParseIncremental(r.Input, r.options...)
+	}
+	input := r.Input[:edit.Offset] + edit.Inserted + r.Input[edit.Offset+edit.Deleted:]
+	if r.Err != nil || len(r.segments) == 0 {
+		return ParseIncremental(input, r.options...)
+	}
+	if maxLength := newParser(r.options).maxInputLength; maxLength > 0 && len(input) > maxLength {
+		return ParseIncremental(input, r.options...)
+	}
+
+	editStart := Pos(edit.Offset)
+	editEnd := Pos(edit.Offset + edit.Deleted)
+	delta := Pos(len(edit.Inserted) - edit.Deleted)
+
+	// Find the range of segments touched by the edit. Segment i spans from its start to the start of segment i+1;
+	// the first segment also covers leading whitespace and the last one the end of the input.
+	first, last := -1, -1
+	for i := range r.segments {
+		start, end := r.segmentSpan(i)
+		if start <= editEnd && editStart <= end {
+			if first == -1 {
+				first = i
+			}
+			last = i
+		}
+	}
+	if first == -1 {
+		return ParseIncremental(input, r.options...)
+	}
+	regionStart, _ := r.segmentSpan(first)
+	_, regionEnd := r.segmentSpan(last)
+	regionEnd += delta
+
+	// The region must still be separated from the following clause by whitespace,
+	// otherwise the edit merged clauses and the structure changed.
+	if int(regionEnd) < len(input) && (regionEnd == regionStart || !isSpace(rune(input[regionEnd-1]))) {
+		return ParseIncremental(input, r.options...)
+	}
+
+	var regionSegments []segment
+	regionText := input[regionStart:regionEnd]
+	if strings.TrimSpace(regionText) != "" {
+		// Complexity is a limit over the whole filter, so it is checked after combining the segments.
+		p := newParser(append(r.options[:len(r.options):len(r.options)], WithMaxComplexity(math.MaxInt)))
+		p.trackSegments = true
+		if _, err := p.run(regionText); err != nil {
+			return ParseIncremental(input, r.options...)
+		}
+		regionSegments = p.segments
+		for i := range regionSegments {
+			regionSegments[i].start += regionStart
+			shiftPositions(regionSegments[i].node, regionStart)
+		}
+	}
+
+	segments := make([]segment, 0, len(r.segments)-(last-first+1)+len(regionSegments))
+	segments = append(segments, r.segments[:first]...)
+	segments = append(segments, regionSegments...)
+	for _, s := range r.segments[last+1:] {
+		s.start += delta
+		shiftPositions(s.node, delta)
+		segments = append(segments, s)
+	}
+
+	result := &ParseResult{Input: input, options: r.options, segments: segments}
+	complexity := 0
+	for _, s := range segments {
+		complexity += s.complexity
+	}
+	if complexity > newParser(r.options).maxComplexity {
+		// Let the full parser report the error with the right position.
+		return ParseIncremental(input, r.options...)
+	}
+
+	switch len(segments) {
+	case 0:
+	case 1:
+		result.Root = segments[0].node
+	default:
+		root := &AndNode{NodeType: NodeAnd, Pos: 0}
+		for _, s := range segments {
+			root.append(s.node)
+		}
+		result.Root = root
+	}
+	return result
+}
+
+// segmentSpan returns the byte range covered by segment i.
+func (r *ParseResult) segmentSpan(i int) (Pos, Pos) {
+	start := r.segments[i].start
+	if i == 0 {
+		start = 0
+	}
+	end := Pos(len(r.Input))
+	if i+1 < len(r.segments) {
+		end = r.segments[i+1].start
+	}
+	return start, end
+}
+
+// shiftPositions moves the positions of all nodes in the tree by delta.
+func shiftPositions(n Node, delta Pos) {
+	switch x := n.(type) {
+	case *OrNode:
+		x.Pos += delta
+		for _, child := range x.Nodes {
+			shiftPositions(child, delta)
+		}
+	case *AndNode:
+		x.Pos += delta
+		for _, child := range x.Nodes {
+			shiftPositions(child, delta)
+		}
+	case *NotNode:
+		x.Pos += delta
+		shiftPositions(x.Expr, delta)
+	case *IsNode:
+		x.Pos += delta
+		shiftPositions(x.Value, delta)
+	case *RangeNode:
+		x.Pos += delta
+		shiftPositions(x.Value, delta)
+	case *NestedNode:
+		x.Pos += delta
+		shiftPositions(x.Expr, delta)
+	case *ExistsNode:
+		x.Pos += delta
+	case *FuzzyNode:
+		x.Pos += delta
+	case *LiteralNode:
+		x.Pos += delta
+	}
+}
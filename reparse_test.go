@@ -0,0 +1,112 @@
+package kqlfilter
+
+import (
+	"encoding/json"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// assertSameAsFullParse checks that an incremental result is identical to parsing the input from scratch,
+// including node positions.
+func assertSameAsFullParse(t *testing.T, r *ParseResult, options ...ParserOption) {
+	t.Helper()
+	full := ParseIncremental(r.Input, options...)
+	if full.Err != nil {
+		require.Error(t, r.Err, "input %q", r.Input)
+		assert.Equal(t, full.Err.Error(), r.Err.Error(), "input %q", r.Input)
+		return
+	}
+	require.NoError(t, r.Err, "input %q", r.Input)
+	expected, err := json.Marshal(full.Root)
+	require.NoError(t, err)
+	actual, err := json.Marshal(r.Root)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(expected), string(actual), "input %q", r.Input)
+}
+
+func TestParseResultApply(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		edit     Edit
+		expected string
+	}{
+		{"replace value", "a:1 b:2 c:3", Edit{Offset: 6, Deleted: 1, Inserted: "42"}, "(a=1 AND b=42 AND c=3)"},
+		{"append clause", "a:1 b:2", Edit{Offset: 7, Inserted: " c:3"}, "(a=1 AND b=2 AND c=3)"},
+		{"prepend clause", "a:1 b:2", Edit{Offset: 0, Inserted: "z:0 "}, "(z=0 AND a=1 AND b=2)"},
+		{"delete clause", "a:1 b:2 c:3", Edit{Offset: 4, Deleted: 4}, "(a=1 AND c=3)"},
+		{"delete all but one", "a:1 b:2", Edit{Offset: 3, Deleted: 4}, "a=1"},
+		{"merge clauses", "a:1 b:2", Edit{Offset: 3, Deleted: 1, Inserted: " OR "}, "(a=1 OR b=2)"},
+		{"join into one value", "a:1 b:2", Edit{Offset: 3, Deleted: 1}, ""},
+		{"split clause", "a:1 OR b:2", Edit{Offset: 3, Deleted: 4, Inserted: " "}, "(a=1 AND b=2)"},
+		{"introduce error", "a:1 b:2 c:3", Edit{Offset: 5, Deleted: 1}, ""},
+		{"fix error", "a:1 b c:3", Edit{Offset: 5, Inserted: ":2"}, "(a=1 AND b=2 AND c=3)"},
+		{"clear input", "a:1 b:2", Edit{Offset: 0, Deleted: 7}, ""},
+		{"edit leading whitespace", "  a:1 b:2", Edit{Offset: 0, Deleted: 1}, "(a=1 AND b=2)"},
+		{"nested group", "a:1 b:(x OR y) c:3", Edit{Offset: 12, Deleted: 1, Inserted: "z"}, "(a=1 AND b=(x OR z) AND c=3)"},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			r := ParseIncremental(test.input).Apply(test.edit)
+			assertSameAsFullParse(t, r)
+			if test.expected != "" {
+				require.NotNil(t, r.Root)
+				assert.Equal(t, test.expected, r.Root.String())
+			}
+		})
+	}
+}
+
+func TestParseResultApplyReusesUntouchedClauses(t *testing.T) {
+	r := ParseIncremental("a:1 b:2 c:3")
+	require.NoError(t, r.Err)
+	first := r.Root.(*AndNode).Nodes[0]
+	last := r.Root.(*AndNode).Nodes[2]
+
+	r = r.Apply(Edit{Offset: 6, Deleted: 1, Inserted: "22"})
+	require.NoError(t, r.Err)
+	nodes := r.Root.(*AndNode).Nodes
+	assert.Same(t, first, nodes[0])
+	assert.Same(t, last, nodes[2])
+	assert.Equal(t, Pos(9), last.Position())
+}
+
+func TestParseResultApplyComplexity(t *testing.T) {
+	options := []ParserOption{WithMaxComplexity(2)}
+	r := ParseIncremental("a:1 OR b:1 c:1 OR d:1", options...)
+	require.NoError(t, r.Err)
+
+	r = r.Apply(Edit{Offset: 14, Inserted: " OR e:1"})
+	require.Error(t, r.Err)
+	assertSameAsFullParse(t, r, options...)
+}
+
+func TestParseResultApplyRandomEdits(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	fragments := []string{" ", "a", ":", "1", " OR ", " and ", "not ", "(", ")", "x:y", " z:2", "*", `"q"`, ">=", "{", "}"}
+
+	r := ParseIncremental("user:1 state:(active OR paused) created>=5 name:jo*")
+	for i := 0; i < 2000; i++ {
+		offset := 0
+		if len(r.Input) > 0 {
+			offset = rnd.Intn(len(r.Input) + 1)
+		}
+		deleted := 0
+		if rest := len(r.Input) - offset; rest > 0 && rnd.Intn(2) == 0 {
+			deleted = rnd.Intn(min(rest, 4) + 1)
+		}
+		inserted := ""
+		if rnd.Intn(3) > 0 {
+			inserted = fragments[rnd.Intn(len(fragments))]
+		}
+		r = r.Apply(Edit{Offset: offset, Deleted: deleted, Inserted: inserted})
+		assertSameAsFullParse(t, r)
+		if len(r.Input) > 120 {
+			r = ParseIncremental("a:1 b:2 c:3 d:4")
+		}
+	}
+}
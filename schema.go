@@ -0,0 +1,313 @@
+package kqlfilter
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// FieldType identifies the type of the values of a filterable field.
+type FieldType int
+
+const (
+	FieldTypeString FieldType = iota
+	FieldTypeInt64
+	FieldTypeFloat64
+	FieldTypeBool
+	FieldTypeTimestamp
+)
+
+func (t FieldType) String() string {
+	switch t {
+	case FieldTypeString:
+		return "string"
+	case FieldTypeInt64:
+		return "int64"
+	case FieldTypeFloat64:
+		return "float64"
+	case FieldTypeBool:
+		return "bool"
+	case FieldTypeTimestamp:
+		return "timestamp"
+	default:
+		return "???"
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (t FieldType) MarshalText() ([]byte, error) {
+	s := t.String()
+	if s == "???" {
+		return nil, fmt.Errorf("unknown field type %d", int(t))
+	}
+	return []byte(s), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (t *FieldType) UnmarshalText(text []byte) error {
+	for ft := FieldTypeString; ft <= FieldTypeTimestamp; ft++ {
+		if ft.String() == string(text) {
+			*t = ft
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown field type %q", text)
+}
+
+// ParseValue converts a value as provided by the user to the Go type of the field type: string, int64, float64, bool
+// or time.Time. Timestamps are parsed as RFC3339 or one of the layouts set with WithTimeLayouts, or as one of the
+// relative time keywords such as `today` (see RelativeTimeToday), which are resolved using the clock and location set
+// with WithClock and WithDefaultLocation.
+// It is useful for converters outside of this package.
+func (t FieldType) ParseValue(value string, options ...ConverterOption) (any, error) {
+	switch t {
+	case FieldTypeInt64:
+		v, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid int64 value: %w", err)
+		}
+		return v, nil
+	case FieldTypeFloat64:
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid float64 value: %w", err)
+		}
+		return v, nil
+	case FieldTypeBool:
+		v, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bool value: %w", err)
+		}
+		return v, nil
+	case FieldTypeTimestamp:
+		o := newConverterOptions(options)
+		v, err := o.parseTime(value)
+		if err != nil {
+			if relative, ok := o.resolveRelativeTime(value); ok {
+				return relative, nil
+			}
+			return nil, fmt.Errorf("invalid timestamp value: %w", err)
+		}
+		return v, nil
+	default:
+		return value, nil
+	}
+}
+
+// FieldSchema describes a field that is allowed to be used in a filter, independently of the backend the filter is
+// converted to.
+type FieldSchema struct {
+	// Name of the column or document field in the backend. Can be omitted if it is equal to the key in the Schema.
+	Column string
+	// Type of the field values. Defaults to FieldTypeString.
+	Type FieldType
+	// A list of aliases for this field, e.g. to accept both `type_id` and `typeId`.
+	Aliases []string
+	// If true, the filter must at least contain this field. Will not apply to empty filters. Defaults to false.
+	Required bool
+	// Match values as analyzed full-text, with `match` and `match_phrase` queries instead of term queries. Only
+	// supported by the Elasticsearch and OpenSearch backends. Defaults to false.
+	Text bool
+	// Allow prefix matching when a wildcard (`*`) is present at the end of a string. Defaults to false.
+	AllowPrefixMatch bool
+	// Allow suffix matching when a wildcard (`*`) is present at the beginning of a string. Defaults to false.
+	AllowSuffixMatch bool
+	// Match string values case-insensitively. See the backend field configs for the exact semantics. Defaults to false.
+	AllowCaseInsensitiveMatch bool
+	// Allow multiple values for this field. Defaults to false.
+	AllowMultipleValues bool
+	// Allow negated matching of multiple values (e.g. `not state:(a OR b)`). Defaults to false.
+	AllowNegation bool
+	// Allow this field to be queried with one or more range operators. Defaults to false.
+	AllowRanges bool
+	// Allow range operators on a string field, which compare values lexicographically. Only applicable in combination
+	// with AllowRanges. Defaults to false.
+	AllowStringRanges bool
+	// A function that takes a string value as provided by the user and converts it to the value stored in the backend.
+	// It should return an error when the user provides an illegal value. Defaults to using the value as-is.
+	MapValue func(string) (any, error)
+	// The values that are allowed for this field, e.g. the values of an enum. Defaults to allowing any value.
+	AllowedValues []string
+	// Which of the AllowedValues are revealed when a value is rejected. Supported by the Spanner and Squirrel backends
+	// and by Validate. Defaults to ValueSuggestionsAll.
+	ValueSuggestions ValueSuggestions
+}
+
+// Schema describes all fields that are allowed to be used in a filter, keyed by their canonical name.
+type Schema map[string]FieldSchema
+
+// Lookup returns the canonical name and schema of the given field, resolving aliases.
+func (s Schema) Lookup(field string) (string, FieldSchema, bool) {
+	if fs, ok := s[field]; ok {
+		return field, fs, true
+	}
+	for name, fs := range s {
+		for _, alias := range fs.Aliases {
+			if alias == field {
+				return name, fs, true
+			}
+		}
+	}
+	return "", FieldSchema{}, false
+}
+
+// FieldNames returns the names and aliases of all fields in the schema, sorted.
+func (s Schema) FieldNames() []string {
+	names := make([]string, 0, len(s))
+	for name, fs := range s {
+		names = append(names, name)
+		names = append(names, fs.Aliases...)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ColumnName returns the name of the column or document field of the given canonical field name.
+func (s Schema) ColumnName(field string) string {
+	if fs, ok := s[field]; ok && fs.Column != "" {
+		return fs.Column
+	}
+	return field
+}
+
+// SpannerFieldConfigs returns the field configs to use with Filter.ToSpannerSQL.
+func (s Schema) SpannerFieldConfigs() map[string]FilterToSpannerFieldConfig {
+	configs := make(map[string]FilterToSpannerFieldConfig, len(s))
+	for name, fs := range s {
+		var columnType FilterToSpannerFieldColumnType
+		switch fs.Type {
+		case FieldTypeInt64:
+			columnType = FilterToSpannerFieldColumnTypeInt64
+		case FieldTypeFloat64:
+			columnType = FilterToSpannerFieldColumnTypeFloat64
+		case FieldTypeBool:
+			columnType = FilterToSpannerFieldColumnTypeBool
+		case FieldTypeTimestamp:
+			columnType = FilterToSpannerFieldColumnTypeTimestamp
+		default:
+			columnType = FilterToSpannerFieldColumnTypeString
+		}
+		configs[name] = FilterToSpannerFieldConfig{
+			ColumnName:                s.ColumnName(name),
+			ColumnType:                columnType,
+			Required:                  fs.Required,
+			AllowPrefixMatch:          fs.AllowPrefixMatch,
+			AllowSuffixMatch:          fs.AllowSuffixMatch,
+			AllowCaseInsensitiveMatch: fs.AllowCaseInsensitiveMatch,
+			AllowMultipleValues:       fs.AllowMultipleValues,
+			AllowNegation:             fs.AllowNegation,
+			AllowRanges:               fs.AllowRanges,
+			AllowStringRanges:         fs.AllowStringRanges,
+			Aliases:                   fs.Aliases,
+			MapValue:                  fs.MapValue,
+			AllowedValues:             fs.AllowedValues,
+			ValueSuggestions:          fs.ValueSuggestions,
+		}
+	}
+	return configs
+}
+
+// fieldOptions returns the settings of the field that the field configs of most converters share.
+func (fs FieldSchema) fieldOptions() FieldOptions {
+	return FieldOptions{
+		Required:            fs.Required,
+		AllowMultipleValues: fs.AllowMultipleValues,
+		AllowNegation:       fs.AllowNegation,
+		AllowRanges:         fs.AllowRanges,
+		Aliases:             fs.Aliases,
+		MapValue:            fs.MapValue,
+		AllowedValues:       fs.AllowedValues,
+	}
+}
+
+// PostgresFieldConfigs returns the field configs to use with Filter.ToPostgresSQL.
+func (s Schema) PostgresFieldConfigs() map[string]FilterToPostgresFieldConfig {
+	configs := make(map[string]FilterToPostgresFieldConfig, len(s))
+	for name, fs := range s {
+		var columnType FilterToPostgresFieldColumnType
+		switch fs.Type {
+		case FieldTypeInt64:
+			columnType = FilterToPostgresFieldColumnTypeBigInt
+		case FieldTypeFloat64:
+			columnType = FilterToPostgresFieldColumnTypeDouble
+		case FieldTypeBool:
+			columnType = FilterToPostgresFieldColumnTypeBoolean
+		case FieldTypeTimestamp:
+			columnType = FilterToPostgresFieldColumnTypeTimestampTZ
+		default:
+			columnType = FilterToPostgresFieldColumnTypeText
+		}
+		configs[name] = FilterToPostgresFieldConfig{
+			ColumnName:                s.ColumnName(name),
+			ColumnType:                columnType,
+			AllowPrefixMatch:          fs.AllowPrefixMatch,
+			AllowSuffixMatch:          fs.AllowSuffixMatch,
+			AllowCaseInsensitiveMatch: fs.AllowCaseInsensitiveMatch,
+			FieldOptions:              fs.fieldOptions(),
+		}
+	}
+	return configs
+}
+
+// SQLiteFieldConfigs returns the field configs to use with Filter.ToSQLiteSQL.
+// Timestamps are assumed to be stored as text, see FilterToSQLiteFieldColumnTypeDatetime.
+func (s Schema) SQLiteFieldConfigs() map[string]FilterToSQLiteFieldConfig {
+	configs := make(map[string]FilterToSQLiteFieldConfig, len(s))
+	for name, fs := range s {
+		var columnType FilterToSQLiteFieldColumnType
+		switch fs.Type {
+		case FieldTypeInt64:
+			columnType = FilterToSQLiteFieldColumnTypeInteger
+		case FieldTypeFloat64:
+			columnType = FilterToSQLiteFieldColumnTypeReal
+		case FieldTypeBool:
+			columnType = FilterToSQLiteFieldColumnTypeBoolean
+		case FieldTypeTimestamp:
+			columnType = FilterToSQLiteFieldColumnTypeDatetime
+		default:
+			columnType = FilterToSQLiteFieldColumnTypeText
+		}
+		configs[name] = FilterToSQLiteFieldConfig{
+			ColumnName:       s.ColumnName(name),
+			ColumnType:       columnType,
+			AllowPrefixMatch: fs.AllowPrefixMatch,
+			AllowSuffixMatch: fs.AllowSuffixMatch,
+			FieldOptions:     fs.fieldOptions(),
+		}
+	}
+	return configs
+}
+
+// SquirrelFieldConfigs returns the field configs to use with Filter.ToSquirrelSql.
+func (s Schema) SquirrelFieldConfigs() map[string]FilterToSquirrelSqlFieldConfig {
+	configs := make(map[string]FilterToSquirrelSqlFieldConfig, len(s))
+	for name, fs := range s {
+		var columnType FilterToSquirrelSqlFieldColumnType
+		switch fs.Type {
+		case FieldTypeInt64:
+			columnType = FilterToSquirrelSqlFieldColumnTypeInt64
+		case FieldTypeFloat64:
+			columnType = FilterToSquirrelSqlFieldColumnTypeFloat64
+		case FieldTypeBool:
+			columnType = FilterToSquirrelSqlFieldColumnTypeBool
+		case FieldTypeTimestamp:
+			columnType = FilterToSquirrelSqlFieldColumnTypeTimestamp
+		default:
+			columnType = FilterToSquirrelSqlFieldColumnTypeString
+		}
+		config := FilterToSquirrelSqlFieldConfig{
+			ColumnName:                s.ColumnName(name),
+			ColumnType:                columnType,
+			AllowPrefixMatch:          fs.AllowPrefixMatch,
+			AllowCaseInsensitiveMatch: fs.AllowCaseInsensitiveMatch,
+			AllowMultipleValues:       fs.AllowMultipleValues,
+			AllowRanges:               fs.AllowRanges,
+			Aliases:                   fs.Aliases,
+			MapValue:                  fs.MapValue,
+			AllowedValues:             fs.AllowedValues,
+			ValueSuggestions:          fs.ValueSuggestions,
+		}
+		configs[name] = config
+	}
+	return configs
+}
@@ -0,0 +1,79 @@
+package kqlfilter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchemaLookup(t *testing.T) {
+	name, fs, ok := testQuerySchema.Lookup("userId")
+	assert.True(t, ok)
+	assert.Equal(t, "user_id", name)
+	assert.Equal(t, FieldTypeInt64, fs.Type)
+
+	name, _, ok = testQuerySchema.Lookup("state")
+	assert.True(t, ok)
+	assert.Equal(t, "state", name)
+
+	_, _, ok = testQuerySchema.Lookup("unknown")
+	assert.False(t, ok)
+
+	assert.Equal(t, "create_time", testQuerySchema.ColumnName("created_at"))
+	assert.Equal(t, "state", testQuerySchema.ColumnName("state"))
+}
+
+func TestSchemaFieldConfigs(t *testing.T) {
+	spannerConfigs := testQuerySchema.SpannerFieldConfigs()
+	assert.Len(t, spannerConfigs, 4)
+	assert.Equal(t, FilterToSpannerFieldColumnTypeTimestamp, spannerConfigs["created_at"].ColumnType)
+	assert.Equal(t, "create_time", spannerConfigs["created_at"].ColumnName)
+	assert.Equal(t, []string{"userId"}, spannerConfigs["user_id"].Aliases)
+
+	squirrelConfigs := testQuerySchema.SquirrelFieldConfigs()
+	assert.Len(t, squirrelConfigs, 4)
+	assert.Equal(t, "user_id", squirrelConfigs["user_id"].ColumnName)
+	assert.Equal(t, FilterToSquirrelSqlFieldColumnTypeInt64, squirrelConfigs["user_id"].ColumnType)
+	assert.Equal(t, []string{"userId"}, squirrelConfigs["user_id"].Aliases)
+}
+
+func TestFieldTypeText(t *testing.T) {
+	for ft := FieldTypeString; ft <= FieldTypeTimestamp; ft++ {
+		text, err := ft.MarshalText()
+		require.NoError(t, err)
+		var decoded FieldType
+		require.NoError(t, decoded.UnmarshalText(text))
+		assert.Equal(t, ft, decoded)
+	}
+
+	var ft FieldType
+	assert.EqualError(t, ft.UnmarshalText([]byte("int32")), `unknown field type "int32"`)
+	_, err := FieldType(42).MarshalText()
+	assert.Error(t, err)
+}
+
+func TestFieldTypeParseValue(t *testing.T) {
+	now := time.Date(2024, 5, 1, 15, 4, 5, 0, time.UTC)
+	testCases := []struct {
+		fieldType FieldType
+		input     string
+		expected  any
+	}{
+		{FieldTypeString, "abc", "abc"},
+		{FieldTypeInt64, "12", int64(12)},
+		{FieldTypeFloat64, "1.5", 1.5},
+		{FieldTypeBool, "true", true},
+		{FieldTypeTimestamp, "2024-01-02T03:04:05Z", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{FieldTypeTimestamp, "today", time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, test := range testCases {
+		v, err := test.fieldType.ParseValue(test.input, WithClock(func() time.Time { return now }))
+		require.NoError(t, err, test.input)
+		assert.Equal(t, test.expected, v, test.input)
+	}
+
+	_, err := FieldTypeInt64.ParseValue("abc")
+	assert.EqualError(t, err, `invalid int64 value: strconv.ParseInt: parsing "abc": invalid syntax`)
+}
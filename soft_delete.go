@@ -0,0 +1,40 @@
+package kqlfilter
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// IncludeDeletedField is a reserved pseudo-field to include soft-deleted rows, written as `_include_deleted:true`.
+// It is only recognized when soft deletes are configured with WithSoftDelete; it then suppresses the condition
+// excluding deleted rows instead of being converted to a column comparison.
+const IncludeDeletedField = "_include_deleted"
+
+// extractIncludeDeleted removes the IncludeDeletedField clause from the filter and returns the filter without it,
+// together with whether soft-deleted rows should be included. The filter is returned as-is if soft deletes are not
+// configured.
+func (o converterOptions) extractIncludeDeleted(f Filter) (Filter, bool, error) {
+	if o.softDeleteColumn == "" {
+		return f, false, nil
+	}
+	includeDeleted := false
+	clauses := make([]Clause, 0, len(f.Clauses))
+	for _, clause := range f.Clauses {
+		if clause.Field != IncludeDeletedField {
+			clauses = append(clauses, clause)
+			continue
+		}
+		if !o.allowIncludeDeleted {
+			return Filter{}, false, fmt.Errorf("field %s: not allowed", IncludeDeletedField)
+		}
+		if clause.Operator != "=" || len(clause.Values) != 1 {
+			return Filter{}, false, fmt.Errorf("field %s: operator %s not supported", IncludeDeletedField, clause.Operator)
+		}
+		value, err := strconv.ParseBool(clause.Values[0])
+		if err != nil {
+			return Filter{}, false, fmt.Errorf("field %s: invalid BOOL value: %w", IncludeDeletedField, err)
+		}
+		includeDeleted = includeDeleted || value
+	}
+	return Filter{Clauses: clauses, Groups: f.Groups}, includeDeleted, nil
+}
@@ -0,0 +1,118 @@
+package kqlfilter
+
+import (
+	"testing"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSoftDelete(t *testing.T) {
+	spannerConfigs := map[string]FilterToSpannerFieldConfig{"state": {}}
+	squirrelConfigs := map[string]FilterToSquirrelSqlFieldConfig{"state": {}}
+
+	testCases := []struct {
+		name             string
+		input            string
+		options          []ConverterOption
+		expectedSpanner  []string
+		expectedParams   map[string]any
+		expectedSquirrel string
+		expectedError    string
+	}{
+		{
+			"deleted rows excluded",
+			"state:active",
+			[]ConverterOption{WithSoftDelete("deleted", false)},
+			[]string{"state=@KQL0", "deleted=@KQL1"},
+			map[string]any{"KQL0": "active", "KQL1": false},
+			"SELECT * FROM t WHERE state = ? AND deleted = ?",
+			"",
+		},
+		{
+			"deleted rows excluded for empty filter",
+			"",
+			[]ConverterOption{WithSoftDelete("deleted", false)},
+			[]string{"deleted=@KQL0"},
+			map[string]any{"KQL0": false},
+			"SELECT * FROM t WHERE deleted = ?",
+			"",
+		},
+		{
+			"deleted rows included",
+			"state:active _include_deleted:true",
+			[]ConverterOption{WithSoftDelete("deleted", true)},
+			[]string{"state=@KQL0"},
+			map[string]any{"KQL0": "active"},
+			"SELECT * FROM t WHERE state = ?",
+			"",
+		},
+		{
+			"include deleted set to false",
+			"_include_deleted:false",
+			[]ConverterOption{WithSoftDelete("deleted", true)},
+			[]string{"deleted=@KQL0"},
+			map[string]any{"KQL0": false},
+			"SELECT * FROM t WHERE deleted = ?",
+			"",
+		},
+		{
+			"include deleted not allowed",
+			"state:active _include_deleted:true",
+			[]ConverterOption{WithSoftDelete("deleted", false)},
+			nil,
+			nil,
+			"",
+			"field _include_deleted: not allowed",
+		},
+		{
+			"invalid value",
+			"_include_deleted:yes",
+			[]ConverterOption{WithSoftDelete("deleted", true)},
+			nil,
+			nil,
+			"",
+			`field _include_deleted: invalid BOOL value: strconv.ParseBool: parsing "yes": invalid syntax`,
+		},
+		{
+			"soft delete not configured",
+			"_include_deleted:true",
+			nil,
+			nil,
+			nil,
+			"",
+			"unknown field: _include_deleted",
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			var f Filter
+			if test.input != "" {
+				var err error
+				f, err = Parse(test.input)
+				require.NoError(t, err)
+			}
+
+			conditions, params, err := f.ToSpannerSQL(spannerConfigs, test.options...)
+			if test.expectedError != "" {
+				require.EqualError(t, err, test.expectedError)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, test.expectedSpanner, conditions)
+				assert.Equal(t, test.expectedParams, params)
+			}
+
+			stmt, err := f.ToSquirrelSql(sq.Select("*").From("t"), squirrelConfigs, test.options...)
+			if test.expectedError != "" {
+				require.ErrorContains(t, err, test.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			sql, _, err := stmt.ToSql()
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedSquirrel, sql)
+		})
+	}
+}
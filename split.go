@@ -0,0 +1,91 @@
+package kqlfilter
+
+import "fmt"
+
+// SplitAST partitions the AST into a part that only references supported fields, which can be pushed down to the
+// target store, and a residual part that has to be evaluated otherwise, e.g. in memory on the results of the pushdown
+// query. Matching both parts is equivalent to matching the original AST.
+//
+// Only the top-level conjunction is split: a clause is pushed down as a whole if all fields it references are
+// supported, so e.g. `a:1 or b:2` is residual if either a or b is unsupported. Fields in nested queries
+// (`parent:{child:value}`) are passed to supported joined with a dot (`parent.child`).
+// Either part is nil if it is empty. The returned parts share nodes with the input AST.
+func SplitAST(node Node, supported func(field string) bool) (pushdown Node, residual Node, err error) {
+	if node == nil {
+		return nil, nil, nil
+	}
+	var pushdownNodes, residualNodes []Node
+	for _, conjunct := range conjuncts(node) {
+		ok, err := allFieldsSupported(conjunct, "", supported)
+		if err != nil {
+			return nil, nil, err
+		}
+		if ok {
+			pushdownNodes = append(pushdownNodes, conjunct)
+		} else {
+			residualNodes = append(residualNodes, conjunct)
+		}
+	}
+	return joinConjuncts(pushdownNodes), joinConjuncts(residualNodes), nil
+}
+
+// conjuncts returns the operands of the top-level conjunction, flattening nested AND nodes.
+func conjuncts(node Node) []Node {
+	and, ok := node.(*AndNode)
+	if !ok {
+		return []Node{node}
+	}
+	var nodes []Node
+	for _, child := range and.Nodes {
+		nodes = append(nodes, conjuncts(child)...)
+	}
+	return nodes
+}
+
+// joinConjuncts returns the conjunction of the nodes, or nil if there are none.
+func joinConjuncts(nodes []Node) Node {
+	switch len(nodes) {
+	case 0:
+		return nil
+	case 1:
+		return nodes[0]
+	default:
+		return &AndNode{NodeType: NodeAnd, Pos: nodes[0].Position(), Nodes: nodes}
+	}
+}
+
+func allFieldsSupported(ast Node, prefix string, supported func(field string) bool) (bool, error) {
+	switch n := ast.(type) {
+	case *AndNode:
+		return allNodesSupported(n.Nodes, prefix, supported)
+	case *OrNode:
+		return allNodesSupported(n.Nodes, prefix, supported)
+	case *NotNode:
+		return allFieldsSupported(n.Expr, prefix, supported)
+	case *IsNode:
+		if nested, ok := n.Value.(*NestedNode); ok {
+			return allFieldsSupported(nested.Expr, prefix+n.Identifier+".", supported)
+		}
+		return supported(prefix + n.Identifier), nil
+	case *RangeNode:
+		return supported(prefix + n.Identifier), nil
+	case *ExistsNode:
+		return supported(prefix + n.Identifier), nil
+	case *FuzzyNode:
+		return supported(prefix + n.Identifier), nil
+	case *LiteralNode:
+		return true, nil
+	default:
+		return false, fmt.Errorf("unsupported node type %s", ast.Type())
+	}
+}
+
+func allNodesSupported(nodes []Node, prefix string, supported func(field string) bool) (bool, error) {
+	for _, child := range nodes {
+		ok, err := allFieldsSupported(child, prefix, supported)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,100 @@
+package kqlfilter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitAST(t *testing.T) {
+	supported := func(field string) bool {
+		return field == "state" || field == "created_at" || field == "owner.id"
+	}
+	testCases := []struct {
+		name             string
+		input            string
+		expectedPushdown string
+		expectedResidual string
+	}{
+		{
+			"all supported",
+			"state:active and created_at >= 1",
+			"state:active and created_at >= 1",
+			"",
+		},
+		{
+			"none supported",
+			"title:foo",
+			"",
+			"title:foo",
+		},
+		{
+			"mixed conjunction",
+			"state:active and title:foo* and created_at >= 1",
+			"state:active and created_at >= 1",
+			"title:foo*",
+		},
+		{
+			"disjunction is not split",
+			"state:active and (title:foo or state:canceled)",
+			"state:active",
+			"title:foo or state:canceled",
+		},
+		{
+			"negation",
+			"not title:foo and not state:active",
+			"not state:active",
+			"not title:foo",
+		},
+		{
+			"nested conjunctions are flattened",
+			"(state:active and title:foo) and (created_at < 5 and description:bar)",
+			"state:active and created_at < 5",
+			"title:foo and description:bar",
+		},
+		{
+			"nested query",
+			"owner:{id:1} and owner:{name:john}",
+			"owner:{id:1}",
+			"owner:{name:john}",
+		},
+		{
+			"boolean literal",
+			"true and title:foo",
+			"true",
+			"title:foo",
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			ast, err := ParseAST(test.input)
+			require.NoError(t, err)
+
+			pushdown, residual, err := SplitAST(ast, supported)
+			require.NoError(t, err)
+			assertSplitPart(t, test.expectedPushdown, pushdown)
+			assertSplitPart(t, test.expectedResidual, residual)
+		})
+	}
+}
+
+func TestSplitASTNil(t *testing.T) {
+	pushdown, residual, err := SplitAST(nil, func(string) bool { return true })
+	require.NoError(t, err)
+	assert.Nil(t, pushdown)
+	assert.Nil(t, residual)
+}
+
+func assertSplitPart(t *testing.T, expected string, part Node) {
+	t.Helper()
+	if expected == "" {
+		assert.Nil(t, part)
+		return
+	}
+	require.NotNil(t, part)
+	expectedAST, err := ParseAST(expected)
+	require.NoError(t, err)
+	assert.Equal(t, expectedAST.String(), part.String())
+}
@@ -0,0 +1,197 @@
+package kqlfilter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// maxSuggestions is the maximum number of suggestions returned by closestMatches.
+const maxSuggestions = 3
+
+// UnknownFieldError is returned when a filter references a field that is not configured.
+type UnknownFieldError struct {
+	// Field as provided in the filter.
+	Field string
+	// Configured field names or aliases that are close to Field, closest first. May be empty.
+	Suggestions []string
+}
+
+// NewUnknownFieldError returns an UnknownFieldError for the field, suggesting the closest of the known field names.
+func NewUnknownFieldError(field string, known []string) *UnknownFieldError {
+	return &UnknownFieldError{Field: field, Suggestions: closestMatches(field, known)}
+}
+
+func (e *UnknownFieldError) Error() string {
+	msg := fmt.Sprintf("unknown field: %s", e.Field)
+	if len(e.Suggestions) > 0 {
+		msg += fmt.Sprintf("; did you mean %s?", joinAlternatives(e.Suggestions))
+	}
+	return msg
+}
+
+// Is reports whether target is ErrUnknownField or the error used by the Squirrel converter for unknown fields,
+// so existing errors.Is checks keep working.
+func (e *UnknownFieldError) Is(target error) bool {
+	return target == ErrUnknownField || target == unknownFieldErr
+}
+
+// joinAlternatives formats values as `a`, `a or b`, or `a, b or c`.
+func joinAlternatives(values []string) string {
+	if len(values) == 1 {
+		return values[0]
+	}
+	return strings.Join(values[:len(values)-1], ", ") + " or " + values[len(values)-1]
+}
+
+// closestMatches returns up to maxSuggestions candidates that are within a small edit distance of target,
+// ordered by distance. Comparison is case-insensitive.
+func closestMatches(target string, candidates []string) []string {
+	type match struct {
+		value    string
+		distance int
+	}
+	// Allow roughly one edit per three characters, so short names don't match everything.
+	maxDistance := min(max((len(target)+2)/3, 1), 3)
+	lowerTarget := strings.ToLower(target)
+
+	var matches []match
+	seen := make(map[string]bool, len(candidates))
+	for _, c := range candidates {
+		if seen[c] || c == target {
+			continue
+		}
+		seen[c] = true
+		d := editDistance(lowerTarget, strings.ToLower(c))
+		if d <= maxDistance {
+			matches = append(matches, match{value: c, distance: d})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].distance != matches[j].distance {
+			return matches[i].distance < matches[j].distance
+		}
+		return matches[i].value < matches[j].value
+	})
+
+	var result []string
+	for i := 0; i < len(matches) && i < maxSuggestions; i++ {
+		result = append(result, matches[i].value)
+	}
+	return result
+}
+
+// editDistance returns the Levenshtein distance between a and b, counting a transposition of two adjacent
+// characters as a single edit (optimal string alignment distance).
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	// Three rows are needed to detect transpositions.
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(rb)]
+}
+
+// InvalidValueError is returned when a value is not allowed for a field, either because it is not one of the
+// advertised allowed values or because the field's MapValue function rejected it.
+type InvalidValueError struct {
+	// Value as provided in the filter.
+	Value string
+	// Error returned by MapValue, or nil if the value is not one of AllowedValues.
+	Err error
+	// Values allowed for the field, if advertised by the field config.
+	AllowedValues []string
+	// Allowed values that are close to Value, closest first. May be empty.
+	Suggestions []string
+}
+
+// NewInvalidValueError returns an InvalidValueError for value, suggesting the closest allowed values.
+// err is the reason the value is invalid, if any.
+func NewInvalidValueError(value string, err error, allowedValues []string) *InvalidValueError {
+	return &InvalidValueError{
+		Value:         value,
+		Err:           err,
+		AllowedValues: allowedValues,
+		Suggestions:   closestMatches(value, allowedValues),
+	}
+}
+
+func (e *InvalidValueError) Error() string {
+	msg := fmt.Sprintf("invalid value %q", e.Value)
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	if len(e.Suggestions) > 0 {
+		msg += fmt.Sprintf("; did you mean %s?", joinAlternatives(e.Suggestions))
+	}
+	if len(e.AllowedValues) > 0 {
+		msg += fmt.Sprintf(" (allowed values: %s)", strings.Join(e.AllowedValues, ", "))
+	}
+	return msg
+}
+
+func (e *InvalidValueError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is ErrValueInvalid.
+func (e *InvalidValueError) Is(target error) bool {
+	return target == ErrValueInvalid
+}
+
+// checkAllowedValue returns an InvalidValueError if allowedValues is not empty and does not contain value.
+func checkAllowedValue(value string, allowedValues []string) error {
+	if len(allowedValues) == 0 {
+		return nil
+	}
+	for _, v := range allowedValues {
+		if v == value {
+			return nil
+		}
+	}
+	return NewInvalidValueError(value, nil, allowedValues)
+}
+
+// ValueSuggestions controls which of the allowed values of a field are revealed by an InvalidValueError, e.g. to avoid
+// leaking internal values of a field to users.
+type ValueSuggestions int
+
+const (
+	// ValueSuggestionsAll lists all allowed values and suggests the closest ones. This is the default.
+	ValueSuggestionsAll ValueSuggestions = iota
+	// ValueSuggestionsClosest only suggests the allowed values that are close to the invalid value.
+	ValueSuggestionsClosest
+	// ValueSuggestionsNone reveals none of the allowed values.
+	ValueSuggestionsNone
+)
+
+// apply removes the allowed values that may not be revealed from err, if it is an InvalidValueError.
+func (s ValueSuggestions) apply(err error) error {
+	valueErr, ok := err.(*InvalidValueError)
+	if !ok || s == ValueSuggestionsAll {
+		return err
+	}
+	limited := *valueErr
+	limited.AllowedValues = nil
+	if s == ValueSuggestionsNone {
+		limited.Suggestions = nil
+	}
+	return &limited
+}
@@ -0,0 +1,169 @@
+package kqlfilter
+
+import (
+	"errors"
+	"testing"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEditDistance(t *testing.T) {
+	testCases := []struct {
+		a, b     string
+		expected int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"", "abc", 3},
+		{"email", "email", 0},
+		{"emial", "email", 1},
+		{"kitten", "sitting", 3},
+		{"user_id", "userid", 1},
+		{"état", "etat", 1},
+	}
+	for _, test := range testCases {
+		assert.Equal(t, test.expected, editDistance(test.a, test.b), "%s -> %s", test.a, test.b)
+	}
+}
+
+func TestClosestMatches(t *testing.T) {
+	candidates := []string{"user_id", "userId", "email", "state", "status", "created_at"}
+
+	assert.Equal(t, []string{"email"}, closestMatches("emial", candidates))
+	assert.Equal(t, []string{"userId", "user_id"}, closestMatches("userid", candidates))
+	assert.Equal(t, []string{"state", "status"}, closestMatches("stat", candidates))
+	assert.Nil(t, closestMatches("foo", candidates))
+	assert.Nil(t, closestMatches("id", candidates))
+}
+
+func TestUnknownFieldError(t *testing.T) {
+	err := NewUnknownFieldError("emial", []string{"email", "name"})
+	assert.Equal(t, "unknown field: emial; did you mean email?", err.Error())
+
+	err = NewUnknownFieldError("stat", []string{"state", "status", "start"})
+	assert.Equal(t, "unknown field: stat; did you mean start, state or status?", err.Error())
+
+	err = NewUnknownFieldError("foo", []string{"email"})
+	assert.Equal(t, "unknown field: foo", err.Error())
+}
+
+func TestUnknownFieldErrorFromConverters(t *testing.T) {
+	f, err := Parse("emial:john@example.com")
+	require.NoError(t, err)
+
+	_, _, err = f.ToSpannerSQL(map[string]FilterToSpannerFieldConfig{
+		"email_address": {Aliases: []string{"email"}},
+		"name":          {},
+	})
+	var unknownField *UnknownFieldError
+	require.ErrorAs(t, err, &unknownField)
+	assert.Equal(t, "emial", unknownField.Field)
+	assert.Equal(t, []string{"email"}, unknownField.Suggestions)
+
+	_, err = f.ToSquirrelSql(sq.Select("*").From("users"), map[string]FilterToSquirrelSqlFieldConfig{
+		"email": {},
+	})
+	require.ErrorAs(t, err, &unknownField)
+	assert.Equal(t, []string{"email"}, unknownField.Suggestions)
+	assert.True(t, errors.Is(err, unknownFieldErr))
+
+	_, err = QueryFromKQL("userid:1", testQuerySchema, SpannerBackend())
+	require.ErrorAs(t, err, &unknownField)
+	assert.Equal(t, "unknown field: userid; did you mean userId or user_id?", err.Error())
+}
+
+func TestInvalidValueError(t *testing.T) {
+	allowed := []string{"active", "canceled", "expired"}
+
+	err := checkAllowedValue("actve", allowed)
+	assert.Equal(t, `invalid value "actve"; did you mean active? (allowed values: active, canceled, expired)`, err.Error())
+
+	err = checkAllowedValue("foo", allowed)
+	assert.Equal(t, `invalid value "foo" (allowed values: active, canceled, expired)`, err.Error())
+
+	assert.NoError(t, checkAllowedValue("active", allowed))
+	assert.NoError(t, checkAllowedValue("foo", nil))
+
+	mapErr := errors.New("unknown state")
+	err = NewInvalidValueError("cancelled", mapErr, allowed)
+	assert.Equal(t, `invalid value "cancelled": unknown state; did you mean canceled? (allowed values: active, canceled, expired)`, err.Error())
+	assert.ErrorIs(t, err, mapErr)
+}
+
+func TestInvalidValueErrorFromConverters(t *testing.T) {
+	f, err := Parse("state:actve")
+	require.NoError(t, err)
+
+	_, _, err = f.ToSpannerSQL(map[string]FilterToSpannerFieldConfig{
+		"state": {AllowedValues: []string{"active", "canceled", "expired"}},
+	})
+	var invalidValue *InvalidValueError
+	require.ErrorAs(t, err, &invalidValue)
+	assert.Equal(t, "actve", invalidValue.Value)
+	assert.Equal(t, []string{"active"}, invalidValue.Suggestions)
+
+	states := map[string]int64{"active": 1, "canceled": 2}
+	_, err = f.ToSquirrelSql(sq.Select("*").From("subscriptions"), map[string]FilterToSquirrelSqlFieldConfig{
+		"state": {
+			ColumnType:    FilterToSquirrelSqlFieldColumnTypeInt64,
+			AllowedValues: []string{"active", "canceled"},
+			MapValue: func(value string) (any, error) {
+				return states[value], nil
+			},
+		},
+	})
+	require.ErrorAs(t, err, &invalidValue)
+	assert.Equal(t, []string{"active"}, invalidValue.Suggestions)
+
+	f, err = Parse("state:active")
+	require.NoError(t, err)
+	_, _, err = f.ToSpannerSQL(map[string]FilterToSpannerFieldConfig{
+		"state": {
+			ColumnType:    FilterToSpannerFieldColumnTypeInt64,
+			AllowedValues: []string{"active", "canceled"},
+			MapValue: func(value string) (any, error) {
+				return nil, errors.New("unknown state")
+			},
+		},
+	})
+	require.ErrorAs(t, err, &invalidValue)
+	assert.EqualError(t, invalidValue.Err, "unknown state")
+}
+
+func TestValueSuggestions(t *testing.T) {
+	allowed := []string{"active", "canceled", "internal_migrating"}
+	f, err := Parse("state:actve")
+	require.NoError(t, err)
+
+	testCases := []struct {
+		suggestions ValueSuggestions
+		expected    string
+	}{
+		{ValueSuggestionsAll, `invalid value "actve"; did you mean active? (allowed values: active, canceled, internal_migrating)`},
+		{ValueSuggestionsClosest, `invalid value "actve"; did you mean active?`},
+		{ValueSuggestionsNone, `invalid value "actve"`},
+	}
+	for _, test := range testCases {
+		_, _, err := f.ToSpannerSQL(map[string]FilterToSpannerFieldConfig{
+			"state": {AllowedValues: allowed, ValueSuggestions: test.suggestions},
+		})
+		var invalidValue *InvalidValueError
+		require.ErrorAs(t, err, &invalidValue)
+		assert.Equal(t, test.expected, invalidValue.Error())
+
+		_, err = f.ToSquirrelSql(sq.Select("*").From("subscriptions"), map[string]FilterToSquirrelSqlFieldConfig{
+			"state": {MapValue: EnumMap(map[string]int{"active": 1, "canceled": 2}), ValueSuggestions: test.suggestions},
+		})
+		require.ErrorAs(t, err, &invalidValue)
+		assert.NotContains(t, invalidValue.Error(), "internal_migrating")
+		assert.True(t, errors.Is(err, ErrValueInvalid))
+
+		ast, err := ParseAST("state:actve")
+		require.NoError(t, err)
+		errs := Validate(ast, Schema{"state": {AllowedValues: allowed, ValueSuggestions: test.suggestions}})
+		require.Len(t, errs, 1)
+		assert.Contains(t, errs[0].Error(), test.expected)
+	}
+}
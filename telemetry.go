@@ -0,0 +1,71 @@
+package kqlfilter
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// QueryStats describes a call of QueryFromKQL, e.g. to record metrics on what users filter on and how expensive it
+// is. See WithTelemetry.
+type QueryStats struct {
+	// Time at which QueryFromKQL was called.
+	Start time.Time
+	// Time spent parsing and validating the input. Zero for empty inputs.
+	ParseDuration time.Duration
+	// Time spent in the WithScope hook and converting the filter with the backend. Zero if the filter is rejected
+	// before.
+	ConvertDuration time.Duration
+	// Number of AND and OR operators of the user filter, as passed to QuotaHook.
+	Complexity int
+	// Fields referenced by the user filter. Empty if the input is empty or can't be parsed.
+	Fields []FieldUsage
+	// Field that caused the filter to be rejected, because it is unknown or not allowed in the way it is used. Empty if
+	// the filter is accepted or rejected for another reason.
+	RejectedField string
+	// Number of clauses of the converted filter, including those added by WithScope. Zero if the filter is rejected.
+	Clauses int
+	// Error returned by QueryFromKQL, if any.
+	Err error
+}
+
+// Duration returns the total time spent parsing and converting the filter.
+func (s QueryStats) Duration() time.Duration {
+	return s.ParseDuration + s.ConvertDuration
+}
+
+// TelemetryHook is called with the stats of a call of QueryFromKQL once it completes, successful or not, and the
+// context passed to QueryFromKQLContext. It must not modify the stats.
+type TelemetryHook func(ctx context.Context, stats QueryStats)
+
+// WithTelemetry sets a hook that records the stats of every call of QueryFromKQL, e.g. as OpenTelemetry metrics:
+//
+//	kqlfilter.WithTelemetry(func(ctx context.Context, stats kqlfilter.QueryStats) {
+//		parseDuration.Record(ctx, stats.ParseDuration.Seconds())
+//		for _, field := range stats.Fields {
+//			fieldUsage.Add(ctx, int64(field.Count), metric.WithAttributes(attribute.String("field", field.Field)))
+//		}
+//		if stats.RejectedField != "" {
+//			rejectedFields.Add(ctx, 1, metric.WithAttributes(attribute.String("field", stats.RejectedField)))
+//		}
+//	})
+//
+// or as a span, using stats.Start and stats.Duration as its timestamps.
+func WithTelemetry(hook TelemetryHook) QueryOption {
+	return func(o *queryOptions) {
+		o.telemetry = hook
+	}
+}
+
+// rejectedField returns the field reported by an UnknownFieldError or FieldError in the chain of err, if any.
+func rejectedField(err error) string {
+	var unknownFieldErr *UnknownFieldError
+	if errors.As(err, &unknownFieldErr) {
+		return unknownFieldErr.Field
+	}
+	var fieldErr *FieldError
+	if errors.As(err, &fieldErr) {
+		return fieldErr.Field
+	}
+	return ""
+}
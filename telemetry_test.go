@@ -0,0 +1,59 @@
+package kqlfilter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryFromKQLTelemetry(t *testing.T) {
+	type ctxKey struct{}
+	ctx := context.WithValue(context.Background(), ctxKey{}, "request-1")
+
+	var stats []QueryStats
+	telemetry := WithTelemetry(func(ctx context.Context, s QueryStats) {
+		assert.Equal(t, "request-1", ctx.Value(ctxKey{}))
+		stats = append(stats, s)
+	})
+	scope := WithScope(func(ast Node) (Node, error) {
+		return And(ast, &IsNode{NodeType: NodeIs, Identifier: "tenant_id", Value: &LiteralNode{NodeType: NodeLiteral, Value: "t1"}}), nil
+	})
+
+	_, err := QueryFromKQLContext(ctx, "user_id:1 and state:(active OR paused)", testQuerySchema, SpannerBackend(), telemetry, scope)
+	require.NoError(t, err)
+	_, err = QueryFromKQLContext(ctx, "user_id:1 or foo:bar", testQuerySchema, SpannerBackend(), telemetry, scope)
+	require.Error(t, err)
+	_, err = QueryFromKQLContext(ctx, "state>a", testQuerySchema, SpannerBackend(), telemetry, scope)
+	require.Error(t, err)
+	_, err = QueryFromKQLContext(ctx, "state:(a", testQuerySchema, SpannerBackend(), telemetry, scope)
+	require.Error(t, err)
+
+	require.Len(t, stats, 4)
+
+	accepted := stats[0]
+	assert.False(t, accepted.Start.IsZero())
+	assert.Positive(t, accepted.ParseDuration)
+	assert.Positive(t, accepted.ConvertDuration)
+	assert.Equal(t, accepted.ParseDuration+accepted.ConvertDuration, accepted.Duration())
+	assert.Equal(t, 2, accepted.Complexity)
+	assert.Equal(t, []FieldUsage{{Field: "user_id", Count: 1, Operators: []string{"="}}, {Field: "state", Count: 1, Operators: []string{"IN"}}}, accepted.Fields)
+	assert.Equal(t, 3, accepted.Clauses)
+	assert.Empty(t, accepted.RejectedField)
+	assert.NoError(t, accepted.Err)
+
+	unknown := stats[1]
+	assert.Equal(t, "foo", unknown.RejectedField)
+	assert.Len(t, unknown.Fields, 2)
+	assert.Zero(t, unknown.Clauses)
+	assert.Zero(t, unknown.ConvertDuration)
+	assert.Error(t, unknown.Err)
+
+	assert.Equal(t, "state", stats[2].RejectedField)
+
+	invalid := stats[3]
+	assert.Empty(t, invalid.RejectedField)
+	assert.Empty(t, invalid.Fields)
+	assert.EqualError(t, invalid.Err, err.Error())
+}
@@ -0,0 +1,103 @@
+package kqlfilter
+
+import "fmt"
+
+// Template is a filter with named placeholders for values, e.g. `owner_id:{{me}} created>{{since}}`, such as a saved
+// search that is shared between users. The template is parsed once, and Execute substitutes the placeholders with
+// literal values, which are never parsed, so that values can't change the structure of the filter.
+type Template struct {
+	ast  Node
+	refs []placeholderRef
+}
+
+// placeholderRef is a placeholder in the AST of a template: the literal node at pos is substituted by the value of
+// name.
+type placeholderRef struct {
+	pos  Pos
+	name string
+}
+
+// ParseTemplate parses a filter in which values can be placeholders, written as `{{name}}`. Names consist of letters,
+// digits, underscores and dots. A placeholder must be a whole value, i.e. it can't be combined with other text or
+// wildcards, and can be used wherever a value can, e.g. in ranges (`created>{{since}}`) and lists of values
+// (`state:({{state}} or active)`). To match the literal text `{{name}}`, quote it.
+func ParseTemplate(input string, options ...ParserOption) (*Template, error) {
+	p := newParser(options)
+	p.placeholders = true
+	ast, err := p.run(input)
+	if err != nil {
+		return nil, err
+	}
+	return &Template{ast: ast, refs: p.placeholderRefs}, nil
+}
+
+// Placeholders returns the distinct names of the placeholders, in order of first occurrence.
+func (t *Template) Placeholders() []string {
+	var names []string
+	seen := make(map[string]bool, len(t.refs))
+	for _, ref := range t.refs {
+		if !seen[ref.name] {
+			seen[ref.name] = true
+			names = append(names, ref.name)
+		}
+	}
+	return names
+}
+
+// String returns the AST of the template, with placeholders as `{{name}}`.
+func (t *Template) String() string {
+	if t.ast == nil {
+		return ""
+	}
+	return t.ast.String()
+}
+
+// Execute returns a copy of the AST of the template with all placeholders substituted by the values of the same name.
+// Values are formatted like the values passed to Builder: strings as is, times in RFC3339 and other values with
+// fmt.Sprint, and asterisks are escaped so that they are matched literally. It returns an error if a value is missing.
+// The template is not modified, so it can be executed concurrently.
+func (t *Template) Execute(values map[string]any) (Node, error) {
+	for _, ref := range t.refs {
+		if _, ok := values[ref.name]; !ok {
+			return nil, fmt.Errorf("missing value for placeholder %s", ref.name)
+		}
+	}
+	if t.ast == nil {
+		return nil, nil
+	}
+	ast := t.ast.Clone()
+	if len(t.refs) > 0 {
+		names := make(map[Pos]string, len(t.refs))
+		for _, ref := range t.refs {
+			names[ref.pos] = ref.name
+		}
+		substitutePlaceholders(ast, names, values)
+	}
+	return ast, nil
+}
+
+// substitutePlaceholders replaces the values of the literal nodes at the positions of placeholders in place.
+func substitutePlaceholders(ast Node, names map[Pos]string, values map[string]any) {
+	switch n := ast.(type) {
+	case *AndNode:
+		for _, child := range n.Nodes {
+			substitutePlaceholders(child, names, values)
+		}
+	case *OrNode:
+		for _, child := range n.Nodes {
+			substitutePlaceholders(child, names, values)
+		}
+	case *NotNode:
+		substitutePlaceholders(n.Expr, names, values)
+	case *IsNode:
+		substitutePlaceholders(n.Value, names, values)
+	case *RangeNode:
+		substitutePlaceholders(n.Value, names, values)
+	case *NestedNode:
+		substitutePlaceholders(n.Expr, names, values)
+	case *LiteralNode:
+		if name, ok := names[n.Pos]; ok {
+			n.Value = formatBuilderValue(values[name])
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package kqlfilter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplate(t *testing.T) {
+	tmpl, err := ParseTemplate(`owner_id:{{me}} and created>{{ since }} and state:({{state}}, archived) and not owner_id:{{me}} and title:"{{me}}"`)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"me", "since", "state"}, tmpl.Placeholders())
+	assert.Equal(t, "(owner_id={{me}} AND created>{{ since }} AND state=({{state}} OR archived) AND NOT owner_id={{me}} AND title={{me}})", tmpl.String())
+
+	ast, err := tmpl.Execute(map[string]any{
+		"me":    "u1 or owner_id:*",
+		"since": time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+		"state": "act*ve",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, `(owner_id=u1 or owner_id:\* AND created>2024-05-01T00:00:00Z AND state=(act\*ve OR archived) AND NOT owner_id=u1 or owner_id:\* AND title={{me}})`, ast.String())
+
+	f, err := convertToFilter(ast)
+	require.NoError(t, err)
+	assert.Equal(t, Clause{Field: "owner_id", Operator: "=", Values: []string{`u1 or owner_id:\*`}}, f.Clauses[0])
+	assert.Equal(t, "u1 or owner_id:*", UnescapeValue(f.Clauses[0].Values[0]))
+
+	// The template is not modified.
+	assert.Contains(t, tmpl.String(), "owner_id={{me}}")
+
+	_, err = tmpl.Execute(map[string]any{"me": "u1"})
+	assert.EqualError(t, err, "missing value for placeholder since")
+}
+
+func TestParseTemplateErrors(t *testing.T) {
+	testCases := []struct {
+		input         string
+		expectedError string
+	}{
+		{"owner_id:{{me", "parser error: unterminated placeholder at pos 9"},
+		{"owner_id:{{my name}}", `parser error: invalid placeholder name "my name" at pos 9`},
+		{"owner_id:{{}}", `parser error: invalid placeholder name "" at pos 9`},
+		{"email:{{user}}@example.com", "parser error: placeholder \"{{user}}\" must be the whole value at pos 14"},
+	}
+	for _, test := range testCases {
+		t.Run(test.input, func(t *testing.T) {
+			_, err := ParseTemplate(test.input)
+			assert.EqualError(t, err, test.expectedError)
+		})
+	}
+
+	// Without templates, double braces are nested queries.
+	_, err := ParseAST("owner_id:{{me}}")
+	assert.Error(t, err)
+}
@@ -0,0 +1,100 @@
+package kqlfilter
+
+import (
+	"time"
+)
+
+// TimeWindow limits the time span that a filter may query on a timestamp field, e.g. to at most 31 days of logs.
+type TimeWindow struct {
+	// Timestamp field that must be bounded. Fields in nested queries are named by their path.
+	Field string
+	// Maximum duration between the lower and upper bound. A missing upper bound is the current time. Zero means no
+	// maximum.
+	Max time.Duration
+	// Length of the window added to filters without a lower bound, ending at the upper bound or the current time.
+	// Zero rejects filters without a lower bound instead.
+	Default time.Duration
+}
+
+// EnforceTimeWindow checks that the filter bounds the timestamp field of the window from below, and that its bounds
+// span at most the maximum of the window. Only bounds that apply to the whole filter count, i.e. range clauses and
+// equalities that are not part of a disjunction or negation. If there are multiple lower or upper bounds, the most
+// restrictive ones are used.
+//
+// If the filter has no lower bound and the window has a default, a `field>=...` clause is added to the filter, and the
+// filter with the added clause is returned. Otherwise, the filter is returned as is. The input AST is not modified.
+//
+// Values are timestamps in time.RFC3339Nano format or relative time keywords such as RelativeTimeToday, which are
+// resolved with WithClock, WithDefaultLocation and WithTimeLayouts. A missing lower bound is reported as a FieldError
+// matching ErrRequiredFieldMissing, and invalid values and windows exceeding the maximum match ErrValueInvalid.
+func EnforceTimeWindow(ast Node, window TimeWindow, options ...ConverterOption) (Node, error) {
+	o := newConverterOptions(options)
+	var lower, upper time.Time
+	var err error
+	collectTimeBounds(ast, "", func(field string, op RangeOperator, equal bool, value string) {
+		if field != window.Field || err != nil {
+			return
+		}
+		t, ok := o.resolveRelativeTime(value)
+		if !ok {
+			t, err = o.parseTime(value)
+			if err != nil {
+				err = newFieldError(field, ErrValueInvalid, "field %s: invalid TIMESTAMP value: %w", field, err)
+				return
+			}
+		}
+		if equal || op == RangeOperatorGt || op == RangeOperatorGte {
+			if lower.IsZero() || t.After(lower) {
+				lower = t
+			}
+		}
+		if equal || op == RangeOperatorLt || op == RangeOperatorLte {
+			if upper.IsZero() || t.Before(upper) {
+				upper = t
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	end := upper
+	if end.IsZero() {
+		end = o.now().In(o.location)
+	}
+	if lower.IsZero() {
+		if window.Default <= 0 {
+			return nil, newFieldError(window.Field, ErrRequiredFieldMissing, "field %s: filter must have a lower bound", window.Field)
+		}
+		if window.Max > 0 && window.Default > window.Max {
+			return nil, newFieldError(window.Field, ErrValueInvalid, "field %s: default time window of %s exceeds maximum of %s", window.Field, window.Default, window.Max)
+		}
+		return NewFilter().Gte(window.Field, end.Add(-window.Default)).AppendTo(ast), nil
+	}
+	if window.Max > 0 && end.Sub(lower) > window.Max {
+		return nil, newFieldError(window.Field, ErrValueInvalid, "field %s: time window of %s exceeds maximum of %s", window.Field, end.Sub(lower), window.Max)
+	}
+	return ast, nil
+}
+
+// collectTimeBounds calls add for each range clause and equality with a single value in the top-level conjunction of
+// the AST, including conjunctions in nested queries.
+func collectTimeBounds(ast Node, prefix string, add func(field string, op RangeOperator, equal bool, value string)) {
+	switch n := ast.(type) {
+	case *AndNode:
+		for _, child := range n.Nodes {
+			collectTimeBounds(child, prefix, add)
+		}
+	case *IsNode:
+		switch v := n.Value.(type) {
+		case *NestedNode:
+			collectTimeBounds(v.Expr, prefix+n.Identifier+".", add)
+		case *LiteralNode:
+			add(prefix+n.Identifier, 0, true, UnescapeValue(v.Value))
+		}
+	case *RangeNode:
+		if lit, ok := n.Value.(*LiteralNode); ok {
+			add(prefix+n.Identifier, n.Operator, false, UnescapeValue(lit.Value))
+		}
+	}
+}
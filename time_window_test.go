@@ -0,0 +1,116 @@
+package kqlfilter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnforceTimeWindow(t *testing.T) {
+	now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+	window := TimeWindow{Field: "created", Max: 31 * 24 * time.Hour}
+	defaultWindow := TimeWindow{Field: "created", Max: 31 * 24 * time.Hour, Default: 24 * time.Hour}
+
+	testCases := []struct {
+		name          string
+		input         string
+		window        TimeWindow
+		expected      string
+		expectedError string
+	}{
+		{
+			name:     "within window",
+			input:    `created>="2024-06-01T00:00:00Z" created<"2024-06-30T00:00:00Z" level:error`,
+			window:   window,
+			expected: `(created>=2024-06-01T00:00:00Z AND created<2024-06-30T00:00:00Z AND level=error)`,
+		},
+		{
+			name:     "lower bound until now",
+			input:    `created>yesterday`,
+			window:   window,
+			expected: `created>yesterday`,
+		},
+		{
+			name:     "most restrictive bounds",
+			input:    `created>="2024-01-01T00:00:00Z" and created>="2024-06-01T00:00:00Z"`,
+			window:   window,
+			expected: `(created>=2024-01-01T00:00:00Z AND created>=2024-06-01T00:00:00Z)`,
+		},
+		{
+			name:     "equality",
+			input:    `created:"2024-06-01T00:00:00Z"`,
+			window:   window,
+			expected: `created=2024-06-01T00:00:00Z`,
+		},
+		{
+			name:     "nested field",
+			input:    `event:{time>today}`,
+			window:   TimeWindow{Field: "event.time", Max: time.Hour * 24},
+			expected: `event={time>today}`,
+		},
+		{
+			name:          "window exceeded",
+			input:         `created>="2024-01-01T00:00:00Z"`,
+			window:        window,
+			expectedError: "field created: time window of 3996h0m0s exceeds maximum of 744h0m0s",
+		},
+		{
+			name:          "window exceeded with upper bound",
+			input:         `created>="2024-01-01T00:00:00Z" created<="2024-03-01T00:00:00Z"`,
+			window:        window,
+			expectedError: "field created: time window of 1440h0m0s exceeds maximum of 744h0m0s",
+		},
+		{
+			name:          "missing lower bound",
+			input:         `level:error or created>"2024-06-01T00:00:00Z"`,
+			window:        window,
+			expectedError: "field created: filter must have a lower bound",
+		},
+		{
+			name:          "invalid value",
+			input:         `created>last_week`,
+			window:        window,
+			expectedError: `field created: invalid TIMESTAMP value: parsing time "last_week" as "2006-01-02T15:04:05.999999999Z07:00": cannot parse "last_week" as "2006"`,
+		},
+		{
+			name:     "default window",
+			input:    `level:error`,
+			window:   defaultWindow,
+			expected: `(level=error AND created>=2024-06-14T12:00:00Z)`,
+		},
+		{
+			name:     "default window before upper bound",
+			input:    `level:error created<"2024-06-01T00:00:00Z"`,
+			window:   defaultWindow,
+			expected: `(level=error AND created<2024-06-01T00:00:00Z AND created>=2024-05-31T00:00:00Z)`,
+		},
+		{
+			name:          "default window exceeds maximum",
+			input:         `level:error`,
+			window:        TimeWindow{Field: "created", Max: time.Hour, Default: 2 * time.Hour},
+			expectedError: "field created: default time window of 2h0m0s exceeds maximum of 1h0m0s",
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			ast, err := ParseAST(test.input)
+			require.NoError(t, err)
+			result, err := EnforceTimeWindow(ast, test.window, WithClock(func() time.Time { return now }))
+			if test.expectedError != "" {
+				assert.EqualError(t, err, test.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, result.String())
+		})
+	}
+
+	result, err := EnforceTimeWindow(nil, defaultWindow, WithClock(func() time.Time { return now }))
+	require.NoError(t, err)
+	assert.Equal(t, `created>=2024-06-14T12:00:00Z`, result.String())
+	_, err = EnforceTimeWindow(nil, window)
+	assert.ErrorIs(t, err, ErrRequiredFieldMissing)
+}
@@ -0,0 +1,145 @@
+package kqlfilter
+
+import "fmt"
+
+// TokenKind identifies the kind of a Token.
+type TokenKind int
+
+const (
+	// TokenSpace is a run of whitespace.
+	TokenSpace TokenKind = iota
+	// TokenString is an unquoted string: a field name or a value, including escape sequences such as `\:`.
+	TokenString
+	// TokenQuotedString is a string between double quotes.
+	TokenQuotedString
+	// TokenBool is one of the boolean constants `true` and `false`, in any case.
+	TokenBool
+	// TokenAnd is the `and` operator.
+	TokenAnd
+	// TokenOr is the `or` operator.
+	TokenOr
+	// TokenNot is the `not` operator.
+	TokenNot
+	// TokenLeftParen is `(`.
+	TokenLeftParen
+	// TokenRightParen is `)`.
+	TokenRightParen
+	// TokenLeftBrace is `{`, which starts a nested query.
+	TokenLeftBrace
+	// TokenRightBrace is `}`.
+	TokenRightBrace
+	// TokenColon is `:`, which separates a field from its value.
+	TokenColon
+	// TokenComma is `,`, which separates values in parentheses. Commas outside parentheses are part of a string.
+	TokenComma
+	// TokenWildcard is `*`.
+	TokenWildcard
+	// TokenRangeOperator is one of `<`, `<=`, `>` and `>=`.
+	TokenRangeOperator
+	// TokenRangeBrackets is a range in brackets, e.g. `[10 TO 20]`.
+	TokenRangeBrackets
+)
+
+var tokenKindName = map[TokenKind]string{
+	TokenSpace:         "space",
+	TokenString:        "string",
+	TokenQuotedString:  "quoted string",
+	TokenBool:          "bool",
+	TokenAnd:           "and",
+	TokenOr:            "or",
+	TokenNot:           "not",
+	TokenLeftParen:     "(",
+	TokenRightParen:    ")",
+	TokenLeftBrace:     "{",
+	TokenRightBrace:    "}",
+	TokenColon:         ":",
+	TokenComma:         ",",
+	TokenWildcard:      "*",
+	TokenRangeOperator: "range",
+	TokenRangeBrackets: "range brackets",
+}
+
+func (k TokenKind) String() string {
+	if s, ok := tokenKindName[k]; ok {
+		return s
+	}
+	return fmt.Sprintf("TokenKind(%d)", int(k))
+}
+
+// Token is a lexical token of a filter.
+type Token struct {
+	Kind TokenKind
+	// Byte position of the start of the token in the input.
+	Pos Pos
+	// Byte position of the end of the token in the input, exclusive.
+	End Pos
+	// Text of the token as written in the input, i.e. input[Pos:End].
+	Text string
+}
+
+// Tokens splits the input into the tokens of the grammar implemented by the parser, e.g. for syntax highlighting in
+// editors. The tokens cover the input without gaps, so concatenating their texts results in the input. Of the parser
+// options, only WithKeywordCase affects tokenization.
+//
+// Tokens only checks the lexical structure of the input, like balanced parentheses and terminated quoted strings, and
+// not whether the tokens form a valid filter. If the input can't be tokenized, it returns the tokens before the
+// offending one together with an error, so that the valid part of an input that is being typed can still be
+// highlighted.
+func Tokens(input string, options ...ParserOption) ([]Token, error) {
+	p := newParser(options)
+	l := lex(input)
+	l.keywordCase = p.keywordCase
+
+	var tokens []Token
+	for {
+		it := l.nextItem()
+		switch it.typ {
+		case itemEOF:
+			return tokens, nil
+		case itemError:
+			return tokens, fmt.Errorf("parser error: %s at pos %d", it.val, it.pos)
+		}
+		end := l.start
+		token := Token{Kind: tokenKind(it.typ), Pos: it.pos, End: end, Text: input[it.pos:end]}
+		if token.Kind == TokenString && token.Text[0] == '"' {
+			token.Kind = TokenQuotedString
+		}
+		tokens = append(tokens, token)
+	}
+}
+
+// tokenKind returns the kind of token of a lexer item.
+func tokenKind(typ itemType) TokenKind {
+	switch typ {
+	case itemSpace:
+		return TokenSpace
+	case itemBool:
+		return TokenBool
+	case itemAnd:
+		return TokenAnd
+	case itemOr:
+		return TokenOr
+	case itemNot:
+		return TokenNot
+	case itemLeftParen:
+		return TokenLeftParen
+	case itemRightParen:
+		return TokenRightParen
+	case itemLeftBrace:
+		return TokenLeftBrace
+	case itemRightBrace:
+		return TokenRightBrace
+	case itemColon:
+		return TokenColon
+	case itemComma:
+		return TokenComma
+	case itemWildcard:
+		return TokenWildcard
+	case itemRangeOperator:
+		return TokenRangeOperator
+	case itemRangeBrackets:
+		return TokenRangeBrackets
+	default:
+		return TokenString
+	}
+}
@@ -0,0 +1,56 @@
+package kqlfilter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokens(t *testing.T) {
+	tokens, err := Tokens(`name:"jo\"n" AND age>=18 not state:(a,b*) user:{x:true}`)
+	require.NoError(t, err)
+
+	var kinds []string
+	var texts []string
+	for _, token := range tokens {
+		if token.Kind == TokenSpace {
+			continue
+		}
+		kinds = append(kinds, token.Kind.String())
+		texts = append(texts, token.Text)
+	}
+	assert.Equal(t, []string{
+		"string", ":", "quoted string", "and", "string", "range", "string", "not", "string", ":", "(", "string", ",",
+		"string", "*", ")", "string", ":", "{", "string", ":", "bool", "}",
+	}, kinds)
+	assert.Equal(t, []string{
+		"name", ":", `"jo\"n"`, "AND", "age", ">=", "18", "not", "state", ":", "(", "a", ",", "b", "*", ")", "user", ":",
+		"{", "x", ":", "true", "}",
+	}, texts)
+	assert.Equal(t, Token{Kind: TokenQuotedString, Pos: 5, End: 12, Text: `"jo\"n"`}, tokens[2])
+
+	input := `a:1 or b\:c:"x y"`
+	tokens, err = Tokens(input)
+	require.NoError(t, err)
+	var sb strings.Builder
+	for _, token := range tokens {
+		sb.WriteString(token.Text)
+	}
+	assert.Equal(t, input, sb.String())
+}
+
+func TestTokensKeywordCase(t *testing.T) {
+	tokens, err := Tokens(`a or b`, WithKeywordCase(KeywordCaseUpper))
+	require.NoError(t, err)
+	require.Len(t, tokens, 5)
+	assert.Equal(t, TokenString, tokens[2].Kind)
+}
+
+func TestTokensError(t *testing.T) {
+	tokens, err := Tokens(`name:jon state:"active`)
+	assert.EqualError(t, err, "parser error: unterminated quoted string at pos 15")
+	require.Len(t, tokens, 6)
+	assert.Equal(t, ":", tokens[5].Text)
+}
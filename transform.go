@@ -16,6 +16,8 @@ func NewNodeMapper() NodeMapper {
 	}
 }
 
+// Map transforms the identifiers and values of the AST in place.
+// Use Node.Clone to keep the original AST, e.g. when it is cached or shared between goroutines.
 func (m NodeMapper) Map(ast Node) error {
 	switch x := ast.(type) {
 	case *AndNode:
@@ -51,6 +53,11 @@ func (m NodeMapper) Map(ast Node) error {
 		if err != nil {
 			return err
 		}
+	case *ExistsNode:
+		x.Identifier = m.TransformIdentifierFunc(x.Identifier)
+	case *FuzzyNode:
+		x.Identifier = m.TransformIdentifierFunc(x.Identifier)
+		x.Value = m.TransformValueFunc(x.Value)
 	case *LiteralNode:
 		x.Value = m.TransformValueFunc(x.Value)
 	}
@@ -0,0 +1,130 @@
+package kqlfilter
+
+import (
+	"sort"
+)
+
+// Validate checks the AST against the schema without converting it, e.g. to validate saved searches when they are
+// saved rather than when they are executed. Unlike the converters, it doesn't stop at the first violation: it returns
+// all violations in the order in which they appear in the filter, or nil if the filter is valid.
+//
+// It checks that the fields exist, that their operators are allowed (multiple values, negated multiple values and
+// ranges), that values are valid for the type of the field, AllowedValues and MapValue, and that required fields are
+// present. Errors can be matched with errors.Is against ErrUnknownField, ErrOperatorNotAllowed, ErrValueInvalid,
+// ErrMultipleValuesNotAllowed and ErrRequiredFieldMissing.
+func Validate(ast Node, schema Schema) []error {
+	if ast == nil {
+		return nil
+	}
+	v := validator{schema: schema, used: make(map[string]bool)}
+	v.validate(ast, "", false)
+
+	var required []string
+	for name, fs := range schema {
+		if fs.Required && !v.used[name] {
+			required = append(required, name)
+		}
+	}
+	sort.Strings(required)
+	for _, name := range required {
+		v.errs = append(v.errs, newFieldError(name, ErrRequiredFieldMissing, "required field %s missing", name))
+	}
+	return v.errs
+}
+
+// validator collects the violations found by Validate.
+type validator struct {
+	schema Schema
+	used   map[string]bool // canonical names of the fields in the filter
+	errs   []error
+}
+
+func (v *validator) validate(ast Node, prefix string, negated bool) {
+	switch n := ast.(type) {
+	case *AndNode:
+		for _, child := range n.Nodes {
+			v.validate(child, prefix, negated)
+		}
+	case *OrNode:
+		for _, child := range n.Nodes {
+			v.validate(child, prefix, negated)
+		}
+	case *NotNode:
+		v.validate(n.Expr, prefix, !negated)
+	case *IsNode:
+		if nested, ok := n.Value.(*NestedNode); ok {
+			v.validate(nested.Expr, prefix+n.Identifier+".", negated)
+			return
+		}
+		field := prefix + n.Identifier
+		fs, ok := v.lookup(field)
+		if !ok {
+			return
+		}
+		switch value := n.Value.(type) {
+		case *LiteralNode:
+			v.validateValue(field, fs, value.Value)
+		case *OrNode:
+			if !fs.AllowMultipleValues {
+				v.errs = append(v.errs, newFieldError(field, ErrMultipleValuesNotAllowed, "field %s: multiple values are not allowed", field))
+			} else if negated && !fs.AllowNegation {
+				v.errs = append(v.errs, newFieldError(field, ErrOperatorNotAllowed, "operator NOT IN not supported for field: %s", field))
+			}
+			for _, child := range value.Nodes {
+				if literal, ok := child.(*LiteralNode); ok {
+					v.validateValue(field, fs, literal.Value)
+				}
+			}
+		}
+	case *RangeNode:
+		field := prefix + n.Identifier
+		fs, ok := v.lookup(field)
+		if !ok {
+			return
+		}
+		if !fs.AllowRanges {
+			v.errs = append(v.errs, newFieldError(field, ErrOperatorNotAllowed, "operator %s not supported for field: %s", n.Operator, field))
+		} else if (fs.Type == FieldTypeString && !fs.AllowStringRanges) || fs.Type == FieldTypeBool {
+			v.errs = append(v.errs, newFieldError(field, ErrOperatorNotAllowed, "operator %s not supported for field type %s", n.Operator, fs.Type))
+		}
+		if literal, ok := n.Value.(*LiteralNode); ok {
+			v.validateValue(field, fs, literal.Value)
+		}
+	case *ExistsNode:
+		v.lookup(prefix + n.Identifier)
+	case *FuzzyNode:
+		v.lookup(prefix + n.Identifier)
+	}
+}
+
+// lookup returns the schema of the field, recording an error if it is unknown.
+func (v *validator) lookup(field string) (FieldSchema, bool) {
+	name, fs, ok := v.schema.Lookup(field)
+	if !ok {
+		v.errs = append(v.errs, NewUnknownFieldError(field, v.schema.FieldNames()))
+		return FieldSchema{}, false
+	}
+	v.used[name] = true
+	return fs, true
+}
+
+// validateValue records an error if the value is not allowed or not valid for the type of the field.
+func (v *validator) validateValue(field string, fs FieldSchema, value string) {
+	if err := checkAllowedValue(value, fs.AllowedValues); err != nil {
+		v.errs = append(v.errs, &FieldError{Field: field, Kind: ErrValueInvalid, Err: fs.ValueSuggestions.apply(err)})
+		return
+	}
+	if fs.MapValue != nil {
+		if _, err := fs.MapValue(value); err != nil {
+			err = fs.ValueSuggestions.apply(err)
+			v.errs = append(v.errs, newFieldError(field, ErrValueInvalid, "field %s: %w", field, err))
+		}
+		return
+	}
+	if fs.Type == FieldTypeString {
+		return
+	}
+	if _, err := fs.Type.ParseValue(UnescapeValue(value)); err != nil {
+		v.errs = append(v.errs, newFieldError(field, ErrValueInvalid, "field %s: %w", field, err))
+	}
+}
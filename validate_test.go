@@ -0,0 +1,82 @@
+package kqlfilter
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	schema := Schema{
+		"user_id":    {Type: FieldTypeInt64, Aliases: []string{"userId"}, Required: true},
+		"state":      {AllowMultipleValues: true, AllowedValues: []string{"active", "paused"}},
+		"tags":       {AllowMultipleValues: true, AllowNegation: true},
+		"name":       {},
+		"id":         {AllowRanges: true, AllowStringRanges: true},
+		"created_at": {Type: FieldTypeTimestamp, AllowRanges: true},
+		"address.city": {
+			MapValue: func(s string) (any, error) {
+				if s == "" || s[0] < 'A' || s[0] > 'Z' {
+					return nil, errors.New("must start with a capital")
+				}
+				return s, nil
+			},
+		},
+	}
+
+	testCases := []struct {
+		name     string
+		input    string
+		expected []string
+		kinds    []error
+	}{
+		{
+			name:  "valid",
+			input: `userId:1 state:(active or paused) not tags:(a or b) created_at >= today id > 01HZX3M5K2 address:{city:Amsterdam}`,
+		},
+		{
+			name:  "all violations",
+			input: `user_id:abc foo:1 name:(a or b) state:deleted name > a created_at:yesterday created_at < "not a time" address:{city:amsterdam} bar:*`,
+			expected: []string{
+				`field user_id: invalid int64 value: strconv.ParseInt: parsing "abc": invalid syntax`,
+				`unknown field: foo`,
+				`field name: multiple values are not allowed`,
+				`invalid value "deleted" (allowed values: active, paused)`,
+				`operator > not supported for field: name`,
+				`field created_at: invalid timestamp value: parsing time "not a time" as "2006-01-02T15:04:05.999999999Z07:00": cannot parse "not a time" as "2006"`,
+				`field address.city: must start with a capital`,
+				`unknown field: bar`,
+			},
+			kinds: []error{ErrValueInvalid, ErrUnknownField, ErrMultipleValuesNotAllowed, ErrValueInvalid, ErrOperatorNotAllowed, ErrValueInvalid, ErrValueInvalid, ErrUnknownField},
+		},
+		{
+			name:     "negated multiple values",
+			input:    `userId:1 not state:(active or paused)`,
+			expected: []string{`operator NOT IN not supported for field: state`},
+			kinds:    []error{ErrOperatorNotAllowed},
+		},
+		{
+			name:     "required field missing",
+			input:    `name:john`,
+			expected: []string{`required field user_id missing`},
+			kinds:    []error{ErrRequiredFieldMissing},
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			ast, err := ParseAST(test.input)
+			require.NoError(t, err)
+			errs := Validate(ast, schema)
+			require.Len(t, errs, len(test.expected))
+			for i, err := range errs {
+				assert.EqualError(t, err, test.expected[i])
+				assert.ErrorIs(t, err, test.kinds[i])
+			}
+		})
+	}
+
+	assert.Nil(t, Validate(nil, schema))
+}
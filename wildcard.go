@@ -0,0 +1,72 @@
+package kqlfilter
+
+import "strings"
+
+// UnescapeValue returns the literal text of a value, replacing escaped asterisks and backslashes (`\*` and `\\`)
+// with the characters themselves. Unescaped asterisks are kept as is.
+// Values without asterisks are not escaped and are returned unchanged, see Clause.Values.
+func UnescapeValue(value string) string {
+	if !strings.Contains(value, "*") {
+		return value
+	}
+	return unescapeWildcards(value)
+}
+
+// SplitWildcards reports whether value ends with a wildcard (prefix match) or starts with one (suffix match) and
+// returns the unescaped text without these wildcards. Only the wildcards allowed by allowPrefix and allowSuffix are
+// split off; any other asterisk is part of the text.
+func SplitWildcards(value string, allowPrefix, allowSuffix bool) (text string, prefix bool, suffix bool) {
+	if !strings.Contains(value, "*") {
+		return value, false, false
+	}
+	if allowPrefix && hasTrailingWildcard(value) {
+		value = value[:len(value)-1]
+		prefix = true
+	}
+	if allowSuffix && strings.HasPrefix(value, "*") {
+		value = value[1:]
+		suffix = true
+	}
+	return unescapeWildcards(value), prefix, suffix
+}
+
+// hasTrailingWildcard reports whether value ends with an asterisk that is not escaped,
+// i.e. that is preceded by an even number of backslashes.
+func hasTrailingWildcard(value string) bool {
+	if !strings.HasSuffix(value, "*") {
+		return false
+	}
+	backslashes := 0
+	for i := len(value) - 2; i >= 0 && value[i] == '\\'; i-- {
+		backslashes++
+	}
+	return backslashes%2 == 0
+}
+
+// containsWildcard reports whether value contains an asterisk that is not escaped.
+func containsWildcard(value string) bool {
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			i++
+		case '*':
+			return true
+		}
+	}
+	return false
+}
+
+// unescapeWildcards replaces `\*` and `\\` with an asterisk and a backslash.
+func unescapeWildcards(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && (s[i+1] == '\\' || s[i+1] == '*') {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,81 @@
+package kqlfilter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseEscapedWildcards(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{`a:b\*`, `b\*`},
+		{`a:\*`, `\*`},
+		{`a:\*b*`, `\*b*`},
+		{`a:b\\*`, `b\\*`},
+		{`a:b\\c`, `b\c`},
+		{`a:"b\*"`, `b\*`},
+		{`a:"b\\c*"`, `b\\c*`},
+	}
+	for _, test := range testCases {
+		ast, err := ParseAST(test.input)
+		require.NoError(t, err, test.input)
+		require.IsType(t, &IsNode{}, ast, test.input)
+		assert.Equal(t, test.expected, ast.(*IsNode).Value.(*LiteralNode).Value, test.input)
+	}
+}
+
+func TestParseEscapedWildcardsInLists(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected Clause
+	}{
+		{`type:(team\*)`, Clause{Field: "type", Operator: "=", Values: []string{`team\*`}}},
+		{`type:(a or b\*)`, Clause{Field: "type", Operator: "IN", Values: []string{"a", `b\*`}}},
+		{`type:(a, b\*)`, Clause{Field: "type", Operator: "IN", Values: []string{"a", `b\*`}}},
+		{`type:(a\\b or "c\*")`, Clause{Field: "type", Operator: "IN", Values: []string{`a\b`, `c\*`}}},
+	}
+	for _, test := range testCases {
+		f, err := Parse(test.input)
+		require.NoError(t, err, test.input)
+		assert.Equal(t, []Clause{test.expected}, f.Clauses, test.input)
+	}
+}
+
+func TestUnescapeValue(t *testing.T) {
+	assert.Equal(t, `b*`, UnescapeValue(`b\*`))
+	assert.Equal(t, `b\*`, UnescapeValue(`b\\*`))
+	assert.Equal(t, `b\c*`, UnescapeValue(`b\\c*`))
+	assert.Equal(t, `b\\c`, UnescapeValue(`b\\c`))
+}
+
+func TestSplitWildcards(t *testing.T) {
+	testCases := []struct {
+		value       string
+		allowPrefix bool
+		allowSuffix bool
+		text        string
+		prefix      bool
+		suffix      bool
+	}{
+		{`john*`, true, true, `john`, true, false},
+		{`john*`, false, true, `john*`, false, false},
+		{`*john`, true, true, `john`, false, true},
+		{`*john*`, true, true, `john`, true, true},
+		{`*john*`, true, false, `*john`, true, false},
+		{`5\*`, true, true, `5*`, false, false},
+		{`5\\*`, true, true, `5\`, true, false},
+		{`\*5*`, true, true, `*5`, true, false},
+		{`*`, true, true, ``, true, false},
+		{`a\b`, true, true, `a\b`, false, false},
+	}
+	for _, test := range testCases {
+		text, prefix, suffix := SplitWildcards(test.value, test.allowPrefix, test.allowSuffix)
+		assert.Equal(t, test.text, text, test.value)
+		assert.Equal(t, test.prefix, prefix, test.value)
+		assert.Equal(t, test.suffix, suffix, test.value)
+	}
+}